@@ -3,10 +3,24 @@ module github.com/fgrimme/time-tracker
 go 1.13
 
 require (
-	github.com/alecthomas/template v0.0.0-20190718012654-fb15b899a751 // indirect
+	github.com/BurntSushi/toml v0.3.1
 	github.com/alecthomas/units v0.0.0-20190924025748-f65c72e2690d // indirect
+	github.com/andybalholm/brotli v1.0.2
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/mux v1.7.3
-	github.com/lib/pq v1.3.0 // indirect
+	github.com/gorilla/websocket v1.4.2
+	github.com/lib/pq v1.3.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/rs/xid v1.2.1
 	github.com/rs/zerolog v1.17.2
-	gopkg.in/alecthomas/kingpin.v2 v2.2.6 // indirect
+	github.com/zenazn/goji v0.9.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.0.0
+	go.opentelemetry.io/otel/exporters/zipkin v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
+	google.golang.org/grpc v1.34.0
+	google.golang.org/protobuf v1.25.0
+	gopkg.in/alecthomas/kingpin.v2 v2.2.6
+	gopkg.in/yaml.v2 v2.3.0
 )
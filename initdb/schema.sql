@@ -1,5 +1,45 @@
+-- trigram similarity finds names of records spelled slightly differently.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- the team of a lead are the users with the lead's id as lead_id.
 CREATE TABLE users (
-    id INT PRIMARY KEY
+    id INT PRIMARY KEY,
+    lead_id INT REFERENCES users(id)
+);
+
+CREATE TABLE clients (
+  id BIGSERIAL PRIMARY KEY,
+  name varchar(256) NOT NULL
+);
+
+CREATE TABLE projects (
+  id BIGSERIAL PRIMARY KEY,
+  client_id BIGINT REFERENCES clients(id),
+  name varchar(256) NOT NULL
+);
+
+-- invoice numbers are sequential without gaps, the counter is incremented in
+-- the transaction creating the invoice.
+CREATE TABLE invoice_numbers (
+  last_number BIGINT NOT NULL
+);
+
+INSERT INTO invoice_numbers(last_number) VALUES(0);
+
+CREATE TABLE invoices (
+  id BIGSERIAL PRIMARY KEY,
+  number BIGINT NOT NULL UNIQUE,
+  client_id BIGINT REFERENCES clients(id) NOT NULL,
+  period_from TIMESTAMP WITH TIME ZONE NOT NULL,
+  period_to TIMESTAMP WITH TIME ZONE NOT NULL,
+  total_amount NUMERIC(14,4) NOT NULL,
+  currency CHAR(3) NOT NULL,
+  -- policy durations were rounded by, an increment of 0 disables rounding
+  rounding_increment BIGINT NOT NULL DEFAULT 0,
+  rounding_mode varchar(10) NOT NULL DEFAULT '',
+  rounding_per varchar(20) NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  voided_at TIMESTAMP WITH TIME ZONE
 );
 
 CREATE TABLE time_records (
@@ -10,9 +50,259 @@ CREATE TABLE time_records (
   start_time_loc varchar(50) NOT NULL,
   stop_time TIMESTAMP WITH TIME ZONE NOT NULL,
   stop_time_loc varchar(50) NOT NULL,
-  duration BIGINT NOT NULL
+  duration BIGINT NOT NULL,
+  project_id BIGINT REFERENCES projects(id),
+  billable BOOLEAN NOT NULL DEFAULT FALSE,
+  -- hourly rate overriding the user, project and client rates
+  rate_amount NUMERIC(14,4) CHECK (rate_amount >= 0),
+  rate_currency CHAR(3),
+  -- records of an invoice are locked against changes until it is voided
+  invoice_id BIGINT REFERENCES invoices(id),
+  -- deleted records stay in the trash until they are restored or purged
+  deleted_at TIMESTAMP WITH TIME ZONE,
+  -- the uuid identifies a record across devices, clients generate it to
+  -- create records offline. The version is incremented by every change.
+  uuid UUID NOT NULL DEFAULT gen_random_uuid(),
+  version BIGINT NOT NULL DEFAULT 1,
+  -- notes describe the work in detail, tags group records across projects
+  notes TEXT NOT NULL DEFAULT '',
+  tags TEXT[] NOT NULL DEFAULT '{}',
+  CONSTRAINT time_records_uuid_key UNIQUE (uuid)
+);
+
+CREATE INDEX time_records_invoice_idx ON time_records(invoice_id);
+CREATE INDEX time_records_deleted_idx ON time_records(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX time_records_tags_idx ON time_records USING GIN (tags);
+
+-- the document of a record searched by full-text search. Words are not
+-- stemmed, users write in different languages, and names weigh more than
+-- notes. Searches must use this function to use the index.
+CREATE FUNCTION time_record_document(name varchar, notes text) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+    setweight(to_tsvector('simple', notes), 'B')
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX time_records_search_idx ON time_records USING GIN (time_record_document(name, notes));
+
+CREATE FUNCTION lock_invoiced_time_records() RETURNS trigger AS $$
+BEGIN
+  IF OLD.invoice_id IS NOT NULL THEN
+    IF TG_OP = 'DELETE' THEN
+      RAISE EXCEPTION 'time record % is locked by invoice %', OLD.id, OLD.invoice_id;
+    END IF;
+    -- only billing or releasing the record is allowed
+    IF (to_jsonb(NEW) - 'invoice_id') IS DISTINCT FROM (to_jsonb(OLD) - 'invoice_id') THEN
+      RAISE EXCEPTION 'time record % is locked by invoice %', OLD.id, OLD.invoice_id;
+    END IF;
+  END IF;
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER time_records_invoice_lock
+  BEFORE UPDATE OR DELETE ON time_records
+  FOR EACH ROW EXECUTE FUNCTION lock_invoiced_time_records();
+
+-- invoice lines fix the rate and amount of a record at the time of invoicing
+CREATE TABLE invoice_lines (
+  invoice_id BIGINT REFERENCES invoices(id) NOT NULL,
+  record_id BIGINT REFERENCES time_records(id) NOT NULL,
+  user_id INT REFERENCES users(id) NOT NULL,
+  name varchar(256),
+  start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+  start_time_loc varchar(50) NOT NULL,
+  duration BIGINT NOT NULL,
+  -- rounded duration, the exact duration is kept in duration
+  billed_duration BIGINT NOT NULL,
+  rate_amount NUMERIC(14,4) NOT NULL,
+  amount NUMERIC(14,4) NOT NULL,
+  PRIMARY KEY (invoice_id, record_id)
+);
+
+-- the history of time records is append-only, every change of a record is
+-- recorded in the transaction making the change. Values are the rows before
+-- and after the change. Records are not referenced since they may be deleted.
+CREATE TABLE record_history (
+  id BIGSERIAL PRIMARY KEY,
+  record_id BIGINT NOT NULL,
+  user_id INT NOT NULL,
+  actor_id INT NOT NULL,
+  action varchar(10) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore', 'purge')),
+  changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  request_id varchar(50) NOT NULL DEFAULT '',
+  before JSONB,
+  after JSONB
+);
+
+CREATE INDEX record_history_record_idx ON record_history(record_id);
+CREATE INDEX record_history_user_idx ON record_history(user_id, id);
+
+CREATE FUNCTION prevent_history_changes() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'record_history is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_history_append_only
+  BEFORE UPDATE OR DELETE ON record_history
+  FOR EACH ROW EXECUTE FUNCTION prevent_history_changes();
+
+-- mutations synced by clients are applied at most once, the outcome of the
+-- first application is returned for replays. Records are not referenced
+-- since they may be purged.
+CREATE TABLE sync_mutations (
+  user_id INT REFERENCES users(id) NOT NULL,
+  mutation_id UUID NOT NULL,
+  record_id BIGINT,
+  status varchar(10) NOT NULL CHECK (status IN ('applied', 'conflict')),
+  reason varchar(20) NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, mutation_id)
+);
+
+-- responses of mutating requests by idempotency key, replayed to retries of
+-- the request. Keys are scoped by the actor, 0 without one, and the method
+-- and path of the request. The status code is NULL while the request is in
+-- progress. Keys are purged after the retention period.
+CREATE TABLE idempotency_keys (
+  actor_id BIGINT NOT NULL DEFAULT 0,
+  method varchar(10) NOT NULL,
+  path TEXT NOT NULL,
+  key varchar(255) NOT NULL,
+  request_hash char(64) NOT NULL,
+  status_code INT,
+  content_type TEXT NOT NULL DEFAULT '',
+  body BYTEA,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  PRIMARY KEY (actor_id, method, path, key)
+);
+
+CREATE INDEX idempotency_keys_created_idx ON idempotency_keys(created_at);
+
+-- token buckets of the rate limits shared by all instances of the service.
+-- Buckets not used for a while are full and purged.
+CREATE TABLE rate_limits (
+  key varchar(255) PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  allowed BOOLEAN NOT NULL,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX rate_limits_updated_idx ON rate_limits(updated_at);
+
+-- hourly rates are append-only, a rate change is a new row with a later
+-- effective date so the history of rates is preserved.
+CREATE TABLE rates (
+  id BIGSERIAL PRIMARY KEY,
+  scope varchar(10) NOT NULL CHECK (scope IN ('user', 'project', 'client')),
+  scope_id BIGINT NOT NULL,
+  amount NUMERIC(14,4) NOT NULL CHECK (amount >= 0),
+  currency CHAR(3) NOT NULL,
+  effective_from TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX rates_scope_idx ON rates(scope, scope_id, effective_from);
+
+-- timesheets are submitted per user and ISO week, the period is the week in
+-- the location of the timesheet. Records starting inside an approved
+-- timesheet can not be changed.
+CREATE TABLE timesheets (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT REFERENCES users(id) NOT NULL,
+  iso_year INT NOT NULL,
+  iso_week INT NOT NULL CHECK (iso_week BETWEEN 1 AND 53),
+  week_loc varchar(50) NOT NULL,
+  period_from TIMESTAMP WITH TIME ZONE NOT NULL,
+  period_to TIMESTAMP WITH TIME ZONE NOT NULL,
+  state varchar(10) NOT NULL DEFAULT 'draft' CHECK (state IN ('draft', 'submitted', 'approved', 'rejected')),
+  UNIQUE (user_id, iso_year, iso_week)
+);
+
+CREATE INDEX timesheets_state_idx ON timesheets(state, user_id);
+
+CREATE TABLE timesheet_events (
+  id BIGSERIAL PRIMARY KEY,
+  timesheet_id BIGINT REFERENCES timesheets(id) NOT NULL,
+  state varchar(10) NOT NULL,
+  actor_id INT NOT NULL,
+  comment TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX timesheet_events_timesheet_idx ON timesheet_events(timesheet_id, id);
+
+-- a user has at most one running timer, stopping it creates a time record.
+CREATE TABLE timers (
+  user_id INT PRIMARY KEY REFERENCES users(id),
+  name varchar(256) NOT NULL DEFAULT '',
+  start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+  start_time_loc varchar(50) NOT NULL,
+  project_id BIGINT REFERENCES projects(id),
+  billable BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- preferences of a user how dates, times and durations are shown, see
+-- locale.Preferences. Empty columns fall back to the locale.
+CREATE TABLE user_profiles (
+  user_id INT PRIMARY KEY REFERENCES users(id),
+  locale varchar(35) NOT NULL DEFAULT '',
+  clock varchar(3) NOT NULL DEFAULT '',
+  decimal_hours BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- webhook subscriptions without user_id receive the events of all users.
+CREATE TABLE webhook_subscriptions (
+  id BIGSERIAL PRIMARY KEY,
+  user_id INT REFERENCES users(id),
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  events TEXT[] NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- the outbox holds the events to deliver to each subscription. Events are
+-- inserted in the transaction making the change and delivered in the
+-- background, failed deliveries are retried at next_attempt_at.
+CREATE TABLE webhook_outbox (
+  id BIGSERIAL PRIMARY KEY,
+  subscription_id BIGINT REFERENCES webhook_subscriptions(id) ON DELETE CASCADE NOT NULL,
+  event varchar(30) NOT NULL,
+  data JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+  state varchar(10) NOT NULL DEFAULT 'pending' CHECK (state IN ('pending', 'delivered', 'failed')),
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_outbox_due_idx ON webhook_outbox(next_attempt_at, id) WHERE state = 'pending';
+
+-- the delivery log records every delivery attempt.
+CREATE TABLE webhook_deliveries (
+  id BIGSERIAL PRIMARY KEY,
+  outbox_id BIGINT REFERENCES webhook_outbox(id) ON DELETE CASCADE NOT NULL,
+  subscription_id BIGINT REFERENCES webhook_subscriptions(id) ON DELETE CASCADE NOT NULL,
+  attempt INT NOT NULL,
+  status_code INT NOT NULL DEFAULT 0,
+  error TEXT NOT NULL DEFAULT '',
+  duration_ms BIGINT NOT NULL,
+  delivered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
 );
 
+CREATE INDEX webhook_deliveries_subscription_idx ON webhook_deliveries(subscription_id, id);
+
+-- version of this schema, the service is not ready unless it matches the
+-- version it requires (store.SchemaVersion). Increment both with every change
+-- of the schema.
+CREATE TABLE schema_version (
+  version INT NOT NULL
+);
+
+INSERT INTO schema_version(version) VALUES(7);
+
 INSERT INTO users(id) VALUES(42);
 
 INSERT INTO
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+// Report reports the records of a user in a period with durations rounded
+// according to rounding. Durations are not rounded if the increment of
+// rounding is 0.
+func (c *Client) Report(ctx context.Context, userID uint64, p Period, rounding billing.Rounding) (*Report, error) {
+	q := p.query(userID)
+	if rounding.Increment != 0 {
+		q.Set("round", strconv.FormatInt(rounding.Increment, 10))
+		if rounding.Mode != "" {
+			q.Set("round_mode", string(rounding.Mode))
+		}
+		if rounding.Per != "" {
+			q.Set("round_per", string(rounding.Per))
+		}
+	}
+	var rep Report
+	if err := c.do(ctx, http.MethodGet, "/report", q, nil, &rep); err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+// CreateRate creates an hourly rate. It is effective from now if its
+// effective date is zero.
+func (c *Client) CreateRate(ctx context.Context, r billing.Rate) (*billing.Rate, error) {
+	var created billing.Rate
+	if err := c.do(ctx, http.MethodPost, "/rate", nil, r, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Rates returns the rates applying to a user.
+func (c *Client) Rates(ctx context.Context, userID uint64) ([]billing.Rate, error) {
+	var rates []billing.Rate
+	if err := c.do(ctx, http.MethodGet, "/rates", userQuery(userID), nil, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// CreateInvoice invoices the unbilled records of a client in a period. It
+// fails with the error codes nothing_to_bill, unrated_records or
+// mixed_currencies if the records cannot be billed.
+func (c *Client) CreateInvoice(ctx context.Context, ir InvoiceRequest) (*Invoice, error) {
+	var inv Invoice
+	if err := c.do(ctx, http.MethodPost, "/invoice", nil, ir, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// Invoice returns an invoice.
+func (c *Client) Invoice(ctx context.Context, id uint64) (*Invoice, error) {
+	var inv Invoice
+	if err := c.do(ctx, http.MethodGet, path("invoices", id), nil, nil, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// VoidInvoice voids an invoice and unlocks its records.
+func (c *Client) VoidInvoice(ctx context.Context, id uint64) (*Invoice, error) {
+	var inv Invoice
+	if err := c.do(ctx, http.MethodPost, path("invoices", id, "void"), nil, nil, &inv); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
@@ -0,0 +1,261 @@
+// Package client is a Go client of the time tracker HTTP API.
+//
+// Every method takes a context which cancels the request, including the
+// waits between retries. Idempotent requests, which are GET, PUT and DELETE
+// requests, are retried on network errors, temporary server errors and when
+// they are rate limited, not before the time of the Retry-After header. POST
+// requests are retried as well if their context carries an idempotency key,
+// see WithIdempotencyKey.
+// Requests the server rejects return an api.Error holding the error code of
+// the response, e.g. not_found.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Defaults of new clients.
+const (
+	DefaultRetries = 3
+	DefaultBackoff = 100 * time.Millisecond
+	DefaultTimeout = 10 * time.Second
+)
+
+// Client is a client of the time tracker API. Its fields must not be changed
+// while requests are made.
+type Client struct {
+	// ActorID is sent as the Actor-Id header, so changes are recorded in
+	// the history on behalf of the actor. It is omitted if 0.
+	ActorID uint64
+	// Retries is the number of times idempotent requests are retried.
+	Retries int
+	// Backoff is the wait before the first retry, it doubles with every
+	// further retry.
+	Backoff time.Duration
+
+	baseURL *url.URL
+	http    *http.Client
+}
+
+// New returns a client of the API at baseURL, e.g. http://localhost:8080.
+// Requests are sent with hc, a client with DefaultTimeout is used if hc is
+// nil.
+func New(baseURL string, hc *http.Client) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("base url must be an absolute http or https url")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if hc == nil {
+		hc = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Client{
+		Retries: DefaultRetries,
+		Backoff: DefaultBackoff,
+		baseURL: u,
+		http:    hc,
+	}, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context which sends requests with the key as
+// Idempotency-Key header. The server replays the response of the first
+// request with the key to retries, so requests with a key are retried like
+// idempotent requests. Use a new key, e.g. from NewUUID, for every change.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// do sends a request with the JSON encoding of in as body, if in is not nil,
+// and decodes the JSON response into out, if out is not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decoding response: %v", method, resp.Request.URL, err)
+	}
+	return nil
+}
+
+// send sends a request and retries idempotent requests. Responses with a
+// status other than 2xx are returned as api.Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, in interface{}) (*http.Response, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	attempts := 1
+	if idempotent(method) || key != "" {
+		attempts += c.Retries
+	}
+	backoff := c.Backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, method, u.String(), key, body)
+		if attempt == attempts || !temporary(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := backoff
+		if resp != nil {
+			// rate limited requests are retried when the server says so
+			if d := retryAfter(resp); d > wait {
+				wait = d
+			}
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(wait):
+			backoff *= 2
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, url, key string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// records are formatted in the locale of the user unless asked for RFC
+	// 3339, which does not depend on the user's preferences
+	req.Header.Set("Accept", "application/json; format=rfc3339")
+	if c.ActorID != 0 {
+		req.Header.Set("Actor-Id", strconv.FormatUint(c.ActorID, 10))
+	}
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	// the request continues the trace of ctx, if any
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	return resp, decodeError(resp)
+}
+
+// decodeError returns the error of a response. The body of responses which
+// are not JSON errors, like from proxies, is used as error code.
+func decodeError(resp *http.Response) error {
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	// the body is read already, the response is kept for the status
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	e := &api.Error{Response: resp}
+	if json.Unmarshal(data, e) != nil || e.Err == "" {
+		e.Err = strings.TrimSpace(string(data))
+	}
+	if e.Err == "" {
+		e.Err = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// idempotent reports whether requests with the method can be retried without
+// repeating their effect.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// temporary reports whether a request failed with a network or server error
+// which may not occur again.
+func temporary(resp *http.Response, err error) bool {
+	if resp == nil {
+		return err != nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusConflict:
+		// the first request with the idempotency key is in progress
+		return ErrorCode(err) == "idempotency_key_in_use"
+	}
+	return false
+}
+
+// retryAfter returns the time to wait of the Retry-After header in seconds,
+// zero if there is none.
+func retryAfter(resp *http.Response) time.Duration {
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
+
+// ErrorCode returns the error code of an error returned by the client, e.g.
+// not_found, or an empty string if the request did not get a response.
+func ErrorCode(err error) string {
+	var e *api.Error
+	if errors.As(err, &e) {
+		return e.Err
+	}
+	return ""
+}
+
+// StatusCode returns the HTTP status code of an error returned by the client
+// or 0 if the request did not get a response.
+func StatusCode(err error) int {
+	var e *api.Error
+	if errors.As(err, &e) && e.Response != nil {
+		return e.Response.StatusCode
+	}
+	return 0
+}
+
+// path joins the path segments, e.g. path("records", 1, "restore").
+func path(segments ...interface{}) string {
+	var b strings.Builder
+	for _, s := range segments {
+		fmt.Fprintf(&b, "/%v", s)
+	}
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CreateRecord creates a time record.
+func (c *Client) CreateRecord(ctx context.Context, r Record) (*Record, error) {
+	var created Record
+	if err := c.do(ctx, http.MethodPost, "/record", nil, r, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Records returns the records of a user stopped in or after the start of the
+// period, latest first.
+func (c *Client) Records(ctx context.Context, userID uint64, p Period) ([]Record, error) {
+	var recs []Record
+	if err := c.do(ctx, http.MethodGet, "/records", p.query(userID), nil, &recs); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// RecordsBetween returns the records of a user overlapping the range from
+// inclusive to exclusive, latest first. The days are those in the location of
+// from, which must be a tz-database location, e.g. Europe/Berlin.
+func (c *Client) RecordsBetween(ctx context.Context, userID uint64, from, to time.Time) ([]Record, error) {
+	recs, err := c.Records(ctx, userID, Period{Unit: Day, Time: from})
+	if err != nil {
+		return nil, err
+	}
+	// the records of the day from is in are returned by the API
+	var between []Record
+	for _, r := range recs {
+		if r.Stop.After(from) && r.Start.Before(to) {
+			between = append(between, r)
+		}
+	}
+	return between, nil
+}
+
+// Search is a full-text search of the names and notes of a user's records.
+// Quoted phrases of Text match consecutive words, words ending in * match
+// words with the prefix. The other fields filter the records, zero values
+// do not filter.
+type Search struct {
+	Text      string
+	From, To  time.Time // records stopped at or after From and started before To
+	ProjectID uint64
+	Tags      []string // records with all of the tags
+	Limit     int      // the server's default limit applies if 0
+}
+
+// SearchResult is a record matching a search. The snippet is the matched
+// part of the name and notes as HTML, matched words are highlighted as
+// <mark>word</mark>.
+type SearchResult struct {
+	Record  Record  `json:"record"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchRecords returns the records of a user matching the search, best
+// matches first.
+func (c *Client) SearchRecords(ctx context.Context, userID uint64, s Search) ([]SearchResult, error) {
+	q := userQuery(userID)
+	q.Set("q", s.Text)
+	if !s.From.IsZero() {
+		q.Set("from", strconv.FormatInt(s.From.Unix(), 10))
+	}
+	if !s.To.IsZero() {
+		q.Set("to", strconv.FormatInt(s.To.Unix(), 10))
+	}
+	if s.ProjectID != 0 {
+		q.Set("project_id", strconv.FormatUint(s.ProjectID, 10))
+	}
+	q["tag"] = s.Tags
+	if s.Limit > 0 {
+		q.Set("limit", strconv.Itoa(s.Limit))
+	}
+	var results []SearchResult
+	if err := c.do(ctx, http.MethodGet, "/records/search", q, nil, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpdateRecord replaces the time record with the id of r.
+func (c *Client) UpdateRecord(ctx context.Context, r Record) (*Record, error) {
+	var updated Record
+	if err := c.do(ctx, http.MethodPut, path("records", r.RecordID), nil, r, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteRecord moves a time record to the trash.
+func (c *Client) DeleteRecord(ctx context.Context, id uint64) error {
+	return c.do(ctx, http.MethodDelete, path("records", id), nil, nil, nil)
+}
+
+// RestoreRecord restores a time record from the trash.
+func (c *Client) RestoreRecord(ctx context.Context, id uint64) (*Record, error) {
+	var restored Record
+	if err := c.do(ctx, http.MethodPost, path("records", id, "restore"), nil, nil, &restored); err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// Trash returns the deleted records of a user.
+func (c *Client) Trash(ctx context.Context, userID uint64) ([]Record, error) {
+	var recs []Record
+	if err := c.do(ctx, http.MethodGet, "/trash", userQuery(userID), nil, &recs); err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// History returns the changes of a time record, oldest first.
+func (c *Client) History(ctx context.Context, recordID uint64) ([]Change, error) {
+	var changes []Change
+	if err := c.do(ctx, http.MethodGet, path("records", recordID, "history"), nil, nil, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Changes returns up to limit changes of a user's records after the change
+// with the id since. The server's default limit applies if limit is 0.
+func (c *Client) Changes(ctx context.Context, userID, since uint64, limit int) ([]Change, error) {
+	q := userQuery(userID)
+	q.Set("since", strconv.FormatUint(since, 10))
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var changes []Change
+	if err := c.do(ctx, http.MethodGet, "/history", q, nil, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func userQuery(userID uint64) url.Values {
+	return url.Values{"user_id": {strconv.FormatUint(userID, 10)}}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NameSuggestion is a name a user gave records, with the project and tags
+// the user usually gives records with the name.
+type NameSuggestion struct {
+	Name      string    `json:"name"`
+	Records   uint64    `json:"records"`
+	LastUsed  time.Time `json:"last_used"`
+	ProjectID uint64    `json:"project_id,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+}
+
+// NameSuggestions returns up to limit names of a user's records completing
+// the prefix, names starting with it first and names used more often and
+// more recently higher. The server's default limit applies if limit is 0.
+func (c *Client) NameSuggestions(ctx context.Context, userID uint64, prefix string, limit int) ([]NameSuggestion, error) {
+	q := userQuery(userID)
+	if prefix != "" {
+		q.Set("prefix", prefix)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var suggestions []NameSuggestion
+	if err := c.do(ctx, http.MethodGet, "/suggestions/names", q, nil, &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// NameMerge renames a user's records with the given names, or with names
+// similar to the canonical name if there are none, to the canonical name.
+// The server's default similarity applies if Similarity is 0.
+type NameMerge struct {
+	UserID     uint64   `json:"user_id"`
+	Name       string   `json:"name"`
+	Names      []string `json:"names,omitempty"`
+	Similarity float64  `json:"similarity,omitempty"`
+	DryRun     bool     `json:"dry_run,omitempty"` // report without renaming
+}
+
+// MergedName is a name merged into the canonical name. Locked records keep
+// their name.
+type MergedName struct {
+	Name    string `json:"name"`
+	Renamed uint64 `json:"renamed"`
+	Locked  uint64 `json:"locked"`
+}
+
+// NameMergeResult is the outcome of a name merge.
+type NameMergeResult struct {
+	Name   string       `json:"name"`
+	Merged []MergedName `json:"merged"`
+	DryRun bool         `json:"dry_run,omitempty"`
+}
+
+// MergeNames renames the records of a name merge.
+func (c *Client) MergeNames(ctx context.Context, m NameMerge) (*NameMergeResult, error) {
+	var result NameMergeResult
+	if err := c.do(ctx, http.MethodPost, "/suggestions/names/merge", nil, m, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Operations of mutations.
+const (
+	Upsert = "upsert"
+	Delete = "delete"
+)
+
+// Outcomes of mutations and reasons of conflicts, see MutationResult.
+const (
+	Applied  = "applied"
+	Conflict = "conflict"
+
+	ConflictStale   = "stale"   // the record changed on the server since the base version
+	ConflictDeleted = "deleted" // the record is in the trash or purged
+	ConflictExists  = "exists"  // a record with the uuid exists already
+	ConflictLocked  = "locked"  // the record is locked by an invoice or an approved timesheet
+)
+
+// Mutation is a change of a record made while offline. Records are
+// identified by their uuid, generate it with NewUUID for new records and the
+// mutation id for every mutation. Syncing a mutation again, e.g. after a
+// network error, returns the outcome of its first application.
+type Mutation struct {
+	MutationID  string  `json:"mutation_id"`
+	Op          string  `json:"op"`           // Upsert or Delete
+	UUID        string  `json:"uuid"`         // uuid of the record
+	BaseVersion uint64  `json:"base_version"` // version the change is based on, 0 for new records
+	Record      *Record `json:"record,omitempty"`
+}
+
+// MutationResult is the outcome of a mutation. For conflicts, Record is the
+// record as kept by the server, nil if it is purged or not accessible.
+type MutationResult struct {
+	MutationID string  `json:"mutation_id"`
+	Status     string  `json:"status"` // Applied or Conflict
+	Reason     string  `json:"reason"` // reason of a conflict
+	Record     *Record `json:"record"`
+}
+
+// SyncResult reports the outcome of every mutation in the order they were
+// synced.
+type SyncResult struct {
+	Applied   int              `json:"applied"`
+	Conflicts int              `json:"conflicts"`
+	Results   []MutationResult `json:"results"`
+}
+
+// Kinds of feed entries.
+const (
+	FeedCreate    = "create"
+	FeedUpdate    = "update"
+	FeedTombstone = "tombstone"
+)
+
+// FeedEntry is the latest change of a record. Tombstones of deleted or
+// purged records have no record.
+type FeedEntry struct {
+	Cursor   uint64  `json:"cursor"`
+	Kind     string  `json:"type"`
+	RecordID uint64  `json:"record_id"`
+	UUID     string  `json:"uuid"`
+	Version  uint64  `json:"version"`
+	Record   *Record `json:"record"`
+}
+
+// Feed is a page of the records changed after a cursor. Pass Cursor to the
+// next call of Feed to continue.
+type Feed struct {
+	Entries []FeedEntry `json:"changes"`
+	Cursor  uint64      `json:"cursor"`
+	More    bool        `json:"more"` // more entries may follow
+}
+
+// Sync applies the mutations of a user in order, at most 100 at a time.
+// Conflicting mutations are not applied, the server's state wins and is
+// returned in their result.
+func (c *Client) Sync(ctx context.Context, userID uint64, mutations []Mutation) (*SyncResult, error) {
+	req := struct {
+		UserID    uint64     `json:"user_id"`
+		Mutations []Mutation `json:"mutations"`
+	}{userID, mutations}
+	if req.Mutations == nil {
+		req.Mutations = []Mutation{}
+	}
+	var res SyncResult
+	if err := c.do(ctx, http.MethodPost, "/sync", nil, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Feed returns up to limit records of a user changed after the cursor since,
+// ordered by their latest change. Pass 0 as since to get all records. The
+// server's default limit applies if limit is 0.
+func (c *Client) Feed(ctx context.Context, userID, since uint64, limit int) (*Feed, error) {
+	q := userQuery(userID)
+	if since > 0 {
+		q.Set("since", strconv.FormatUint(since, 10))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var f Feed
+	if err := c.do(ctx, http.MethodGet, "/changes", q, nil, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// NewUUID returns a random (version 4) UUID to identify records and
+// mutations created by the client.
+func NewUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// StartTimer starts a timer for the user of t. It fails with the error code
+// timer_running if the user has a running timer.
+func (c *Client) StartTimer(ctx context.Context, t Timer) (*Timer, error) {
+	var started Timer
+	if err := c.do(ctx, http.MethodPost, "/timer/start", nil, t, &started); err != nil {
+		return nil, err
+	}
+	return &started, nil
+}
+
+// Timer returns the running timer of a user. It fails with the error code
+// not_found if no timer is running.
+func (c *Client) Timer(ctx context.Context, userID uint64) (*Timer, error) {
+	var t Timer
+	if err := c.do(ctx, http.MethodGet, "/timer", userQuery(userID), nil, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// StopTimer stops the running timer of a user at stop in the tz-database
+// location stopLoc and returns the created time record. The server's time is
+// used if stop is zero and the location of stop if stopLoc is empty.
+func (c *Client) StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*Record, error) {
+	req := struct {
+		UserID  uint64 `json:"user_id"`
+		Stop    int64  `json:"stop_time,omitempty"`
+		StopLoc string `json:"stop_loc"`
+	}{
+		UserID:  userID,
+		StopLoc: zone(stopLoc, stop),
+	}
+	if !stop.IsZero() {
+		req.Stop = stop.Unix()
+	}
+	var rec Record
+	if err := c.do(ctx, http.MethodPost, "/timer/stop", nil, req, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateTimesheet creates the draft timesheet of a user for an ISO week in
+// the tz-database zone, or returns the existing one.
+func (c *Client) CreateTimesheet(ctx context.Context, userID uint64, year, week int, zone string) (*Timesheet, error) {
+	req := struct {
+		UserID uint64 `json:"user_id"`
+		Year   int    `json:"year"`
+		Week   int    `json:"week"`
+		Tz     string `json:"tz"`
+	}{userID, year, week, zone}
+	var t Timesheet
+	if err := c.do(ctx, http.MethodPost, "/timesheet", nil, req, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Timesheets returns the timesheets of a user.
+func (c *Client) Timesheets(ctx context.Context, userID uint64) ([]Timesheet, error) {
+	var sheets []Timesheet
+	if err := c.do(ctx, http.MethodGet, "/timesheets", userQuery(userID), nil, &sheets); err != nil {
+		return nil, err
+	}
+	return sheets, nil
+}
+
+// PendingTimesheets returns the submitted timesheets waiting for the approval
+// of a lead.
+func (c *Client) PendingTimesheets(ctx context.Context, leadID uint64) ([]Timesheet, error) {
+	q := url.Values{"lead_id": {strconv.FormatUint(leadID, 10)}}
+	var sheets []Timesheet
+	if err := c.do(ctx, http.MethodGet, "/timesheets/pending", q, nil, &sheets); err != nil {
+		return nil, err
+	}
+	return sheets, nil
+}
+
+// Timesheet returns a timesheet with its events and records.
+func (c *Client) Timesheet(ctx context.Context, id uint64) (*Timesheet, error) {
+	var t Timesheet
+	if err := c.do(ctx, http.MethodGet, path("timesheets", id), nil, nil, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SubmitTimesheet submits a timesheet for approval on behalf of the actor of
+// the client.
+func (c *Client) SubmitTimesheet(ctx context.Context, id uint64) (*Timesheet, error) {
+	return c.setTimesheetState(ctx, id, "submit", "")
+}
+
+// ApproveTimesheet approves a submitted timesheet on behalf of the actor of
+// the client, who must be the lead of its user.
+func (c *Client) ApproveTimesheet(ctx context.Context, id uint64) (*Timesheet, error) {
+	return c.setTimesheetState(ctx, id, "approve", "")
+}
+
+// RejectTimesheet rejects a submitted timesheet on behalf of the actor of the
+// client. The comment explains what needs to be fixed and is required.
+func (c *Client) RejectTimesheet(ctx context.Context, id uint64, comment string) (*Timesheet, error) {
+	return c.setTimesheetState(ctx, id, "reject", comment)
+}
+
+func (c *Client) setTimesheetState(ctx context.Context, id uint64, action, comment string) (*Timesheet, error) {
+	req := struct {
+		Comment string `json:"comment"`
+	}{comment}
+	var t Timesheet
+	if err := c.do(ctx, http.MethodPost, path("timesheets", id, action), nil, req, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,599 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+// timeFormat is the format of points in time in the user's location in
+// responses of the API that are not formatted in RFC 3339.
+const timeFormat = "02 Jan 2006 15:04:05"
+
+// Periods records and reports are queried for.
+const (
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// Period is the day, week or month containing a point in time in a location.
+type Period struct {
+	Unit string    // Day, Week or Month, defaults to Day
+	Time time.Time // point in time in the period
+	// Zone is the tz-database name of the location, e.g. Europe/Berlin. It
+	// defaults to the location of Time.
+	Zone string
+}
+
+func (p Period) query(userID uint64) url.Values {
+	zone := p.Zone
+	if zone == "" {
+		zone = p.Time.Location().String()
+	}
+	unit := p.Unit
+	if unit == "" {
+		unit = Day
+	}
+	return url.Values{
+		"user_id": {strconv.FormatUint(userID, 10)},
+		"tz":      {zone},
+		"ts":      {strconv.FormatInt(p.Time.Unix(), 10)},
+		"period":  {unit},
+	}
+}
+
+// Record is a time record. The start and stop are in the user's location at
+// the time, given by the tz-database names StartLoc and StopLoc.
+type Record struct {
+	RecordID  uint64
+	UserID    uint64
+	Name      string
+	Start     time.Time
+	StartLoc  string
+	Stop      time.Time
+	StopLoc   string
+	Duration  time.Duration // defaults to the time between start and stop
+	ProjectID uint64        // 0 if not assigned to a project
+	ClientID  uint64        // client of the project, set by the server
+	Billable  bool
+	Rate      *billing.Money // overrides the rates of the user, project and client
+	InvoiceID uint64         // invoice the record is locked by, set by the server
+	Deleted   *time.Time     // time the record was moved to the trash
+	UUID      string         // identifies the record across devices, see NewUUID
+	Version   uint64         // incremented by every change, set by the server
+	Notes     string         // details of the work
+	Tags      []string       // stored in lower case without duplicates
+}
+
+// MarshalJSON encodes the record as payload of requests, the start and stop
+// in seconds since UNIX epoch.
+func (r Record) MarshalJSON() ([]byte, error) {
+	d := r.Duration
+	if d == 0 {
+		d = r.Stop.Sub(r.Start)
+	}
+	return json.Marshal(struct {
+		RecordID  uint64         `json:"record_id,omitempty"`
+		UserID    uint64         `json:"user_id"`
+		Name      string         `json:"name"`
+		Start     int64          `json:"start_time"`
+		StartLoc  string         `json:"start_loc"`
+		Stop      int64          `json:"stop_time"`
+		StopLoc   string         `json:"stop_loc"`
+		Duration  int64          `json:"duration"`
+		ProjectID uint64         `json:"project_id,omitempty"`
+		Billable  bool           `json:"billable,omitempty"`
+		Rate      *billing.Money `json:"rate,omitempty"`
+		UUID      string         `json:"uuid,omitempty"`
+		Notes     string         `json:"notes,omitempty"`
+		Tags      []string       `json:"tags,omitempty"`
+	}{
+		RecordID:  r.RecordID,
+		UserID:    r.UserID,
+		Name:      r.Name,
+		Start:     r.Start.Unix(),
+		StartLoc:  zone(r.StartLoc, r.Start),
+		Stop:      r.Stop.Unix(),
+		StopLoc:   zone(r.StopLoc, r.Stop),
+		Duration:  int64(d / time.Second),
+		ProjectID: r.ProjectID,
+		Billable:  r.Billable,
+		Rate:      r.Rate,
+		UUID:      r.UUID,
+		Notes:     r.Notes,
+		Tags:      r.Tags,
+	})
+}
+
+// UnmarshalJSON decodes a record of a response.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var v struct {
+		RecordID  uint64         `json:"record_id"`
+		UserID    uint64         `json:"user_id"`
+		Name      string         `json:"name"`
+		Start     string         `json:"start_time"`
+		StartLoc  string         `json:"start_loc"`
+		Stop      string         `json:"stop_time"`
+		StopLoc   string         `json:"stop_loc"`
+		Duration  string         `json:"duration"`
+		ProjectID uint64         `json:"project_id"`
+		ClientID  uint64         `json:"client_id"`
+		Billable  bool           `json:"billable"`
+		Rate      *billing.Money `json:"rate"`
+		InvoiceID uint64         `json:"invoice_id"`
+		Deleted   *time.Time     `json:"deleted_at"`
+		UUID      string         `json:"uuid"`
+		Version   uint64         `json:"version"`
+		Notes     string         `json:"notes"`
+		Tags      []string       `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	start, err := parseTime(v.Start, v.StartLoc)
+	if err != nil {
+		return err
+	}
+	stop, err := parseTime(v.Stop, v.StopLoc)
+	if err != nil {
+		return err
+	}
+	d, err := parseDuration(v.Duration)
+	if err != nil {
+		return err
+	}
+	*r = Record{
+		RecordID:  v.RecordID,
+		UserID:    v.UserID,
+		Name:      v.Name,
+		Start:     start,
+		StartLoc:  v.StartLoc,
+		Stop:      stop,
+		StopLoc:   v.StopLoc,
+		Duration:  d,
+		ProjectID: v.ProjectID,
+		ClientID:  v.ClientID,
+		Billable:  v.Billable,
+		Rate:      v.Rate,
+		InvoiceID: v.InvoiceID,
+		Deleted:   v.Deleted,
+		UUID:      v.UUID,
+		Version:   v.Version,
+		Notes:     v.Notes,
+		Tags:      v.Tags,
+	}
+	return nil
+}
+
+// Change is an entry in the history of time records. Before and after hold
+// the stored record, before is null for creations and after for purges.
+type Change struct {
+	ChangeID  uint64          `json:"change_id"`
+	RecordID  uint64          `json:"record_id"`
+	UserID    uint64          `json:"user_id"`
+	ActorID   uint64          `json:"actor_id"`
+	Action    string          `json:"action"` // create, update, delete, restore or purge
+	Changed   time.Time       `json:"changed_at"`
+	RequestID string          `json:"request_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+}
+
+// Timer is the running time record of a user.
+type Timer struct {
+	UserID    uint64
+	Name      string
+	Start     time.Time // defaults to now when started
+	StartLoc  string    // defaults to the location of Start
+	ProjectID uint64
+	Billable  bool
+}
+
+// MarshalJSON encodes the timer as payload of requests to start it.
+func (t Timer) MarshalJSON() ([]byte, error) {
+	v := struct {
+		UserID    uint64 `json:"user_id"`
+		Name      string `json:"name"`
+		Start     int64  `json:"start_time,omitempty"`
+		StartLoc  string `json:"start_loc"`
+		ProjectID uint64 `json:"project_id,omitempty"`
+		Billable  bool   `json:"billable,omitempty"`
+	}{
+		UserID:    t.UserID,
+		Name:      t.Name,
+		StartLoc:  zone(t.StartLoc, t.Start),
+		ProjectID: t.ProjectID,
+		Billable:  t.Billable,
+	}
+	if !t.Start.IsZero() {
+		v.Start = t.Start.Unix()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a timer of a response.
+func (t *Timer) UnmarshalJSON(data []byte) error {
+	var v struct {
+		UserID    uint64 `json:"user_id"`
+		Name      string `json:"name"`
+		Start     string `json:"start_time"`
+		StartLoc  string `json:"start_loc"`
+		ProjectID uint64 `json:"project_id"`
+		Billable  bool   `json:"billable"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	start, err := parseTime(v.Start, v.StartLoc)
+	if err != nil {
+		return err
+	}
+	*t = Timer{
+		UserID:    v.UserID,
+		Name:      v.Name,
+		Start:     start,
+		StartLoc:  v.StartLoc,
+		ProjectID: v.ProjectID,
+		Billable:  v.Billable,
+	}
+	return nil
+}
+
+// Report sums up the records of a user in a period. Rounded durations are
+// rounded according to the rounding policy of the report.
+type Report struct {
+	Duration                time.Duration
+	RoundedDuration         time.Duration
+	BillableDuration        time.Duration
+	RoundedBillableDuration time.Duration
+	UnratedDuration         time.Duration   // billable without applicable rate
+	Amounts                 []billing.Money // billable amounts per currency
+	Rounding                billing.Rounding
+	Lines                   []ReportLine
+}
+
+// ReportLine is the entry of a record in a report.
+type ReportLine struct {
+	RecordID        uint64
+	Name            string
+	ProjectID       uint64
+	Duration        time.Duration
+	RoundedDuration time.Duration
+	Billable        bool
+	Rate            *billing.Money
+	Amount          *billing.Money
+}
+
+// UnmarshalJSON decodes a report of a response.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Duration                duration         `json:"duration"`
+		RoundedDuration         duration         `json:"rounded_duration"`
+		BillableDuration        duration         `json:"billable_duration"`
+		RoundedBillableDuration duration         `json:"rounded_billable_duration"`
+		UnratedDuration         duration         `json:"unrated_duration"`
+		Amounts                 []billing.Money  `json:"amounts"`
+		Rounding                billing.Rounding `json:"rounding"`
+		Lines                   []struct {
+			RecordID        uint64         `json:"record_id"`
+			Name            string         `json:"name"`
+			ProjectID       uint64         `json:"project_id"`
+			Duration        duration       `json:"duration"`
+			RoundedDuration duration       `json:"rounded_duration"`
+			Billable        bool           `json:"billable"`
+			Rate            *billing.Money `json:"rate"`
+			Amount          *billing.Money `json:"amount"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Report{
+		Duration:                time.Duration(v.Duration),
+		RoundedDuration:         time.Duration(v.RoundedDuration),
+		BillableDuration:        time.Duration(v.BillableDuration),
+		RoundedBillableDuration: time.Duration(v.RoundedBillableDuration),
+		UnratedDuration:         time.Duration(v.UnratedDuration),
+		Amounts:                 v.Amounts,
+		Rounding:                v.Rounding,
+		Lines:                   make([]ReportLine, len(v.Lines)),
+	}
+	for i, l := range v.Lines {
+		r.Lines[i] = ReportLine{
+			RecordID:        l.RecordID,
+			Name:            l.Name,
+			ProjectID:       l.ProjectID,
+			Duration:        time.Duration(l.Duration),
+			RoundedDuration: time.Duration(l.RoundedDuration),
+			Billable:        l.Billable,
+			Rate:            l.Rate,
+			Amount:          l.Amount,
+		}
+	}
+	return nil
+}
+
+// InvoiceRequest requests an invoice of the unbilled records of a client in
+// the period from inclusive to exclusive.
+type InvoiceRequest struct {
+	ClientID uint64
+	From     time.Time
+	To       time.Time
+	Rounding billing.Rounding // durations are billed exact if not set
+}
+
+// MarshalJSON encodes the request with the period in seconds since UNIX
+// epoch.
+func (ir InvoiceRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ClientID uint64           `json:"client_id"`
+		From     int64            `json:"from"`
+		To       int64            `json:"to"`
+		Rounding billing.Rounding `json:"rounding"`
+	}{ir.ClientID, ir.From.Unix(), ir.To.Unix(), ir.Rounding})
+}
+
+// Invoice bills the billable time records of a client in a period. Dates are
+// midnight in UTC.
+type Invoice struct {
+	InvoiceID  uint64
+	Number     uint64
+	ClientID   uint64
+	ClientName string
+	From       time.Time
+	To         time.Time // inclusive
+	Issued     time.Time
+	Voided     bool
+	Rounding   billing.Rounding
+	Lines      []InvoiceLine
+	Total      billing.Money
+}
+
+// InvoiceLine is the entry of a record in an invoice.
+type InvoiceLine struct {
+	RecordID uint64
+	UserID   uint64
+	Name     string
+	Date     time.Time // date in the location the work started
+	Hours    string    // billed hours as decimal
+	Duration time.Duration
+	Billed   time.Duration
+	Rate     billing.Money
+	Amount   billing.Money
+}
+
+// UnmarshalJSON decodes an invoice of a response.
+func (inv *Invoice) UnmarshalJSON(data []byte) error {
+	var v struct {
+		InvoiceID  uint64           `json:"invoice_id"`
+		Number     uint64           `json:"number"`
+		ClientID   uint64           `json:"client_id"`
+		ClientName string           `json:"client_name"`
+		From       date             `json:"period_from"`
+		To         date             `json:"period_to"`
+		Issued     date             `json:"issue_date"`
+		Voided     bool             `json:"voided"`
+		Rounding   billing.Rounding `json:"rounding"`
+		Lines      []struct {
+			RecordID uint64        `json:"record_id"`
+			UserID   uint64        `json:"user_id"`
+			Name     string        `json:"name"`
+			Date     date          `json:"date"`
+			Hours    string        `json:"hours"`
+			Duration duration      `json:"duration"`
+			Billed   duration      `json:"billed_duration"`
+			Rate     billing.Money `json:"rate"`
+			Amount   billing.Money `json:"amount"`
+		} `json:"lines"`
+		Total billing.Money `json:"total"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*inv = Invoice{
+		InvoiceID:  v.InvoiceID,
+		Number:     v.Number,
+		ClientID:   v.ClientID,
+		ClientName: v.ClientName,
+		From:       time.Time(v.From),
+		To:         time.Time(v.To),
+		Issued:     time.Time(v.Issued),
+		Voided:     v.Voided,
+		Rounding:   v.Rounding,
+		Lines:      make([]InvoiceLine, len(v.Lines)),
+		Total:      v.Total,
+	}
+	for i, l := range v.Lines {
+		inv.Lines[i] = InvoiceLine{
+			RecordID: l.RecordID,
+			UserID:   l.UserID,
+			Name:     l.Name,
+			Date:     time.Time(l.Date),
+			Hours:    l.Hours,
+			Duration: time.Duration(l.Duration),
+			Billed:   time.Duration(l.Billed),
+			Rate:     l.Rate,
+			Amount:   l.Amount,
+		}
+	}
+	return nil
+}
+
+// Timesheet states.
+const (
+	TimesheetDraft     = "draft"
+	TimesheetSubmitted = "submitted"
+	TimesheetApproved  = "approved"
+	TimesheetRejected  = "rejected"
+)
+
+// Timesheet is the weekly timesheet of a user. Events and records are only
+// returned for single timesheets.
+type Timesheet struct {
+	TimesheetID uint64
+	UserID      uint64
+	Year        int // ISO year
+	Week        int // ISO week
+	Zone        string
+	State       string
+	Start       time.Time // first day of the week in Zone
+	Stop        time.Time // first day of the next week in Zone
+	Events      []TimesheetEvent
+	Records     []Record
+}
+
+// TimesheetEvent is a change of the state of a timesheet.
+type TimesheetEvent struct {
+	State   string    `json:"state"`
+	ActorID uint64    `json:"actor_id"`
+	Comment string    `json:"comment"`
+	Created time.Time `json:"created_at"`
+}
+
+// UnmarshalJSON decodes a timesheet of a response.
+func (t *Timesheet) UnmarshalJSON(data []byte) error {
+	var v struct {
+		TimesheetID uint64           `json:"timesheet_id"`
+		UserID      uint64           `json:"user_id"`
+		Year        int              `json:"year"`
+		Week        int              `json:"week"`
+		Zone        string           `json:"tz"`
+		State       string           `json:"state"`
+		Start       string           `json:"start_time"`
+		Stop        string           `json:"stop_time"`
+		Events      []TimesheetEvent `json:"events"`
+		Records     []Record         `json:"records"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	start, err := parseTime(v.Start, v.Zone)
+	if err != nil {
+		return err
+	}
+	stop, err := parseTime(v.Stop, v.Zone)
+	if err != nil {
+		return err
+	}
+	*t = Timesheet{
+		TimesheetID: v.TimesheetID,
+		UserID:      v.UserID,
+		Year:        v.Year,
+		Week:        v.Week,
+		Zone:        v.Zone,
+		State:       v.State,
+		Start:       start,
+		Stop:        stop,
+		Events:      v.Events,
+		Records:     v.Records,
+	}
+	return nil
+}
+
+// zone returns the name of the location of t if name is empty.
+func zone(name string, t time.Time) string {
+	if name != "" {
+		return name
+	}
+	return t.Location().String()
+}
+
+// parseTime parses a point in time formatted by the API in RFC 3339 or
+// timeFormat in the tz-database location.
+func parseTime(value, loc string) (time.Time, error) {
+	l, err := time.LoadLocation(loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(l), nil
+	}
+	return time.ParseInLocation(timeFormat, value, l)
+}
+
+// parseDuration parses a duration formatted by the API as ISO 8601 duration,
+// e.g. "PT1H30M", or as hours, minutes and seconds, e.g. "01:30:00".
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasPrefix(value, "PT") || strings.HasPrefix(value, "-PT") {
+		return parseISODuration(value)
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// parseISODuration parses an ISO 8601 duration in hours, minutes and
+// seconds, e.g. "PT1H30M".
+func parseISODuration(value string) (time.Duration, error) {
+	s := value
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "PT")
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var d time.Duration
+	for _, unit := range []struct {
+		designator string
+		d          time.Duration
+	}{{"H", time.Hour}, {"M", time.Minute}, {"S", time.Second}} {
+		i := strings.Index(s, unit.designator)
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		d += time.Duration(n) * unit.d
+		s = s[i+1:]
+	}
+	if s != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
+
+// duration is a duration formatted by the API, see parseDuration.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := parseDuration(s)
+	*d = duration(v)
+	return err
+}
+
+// date is a date formatted like 2006-01-02.
+type date time.Time
+
+func (d *date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse("2006-01-02", s)
+	*d = date(t)
+	return err
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+)
+
+// CreateWebhook subscribes a URL to events. The secret is not returned.
+func (c *Client) CreateWebhook(ctx context.Context, s webhook.Subscription) (*webhook.Subscription, error) {
+	var created webhook.Subscription
+	if err := c.do(ctx, http.MethodPost, "/webhook", nil, s, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Webhooks returns the webhook subscriptions without their secrets.
+func (c *Client) Webhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	var subs []webhook.Subscription
+	if err := c.do(ctx, http.MethodGet, "/webhooks", nil, nil, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// DeleteWebhook deletes a webhook subscription.
+func (c *Client) DeleteWebhook(ctx context.Context, id uint64) error {
+	return c.do(ctx, http.MethodDelete, path("webhooks", id), nil, nil, nil)
+}
+
+// Deliveries returns up to limit delivery attempts of a subscription, latest
+// first. The server's default limit applies if limit is 0.
+func (c *Client) Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]webhook.Delivery, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var deliveries []webhook.Delivery
+	if err := c.do(ctx, http.MethodGet, path("webhooks", subscriptionID, "deliveries"), q, nil, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,5 @@
+// Package rpc contains the protocol buffer definition of the gRPC API and the
+// code generated from it. The API is served by the server package.
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative timetracker.proto
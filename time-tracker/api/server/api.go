@@ -5,113 +5,202 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"path"
 	"strconv"
 	"time"
 
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
 	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
 )
 
 // timeRecordStore handles operations on time records.
 type timeRecordStore interface {
 	Create(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error)
 	Get(ctx context.Context, userID uint64, t time.Time) ([]store.TimeRecord, error)
+	Record(ctx context.Context, id uint64) (*store.TimeRecord, error)
+	GetVersion(ctx context.Context, userID uint64, t time.Time) (store.RecordsVersion, error)
+	Update(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error)
+	Delete(ctx context.Context, id uint64) error
+	Restore(ctx context.Context, id uint64) (*store.TimeRecord, error)
+	Trash(ctx context.Context, userID uint64) ([]store.TimeRecord, error)
 }
 
 // recordService provides API methods to operate on time records.
 type timeRecordService struct {
 	timeRecordStore
+	localizer
 	timeout time.Duration
 }
 
 // ServeHTTP serves requests to the time record enpoint.
 func (rs *timeRecordService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// handle CORS preflight requests
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
 	ctx, cancel := context.WithTimeout(r.Context(), rs.timeout)
 	defer cancel()
 	// we attach the logger from the request to the context so we do not need
 	// to pass it as an parameter
 	ctx = loggerFromRequest(r).WithContext(ctx)
+	// changes made by the request are recorded with the actor and request id
+	ctx = store.WithAudit(ctx, auditFromRequest(r))
+	// records are formatted as the client asks, unknown formats are rejected
+	// before anything is changed
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Accept-Language")
+	format, code, err := recordFormat(r)
+	if err != nil {
+		writeError(w, r, err, code)
+		return
+	}
 
-	_, route := path.Split(r.URL.Path)
-	switch route {
+	switch routeName(r) {
 	case "record":
 		var tr store.TimeRecord
 		decoder := json.NewDecoder(r.Body)
 		decoder.DisallowUnknownFields() // catch unwanted fields
 		if err := decoder.Decode(&tr); err != nil {
-			writeError(w, r, err, http.StatusInternalServerError)
+			writeError(w, r, err, http.StatusBadRequest)
 			return
 		}
-		rs.createRecord(ctx, w, r, tr)
+		rs.createRecord(ctx, w, r, tr, format)
 		return
 
 	case "records":
-		q := r.URL.Query()
-
-		// get the user id from the requests params
-		// if not supplied, we consider the request as malformed
-		uid := q.Get("user_id")
-		if len(uid) == 0 {
-			writeError(w, r, errBadRequest, http.StatusBadRequest)
+		pq, code, err := parsePeriodQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
 			return
 		}
-		userID, err := strconv.ParseUint(uid, 10, 64) // mux validates type
+		rs.getRecords(ctx, w, r, pq.userID, pq.t, pq.loc, pq.period, format)
+		return
+
+	case "search":
+		sq, code, err := parseSearchQuery(r)
 		if err != nil {
-			writeError(w, r, errInternal, http.StatusInternalServerError)
+			writeError(w, r, err, code)
 			return
 		}
-		// get the timestamp from the requests params
-		// if not supplied, we consider the request as malformed
-		ts := q.Get("ts")
-		if len(ts) == 0 {
+		rs.searchRecords(ctx, w, r, sq, format)
+		return
+
+	case "record_id":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
 			writeError(w, r, err, http.StatusBadRequest)
 			return
 		}
-		timestamp, err := strconv.ParseInt(ts, 10, 64) // mux validates type
-		if err != nil {
-			writeError(w, r, errInternal, http.StatusInternalServerError)
+		if r.Method == "DELETE" {
+			rs.deleteRecord(ctx, w, r, id)
+			return
+		}
+		var tr store.TimeRecord
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&tr); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
 			return
 		}
-		t := time.Unix(timestamp, 0)
+		tr.RecordID = id
+		rs.updateRecord(ctx, w, r, tr, format)
+		return
 
-		// get the tz-database zone name from the requests params
-		// if not supplied, we assume UTC
-		zone := q.Get("tz")
-		loc, err := time.LoadLocation(zone)
+	case "restore":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
 		if err != nil {
 			writeError(w, r, err, http.StatusBadRequest)
 			return
 		}
+		rs.restoreRecord(ctx, w, r, id, format)
+		return
 
-		// get the time period from the requests params
-		// if not supplied, we assume DAY
-		period := q.Get("period")
-		if len(period) == 0 {
-			period = DAY
+	case "trash":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
 		}
-
-		rs.getRecords(ctx, w, r, userID, t.In(loc), loc, period)
+		rs.getTrash(ctx, w, r, userID, format)
 		return
 	}
 	writeError(w, r, errNotFound, http.StatusNotFound)
 	return
 }
 
-func (rs *timeRecordService) createRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, tr store.TimeRecord) {
+func (rs *timeRecordService) createRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, tr store.TimeRecord, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.createRecord")
+	defer span.End()
 	rec, err := rs.Create(ctx, tr)
+	switch err {
+	case nil:
+		encodeJSON(w, r, rs.formatRecord(ctx, r, rec, format), http.StatusOK)
+	case store.ErrRecordLocked:
+		writeError(w, r, errLocked, http.StatusConflict)
+	case store.ErrRecordExists:
+		writeError(w, r, errExists, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (rs *timeRecordService) updateRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, tr store.TimeRecord, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.updateRecord")
+	defer span.End()
+	rec, err := rs.Update(ctx, tr)
+	switch err {
+	case nil:
+		encodeJSON(w, r, rs.formatRecord(ctx, r, rec, format), http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrRecordLocked:
+		writeError(w, r, errLocked, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (rs *timeRecordService) deleteRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.deleteRecord")
+	defer span.End()
+	switch err := rs.Delete(ctx, id); err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrRecordLocked:
+		writeError(w, r, errLocked, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (rs *timeRecordService) restoreRecord(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.restoreRecord")
+	defer span.End()
+	rec, err := rs.Restore(ctx, id)
+	switch err {
+	case nil:
+		encodeJSON(w, r, rs.formatRecord(ctx, r, rec, format), http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrRecordLocked:
+		writeError(w, r, errLocked, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (rs *timeRecordService) getTrash(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.getTrash")
+	defer span.End()
+	recs, err := rs.Trash(ctx, userID)
 	if err != nil {
 		writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
-	encodeJSON(w, r, rec, http.StatusOK)
+	encodeJSON(w, r, rs.formatRecords(ctx, r, userID, recs, format), http.StatusOK)
 }
 
-func (rs *timeRecordService) getRecords(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64, t time.Time, loc *time.Location, period string) {
+func (rs *timeRecordService) getRecords(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64, t time.Time, loc *time.Location, period string, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.getRecords")
+	defer span.End()
 	day, err := getStartOfPeriod(t, loc, period)
 	if err != nil {
 		writeError(w, r, err, http.StatusInternalServerError)
@@ -122,7 +211,114 @@ func (rs *timeRecordService) getRecords(ctx context.Context, w http.ResponseWrit
 		writeError(w, r, err, http.StatusInternalServerError)
 		return
 	}
-	encodeJSON(w, r, recs, http.StatusOK)
+	encodeJSON(w, r, rs.formatRecords(ctx, r, userID, recs, format), http.StatusOK)
+}
+
+// recordsTag returns the ETag of the records of a period requested by r, see
+// store.RecordsVersion. The tag depends on the format and locale of the
+// records. Invalid queries get no tag, they are rejected when they are served.
+func (rs *timeRecordService) recordsTag(r *http.Request) (string, error) {
+	pq, _, err := parsePeriodQuery(r)
+	if err != nil {
+		return "", nil
+	}
+	format, _, err := recordFormat(r)
+	if err != nil {
+		return "", nil
+	}
+	day, err := getStartOfPeriod(pq.t, pq.loc, pq.period)
+	if err != nil {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), rs.timeout)
+	defer cancel()
+	v, err := rs.GetVersion(ctx, pq.userID, day)
+	if err != nil {
+		return "", err
+	}
+	tag := fmt.Sprintf("%d-%d-%d", v.Count, v.MaxID, v.VersionSum)
+	if format != store.FormatHuman {
+		tag += "-" + string(format)
+	} else if f := rs.formatter(ctx, r, pq.userID); f.Key() != (locale.Formatter{}).Key() {
+		tag += "-" + f.Key()
+	}
+	return tag, nil
+}
+
+// periodQuery holds the query parameters of requests for a user's records in
+// a certain period.
+type periodQuery struct {
+	userID uint64
+	t      time.Time // time in loc
+	loc    *time.Location
+	period string
+}
+
+// parsePeriodQuery parses the query parameters of requests for a user's
+// records in a certain period. Returns the HTTP status code to respond with if
+// the parameters are invalid.
+func parsePeriodQuery(r *http.Request) (periodQuery, int, error) {
+	userID, code, err := parseUserQuery(r)
+	if err != nil {
+		return periodQuery{}, code, err
+	}
+	q := r.URL.Query()
+
+	// get the timestamp from the requests params
+	// if not supplied, we consider the request as malformed
+	ts := q.Get("ts")
+	if len(ts) == 0 {
+		return periodQuery{}, http.StatusBadRequest, errBadRequest
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64) // mux validates type
+	if err != nil {
+		return periodQuery{}, http.StatusInternalServerError, errInternal
+	}
+
+	pq, err := newPeriodQuery(userID, time.Unix(timestamp, 0), q.Get("tz"), q.Get("period"))
+	if err != nil {
+		return periodQuery{}, http.StatusBadRequest, err
+	}
+	return pq, 0, nil
+}
+
+// newPeriodQuery returns the query for a user's records in the period of the
+// given kind containing t in the tz-database zone. An empty zone is UTC and
+// an empty period a day.
+func newPeriodQuery(userID uint64, t time.Time, zone string, period string) (periodQuery, error) {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return periodQuery{}, err
+	}
+	switch period {
+	case "":
+		period = DAY
+	case DAY, WEEK, MONTH:
+	default:
+		return periodQuery{}, fmt.Errorf("unknown period: %s", period)
+	}
+	return periodQuery{
+		userID: userID,
+		t:      t.In(loc),
+		loc:    loc,
+		period: period,
+	}, nil
+}
+
+// parseUserQuery parses the user id from the query parameters. Returns the
+// HTTP status code to respond with if the parameter is invalid.
+func parseUserQuery(r *http.Request) (uint64, int, error) {
+	// get the user id from the requests params
+	// if not supplied, we consider the request as malformed
+	uid := r.URL.Query().Get("user_id")
+	if len(uid) == 0 {
+		return 0, http.StatusBadRequest, errBadRequest
+	}
+	userID, err := strconv.ParseUint(uid, 10, 64) // mux validates type
+	if err != nil {
+		return 0, http.StatusInternalServerError, errInternal
+	}
+	return userID, 0, nil
 }
 
 // getStartOfPeriod returns the start or the first day in the given period.
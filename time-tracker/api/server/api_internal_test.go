@@ -13,7 +13,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
 	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
 )
 
 // uses the user id to get the test data.
@@ -32,6 +34,24 @@ func (rs *mockTimeRecordStore) Create(ctx context.Context, r store.TimeRecord) (
 func (rs *mockTimeRecordStore) Get(ctx context.Context, userID uint64, t time.Time) ([]store.TimeRecord, error) {
 	return make([]store.TimeRecord, 1), getRecordTests[userID].e
 }
+func (rs *mockTimeRecordStore) Record(ctx context.Context, id uint64) (*store.TimeRecord, error) {
+	return &store.TimeRecord{RecordID: id}, changeRecordTests[id].e
+}
+func (rs *mockTimeRecordStore) GetVersion(ctx context.Context, userID uint64, t time.Time) (store.RecordsVersion, error) {
+	return store.RecordsVersion{Count: 1}, getRecordTests[userID].e
+}
+func (rs *mockTimeRecordStore) Update(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error) {
+	return &r, changeRecordTests[r.RecordID].e
+}
+func (rs *mockTimeRecordStore) Delete(ctx context.Context, id uint64) error {
+	return changeRecordTests[id].e
+}
+func (rs *mockTimeRecordStore) Restore(ctx context.Context, id uint64) (*store.TimeRecord, error) {
+	return &store.TimeRecord{RecordID: id}, changeRecordTests[id].e
+}
+func (rs *mockTimeRecordStore) Trash(ctx context.Context, userID uint64) ([]store.TimeRecord, error) {
+	return make([]store.TimeRecord, 0), nil
+}
 
 // test cases indexed by user id
 var createRecordTests = map[uint64]struct {
@@ -49,13 +69,12 @@ var createRecordTests = map[uint64]struct {
 		s: http.StatusNotFound,
 		b: []byte("404 page not found"),
 	},
-	1: { // 500
-		d: "expect mal-formed JSON payload to result in 500",
-		e: errInternal,
+	1: { // 400
+		d: "expect mal-formed JSON payload to result in 400",
 		u: "record",
 		p: `{"user_id":2`, // missing closing brace
-		s: http.StatusInternalServerError,
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errInternal.Error())),
+		s: http.StatusBadRequest,
+		b: errorBody(errBadRequest),
 	},
 	2: { // 500
 		d: "expect store error to result in 500",
@@ -63,15 +82,30 @@ var createRecordTests = map[uint64]struct {
 		u: "record",
 		p: `{"user_id":2}`, // user_id is the testcase-id used by the mock store
 		s: http.StatusInternalServerError,
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errInternal.Error())),
+		b: errorBody(errInternal),
 	},
 	// success
 	3: {
 		d: "expect to successfully create and return a time record",
 		u: "record",
-		p: `{"user_id":3,"name":"foo","start_time":1577833200,"start_loc":"Europe/Berlin","stop":1577836800,"stop_loc":"Europe/Berlin", "duration":3600}`,
+		p: `{"user_id":3,"name":"foo","start_time":1577833200,"start_loc":"Europe/Berlin","stop_time":1577836800,"stop_loc":"Europe/Berlin", "duration":3600}`,
 		s: http.StatusOK,
-		b: []byte(`{"record_id":3,"user_id":3,"name":"foo","start_time":"01 Jan 2020 00:00:00","start_loc":"Europe/Berlin","stop":"01 Jan 2020 01:00:00","stop_loc":"Europe/Berlin", "duration":"01:00:00"}`),
+		b: []byte(`{"record_id":3,"user_id":3,"name":"foo","start_time":"01 Jan 2020 00:00:00","start_loc":"Europe/Berlin","stop_time":"01 Jan 2020 01:00:00","stop_loc":"Europe/Berlin", "duration":"01:00:00"}`),
+	},
+	// errors of the record
+	4: { // 400
+		d: "expect negative rate to result in 400",
+		u: "record",
+		p: `{"user_id":4,"start_time":1577833200,"start_loc":"UTC","stop_time":1577836800,"stop_loc":"UTC","rate":{"amount":"-80","currency":"EUR"}}`,
+		s: http.StatusBadRequest,
+		b: errorBody(errBadRequest),
+	},
+	5: { // 400
+		d: "expect unknown location to result in 400",
+		u: "record",
+		p: `{"user_id":5,"start_time":1577833200,"start_loc":"Mars/Olympus","stop_time":1577836800,"stop_loc":"UTC"}`,
+		s: http.StatusBadRequest,
+		b: errorBody(errBadRequest),
 	},
 }
 
@@ -80,6 +114,7 @@ func TestServeHTTPCreate(t *testing.T) {
 	// control the data and errors we return
 	rs := &timeRecordService{
 		&mockTimeRecordStore{},
+		localizer{&mockProfileStore{}},
 		200 * time.Millisecond,
 	}
 	// test server
@@ -134,18 +169,18 @@ var getRecordTests = map[uint64]struct {
 	0: { // 400
 		d: "expect missing user id to result in 400",
 		s: http.StatusBadRequest,
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errBadRequest.Error())),
+		b: errorBody(errBadRequest),
 	},
 	1: { // 400
 		d: "expect missing timestamp to result in 400",
 		s: http.StatusBadRequest,
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errBadRequest.Error())),
+		b: errorBody(errBadRequest),
 		p: params{u: "1"},
 	},
 	2: { // 500
 		d: "expect wrong timezone to result in 500",
 		s: http.StatusInternalServerError,
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errInternal.Error())),
+		b: errorBody(errInternal),
 		p: params{u: "1", ts: "invalid"},
 	},
 	3: { // 500
@@ -153,7 +188,7 @@ var getRecordTests = map[uint64]struct {
 		e: errInternal,
 		s: http.StatusInternalServerError,
 		p: params{u: "3", ts: "0"}, // timezone and location can be empty
-		b: []byte(fmt.Sprintf(`{"error":"%s"}`, errInternal.Error())),
+		b: errorBody(errInternal),
 	},
 	// success
 	4: { // 200
@@ -169,6 +204,7 @@ func TestServeHTTPGet(t *testing.T) {
 	// control the data and errors we return
 	rs := &timeRecordService{
 		&mockTimeRecordStore{},
+		localizer{&mockProfileStore{}},
 		200 * time.Millisecond,
 	}
 	// test server
@@ -211,6 +247,94 @@ func TestServeHTTPGet(t *testing.T) {
 	}
 }
 
+// test cases indexed by record id
+var changeRecordTests = map[uint64]struct {
+	d string // description of test case
+	e error  // mock store error
+	m string // HTTP method
+	u string // path segment following the record id
+	s int    // expected http status code
+}{
+	1: {
+		d: "expect successful update",
+		m: "PUT",
+		s: http.StatusOK,
+	},
+	2: {
+		d: "expect update of unknown record to result in 404",
+		e: store.ErrNotFound,
+		m: "PUT",
+		s: http.StatusNotFound,
+	},
+	3: {
+		d: "expect update of invoiced record to result in 409",
+		e: store.ErrRecordLocked,
+		m: "PUT",
+		s: http.StatusConflict,
+	},
+	4: {
+		d: "expect successful delete",
+		m: "DELETE",
+		s: http.StatusNoContent,
+	},
+	5: {
+		d: "expect delete of invoiced record to result in 409",
+		e: store.ErrRecordLocked,
+		m: "DELETE",
+		s: http.StatusConflict,
+	},
+	6: {
+		d: "expect successful restore",
+		m: "POST",
+		u: "restore",
+		s: http.StatusOK,
+	},
+	7: {
+		d: "expect restore of record not in trash to result in 404",
+		e: store.ErrNotFound,
+		m: "POST",
+		u: "restore",
+		s: http.StatusNotFound,
+	},
+}
+
+func TestServeHTTPChange(t *testing.T) {
+	rs := &timeRecordService{
+		&mockTimeRecordStore{},
+		localizer{&mockProfileStore{}},
+		200 * time.Millisecond,
+	}
+	router := mux.NewRouter()
+	router.Handle("/records/{id:[0-9]+}", rs).Methods("PUT", "DELETE").Name("record_id")
+	router.Handle("/records/{id:[0-9]+}/restore", rs).Methods("POST").Name("restore")
+	s := httptest.NewServer(router)
+	defer s.Close()
+	c := s.Client()
+
+	for id, tc := range changeRecordTests {
+		tt := tc
+		url := fmt.Sprintf("%s/records/%d", s.URL, id)
+		if tc.u != "" {
+			url += "/" + tc.u
+		}
+		t.Run(tt.d, func(t *testing.T) {
+			p := `{"user_id":3,"name":"foo","start_time":1577833200,"start_loc":"Europe/Berlin","stop_time":1577836800,"stop_loc":"Europe/Berlin","duration":3600}`
+			req, err := http.NewRequest(tt.m, url, strings.NewReader(p))
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			resp.Body.Close()
+			if want, got := tt.s, resp.StatusCode; want != got {
+				t.Errorf("want status code %d got %d", want, got)
+			}
+		})
+	}
+}
+
 var startPeriodTests = []struct {
 	d  string         // description of test case
 	t  time.Time      // param t
@@ -297,3 +421,9 @@ func TestGetStartOfPeriod(t *testing.T) {
 		}
 	}
 }
+
+// errorBody returns the response body of an error for clients accepting any
+// language.
+func errorBody(err error) []byte {
+	return []byte(fmt.Sprintf(`{"error":"%s","message":"%s"}`, err, locale.English.Message(err.Error())))
+}
@@ -0,0 +1,525 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api"
+	"github.com/fgrimme/time-tracker/time-tracker/api/client"
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/rs/zerolog"
+)
+
+// memStore keeps time records, timers, rates and webhook subscriptions in
+// memory. The other stores return the data of mockDatastore.
+type memStore struct {
+	*mockDatastore
+
+	mu       sync.Mutex
+	lastID   uint64
+	records  map[uint64]store.TimeRecord
+	timers   map[uint64]store.Timer
+	rates    []billing.Rate
+	lastSub  uint64
+	webhooks map[uint64]webhook.Subscription
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		mockDatastore: &mockDatastore{mockRPCStore: &mockRPCStore{}},
+		records:       map[uint64]store.TimeRecord{},
+		timers:        map[uint64]store.Timer{},
+		webhooks:      map[uint64]webhook.Subscription{},
+	}
+}
+
+func (ms *memStore) Create(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.lastID++
+	r.RecordID = ms.lastID
+	ms.records[r.RecordID] = r
+	return &r, nil
+}
+func (ms *memStore) Get(ctx context.Context, userID uint64, t time.Time) ([]store.TimeRecord, error) {
+	return ms.filter(func(r store.TimeRecord) bool {
+		return r.UserID == userID && r.Deleted == nil && !r.Stop.Before(t)
+	}), nil
+}
+func (ms *memStore) Search(ctx context.Context, q store.SearchQuery) ([]store.SearchResult, error) {
+	return store.SearchRecords(ms.filter(func(r store.TimeRecord) bool { return r.UserID == q.UserID }), q), nil
+}
+func (ms *memStore) Update(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if old, ok := ms.records[r.RecordID]; !ok || old.Deleted != nil {
+		return nil, store.ErrNotFound
+	}
+	ms.records[r.RecordID] = r
+	return &r, nil
+}
+func (ms *memStore) Delete(ctx context.Context, id uint64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	r, ok := ms.records[id]
+	if !ok || r.Deleted != nil {
+		return store.ErrNotFound
+	}
+	now := time.Now().UTC()
+	r.Deleted = &now
+	ms.records[id] = r
+	return nil
+}
+func (ms *memStore) Restore(ctx context.Context, id uint64) (*store.TimeRecord, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	r, ok := ms.records[id]
+	if !ok || r.Deleted == nil {
+		return nil, store.ErrNotFound
+	}
+	r.Deleted = nil
+	ms.records[id] = r
+	return &r, nil
+}
+func (ms *memStore) Trash(ctx context.Context, userID uint64) ([]store.TimeRecord, error) {
+	return ms.filter(func(r store.TimeRecord) bool {
+		return r.UserID == userID && r.Deleted != nil
+	}), nil
+}
+
+// filter returns the records matching keep, latest first.
+func (ms *memStore) filter(keep func(store.TimeRecord) bool) []store.TimeRecord {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	recs := make([]store.TimeRecord, 0)
+	for _, r := range ms.records {
+		if keep(r) {
+			recs = append(recs, r)
+		}
+	}
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Start.After(recs[j].Start) })
+	return recs
+}
+
+func (ms *memStore) StartTimer(ctx context.Context, t store.Timer) (*store.Timer, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.timers[t.UserID]; ok {
+		return nil, store.ErrTimerRunning
+	}
+	ms.timers[t.UserID] = t
+	return &t, nil
+}
+func (ms *memStore) Timer(ctx context.Context, userID uint64) (*store.Timer, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	t, ok := ms.timers[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &t, nil
+}
+func (ms *memStore) StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*store.TimeRecord, error) {
+	ms.mu.Lock()
+	t, ok := ms.timers[userID]
+	if ok && stop.Before(t.Start) {
+		ms.mu.Unlock()
+		return nil, store.ErrInvalidStop
+	}
+	delete(ms.timers, userID)
+	ms.mu.Unlock()
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return ms.Create(ctx, store.TimeRecord{
+		UserID:    userID,
+		Name:      t.Name,
+		Start:     t.Start,
+		StartLoc:  t.StartLoc,
+		Stop:      stop,
+		StopLoc:   stopLoc,
+		Duration:  int64(stop.Sub(t.Start) / time.Second),
+		ProjectID: t.ProjectID,
+		Billable:  t.Billable,
+	})
+}
+func (ms *memStore) CreateRate(ctx context.Context, r billing.Rate) (*billing.Rate, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	r.RateID = uint64(len(ms.rates) + 1)
+	ms.rates = append(ms.rates, r)
+	return &r, nil
+}
+func (ms *memStore) Rates(ctx context.Context, userID uint64) ([]billing.Rate, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return append([]billing.Rate(nil), ms.rates...), nil
+}
+
+// CreateWebhook keeps the secret like the store does and returns the
+// subscription without it.
+func (ms *memStore) CreateWebhook(ctx context.Context, s webhook.Subscription) (*webhook.Subscription, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.lastSub++
+	s.SubscriptionID, s.Created = ms.lastSub, time.Now().UTC()
+	ms.webhooks[s.SubscriptionID] = s
+	s.Secret = ""
+	return &s, nil
+}
+func (ms *memStore) Webhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	subs := make([]webhook.Subscription, 0, len(ms.webhooks))
+	for _, s := range ms.webhooks {
+		s.Secret = ""
+		subs = append(subs, s)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].SubscriptionID < subs[j].SubscriptionID })
+	return subs, nil
+}
+func (ms *memStore) DeleteWebhook(ctx context.Context, id uint64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.webhooks[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(ms.webhooks, id)
+	return nil
+}
+func (ms *memStore) Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]webhook.Delivery, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.webhooks[subscriptionID]; !ok {
+		return nil, store.ErrNotFound
+	}
+	// nothing is delivered without a dispatcher
+	return []webhook.Delivery{}, nil
+}
+
+// newClient returns a client of an API server operating on an in-memory
+// store and a function to stop the server. The handler of the API is wrapped
+// by wrap if it is not nil.
+func newClient(t *testing.T, wrap func(http.Handler) http.Handler) (*client.Client, func()) {
+	h, err := newHandler(newMemStore(), events.NewHub(), time.Second, config.NewLive(config.Settings{ValidateRequests: true}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrap != nil {
+		h = wrap(h)
+	}
+	srv := httptest.NewServer(h)
+	c, err := client.New(srv.URL, srv.Client())
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	c.Backoff = time.Millisecond
+	return c, srv.Close
+}
+
+func TestClientRecords(t *testing.T) {
+	c, stop := newClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2020, time.January, 6, 9, 0, 0, 0, berlin)
+	rate, _ := billing.ParseMoney("80", "EUR")
+
+	created, err := c.CreateRecord(ctx, client.Record{
+		UserID:   42,
+		Name:     "code review",
+		Start:    start,
+		Stop:     start.Add(90 * time.Minute),
+		Billable: true,
+		Rate:     &rate,
+		Notes:    "search with <mark> in the snippets",
+		Tags:     []string{"Review"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.RecordID == 0 || created.StartLoc != "Europe/Berlin" || !created.Start.Equal(start) || created.Duration != 90*time.Minute {
+		t.Errorf("unexpected record %+v", created)
+	}
+	if created.Rate == nil || created.Rate.String() != "80.00" {
+		t.Errorf("want rate 80.00 got %v", created.Rate)
+	}
+
+	recs, err := c.Records(ctx, 42, client.Period{Unit: client.Week, Time: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].RecordID != created.RecordID {
+		t.Fatalf("want created record got %+v", recs)
+	}
+	recs, err = c.RecordsBetween(ctx, 42, start.Add(2*time.Hour), start.Add(3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 0 {
+		t.Errorf("want no records after the record got %+v", recs)
+	}
+
+	created.Name = "pairing"
+	updated, err := c.UpdateRecord(ctx, *created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "pairing" {
+		t.Errorf("want updated name got %s", updated.Name)
+	}
+
+	// snippets are escaped before the matches are highlighted
+	results, err := c.SearchRecords(ctx, 42, client.Search{Text: `"search wi*" pair*`, From: start, Tags: []string{"review"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Record.RecordID != created.RecordID || len(results[0].Record.Tags) != 1 {
+		t.Fatalf("want updated record got %+v", results)
+	}
+	if want, got := "<mark>pairing</mark> – <mark>search</mark> <mark>with</mark> &lt;mark&gt; in the snippets", results[0].Snippet; want != got {
+		t.Errorf("want snippet %s got %s", want, got)
+	}
+
+	if err := c.DeleteRecord(ctx, created.RecordID); err != nil {
+		t.Fatal(err)
+	}
+	trash, err := c.Trash(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash) != 1 || trash[0].Deleted == nil {
+		t.Fatalf("want deleted record in trash got %+v", trash)
+	}
+	if _, err := c.RestoreRecord(ctx, created.RecordID); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := c.Report(ctx, 42, client.Period{Unit: client.Week, Time: start}, billing.Rounding{Increment: 3600})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Duration != 90*time.Minute || rep.RoundedBillableDuration != 2*time.Hour {
+		t.Errorf("unexpected report %+v", rep)
+	}
+	if len(rep.Amounts) != 1 || rep.Amounts[0].String() != "160.00" {
+		t.Errorf("want amount 160.00 got %v", rep.Amounts)
+	}
+}
+
+func TestClientTimer(t *testing.T) {
+	c, stop := newClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Now().In(berlin).Add(-time.Hour).Truncate(time.Second)
+
+	if _, err := c.StartTimer(ctx, client.Timer{UserID: 7, Name: "standup", Start: start}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.StartTimer(ctx, client.Timer{UserID: 7, StartLoc: "Europe/Berlin"})
+	if want, got := errTimerRunning.Error(), client.ErrorCode(err); want != got {
+		t.Errorf("want error %s got %v", want, err)
+	}
+	timer, err := c.Timer(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Name != "standup" || !timer.Start.Equal(start) {
+		t.Errorf("unexpected timer %+v", timer)
+	}
+	rec, err := c.StopTimer(ctx, 7, start.Add(time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Duration != time.Hour || rec.StopLoc != "Europe/Berlin" {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestClientSuggestions(t *testing.T) {
+	c, stop := newClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	suggestions, err := c.NameSuggestions(ctx, 1, "cli", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Name != "Client call" || suggestions[0].ProjectID != 7 || len(suggestions[0].Tags) != 1 {
+		t.Fatalf("unexpected suggestions %+v", suggestions)
+	}
+	res, err := c.MergeNames(ctx, client.NameMerge{UserID: 1, Name: "Client call", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || len(res.Merged) != 2 || res.Merged[1].Locked != 1 {
+		t.Errorf("unexpected merge %+v", res)
+	}
+	_, err = c.MergeNames(ctx, client.NameMerge{UserID: 1})
+	if want, got := errBadRequest.Error(), client.ErrorCode(err); want != got {
+		t.Errorf("want error %s got %v", want, err)
+	}
+}
+
+func TestClientSync(t *testing.T) {
+	c, stop := newClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+	start := time.Date(2020, time.January, 6, 9, 0, 0, 0, time.UTC)
+
+	var ids [4]string
+	for i := range ids {
+		id, err := client.NewUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids[i] = id; !store.ValidUUID(id) {
+			t.Fatalf("invalid uuid %s", id)
+		}
+	}
+	res, err := c.Sync(ctx, 42, []client.Mutation{
+		{MutationID: ids[0], Op: client.Upsert, UUID: ids[1], Record: &client.Record{Name: "train", Start: start, Stop: start.Add(time.Hour)}},
+		// the mock store reports base version 3 as stale
+		{MutationID: ids[2], Op: client.Delete, UUID: ids[3], BaseVersion: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Applied != 1 || res.Conflicts != 1 || len(res.Results) != 2 {
+		t.Fatalf("want one applied and one conflict got %+v", res)
+	}
+	if r := res.Results[0]; r.Status != client.Applied || r.Record == nil || r.Record.UUID != ids[1] || r.Record.Version != 1 {
+		t.Errorf("want applied upsert got %+v", r)
+	}
+	if r := res.Results[1]; r.Status != client.Conflict || r.Reason != client.ConflictStale || r.Record == nil || r.Record.Version != 4 {
+		t.Errorf("want stale delete with the server's record got %+v", r)
+	}
+
+	feed, err := c.Feed(ctx, 42, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.More || feed.Cursor != 12 || len(feed.Entries) != 2 {
+		t.Fatalf("want full page up to cursor 12 got %+v", feed)
+	}
+	if e := feed.Entries[0]; e.Kind != client.FeedCreate || e.Record == nil || e.Record.UUID != e.UUID {
+		t.Errorf("want create with record got %+v", e)
+	}
+	if e := feed.Entries[1]; e.Kind != client.FeedTombstone || e.Record != nil {
+		t.Errorf("want tombstone without record got %+v", e)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	c, stop := newClient(t, nil)
+	defer stop()
+
+	_, err := c.Timer(context.Background(), 1)
+	e, ok := err.(*api.Error)
+	if !ok {
+		t.Fatalf("want api error got %T: %v", err, err)
+	}
+	if want, got := errNotFound.Error(), e.Err; want != got {
+		t.Errorf("want error %s got %s", want, got)
+	}
+	if want, got := http.StatusNotFound, client.StatusCode(err); want != got {
+		t.Errorf("want status code %d got %d", want, got)
+	}
+	if want, got := "GET", e.Response.Request.Method; want != got {
+		t.Errorf("want method %s in error got %s", want, got)
+	}
+
+	// rejected by the request validation
+	_, err = c.StartTimer(context.Background(), client.Timer{StartLoc: "Europe/Berlin"})
+	if want, got := errBadRequest.Error(), client.ErrorCode(err); want != got {
+		t.Errorf("want error %s got %v", want, got)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var requests, failures int32
+	// fails the first two requests as if the server was restarting
+	c, stop := newClient(t, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, 1) <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	defer stop()
+	ctx := context.Background()
+
+	if _, err := c.Rates(ctx, 1); err != nil {
+		t.Fatalf("want idempotent request to be retried got %v", err)
+	}
+	if want, got := int32(3), atomic.LoadInt32(&requests); want != got {
+		t.Errorf("want %d requests got %d", want, got)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&failures, 0)
+	hourly, _ := billing.ParseMoney("60", "EUR")
+	_, err := c.CreateRate(ctx, billing.Rate{Scope: billing.ScopeUser, ScopeID: 1, Hourly: hourly})
+	if want, got := http.StatusServiceUnavailable, client.StatusCode(err); want != got {
+		t.Errorf("want status code %d got %d", want, got)
+	}
+	if want, got := int32(1), atomic.LoadInt32(&requests); want != got {
+		t.Errorf("want POST not to be retried, got %d requests", got)
+	}
+
+	// POST requests with an idempotency key are retried
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&failures, 0)
+	if _, err := c.CreateRate(client.WithIdempotencyKey(ctx, "rate-1"), billing.Rate{Scope: billing.ScopeUser, ScopeID: 1, Hourly: hourly}); err != nil {
+		t.Fatalf("want POST with idempotency key to be retried got %v", err)
+	}
+	if want, got := int32(3), atomic.LoadInt32(&requests); want != got {
+		t.Errorf("want %d requests got %d", want, got)
+	}
+
+	// rate limited requests are retried
+	atomic.StoreInt32(&requests, 0)
+	atomic.StoreInt32(&failures, 0)
+	limited, stopLimited := newClient(t, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if atomic.AddInt32(&failures, 1) <= 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	})
+	defer stopLimited()
+	if _, err := limited.Rates(ctx, 1); err != nil {
+		t.Fatalf("want rate limited request to be retried got %v", err)
+	}
+	if want, got := int32(2), atomic.LoadInt32(&requests); want != got {
+		t.Errorf("want %d requests got %d", want, got)
+	}
+
+	// retries end with the context
+	atomic.StoreInt32(&failures, -100)
+	c.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Rates(ctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("want deadline exceeded got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+)
+
+func TestCompression(t *testing.T) {
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		d      string // description of test case
+		u      string // route of test request
+		accept string // Accept-Encoding of the request
+		enc    string // expected Content-Encoding
+	}{
+		{d: "brotli preferred", u: "/openapi.json", accept: "gzip, deflate, br", enc: "br"},
+		{d: "gzip", u: "/openapi.json", accept: "gzip", enc: "gzip"},
+		{d: "quality", u: "/openapi.json", accept: "br;q=0.5, gzip;q=1.0", enc: "gzip"},
+		{d: "refused", u: "/openapi.json", accept: "br;q=0, *;q=0.1", enc: "gzip"},
+		{d: "identity", u: "/openapi.json", accept: "identity"},
+		{d: "none", u: "/openapi.json"},
+		{d: "small body", u: "/live", accept: "gzip, br"},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.u, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept-Encoding", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("want status %d got %d", http.StatusOK, w.Code)
+			}
+			if w, g := tt.enc, w.Header().Get("Content-Encoding"); w != g {
+				t.Errorf("want encoding %q got %q", w, g)
+			}
+			if vary := w.Header()["Vary"]; !contains(vary, "Accept-Encoding") {
+				t.Errorf("want vary Accept-Encoding got %v", vary)
+			}
+			if w, g := "application/json", w.Header().Get("Content-Type"); w != g {
+				t.Errorf("want content type %q got %q", w, g)
+			}
+
+			var body io.Reader = w.Body
+			switch tt.enc {
+			case "br":
+				body = brotli.NewReader(body)
+			case "gzip":
+				if body, err = gzip.NewReader(body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b, err := ioutil.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.u == "/openapi.json" && string(b) != openAPISpec {
+				t.Errorf("want the OpenAPI document got %.50q...", b)
+			}
+		})
+	}
+}
+
+func TestRecordsETag(t *testing.T) {
+	ms := &mockRPCStore{version: 1}
+	h, err := newHandler(&mockDatastore{mockRPCStore: ms}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(u, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", u, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	const u = "/records?user_id=1&tz=UTC&ts=1579000000&period=month"
+
+	w := get(u, "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("want status 200 with an etag got %d with %q", w.Code, etag)
+	}
+	if w, g := `W/"1-1-1"`, etag; w != g {
+		t.Errorf("want etag %s got %s", w, g)
+	}
+
+	w = get(u, etag)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("want status 304 got %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("want no body got %q", w.Body)
+	}
+	if w := get(u, `"other", `+etag); w.Code != http.StatusNotModified {
+		t.Errorf("list of tags: want status 304 got %d", w.Code)
+	}
+
+	// a change of a record changes the tag
+	ms.version = 2
+	w = get(u, etag)
+	if w.Code != http.StatusOK {
+		t.Errorf("changed records: want status 200 got %d", w.Code)
+	}
+	if g := w.Header().Get("ETag"); g == etag {
+		t.Errorf("changed records: want etag other than %s", etag)
+	}
+
+	// the request is still served if the version fails
+	ms.errs = map[uint64]error{1: errInternal}
+	w = get(u, etag)
+	if w.Code != http.StatusInternalServerError || w.Header().Get("ETag") != "" {
+		t.Errorf("failing store: want status 500 without etag got %d with %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
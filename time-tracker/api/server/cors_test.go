@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/rs/zerolog"
+)
+
+func TestCORS(t *testing.T) {
+	policy := middleware.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Idempotency-Key", "traceparent"},
+		ExposedHeaders:   []string{"Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	live := config.NewLive(config.Settings{CORS: policy})
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, live, nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		d       string            // description of test case
+		m       string            // HTTP method
+		u       string            // route of test request
+		origin  string            // origin of the request
+		method  string            // method requested by a preflight
+		headers string            // headers requested by a preflight
+		s       int               // expected http status code
+		h       map[string]string // expected response headers, empty if absent
+	}{
+		{
+			d: "preflight", m: "OPTIONS", u: "/record", origin: "https://app.example.com", method: "POST", headers: "content-type, idempotency-key",
+			s: http.StatusNoContent,
+			h: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "GET, POST, PUT, DELETE",
+				"Access-Control-Allow-Headers":     "Content-Type, Idempotency-Key, traceparent",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			d: "preflight before routing", m: "OPTIONS", u: "/records", origin: "https://app.example.com", method: "GET",
+			s: http.StatusNoContent,
+			h: map[string]string{"Access-Control-Allow-Origin": "https://app.example.com"},
+		},
+		{
+			d: "preflight of wildcard origin", m: "OPTIONS", u: "/records/1", origin: "https://staging.example.org", method: "PUT", headers: "Content-Type",
+			s: http.StatusNoContent,
+			h: map[string]string{"Access-Control-Allow-Origin": "https://staging.example.org"},
+		},
+		{
+			d: "preflight of forbidden origin", m: "OPTIONS", u: "/record", origin: "https://example.org", method: "POST",
+			s: http.StatusForbidden,
+			h: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			d: "preflight of forbidden method", m: "OPTIONS", u: "/records/1", origin: "https://app.example.com", method: "PATCH",
+			s: http.StatusForbidden,
+			h: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+		},
+		{
+			d: "preflight of forbidden header", m: "OPTIONS", u: "/record", origin: "https://app.example.com", method: "POST", headers: "X-Custom",
+			s: http.StatusForbidden,
+			h: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			d: "request of allowed origin", m: "GET", u: "/timer?user_id=1", origin: "https://app.example.com",
+			s: http.StatusOK,
+			h: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "Request-Id",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			d: "request of forbidden origin", m: "GET", u: "/timer?user_id=1", origin: "https://evil.com",
+			s: http.StatusOK,
+			h: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			d: "request without origin", m: "GET", u: "/timer?user_id=1",
+			s: http.StatusOK,
+			h: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			d: "options without preflight", m: "OPTIONS", u: "/record",
+			s: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			r := httptest.NewRequest(tt.m, tt.u, nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.method != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.method)
+			}
+			if tt.headers != "" {
+				r.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if want, got := tt.s, w.Code; want != got {
+				t.Errorf("want status code %d got %d", want, got)
+			}
+			for k, v := range tt.h {
+				if got := w.Header().Get(k); got != v {
+					t.Errorf("%s: want %q got %q", k, v, got)
+				}
+			}
+		})
+	}
+
+	// the policy is applied to running handlers when the settings change
+	live.Store(config.Settings{CORS: middleware.CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}})
+	r := httptest.NewRequest("OPTIONS", "/records", nil)
+	r.Header.Set("Origin", "https://evil.com")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if want, got := http.StatusNoContent, w.Code; want != got {
+		t.Errorf("reloaded policy: want status code %d got %d", want, got)
+	}
+	if want, got := "*", w.Header().Get("Access-Control-Allow-Origin"); want != got {
+		t.Errorf("reloaded policy: want origin %q got %q", want, got)
+	}
+}
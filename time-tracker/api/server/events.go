@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/gorilla/websocket"
+)
+
+// heartbeat is the interval in which idle streams send a keep-alive, so
+// proxies do not close them and dead clients are detected.
+const heartbeat = 15 * time.Second
+
+// subscriber subscribes to the events of a user.
+type subscriber interface {
+	Subscribe(userID uint64) *events.Subscription
+}
+
+// eventService streams the changes of a user's time records and timers as
+// Server-Sent Events or over a WebSocket.
+//
+// Streams are long-lived, so they can not be subject to the WriteTimeout of
+// the server, which limits the time to write a whole response. Streams take
+// over the connection instead and limit the time of each write to the same
+// timeout.
+type eventService struct {
+	subscriber
+	upgrader websocket.Upgrader
+}
+
+// newEventService returns an eventService whose WebSocket handshakes are
+// checked against the CORS policy, since browsers do not apply it to
+// WebSockets. Handshakes without Origin header are made by other clients.
+func newEventService(s subscriber, policy func() middleware.CORSPolicy) *eventService {
+	return &eventService{
+		subscriber: s,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || policy().AllowOrigin(origin)
+			},
+		},
+	}
+}
+
+// ServeHTTP serves requests to the event stream. Requests to upgrade to the
+// WebSocket protocol are served over a WebSocket, all others as Server-Sent
+// Events.
+func (es *eventService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, code, err := parseUserQuery(r)
+	if err != nil {
+		writeError(w, r, err, code)
+		return
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		es.serveWebSocket(w, r, userID)
+		return
+	}
+	es.serveSSE(w, r, userID)
+}
+
+// serveSSE streams events as Server-Sent Events until the client goes away
+// or the subscription ends.
+func (es *eventService) serveSSE(w http.ResponseWriter, r *http.Request, userID uint64) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, r, errors.New("response does not support hijacking"), http.StatusInternalServerError)
+		return
+	}
+	sub := es.Subscribe(userID)
+	defer sub.Close()
+
+	// headers set by middleware like CORS are kept
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "close")
+	h.Set("X-Accel-Buffering", "no") // disables response buffering in nginx
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+	logger := loggerFromRequest(r)
+
+	// the stream ends when the client closes the connection
+	conn.SetReadDeadline(time.Time{})
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, rw)
+		close(gone)
+	}()
+
+	// the response has no length, it ends when the connection is closed
+	fmt.Fprintf(rw, "HTTP/1.1 200 OK\r\n")
+	h.Write(rw)
+	fmt.Fprintf(rw, "\r\nretry: %d\n\n", (3 * time.Second).Milliseconds())
+	if err := flush(conn, rw); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			// JSON is written in a single line, so it is a single data field
+			fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", e.Type, e.Data)
+		case <-ticker.C:
+			fmt.Fprint(rw, ": heartbeat\n\n")
+		case <-gone:
+			return
+		}
+		if err := flush(conn, rw); err != nil {
+			logger.Debug().Err(err).Msg("event stream closed")
+			return
+		}
+	}
+}
+
+// flush writes the buffered data to the connection within the write timeout
+// of the server.
+func flush(conn net.Conn, rw *bufio.ReadWriter) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return rw.Flush()
+}
+
+// serveWebSocket streams events as JSON messages over a WebSocket until the
+// client goes away or the subscription ends. Clients must answer pings.
+func (es *eventService) serveWebSocket(w http.ResponseWriter, r *http.Request, userID uint64) {
+	ws, err := es.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // the upgrader responded with an error already
+	}
+	defer ws.Close()
+	sub := es.Subscribe(userID)
+	defer sub.Close()
+	logger := loggerFromRequest(r)
+
+	// read messages to process pongs and notice when the client closes the
+	// connection, clients which do not answer pings are dropped
+	ws.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(2 * heartbeat))
+	})
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := ws.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+				ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeTimeout))
+				return
+			}
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = ws.WriteJSON(e)
+		case <-ticker.C:
+			err = ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		case <-gone:
+			return
+		}
+		if err != nil {
+			logger.Debug().Err(err).Msg("event stream closed")
+			return
+		}
+	}
+}
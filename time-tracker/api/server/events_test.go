@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// newEventServer returns a test server streaming the events of the hub. The
+// write timeout of the server is shorter than the duration of the tests.
+func newEventServer(h *events.Hub) *httptest.Server {
+	router := mux.NewRouter()
+	policy := func() middleware.CORSPolicy {
+		return middleware.CORSPolicy{AllowedOrigins: []string{"https://*.example.com"}}
+	}
+	router.Handle("/events", newEventService(h, policy)).Methods("GET").Queries("user_id", "{id:[0-9]+}")
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	return srv
+}
+
+// publish publishes an event once the user subscribed.
+func publish(t *testing.T, h *events.Hub, e events.Event) {
+	// the stream outlives the write timeout of the server
+	time.Sleep(100 * time.Millisecond)
+	if err := h.Publish(context.Background(), e); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	h := events.NewHub()
+	srv := newEventServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events?user_id=42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if want, got := "text/event-stream", resp.Header.Get("Content-Type"); want != got {
+		t.Errorf("want content type %s got %s", want, got)
+	}
+
+	go publish(t, h, events.Event{Type: "timer.started", UserID: 42, Data: json.RawMessage(`{"user_id":42}`)})
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), "event:") || strings.HasPrefix(scanner.Text(), "data:") {
+			lines = append(lines, scanner.Text())
+		}
+		if len(lines) == 2 {
+			break
+		}
+	}
+	if want, got := "event: timer.started|data: {\"user_id\":42}", strings.Join(lines, "|"); want != got {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	// the stream ends when the hub is closed on shutdown
+	h.Close()
+	for scanner.Scan() {
+	}
+}
+
+func TestServeWebSocket(t *testing.T) {
+	h := events.NewHub()
+	srv := newEventServer(h)
+	defer srv.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/events?user_id=42", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	go publish(t, h, events.Event{Type: "record.created", UserID: 42, Data: json.RawMessage(`{"record_id":1}`)})
+	var e events.Event
+	if err := ws.ReadJSON(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "record.created" || e.UserID != 42 || string(e.Data) != `{"record_id":1}` {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	h.Close()
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("want close going away got %v", err)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	h := events.NewHub()
+	defer h.Close()
+	srv := newEventServer(h)
+	defer srv.Close()
+
+	tests := []struct {
+		d      string // description of test case
+		origin string // origin of the handshake
+		s      int    // expected http status code
+	}{
+		{d: "allowed origin", origin: "https://app.example.com", s: http.StatusSwitchingProtocols},
+		{d: "no origin", s: http.StatusSwitchingProtocols},
+		{d: "forbidden origin", origin: "https://evil.com", s: http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			ws, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/events?user_id=42", header)
+			if ws != nil {
+				ws.Close()
+			}
+			if resp == nil {
+				t.Fatal(err)
+			}
+			if w, g := tt.s, resp.StatusCode; w != g {
+				t.Errorf("want status code %d got %d", w, g)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// recordFormat returns the format of the time records in the response to r,
+// see store.Format. The format query parameter takes precedence over the
+// format parameter of a JSON media range in the Accept header, e.g.
+// application/json; format=rfc3339. Ranges are considered in the order of
+// their quality and the first known format is taken, records are formatted
+// in store.FormatHuman if none has a format. Returns the HTTP status code to
+// respond with if no format is known.
+func recordFormat(r *http.Request) (store.Format, int, error) {
+	if q := r.URL.Query().Get("format"); q != "" {
+		f, err := store.ParseFormat(q)
+		if err != nil {
+			return "", http.StatusBadRequest, err
+		}
+		return f, 0, nil
+	}
+
+	type mediaRange struct {
+		format string
+		q      float64
+	}
+	var ranges []mediaRange
+	for _, s := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(s))
+		if err != nil || params["format"] == "" {
+			continue
+		}
+		if mt != "application/json" && mt != "application/*" && mt != "*/*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{params["format"], q})
+		}
+	}
+	if len(ranges) == 0 {
+		return store.FormatHuman, 0, nil
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	for _, mr := range ranges {
+		if f, err := store.ParseFormat(mr.format); err == nil {
+			return f, 0, nil
+		}
+	}
+	return "", http.StatusNotAcceptable, errNotAcceptable
+}
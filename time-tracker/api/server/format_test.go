@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+)
+
+func TestRecordFormat(t *testing.T) {
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{ValidateRequests: true}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const u = "/records?user_id=1&tz=Europe/Berlin&ts=1579939200&period=day"
+
+	tests := []struct {
+		d        string // description of test case
+		q        string // additional query parameters
+		accept   string // Accept header of the request
+		s        int    // expected http status code
+		start    string // expected start time of the record
+		duration string // expected duration of the record
+	}{
+		{d: "default", s: http.StatusOK, start: "25 Jan 2020 00:00:00", duration: "01:30:00"},
+		{d: "plain accept", accept: "application/json", s: http.StatusOK, start: "25 Jan 2020 00:00:00", duration: "01:30:00"},
+		{d: "accept parameter", accept: "application/json; format=rfc3339", s: http.StatusOK, start: "2020-01-25T00:00:00+01:00", duration: "PT1H30M"},
+		{d: "accept quality", accept: "application/json; format=human; q=0.5, application/json; format=rfc3339", s: http.StatusOK, start: "2020-01-25T00:00:00+01:00", duration: "PT1H30M"},
+		{d: "query", q: "&format=rfc3339", s: http.StatusOK, start: "2020-01-25T00:00:00+01:00", duration: "PT1H30M"},
+		{d: "query over accept", q: "&format=human", accept: "application/json; format=rfc3339", s: http.StatusOK, start: "25 Jan 2020 00:00:00", duration: "01:30:00"},
+		{d: "unknown accept format", accept: "application/json; format=unix", s: http.StatusNotAcceptable},
+		{d: "known accept format of lower quality", accept: "application/json; format=unix, application/json; format=rfc3339; q=0.5", s: http.StatusOK, start: "2020-01-25T00:00:00+01:00", duration: "PT1H30M"},
+		{d: "no known accept format", accept: "application/json; format=unix, application/json; format=x; q=0.5", s: http.StatusNotAcceptable},
+		{d: "unknown query format", q: "&format=unix", s: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			req := httptest.NewRequest("GET", u+tt.q, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.s {
+				t.Fatalf("want status %d got %d: %s", tt.s, w.Code, w.Body)
+			}
+			if tt.s != http.StatusOK {
+				return
+			}
+			var recs []struct {
+				Start    string `json:"start_time"`
+				Duration string `json:"duration"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&recs); err != nil {
+				t.Fatal(err)
+			}
+			if len(recs) != 1 {
+				t.Fatalf("want 1 record got %d", len(recs))
+			}
+			if recs[0].Start != tt.start || recs[0].Duration != tt.duration {
+				t.Errorf("want start %s and duration %s got %s and %s", tt.start, tt.duration, recs[0].Start, recs[0].Duration)
+			}
+		})
+	}
+}
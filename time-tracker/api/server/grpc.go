@@ -0,0 +1,466 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api/rpc"
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer represents a gRPC server. It serves the records, timers and
+// reports of the HTTP API on a separate port and operates on the same store
+// with the same validation.
+type GRPCServer struct {
+	addr   string
+	server *grpc.Server
+	logger zerolog.Logger
+}
+
+// NewGRPC returns a GRPCServer instance with the services registered.
+func NewGRPC(grpcAddr string, timeout time.Duration, ds datastore, logger zerolog.Logger) *GRPCServer {
+	return &GRPCServer{
+		addr:   grpcAddr,
+		server: newGRPCServer(ds, timeout, logger),
+		logger: logger,
+	}
+}
+
+// rpcStore combines the stores the gRPC services operate on.
+type rpcStore interface {
+	timeRecordStore
+	timerStore
+	rateStore
+}
+
+// newGRPCServer creates a gRPC server that operates on time records.
+func newGRPCServer(ds rpcStore, timeout time.Duration, logger zerolog.Logger) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		newContextLogInterceptor(logger),
+		recoverInterceptor,
+		newTimeoutInterceptor(timeout),
+	))
+	rpc.RegisterRecordsServer(server, &recordRPC{timeRecordStore: ds})
+	rpc.RegisterTimersServer(server, &timerRPC{timerStore: ds})
+	rpc.RegisterReportsServer(server, &reportRPC{reports: reportService{records: ds, rates: ds}})
+	return server
+}
+
+func (s *GRPCServer) Run() {
+	s.logger.Info().Msgf("grpc server listening on %s", s.addr)
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Fatal().Err(err).Msg("grpc server exited with error")
+	}
+	// Serve returns nil once the server is stopped
+	if err := s.server.Serve(lis); err != nil {
+		s.logger.Fatal().Err(err).Msg("grpc server exited with error")
+	}
+}
+
+func (s *GRPCServer) Shutdown(ctx context.Context) {
+	s.logger.Info().Msg("shutting down grpc server")
+
+	// this stops accepting new calls and waits for the running ones to
+	// finish, calls still running at the deadline are canceled.
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Error().Err(ctx.Err()).Msg("grpc server shutdown error")
+		s.server.Stop()
+	}
+}
+
+// newContextLogInterceptor returns an interceptor that adds a logger with a
+// request id to the context of calls and logs them, like the context log
+// middleware of the HTTP server. Changes made by the call are recorded with
+// the request id and the actor given in the actor-id metadata.
+func newContextLogInterceptor(logger zerolog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		id := xid.New().String()
+		lc := logger.With().Str("req_id", id).Str("method", info.FullMethod)
+		if p, ok := peer.FromContext(ctx); ok {
+			lc = lc.Str("ip", p.Addr.String())
+		}
+		l := lc.Logger()
+		ctx = l.WithContext(ctx)
+		ctx = store.WithAudit(ctx, auditFromMetadata(ctx, id))
+		grpc.SetHeader(ctx, metadata.Pairs("request-id", id))
+
+		resp, err := handler(ctx, req)
+		l.Info().
+			Str("code", status.Code(err).String()).
+			Dur("duration", time.Since(start)).
+			Msg("")
+		return resp, err
+	}
+}
+
+// auditFromMetadata returns the audit information of a call, see
+// auditFromRequest.
+func auditFromMetadata(ctx context.Context, requestID string) store.Audit {
+	a := store.Audit{RequestID: requestID}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("actor-id"); len(v) > 0 {
+		if actor, err := strconv.ParseUint(v[0], 10, 64); err == nil {
+			a.ActorID = actor
+		}
+	}
+	return a
+}
+
+// recoverInterceptor responds to calls which panic with an internal error.
+func recoverInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			zerolog.Ctx(ctx).Error().Interface("err", p).Msg("PANIC")
+			err = status.Error(codes.Internal, errInternal.Error())
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// newTimeoutInterceptor returns an interceptor that limits the time to handle
+// a call.
+func newTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// rpcError converts an error of the store to a status with the same error as
+// the HTTP API. Unexpected errors are hidden from the client.
+func rpcError(ctx context.Context, err error) error {
+	switch err {
+	case store.ErrNotFound:
+		return status.Error(codes.NotFound, errNotFound.Error())
+	case store.ErrRecordLocked:
+		return status.Error(codes.FailedPrecondition, errLocked.Error())
+	case store.ErrTimerRunning:
+		return status.Error(codes.AlreadyExists, errTimerRunning.Error())
+	case store.ErrInvalidStop:
+		return status.Error(codes.InvalidArgument, errInvalidStop.Error())
+	}
+	zerolog.Ctx(ctx).Error().Err(err).Msg("unexpected rpc error")
+	return status.Error(codes.Internal, errInternal.Error())
+}
+
+// invalidArgument logs why a request is invalid and responds with a bad
+// request error, see writeError.
+func invalidArgument(ctx context.Context, err error) error {
+	zerolog.Ctx(ctx).Error().Err(err).Msg("bad request")
+	return status.Error(codes.InvalidArgument, errBadRequest.Error())
+}
+
+var errMissingUser = errors.New("missing user id")
+
+// recordRPC provides gRPC methods to operate on time records.
+type recordRPC struct {
+	rpc.UnimplementedRecordsServer
+	timeRecordStore
+}
+
+func (rs *recordRPC) CreateRecord(ctx context.Context, req *rpc.CreateRecordRequest) (*rpc.Record, error) {
+	tr, err := recordFromProto(req.Record)
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	created, err := rs.Create(ctx, tr)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordToProto(created), nil
+}
+
+func (rs *recordRPC) ListRecords(ctx context.Context, req *rpc.ListRecordsRequest) (*rpc.ListRecordsResponse, error) {
+	pq, err := periodQueryFromProto(req.UserId, req.Ts, req.Tz, req.Period)
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	day, err := getStartOfPeriod(pq.t, pq.loc, pq.period)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	recs, err := rs.Get(ctx, pq.userID, day)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordsToProto(recs), nil
+}
+
+func (rs *recordRPC) UpdateRecord(ctx context.Context, req *rpc.UpdateRecordRequest) (*rpc.Record, error) {
+	tr, err := recordFromProto(req.Record)
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	tr.RecordID = req.Record.RecordId
+	// records of the gRPC API have no notes and tags yet, the stored ones
+	// are kept instead of being cleared
+	current, err := rs.Record(ctx, tr.RecordID)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	tr.Notes, tr.Tags = current.Notes, current.Tags
+	updated, err := rs.Update(ctx, tr)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordToProto(updated), nil
+}
+
+func (rs *recordRPC) DeleteRecord(ctx context.Context, req *rpc.DeleteRecordRequest) (*emptypb.Empty, error) {
+	if err := rs.Delete(ctx, req.RecordId); err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (rs *recordRPC) RestoreRecord(ctx context.Context, req *rpc.RestoreRecordRequest) (*rpc.Record, error) {
+	restored, err := rs.Restore(ctx, req.RecordId)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordToProto(restored), nil
+}
+
+func (rs *recordRPC) ListTrash(ctx context.Context, req *rpc.ListTrashRequest) (*rpc.ListRecordsResponse, error) {
+	if req.UserId == 0 {
+		return nil, invalidArgument(ctx, errMissingUser)
+	}
+	recs, err := rs.Trash(ctx, req.UserId)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordsToProto(recs), nil
+}
+
+// timerRPC provides gRPC methods to start and stop timers.
+type timerRPC struct {
+	rpc.UnimplementedTimersServer
+	timerStore
+}
+
+func (ts *timerRPC) StartTimer(ctx context.Context, req *rpc.StartTimerRequest) (*rpc.Timer, error) {
+	if req.Timer == nil {
+		return nil, invalidArgument(ctx, errors.New("missing timer"))
+	}
+	t, err := store.NewTimer(store.TimerStamp{
+		UserID:    req.Timer.UserId,
+		Name:      req.Timer.Name,
+		Start:     req.Timer.StartTime.GetSeconds(),
+		StartLoc:  req.Timer.StartLoc,
+		ProjectID: req.Timer.ProjectId,
+		Billable:  req.Timer.Billable,
+	})
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	if err := startNow(&t); err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	started, err := ts.timerStore.StartTimer(ctx, t)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return timerToProto(started), nil
+}
+
+func (ts *timerRPC) GetTimer(ctx context.Context, req *rpc.GetTimerRequest) (*rpc.Timer, error) {
+	t, err := ts.Timer(ctx, req.UserId)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return timerToProto(t), nil
+}
+
+func (ts *timerRPC) StopTimer(ctx context.Context, req *rpc.StopTimerRequest) (*rpc.Record, error) {
+	stop, err := timerStop{
+		UserID:  req.UserId,
+		Stop:    req.StopTime.GetSeconds(),
+		StopLoc: req.StopLoc,
+	}.stopTime()
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	rec, err := ts.timerStore.StopTimer(ctx, req.UserId, stop, req.StopLoc)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return recordToProto(rec), nil
+}
+
+// reportRPC provides gRPC methods to create reports of time records.
+type reportRPC struct {
+	rpc.UnimplementedReportsServer
+	reports reportService
+}
+
+func (rs *reportRPC) GetReport(ctx context.Context, req *rpc.GetReportRequest) (*rpc.Report, error) {
+	pq, err := periodQueryFromProto(req.UserId, req.Ts, req.Tz, req.Period)
+	if err != nil {
+		return nil, invalidArgument(ctx, err)
+	}
+	var rounding billing.Rounding
+	if inc := req.Rounding.GetIncrement().GetSeconds(); inc != 0 {
+		rounding, err = billing.ParseRounding(strconv.FormatInt(inc, 10), req.Rounding.Mode, req.Rounding.Per)
+		if err != nil {
+			return nil, invalidArgument(ctx, err)
+		}
+	}
+	rep, err := rs.reports.report(ctx, pq, rounding)
+	if err != nil {
+		return nil, rpcError(ctx, err)
+	}
+	return reportToProto(rep), nil
+}
+
+// periodQueryFromProto returns the query for a user's records in a period,
+// see newPeriodQuery. The user and the timestamp are required.
+func periodQueryFromProto(userID uint64, ts *timestamppb.Timestamp, zone, period string) (periodQuery, error) {
+	if userID == 0 {
+		return periodQuery{}, errMissingUser
+	}
+	if ts == nil {
+		return periodQuery{}, errors.New("missing timestamp")
+	}
+	return newPeriodQuery(userID, time.Unix(ts.Seconds, 0), zone, period)
+}
+
+// recordFromProto converts a record to a time record in the user's location,
+// see store.NewTimeRecord.
+func recordFromProto(r *rpc.Record) (store.TimeRecord, error) {
+	if r == nil {
+		return store.TimeRecord{}, errors.New("missing record")
+	}
+	ts := store.TimeStamp{
+		UserID:    r.UserId,
+		Name:      r.Name,
+		Start:     r.StartTime.GetSeconds(),
+		StartLoc:  r.StartLoc,
+		Stop:      r.StopTime.GetSeconds(),
+		StopLoc:   r.StopLoc,
+		Duration:  r.Duration.GetSeconds(),
+		ProjectID: r.ProjectId,
+		Billable:  r.Billable,
+	}
+	if r.Rate != nil {
+		rate, err := billing.ParseMoney(r.Rate.Amount, r.Rate.Currency)
+		if err != nil {
+			return store.TimeRecord{}, err
+		}
+		ts.Rate = &rate
+	}
+	return store.NewTimeRecord(ts)
+}
+
+// recordToProto converts a record to its message. The wall clocks the store
+// reads are anchored in the record's locations, timestamps are points in
+// time.
+func recordToProto(r *store.TimeRecord) *rpc.Record {
+	pr := &rpc.Record{
+		RecordId:  r.RecordID,
+		UserId:    r.UserID,
+		Name:      r.Name,
+		StartTime: timestamppb.New(store.InLocation(r.Start, r.StartLoc)),
+		StartLoc:  r.StartLoc,
+		StopTime:  timestamppb.New(store.InLocation(r.Stop, r.StopLoc)),
+		StopLoc:   r.StopLoc,
+		Duration:  secondsToProto(r.Duration),
+		ProjectId: r.ProjectID,
+		ClientId:  r.ClientID,
+		Billable:  r.Billable,
+		Rate:      moneyToProto(r.Rate),
+		InvoiceId: r.InvoiceID,
+	}
+	if r.Deleted != nil {
+		pr.DeletedAt = timestamppb.New(*r.Deleted)
+	}
+	return pr
+}
+
+func recordsToProto(recs []store.TimeRecord) *rpc.ListRecordsResponse {
+	resp := &rpc.ListRecordsResponse{Records: make([]*rpc.Record, len(recs))}
+	for i := range recs {
+		resp.Records[i] = recordToProto(&recs[i])
+	}
+	return resp
+}
+
+func timerToProto(t *store.Timer) *rpc.Timer {
+	return &rpc.Timer{
+		UserId:    t.UserID,
+		Name:      t.Name,
+		StartTime: timestamppb.New(store.InLocation(t.Start, t.StartLoc)),
+		StartLoc:  t.StartLoc,
+		ProjectId: t.ProjectID,
+		Billable:  t.Billable,
+	}
+}
+
+func reportToProto(rep report) *rpc.Report {
+	pr := &rpc.Report{
+		Duration:                secondsToProto(int64(rep.Duration)),
+		RoundedDuration:         secondsToProto(int64(rep.RoundedDuration)),
+		BillableDuration:        secondsToProto(int64(rep.BillableDuration)),
+		RoundedBillableDuration: secondsToProto(int64(rep.RoundedBillableDuration)),
+		UnratedDuration:         secondsToProto(int64(rep.UnratedDuration)),
+		Amounts:                 make([]*rpc.Money, len(rep.Amounts)),
+		Rounding: &rpc.Rounding{
+			Increment: secondsToProto(rep.Rounding.Increment),
+			Mode:      string(rep.Rounding.Mode),
+			Per:       string(rep.Rounding.Per),
+		},
+		Lines: make([]*rpc.Report_Line, len(rep.Lines)),
+	}
+	for i := range rep.Amounts {
+		pr.Amounts[i] = moneyToProto(&rep.Amounts[i])
+	}
+	for i, line := range rep.Lines {
+		pr.Lines[i] = &rpc.Report_Line{
+			RecordId:        line.RecordID,
+			Name:            line.Name,
+			ProjectId:       line.ProjectID,
+			Duration:        secondsToProto(int64(line.Duration)),
+			RoundedDuration: secondsToProto(int64(line.RoundedDuration)),
+			Billable:        line.Billable,
+			Rate:            moneyToProto(line.Rate),
+			Amount:          moneyToProto(line.Amount),
+		}
+	}
+	return pr
+}
+
+// moneyToProto converts an amount of money, rounded to the minor unit of the
+// currency like in the HTTP API.
+func moneyToProto(m *billing.Money) *rpc.Money {
+	if m == nil {
+		return nil
+	}
+	return &rpc.Money{Amount: m.String(), Currency: m.Currency}
+}
+
+func secondsToProto(seconds int64) *durationpb.Duration {
+	return durationpb.New(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api/rpc"
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// mockRPCStore records the time records it receives and fails with the
+// error of the user or record id.
+type mockRPCStore struct {
+	errs    map[uint64]error
+	created store.TimeRecord
+	updated store.TimeRecord
+	version uint64 // sum of the versions of the records Get returns
+}
+
+func (ms *mockRPCStore) Create(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error) {
+	ms.created = r
+	r.RecordID = 1
+	return &r, ms.errs[r.UserID]
+}
+func (ms *mockRPCStore) Get(ctx context.Context, userID uint64, t time.Time) ([]store.TimeRecord, error) {
+	return []store.TimeRecord{
+		{RecordID: 1, UserID: userID, Start: t, Duration: 5400, Billable: true},
+	}, ms.errs[userID]
+}
+func (ms *mockRPCStore) Record(ctx context.Context, id uint64) (*store.TimeRecord, error) {
+	return &store.TimeRecord{RecordID: id, Notes: "notes", Tags: []string{"tag"}}, ms.errs[id]
+}
+func (ms *mockRPCStore) GetVersion(ctx context.Context, userID uint64, t time.Time) (store.RecordsVersion, error) {
+	return store.RecordsVersion{Count: 1, MaxID: 1, VersionSum: ms.version}, ms.errs[userID]
+}
+func (ms *mockRPCStore) Update(ctx context.Context, r store.TimeRecord) (*store.TimeRecord, error) {
+	ms.updated = r
+	return &r, ms.errs[r.RecordID]
+}
+func (ms *mockRPCStore) Delete(ctx context.Context, id uint64) error {
+	return ms.errs[id]
+}
+func (ms *mockRPCStore) Restore(ctx context.Context, id uint64) (*store.TimeRecord, error) {
+	return &store.TimeRecord{RecordID: id}, ms.errs[id]
+}
+func (ms *mockRPCStore) Trash(ctx context.Context, userID uint64) ([]store.TimeRecord, error) {
+	return nil, ms.errs[userID]
+}
+func (ms *mockRPCStore) StartTimer(ctx context.Context, t store.Timer) (*store.Timer, error) {
+	return &t, ms.errs[t.UserID]
+}
+func (ms *mockRPCStore) Timer(ctx context.Context, userID uint64) (*store.Timer, error) {
+	return &store.Timer{UserID: userID}, ms.errs[userID]
+}
+func (ms *mockRPCStore) StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*store.TimeRecord, error) {
+	return &store.TimeRecord{UserID: userID, Stop: stop, StopLoc: stopLoc}, ms.errs[userID]
+}
+func (ms *mockRPCStore) CreateRate(ctx context.Context, r billing.Rate) (*billing.Rate, error) {
+	return &r, nil
+}
+func (ms *mockRPCStore) Rates(ctx context.Context, userID uint64) ([]billing.Rate, error) {
+	hourly, _ := billing.ParseMoney("60", "EUR")
+	return []billing.Rate{{Scope: billing.ScopeUser, ScopeID: userID, Hourly: hourly}}, nil
+}
+
+// dialRPC serves the gRPC API on an in-memory listener and returns a client
+// connection to it and a function to close both.
+func dialRPC(t *testing.T, ms *mockRPCStore) (*grpc.ClientConn, func()) {
+	lis := bufconn.Listen(1 << 20)
+	srv := newGRPCServer(ms, 200*time.Millisecond, zerolog.Nop())
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		srv.Stop()
+		t.Fatal(err)
+	}
+	return conn, func() {
+		conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	ms := &mockRPCStore{errs: map[uint64]error{
+		2: store.ErrNotFound,
+		3: store.ErrRecordLocked,
+		4: store.ErrTimerRunning,
+		5: store.ErrInvalidStop,
+		6: errInternal,
+	}}
+	conn, stop := dialRPC(t, ms)
+	defer stop()
+	records := rpc.NewRecordsClient(conn)
+	timers := rpc.NewTimersClient(conn)
+	reports := rpc.NewReportsClient(conn)
+	ts := timestamppb.New(time.Unix(1577833200, 0))
+
+	tests := []struct {
+		d string // description of test case
+		c func(ctx context.Context) error
+		s codes.Code // expected status code
+		e string     // expected error
+	}{
+		{
+			d: "expect unknown location to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := records.CreateRecord(ctx, &rpc.CreateRecordRequest{Record: &rpc.Record{UserId: 1, StartLoc: "Mars/Olympus"}})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errBadRequest.Error(),
+		},
+		{
+			d: "expect negative rate to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := records.CreateRecord(ctx, &rpc.CreateRecordRequest{Record: &rpc.Record{UserId: 1, StartLoc: "UTC", StopLoc: "UTC", Rate: &rpc.Money{Amount: "-80", Currency: "EUR"}}})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errBadRequest.Error(),
+		},
+		{
+			d: "expect missing timestamp to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := records.ListRecords(ctx, &rpc.ListRecordsRequest{UserId: 1, Tz: "Europe/Berlin"})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errBadRequest.Error(),
+		},
+		{
+			d: "expect unknown period to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := reports.GetReport(ctx, &rpc.GetReportRequest{UserId: 1, Ts: ts, Period: "year"})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errBadRequest.Error(),
+		},
+		{
+			d: "expect invalid rounding to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := reports.GetReport(ctx, &rpc.GetReportRequest{UserId: 1, Ts: ts, Rounding: &rpc.Rounding{
+					Increment: durationpb.New(time.Minute),
+					Mode:      "sideways",
+				}})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errBadRequest.Error(),
+		},
+		{
+			d: "expect missing record to result in not found",
+			c: func(ctx context.Context) error {
+				_, err := records.RestoreRecord(ctx, &rpc.RestoreRecordRequest{RecordId: 2})
+				return err
+			},
+			s: codes.NotFound,
+			e: errNotFound.Error(),
+		},
+		{
+			d: "expect locked record to result in failed precondition",
+			c: func(ctx context.Context) error {
+				_, err := records.DeleteRecord(ctx, &rpc.DeleteRecordRequest{RecordId: 3})
+				return err
+			},
+			s: codes.FailedPrecondition,
+			e: errLocked.Error(),
+		},
+		{
+			d: "expect running timer to result in already exists",
+			c: func(ctx context.Context) error {
+				_, err := timers.StartTimer(ctx, &rpc.StartTimerRequest{Timer: &rpc.Timer{UserId: 4, StartLoc: "Europe/Berlin"}})
+				return err
+			},
+			s: codes.AlreadyExists,
+			e: errTimerRunning.Error(),
+		},
+		{
+			d: "expect stop before start to result in invalid argument",
+			c: func(ctx context.Context) error {
+				_, err := timers.StopTimer(ctx, &rpc.StopTimerRequest{UserId: 5, StopLoc: "Europe/Berlin"})
+				return err
+			},
+			s: codes.InvalidArgument,
+			e: errInvalidStop.Error(),
+		},
+		{
+			d: "expect store error to be hidden",
+			c: func(ctx context.Context) error {
+				_, err := records.ListTrash(ctx, &rpc.ListTrashRequest{UserId: 6})
+				return err
+			},
+			s: codes.Internal,
+			e: errInternal.Error(),
+		},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			st := status.Convert(tt.c(context.Background()))
+			if want, got := tt.s, st.Code(); want != got {
+				t.Errorf("want status code %s got %s", want, got)
+			}
+			if want, got := tt.e, st.Message(); want != got {
+				t.Errorf("want error %s got %s", want, got)
+			}
+		})
+	}
+}
+
+func TestGRPCCreateRecord(t *testing.T) {
+	ms := &mockRPCStore{}
+	conn, stop := dialRPC(t, ms)
+	defer stop()
+	records := rpc.NewRecordsClient(conn)
+
+	rec, err := records.CreateRecord(context.Background(), &rpc.CreateRecordRequest{Record: &rpc.Record{
+		UserId:    1,
+		Name:      "foo",
+		StartTime: timestamppb.New(time.Unix(1577833200, 0)),
+		StartLoc:  "Europe/Berlin",
+		StopTime:  timestamppb.New(time.Unix(1577836800, 0)),
+		StopLoc:   "America/New_York",
+		Duration:  durationpb.New(time.Hour),
+		Rate:      &rpc.Money{Amount: "80.5", Currency: "EUR"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// times are converted to the user's location like in the HTTP API
+	if want, got := "2020-01-01 00:00:00 +0100 CET", ms.created.Start.String(); want != got {
+		t.Errorf("want start %s got %s", want, got)
+	}
+	if want, got := "2019-12-31 19:00:00 -0500 EST", ms.created.Stop.String(); want != got {
+		t.Errorf("want stop %s got %s", want, got)
+	}
+	if rec.RecordId != 1 || rec.StartTime.AsTime().Unix() != 1577833200 || rec.Duration.AsDuration() != time.Hour {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if want, got := "80.50", rec.Rate.Amount; want != got {
+		t.Errorf("want rate %s got %s", want, got)
+	}
+}
+
+func TestGRPCUpdateRecord(t *testing.T) {
+	ms := &mockRPCStore{}
+	conn, stop := dialRPC(t, ms)
+	defer stop()
+	records := rpc.NewRecordsClient(conn)
+
+	_, err := records.UpdateRecord(context.Background(), &rpc.UpdateRecordRequest{Record: &rpc.Record{
+		RecordId:  1,
+		UserId:    1,
+		Name:      "bar",
+		StartTime: timestamppb.New(time.Unix(1577833200, 0)),
+		StartLoc:  "Europe/Berlin",
+		StopTime:  timestamppb.New(time.Unix(1577836800, 0)),
+		StopLoc:   "Europe/Berlin",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the gRPC API can not change notes and tags, they are kept
+	if ms.updated.Name != "bar" || ms.updated.Notes != "notes" || len(ms.updated.Tags) != 1 {
+		t.Errorf("want name, notes and tags kept got %+v", ms.updated)
+	}
+}
+
+func TestRecordToProto(t *testing.T) {
+	// the store reads the wall clock in the user's location with a +00 offset
+	read := time.FixedZone("", 0)
+	pr := recordToProto(&store.TimeRecord{
+		RecordID: 1,
+		Start:    time.Date(2020, time.January, 25, 9, 0, 0, 0, read),
+		StartLoc: "Europe/Berlin",
+		Stop:     time.Date(2020, time.January, 25, 10, 30, 0, 0, read),
+		StopLoc:  "America/New_York",
+	})
+	if want, got := int64(1579939200), pr.StartTime.AsTime().Unix(); want != got {
+		t.Errorf("want start %d got %d", want, got)
+	}
+	if want, got := int64(1579966200), pr.StopTime.AsTime().Unix(); want != got {
+		t.Errorf("want stop %d got %d", want, got)
+	}
+	pt := timerToProto(&store.Timer{Start: time.Date(2020, time.January, 25, 9, 0, 0, 0, read), StartLoc: "Europe/Berlin"})
+	if want, got := int64(1579939200), pt.StartTime.AsTime().Unix(); want != got {
+		t.Errorf("want timer start %d got %d", want, got)
+	}
+}
+
+func TestGRPCReport(t *testing.T) {
+	conn, stop := dialRPC(t, &mockRPCStore{})
+	defer stop()
+	reports := rpc.NewReportsClient(conn)
+
+	rep, err := reports.GetReport(context.Background(), &rpc.GetReportRequest{
+		UserId: 42,
+		Ts:     timestamppb.New(time.Unix(1577833200, 0)),
+		Tz:     "Europe/Berlin",
+		Period: WEEK,
+		Rounding: &rpc.Rounding{
+			Increment: durationpb.New(time.Hour),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 90*time.Minute, rep.Duration.AsDuration(); want != got {
+		t.Errorf("want duration %s got %s", want, got)
+	}
+	if want, got := 2*time.Hour, rep.RoundedBillableDuration.AsDuration(); want != got {
+		t.Errorf("want rounded billable duration %s got %s", want, got)
+	}
+	if rep.Rounding.Mode != string(billing.RoundUp) || rep.Rounding.Per != string(billing.PerRecord) {
+		t.Errorf("want default rounding got %v", rep.Rounding)
+	}
+	if len(rep.Amounts) != 1 || rep.Amounts[0].Amount != "120.00" || rep.Amounts[0].Currency != "EUR" {
+		t.Errorf("unexpected amounts %v", rep.Amounts)
+	}
+}
+
+func TestGRPCRequestID(t *testing.T) {
+	conn, stop := dialRPC(t, &mockRPCStore{})
+	defer stop()
+	records := rpc.NewRecordsClient(conn)
+
+	var header metadata.MD
+	if _, err := records.DeleteRecord(context.Background(), &rpc.DeleteRecordRequest{RecordId: 1}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if id := header.Get("request-id"); len(id) != 1 || id[0] == "" {
+		t.Errorf("want request id in header got %v", id)
+	}
+}
+
+func TestAuditFromMetadata(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("actor-id", "7"))
+	if want, got := (store.Audit{ActorID: 7, RequestID: "abc"}), auditFromMetadata(ctx, "abc"); want != got {
+		t.Errorf("want audit %+v got %+v", want, got)
+	}
+	// an invalid actor is ignored
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("actor-id", "x"))
+	if want, got := (store.Audit{RequestID: "abc"}), auditFromMetadata(ctx, "abc"); want != got {
+		t.Errorf("want audit %+v got %+v", want, got)
+	}
+}
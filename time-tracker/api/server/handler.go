@@ -4,14 +4,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
 	"time"
 
 	"github.com/fgrimme/time-tracker/time-tracker/api"
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
 	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/fgrimme/time-tracker/time-tracker/openapi"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/hlog"
+	"go.opentelemetry.io/otel"
 )
 
 // HTTP errors
@@ -19,38 +27,209 @@ var (
 	errInternal   = errors.New("internal_error")
 	errNotFound   = errors.New("not_found")
 	errBadRequest = errors.New("bad_request")
+	errLocked     = errors.New("locked")
+	errExists     = errors.New("record_exists")
+	// the record format asked for by the Accept header is unknown
+	errNotAcceptable = errors.New("not_acceptable")
 )
 
+var tracer = otel.Tracer("github.com/fgrimme/time-tracker/time-tracker/api/server")
+
+// zonePattern matches the names of the tz database, e.g. UTC, Europe/Berlin
+// or America/Argentina/Buenos_Aires.
+const zonePattern = `[A-Za-z][A-Za-z0-9_+\-]*(?:/[A-Za-z0-9_+\-]+)*`
+
 const (
 	DAY   = "day"
 	WEEK  = "week"
 	MONTH = "month"
 )
 
-// newHandler creates a HTTP handler that operates on time records.
-func newHandler(ts timeRecordStore, timeout time.Duration, logger zerolog.Logger) (http.Handler, error) {
+// datastore combines the stores the services operate on.
+type datastore interface {
+	timeRecordStore
+	rateStore
+	invoiceStore
+	historyStore
+	timesheetStore
+	timerStore
+	profileStore
+	suggestionStore
+	webhookStore
+	syncStore
+	middleware.IdempotencyStore
+	healthStore
+}
+
+// newHandler creates a HTTP handler that operates on time records. Requests
+// are validated against the OpenAPI document while the settings of live say
+// so. The CORS policy of the settings is applied before routing, so
+// preflights are answered for all routes. Responses are compressed as the
+// client accepts. The rate limits of the settings are
+// kept by limiter, in memory if it is nil. Request metrics are registered with
+// reg unless it is nil.
+func newHandler(ds datastore, broker events.Broker, timeout time.Duration, live *config.Live, limiter middleware.Limiter, reg prometheus.Registerer, logger zerolog.Logger) (http.Handler, error) {
+	router, err := newRouter(ds, broker, timeout, live, limiter, reg, logger)
+	if err != nil {
+		return nil, err
+	}
+	return middleware.Use(router, middleware.NewCompression(), middleware.NewCORS(corsPolicy(live))), nil
+}
+
+// corsPolicy returns the current CORS policy of live.
+func corsPolicy(live *config.Live) func() middleware.CORSPolicy {
+	return func() middleware.CORSPolicy { return live.Load().CORS }
+}
+
+// newRouter creates the router of the services, see newHandler.
+func newRouter(ds datastore, broker events.Broker, timeout time.Duration, live *config.Live, limiter middleware.Limiter, reg prometheus.Registerer, logger zerolog.Logger) (*mux.Router, error) {
+	spec, err := openapi.Load([]byte(openAPISpec))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %v", err)
+	}
+
 	var mw []middleware.Middleware
+	validate := func() bool { return live.Load().ValidateRequests }
+	mw = append(mw, middleware.NewToggle(validate, middleware.NewRequestValidator(spec)))
+	// retries replay the stored response including bad requests, panics
+	// release the key before they are recovered
+	mw = append(mw, middleware.NewIdempotency(ds, idempotencyActor))
+	// rejected requests are cheap, they are counted, traced and logged but
+	// do not reach the idempotency store
+	if limiter == nil {
+		limiter = middleware.NewMemoryLimiter()
+	}
+	limits := func() middleware.RateLimits { return live.Load().RateLimits }
+	mw = append(mw, middleware.NewRateLimit(limiter, limits, rateLimitKey))
 	mw = append(mw, middleware.NewRecoverHandler())
+	if reg != nil {
+		// the status of recovered panics is counted
+		metrics, err := middleware.NewMetrics(reg)
+		if err != nil {
+			return nil, err
+		}
+		mw = append(mw, metrics)
+	}
+	mw = append(mw, middleware.NewTracing())
 	mw = append(mw, middleware.NewContextLog(logger)...)
-	mw = append(mw, middleware.NewCORSHandler())
 
 	// service that handles HTTP requests and holds a store to operate on a database
-	recordSrvc := middleware.Use(&timeRecordService{ts, timeout}, mw...)
+	rs := &timeRecordService{ds, localizer{ds}, timeout}
+	recordSrvc := middleware.Use(rs, mw...)
+	// clients polling the records of a period get a 304 if they did not
+	// change, which saves reading and encoding them
+	etag := middleware.NewETag(rs.recordsTag)
+	recordsSrvc := middleware.Use(rs, append([]middleware.Middleware{etag}, mw...)...)
+	rateSrvc := middleware.Use(&rateService{ds, timeout}, mw...)
+	reportSrvc := middleware.Use(&reportService{ds, ds, localizer{ds}, timeout}, mw...)
+	invoiceSrvc := middleware.Use(&invoiceService{ds, localizer{ds}, timeout}, mw...)
+	historySrvc := middleware.Use(&historyService{ds, timeout}, mw...)
+	timesheetSrvc := middleware.Use(&timesheetService{ds, timeout}, mw...)
+	timerSrvc := middleware.Use(&timerService{ds, localizer{ds}, timeout}, mw...)
+	profileSrvc := middleware.Use(&profileService{ds, timeout}, mw...)
+	suggestionSrvc := middleware.Use(&suggestionService{ds, timeout}, mw...)
+	webhookSrvc := middleware.Use(&webhookService{ds, timeout}, mw...)
+	syncSrvc := middleware.Use(&syncService{ds, timeout}, mw...)
+	eventSrvc := middleware.Use(newEventService(broker, corsPolicy(live)), mw...)
 
 	router := mux.NewRouter()
-	router.Handle("/ready", &readinessHandler{}).Methods("GET")
+	router.Handle("/live", livenessHandler{}).Methods("GET")
+	router.Handle("/ready", &readinessHandler{newChecker(ds)}).Methods("GET")
+	router.Handle("/openapi.json", openAPIHandler{}).Methods("GET")
 
-	router.Handle("/record", recordSrvc).Methods("POST", "OPTIONS")
-	router.Handle("/records", recordSrvc).
-		Methods("GET", "OPTIONS").
+	router.Handle("/record", recordSrvc).Methods("POST")
+	router.Handle("/records", recordsSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Queries("tz", "{tz:"+zonePattern+"}").
+		Queries("ts", "{ts:[0-9]+}").
+		Queries("period", fmt.Sprintf("{period:(?:%s|%s|%s)}", DAY, WEEK, MONTH))
+	router.Handle("/records/search", recordSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Name("search")
+	router.Handle("/records/{id:[0-9]+}", recordSrvc).Methods("PUT", "DELETE").Name("record_id")
+	router.Handle("/records/{id:[0-9]+}/restore", recordSrvc).Methods("POST").Name("restore")
+	router.Handle("/trash", recordSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}")
+	router.Handle("/records/{id:[0-9]+}/history", historySrvc).Methods("GET").Name("record_history")
+	router.Handle("/history", historySrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}")
+	router.Handle("/sync", syncSrvc).Methods("POST").Name("sync")
+	router.Handle("/changes", syncSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Name("changes")
+	router.Handle("/report", reportSrvc).
+		Methods("GET").
 		Queries("user_id", "{id:[0-9]+}").
-		Queries("tz", "{tz:[A-Za-z]+/[A-Za-z]+}").
+		Queries("tz", "{tz:"+zonePattern+"}").
 		Queries("ts", "{ts:[0-9]+}").
 		Queries("period", fmt.Sprintf("{period:(?:%s|%s|%s)}", DAY, WEEK, MONTH))
 
+	router.Handle("/rate", rateSrvc).Methods("POST")
+	router.Handle("/rates", rateSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}")
+
+	router.Handle("/invoice", invoiceSrvc).Methods("POST").Name("invoice")
+	router.Handle("/invoices/{id:[0-9]+}", invoiceSrvc).Methods("GET").Name("invoices")
+	router.Handle("/invoices/{id:[0-9]+}/void", invoiceSrvc).Methods("POST").Name("void")
+
+	router.Handle("/timesheet", timesheetSrvc).Methods("POST").Name("timesheet")
+	router.Handle("/timesheets", timesheetSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Name("timesheets")
+	router.Handle("/timesheets/pending", timesheetSrvc).
+		Methods("GET").
+		Queries("lead_id", "{lead_id:[0-9]+}").
+		Name("pending")
+	router.Handle("/timesheets/{id:[0-9]+}", timesheetSrvc).Methods("GET").Name("timesheet_id")
+	router.Handle("/timesheets/{id:[0-9]+}/{action:(?:submit|approve|reject)}", timesheetSrvc).
+		Methods("POST").
+		Name("timesheet_state")
+
+	router.Handle("/timer", timerSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Name("timer")
+	router.Handle("/timer/start", timerSrvc).Methods("POST").Name("timer_start")
+	router.Handle("/timer/stop", timerSrvc).Methods("POST").Name("timer_stop")
+
+	router.Handle("/profile", profileSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}")
+	router.Handle("/profile", profileSrvc).Methods("PUT")
+
+	router.Handle("/suggestions/names", suggestionSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}").
+		Name("name_suggestions")
+	router.Handle("/suggestions/names/merge", suggestionSrvc).Methods("POST").Name("merge_names")
+
+	router.Handle("/events", eventSrvc).
+		Methods("GET").
+		Queries("user_id", "{id:[0-9]+}")
+
+	router.Handle("/webhook", webhookSrvc).Methods("POST").Name("webhook")
+	router.Handle("/webhooks", webhookSrvc).Methods("GET").Name("webhooks")
+	router.Handle("/webhooks/{id:[0-9]+}", webhookSrvc).Methods("DELETE").Name("webhook_id")
+	router.Handle("/webhooks/{id:[0-9]+}/deliveries", webhookSrvc).Methods("GET").Name("deliveries")
+
 	return router, nil
 }
 
+// openAPIHandler serves the OpenAPI document of the API.
+type openAPIHandler struct{}
+
+func (openAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, openAPISpec)
+}
+
 // encodeJSON encodes v to w in JSON format.
 func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -60,6 +239,17 @@ func encodeJSON(w http.ResponseWriter, r *http.Request, v interface{}, status in
 	}
 }
 
+// routeName returns the name of the route that matched the request or the
+// last path segment if the route has no name. Routes with path variables are
+// named so services can switch on the route name.
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+		return route.GetName()
+	}
+	_, name := path.Split(r.URL.Path)
+	return name
+}
+
 func loggerFromRequest(r *http.Request) *zerolog.Logger {
 	logger := hlog.FromRequest(r).With().
 		Str("method", r.Method).
@@ -68,7 +258,8 @@ func loggerFromRequest(r *http.Request) *zerolog.Logger {
 	return &logger
 }
 
-// writeError writes an error to the HTTP response in JSON format.
+// writeError writes an error to the HTTP response in JSON format. The message
+// of the error is in the language accepted by the client.
 func writeError(w http.ResponseWriter, r *http.Request, err error, code int) {
 	// prepare log
 	logger := loggerFromRequest(r).With().
@@ -85,5 +276,6 @@ func writeError(w http.ResponseWriter, r *http.Request, err error, code int) {
 	} else {
 		logger.Debug().Msg("http error")
 	}
-	encodeJSON(w, r, &api.Error{Err: err.Error()}, code)
+	msg := locale.Match(r.Header.Get("Accept-Language")).Message(err.Error())
+	encodeJSON(w, r, &api.Error{Err: err.Error(), Message: msg}, code)
 }
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/hlog"
+)
+
+// defaultChangeLimit is the number of changes returned by the change feed if
+// the request does not specify a limit.
+const defaultChangeLimit = 100
+
+// historyStore provides the history of changes to time records.
+type historyStore interface {
+	History(ctx context.Context, recordID uint64) ([]store.Change, error)
+	Changes(ctx context.Context, userID, since uint64, limit int) ([]store.Change, error)
+}
+
+// historyService provides API methods to read the history of time records.
+type historyService struct {
+	historyStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the history endpoints.
+func (hs *historyService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), hs.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "record_history":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		hs.getHistory(ctx, w, r, id)
+		return
+
+	case "history":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		q := r.URL.Query()
+		var since uint64
+		if s := q.Get("since"); s != "" {
+			if since, err = strconv.ParseUint(s, 10, 64); err != nil {
+				writeError(w, r, err, http.StatusBadRequest)
+				return
+			}
+		}
+		limit := defaultChangeLimit
+		if l := q.Get("limit"); l != "" {
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > 1000 {
+				writeError(w, r, errBadRequest, http.StatusBadRequest)
+				return
+			}
+		}
+		hs.getChanges(ctx, w, r, userID, since, limit)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (hs *historyService) getHistory(ctx context.Context, w http.ResponseWriter, r *http.Request, recordID uint64) {
+	changes, err := hs.History(ctx, recordID)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	if len(changes) == 0 {
+		writeError(w, r, errNotFound, http.StatusNotFound)
+		return
+	}
+	encodeJSON(w, r, changes, http.StatusOK)
+}
+
+func (hs *historyService) getChanges(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, since uint64, limit int) {
+	changes, err := hs.Changes(ctx, userID, since, limit)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, changes, http.StatusOK)
+}
+
+// auditFromRequest returns the audit information of a request. The request id
+// is generated by hlog.RequestIDHandler. Since there is no authentication in
+// place, the actor is taken from the Actor-Id header. If it is missing, the
+// user owning the record is recorded as actor.
+func auditFromRequest(r *http.Request) store.Audit {
+	var a store.Audit
+	if id, ok := hlog.IDFromRequest(r); ok {
+		a.RequestID = id.String()
+	}
+	if actor, err := strconv.ParseUint(r.Header.Get("Actor-Id"), 10, 64); err == nil {
+		a.ActorID = actor
+	}
+	return a
+}
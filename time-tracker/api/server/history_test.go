@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/zerolog/hlog"
+)
+
+func TestAuditFromRequest(t *testing.T) {
+	tests := []struct {
+		d     string // description of test case
+		actor string // Actor-Id header
+		want  uint64 // expected actor id
+	}{
+		{d: "expect actor from header", actor: "7", want: 7},
+		{d: "expect unknown actor without header", actor: "", want: 0},
+		{d: "expect unknown actor for invalid header", actor: "foo", want: 0},
+	}
+	for _, tc := range tests {
+		var got store.Audit
+		h := hlog.RequestIDHandler("req_id", "Request-Id")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = auditFromRequest(r)
+		}))
+		r := httptest.NewRequest("PUT", "/records/1", nil)
+		r.Header.Set("Actor-Id", tc.actor)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got.ActorID != tc.want {
+			t.Errorf("%s: want actor %d got %d", tc.d, tc.want, got.ActorID)
+		}
+		if got.RequestID == "" {
+			t.Errorf("%s: want request id", tc.d)
+		}
+	}
+}
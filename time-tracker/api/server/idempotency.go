@@ -0,0 +1,9 @@
+package server
+
+import "net/http"
+
+// idempotencyActor returns the actor whose idempotency keys a request uses,
+// the actor of its audit information.
+func idempotencyActor(r *http.Request) uint64 {
+	return auditFromRequest(r).ActorID
+}
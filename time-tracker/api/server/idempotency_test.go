@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/zerolog"
+)
+
+// mockKeyStore keeps idempotency keys in memory.
+type mockKeyStore struct {
+	mu     sync.Mutex
+	hashes map[store.IdempotencyKey]string
+	resps  map[store.IdempotencyKey]*store.StoredResponse
+}
+
+func (ks *mockKeyStore) ReserveKey(ctx context.Context, key store.IdempotencyKey, hash string) (*store.StoredResponse, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.hashes == nil {
+		ks.hashes, ks.resps = map[store.IdempotencyKey]string{}, map[store.IdempotencyKey]*store.StoredResponse{}
+	}
+	h, ok := ks.hashes[key]
+	switch {
+	case !ok:
+		ks.hashes[key] = hash
+		return nil, nil
+	case h != hash:
+		return nil, store.ErrKeyMismatch
+	case ks.resps[key] == nil:
+		return nil, store.ErrKeyInUse
+	}
+	return ks.resps[key], nil
+}
+func (ks *mockKeyStore) SaveResponse(ctx context.Context, key store.IdempotencyKey, resp store.StoredResponse) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.resps[key] = &resp
+	return nil
+}
+func (ks *mockKeyStore) ReleaseKey(ctx context.Context, key store.IdempotencyKey) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.resps[key] == nil {
+		delete(ks.hashes, key)
+	}
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	ms := &mockDatastore{mockRPCStore: &mockRPCStore{errs: map[uint64]error{3: store.ErrRecordLocked, 6: errInternal}}}
+	h, err := newHandler(ms, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(userID string) string {
+		return `{"user_id":` + userID + `,"start_time":1577833200,"start_loc":"UTC","stop_time":1577836800,"stop_loc":"UTC"}`
+	}
+	tests := []struct {
+		d        string // description of test case
+		m        string // http method of test request
+		u        string // route of test request
+		k        string // idempotency key
+		actor    string // Actor-Id header
+		p        string // request payload
+		s        int    // expected http status code
+		replayed bool
+		created  bool // the request reached the store
+	}{
+		{d: "expect record created", m: "POST", u: "/record", k: "a", p: record("1"), s: http.StatusOK, created: true},
+		{d: "expect response replayed", m: "POST", u: "/record", k: "a", p: record("1"), s: http.StatusOK, replayed: true},
+		{d: "expect mismatch for other body", m: "POST", u: "/record", k: "a", p: record("7"), s: http.StatusUnprocessableEntity},
+		{d: "expect key scoped by actor", m: "POST", u: "/record", k: "a", actor: "7", p: record("1"), s: http.StatusOK, created: true},
+		{d: "expect response of actor replayed", m: "POST", u: "/record", k: "a", actor: "7", p: record("1"), s: http.StatusOK, replayed: true},
+		{d: "expect key scoped by route", m: "PUT", u: "/records/1", k: "a", p: record("1"), s: http.StatusOK},
+		{d: "expect mismatch for other query", m: "PUT", u: "/records/1?x=1", k: "a", p: record("1"), s: http.StatusUnprocessableEntity},
+		{d: "expect record created without key", m: "POST", u: "/record", p: record("1"), s: http.StatusOK, created: true},
+		{d: "expect bad request for invalid key", m: "POST", u: "/record", k: "a b", p: record("1"), s: http.StatusBadRequest},
+		{d: "expect conflict stored", m: "POST", u: "/record", k: "b", p: record("3"), s: http.StatusConflict, created: true},
+		{d: "expect conflict replayed", m: "POST", u: "/record", k: "b", p: record("3"), s: http.StatusConflict, replayed: true},
+		{d: "expect internal error", m: "POST", u: "/record", k: "c", p: record("6"), s: http.StatusInternalServerError, created: true},
+		{d: "expect internal error not stored", m: "POST", u: "/record", k: "c", p: record("6"), s: http.StatusInternalServerError, created: true},
+	}
+	for _, tc := range tests {
+		ms.created.UserID = 0
+		req := httptest.NewRequest(tc.m, tc.u, strings.NewReader(tc.p))
+		if tc.k != "" {
+			req.Header.Set("Idempotency-Key", tc.k)
+		}
+		if tc.actor != "" {
+			req.Header.Set("Actor-Id", tc.actor)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.s {
+			t.Errorf("%s: want status code %d got %d: %s", tc.d, tc.s, w.Code, w.Body)
+		}
+		if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != tc.replayed {
+			t.Errorf("%s: want replayed %v got %v", tc.d, tc.replayed, replayed)
+		}
+		if created := ms.created.UserID != 0; created != tc.created {
+			t.Errorf("%s: want record created %v got %v", tc.d, tc.created, created)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/invoice"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+)
+
+// Invoice errors exposed to clients.
+var (
+	errNothingToBill   = errors.New("nothing_to_bill")
+	errUnrated         = errors.New("unrated_records")
+	errMixedCurrencies = errors.New("mixed_currencies")
+	errConflict        = errors.New("conflict")
+)
+
+// invoiceStore handles operations on invoices.
+type invoiceStore interface {
+	UnbilledRecords(ctx context.Context, clientID uint64, from, to time.Time) ([]store.TimeRecord, error)
+	ClientRates(ctx context.Context, clientID uint64) ([]billing.Rate, error)
+	CreateInvoice(ctx context.Context, inv store.Invoice) (*store.Invoice, error)
+	Invoice(ctx context.Context, id uint64) (*store.Invoice, error)
+	VoidInvoice(ctx context.Context, id uint64) (*store.Invoice, error)
+}
+
+// invoiceRequest is the payload of requests to create an invoice. The period
+// is given as seconds since UNIX epoch, the end is exclusive. Durations are
+// billed exact if no rounding policy is given.
+type invoiceRequest struct {
+	ClientID uint64           `json:"client_id"`
+	From     int64            `json:"from"`
+	To       int64            `json:"to"`
+	Rounding billing.Rounding `json:"rounding"`
+}
+
+// invoiceService provides API methods to operate on invoices.
+type invoiceService struct {
+	invoiceStore
+	localizer
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the invoice endpoints.
+func (is *invoiceService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), is.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "invoice":
+		var ir invoiceRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&ir); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if ir.ClientID == 0 || ir.To <= ir.From || ir.Rounding.Validate() != nil {
+			writeError(w, r, errBadRequest, http.StatusBadRequest)
+			return
+		}
+		is.createInvoice(ctx, w, r, ir)
+		return
+
+	case "invoices":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		is.getInvoice(ctx, w, r, id, r.URL.Query().Get("format"))
+		return
+
+	case "void":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		is.voidInvoice(ctx, w, r, id)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (is *invoiceService) createInvoice(ctx context.Context, w http.ResponseWriter, r *http.Request, ir invoiceRequest) {
+	from, to := time.Unix(ir.From, 0), time.Unix(ir.To, 0)
+	recs, err := is.UnbilledRecords(ctx, ir.ClientID, from, to)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	rates, err := is.ClientRates(ctx, ir.ClientID)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	inv, err := invoice.Build(ir.ClientID, from, to, recs, rates, ir.Rounding)
+	switch {
+	case err == invoice.ErrNoRecords:
+		writeError(w, r, errNothingToBill, http.StatusUnprocessableEntity)
+		return
+	case err == invoice.ErrMixedCurrencies:
+		writeError(w, r, errMixedCurrencies, http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		loggerFromRequest(r).Debug().Err(err).Msg("failed to build invoice")
+		writeError(w, r, errUnrated, http.StatusUnprocessableEntity)
+		return
+	}
+	created, err := is.CreateInvoice(ctx, inv)
+	if err == store.ErrRecordLocked {
+		// records got billed or changed by a concurrent request
+		writeError(w, r, errConflict, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, invoice.NewDocument(*created), http.StatusOK)
+}
+
+func (is *invoiceService) getInvoice(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64, format string) {
+	inv, err := is.Invoice(ctx, id)
+	if err == store.ErrNotFound {
+		writeError(w, r, errNotFound, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	switch format {
+	case "", "json":
+		encodeJSON(w, r, invoice.NewDocument(*inv), http.StatusOK)
+	case "html":
+		// the document is read by the actor, e.g. the accountant who sends
+		// it to the client
+		f := is.formatter(ctx, r, auditFromRequest(r).ActorID)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Language", f.Lang())
+		w.Header().Add("Vary", "Accept-Language")
+		if err := invoice.WriteHTML(w, *inv, f); err != nil {
+			loggerFromRequest(r).Error().Err(err).Msg("failed to render invoice")
+		}
+	case "ubl":
+		w.Header().Set("Content-Type", "application/xml")
+		if err := invoice.WriteUBL(w, *inv); err != nil {
+			loggerFromRequest(r).Error().Err(err).Msg("failed to render invoice")
+		}
+	default:
+		writeError(w, r, errBadRequest, http.StatusBadRequest)
+	}
+}
+
+func (is *invoiceService) voidInvoice(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64) {
+	inv, err := is.VoidInvoice(ctx, id)
+	switch err {
+	case nil:
+		encodeJSON(w, r, invoice.NewDocument(*inv), http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrInvoiceVoided:
+		writeError(w, r, errConflict, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
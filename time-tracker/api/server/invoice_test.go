@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+)
+
+// uses the invoice id to get the test data.
+type mockInvoiceStore struct{}
+
+func (is *mockInvoiceStore) UnbilledRecords(ctx context.Context, clientID uint64, from, to time.Time) ([]store.TimeRecord, error) {
+	return nil, nil
+}
+func (is *mockInvoiceStore) ClientRates(ctx context.Context, clientID uint64) ([]billing.Rate, error) {
+	return nil, nil
+}
+func (is *mockInvoiceStore) CreateInvoice(ctx context.Context, inv store.Invoice) (*store.Invoice, error) {
+	return &inv, nil
+}
+func (is *mockInvoiceStore) Invoice(ctx context.Context, id uint64) (*store.Invoice, error) {
+	return invoiceTests[id].inv, invoiceTests[id].e
+}
+func (is *mockInvoiceStore) VoidInvoice(ctx context.Context, id uint64) (*store.Invoice, error) {
+	return invoiceTests[id].inv, invoiceTests[id].e
+}
+
+// test cases indexed by invoice id
+var invoiceTests = map[uint64]struct {
+	d   string         // description of test case
+	inv *store.Invoice // mock store response
+	e   error          // mock store error
+	m   string         // HTTP method
+	u   string         // route of test request
+	s   int            // expected http status code
+	ct  string         // expected content type
+}{
+	1: {
+		d: "expect unknown invoice to result in 404",
+		e: store.ErrNotFound,
+		m: "GET",
+		u: "/invoices/1",
+		s: http.StatusNotFound,
+	},
+	2: {
+		d: "expect voiding a voided invoice to result in 409",
+		e: store.ErrInvoiceVoided,
+		m: "POST",
+		u: "/invoices/2/void",
+		s: http.StatusConflict,
+	},
+	3: {
+		d:   "expect invoice rendered as html",
+		inv: &store.Invoice{InvoiceID: 3},
+		m:   "GET",
+		u:   "/invoices/3?format=html",
+		s:   http.StatusOK,
+		ct:  "text/html; charset=utf-8",
+	},
+	4: {
+		d:   "expect invoice rendered as ubl",
+		inv: &store.Invoice{InvoiceID: 4},
+		m:   "GET",
+		u:   "/invoices/4?format=ubl",
+		s:   http.StatusOK,
+		ct:  "application/xml",
+	},
+	5: {
+		d: "expect empty invoice to result in 422",
+		m: "POST",
+		u: "/invoice",
+		s: http.StatusUnprocessableEntity,
+	},
+}
+
+func TestServeHTTPInvoice(t *testing.T) {
+	is := &invoiceService{&mockInvoiceStore{}, localizer{&mockProfileStore{}}, 200 * time.Millisecond}
+	router := mux.NewRouter()
+	router.Handle("/invoice", is).Methods("POST").Name("invoice")
+	router.Handle("/invoices/{id:[0-9]+}", is).Methods("GET").Name("invoices")
+	router.Handle("/invoices/{id:[0-9]+}/void", is).Methods("POST").Name("void")
+
+	for _, tc := range invoiceTests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			var r *http.Request
+			if tt.u == "/invoice" {
+				r = httptest.NewRequest(tt.m, tt.u, strings.NewReader(`{"client_id":3,"from":0,"to":1}`))
+			} else {
+				r = httptest.NewRequest(tt.m, tt.u, nil)
+			}
+			router.ServeHTTP(w, r)
+			if want, got := tt.s, w.Code; want != got {
+				t.Errorf("want status code %d got %d", want, got)
+			}
+			if want, got := tt.ct, w.Header().Get("Content-Type"); want != "" && want != got {
+				t.Errorf("want content type %s got %s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+// MetricsServer serves the metrics gathered by a registry at /metrics for
+// Prometheus to scrape.
+type MetricsServer struct {
+	server *http.Server
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	scraped chan struct{} // closed on the next scrape, if waited for
+}
+
+// NewMetricsServer returns a MetricsServer serving the metrics of g.
+func NewMetricsServer(addr string, g prometheus.Gatherer, logger zerolog.Logger) *MetricsServer {
+	s := &MetricsServer{logger: logger}
+	metrics := promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metrics.ServeHTTP(w, r)
+		s.mu.Lock()
+		if s.scraped != nil {
+			close(s.scraped)
+			s.scraped = nil
+		}
+		s.mu.Unlock()
+	}))
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      mux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: writeTimeout,
+	}
+	return s
+}
+
+func (s *MetricsServer) Run() {
+	s.logger.Info().Msgf("metrics server listening on %s", s.server.Addr)
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		s.logger.Fatal().Err(err).Msg("metrics server exited with error")
+	}
+}
+
+// Shutdown waits for the next scrape, so the final values of the metrics are
+// collected, and shuts the server down. The wait ends early when ctx is done,
+// so the deadline of ctx should be longer than the scrape interval.
+func (s *MetricsServer) Shutdown(ctx context.Context) {
+	s.logger.Info().Msg("waiting for a final scrape of the metrics")
+	scraped := make(chan struct{})
+	s.mu.Lock()
+	s.scraped = scraped
+	s.mu.Unlock()
+	select {
+	case <-scraped:
+	case <-ctx.Done():
+		s.logger.Warn().Err(ctx.Err()).Msg("metrics were not scraped before shutdown")
+	}
+
+	s.logger.Info().Msg("shutting down metrics server")
+	// the scrape has been answered, a short grace period is enough
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error().Err(err).Msg("metrics server shutdown error")
+	}
+}
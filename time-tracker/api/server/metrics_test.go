@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	ms := &mockDatastore{mockRPCStore: &mockRPCStore{errs: map[uint64]error{6: errInternal}}}
+	h, err := newHandler(ms, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, reg, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*http.Request{
+		httptest.NewRequest("DELETE", "/records/1", nil),
+		httptest.NewRequest("DELETE", "/records/7", nil),
+		httptest.NewRequest("DELETE", "/records/6", nil),
+		httptest.NewRequest("GET", "/rates?user_id=1", nil),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	tests := []struct {
+		d      string // description of test case
+		labels string // labels of the counter
+		n      float64
+	}{
+		{d: "expect requests of a route counted together", labels: "DELETE /records/{id:[0-9]+} 204", n: 2},
+		{d: "expect failed requests counted by status", labels: "DELETE /records/{id:[0-9]+} 500", n: 1},
+		{d: "expect requests counted by method", labels: "GET /rates 200", n: 1},
+	}
+	counts := map[string]float64{}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var values []string
+			for _, l := range m.GetLabel() {
+				values = append(values, l.GetValue())
+			}
+			// labels are sorted by name: method, route, status
+			counts[strings.Join(values, " ")] = m.GetCounter().GetValue()
+		}
+	}
+	for _, tc := range tests {
+		if got := counts[tc.labels]; got != tc.n {
+			t.Errorf("%s: want %v requests with labels %s got %v", tc.d, tc.n, tc.labels, got)
+		}
+	}
+
+	// the collectors are registered once per handler
+	if _, err := newHandler(ms, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, reg, zerolog.Nop()); err == nil {
+		t.Error("want error registering metrics twice")
+	}
+}
+
+func TestMetricsServerShutdown(t *testing.T) {
+	s := NewMetricsServer("localhost:0", prometheus.NewRegistry(), zerolog.Nop())
+
+	// the shutdown waits for the next scrape
+	done := make(chan struct{})
+	go func() {
+		s.Shutdown(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("want shutdown to wait for a scrape")
+	case <-time.After(20 * time.Millisecond):
+	}
+	for {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("want status code %d got %d", http.StatusOK, w.Code)
+		}
+		select {
+		case <-done:
+		case <-time.After(10 * time.Millisecond):
+			// the shutdown may not be waiting yet
+			continue
+		}
+		break
+	}
+
+	// the wait ends with the context
+	s = NewMetricsServer("localhost:0", prometheus.NewRegistry(), zerolog.Nop())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.Shutdown(ctx)
+}
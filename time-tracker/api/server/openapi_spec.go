@@ -0,0 +1,4823 @@
+package server
+
+// openAPISpec is the OpenAPI 3 document of the HTTP API. It is kept in sync
+// with the routes of newHandler by the tests, which validate requests and
+// responses of all routes against it.
+const openAPISpec = `{
+	"openapi": "3.0.3",
+	"info": {
+		"title": "Time Tracker API",
+		"version": "1.0.0"
+	},
+	"paths": {
+		"/live": {
+			"get": {
+				"operationId": "getLive",
+				"summary": "Liveness of the service, dependencies are not checked",
+				"responses": {
+					"200": {
+						"description": "alive",
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object",
+									"properties": {
+										"status": {
+											"type": "string",
+											"enum": [
+												"ok"
+											]
+										}
+									},
+									"required": [
+										"status"
+									]
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/ready": {
+			"get": {
+				"operationId": "getReady",
+				"summary": "Readiness of the service with the outcome of every check",
+				"responses": {
+					"200": {
+						"description": "ready",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Health"
+								}
+							}
+						}
+					},
+					"503": {
+						"description": "a check fails or the service is shutting down",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Health"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/openapi.json": {
+			"get": {
+				"operationId": "getOpenAPI",
+				"summary": "This OpenAPI document",
+				"responses": {
+					"200": {
+						"description": "OpenAPI 3 document",
+						"content": {
+							"application/json": {
+								"schema": {
+									"type": "object"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/record": {
+			"post": {
+				"operationId": "createRecord",
+				"summary": "Create a time record",
+				"parameters": [
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/TimeRecordInput"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "created record",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecord"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "locked, the record is inside an approved timesheet, or record_exists, a record with the uuid exists, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/records": {
+			"get": {
+				"operationId": "getRecords",
+				"summary": "Fetch a user's records in the day, week or month containing ts",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "tz",
+						"in": "query",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin",
+						"required": true,
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "ts",
+						"in": "query",
+						"description": "point in time in the period, seconds since UNIX epoch",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64"
+						}
+					},
+					{
+						"name": "period",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "string",
+							"enum": [
+								"day",
+								"week",
+								"month"
+							]
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "If-None-Match",
+						"in": "header",
+						"description": "ETag of the records the client has, answered with 304 if they did not change",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "records",
+						"headers": {
+							"ETag": {
+								"description": "weak tag of the records, changes with every change of them",
+								"schema": {
+									"type": "string"
+								}
+							}
+						},
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecords"
+								}
+							}
+						}
+					},
+					"304": {
+						"description": "the records did not change",
+						"headers": {
+							"ETag": {
+								"description": "weak tag of the records, changes with every change of them",
+								"schema": {
+									"type": "string"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/records/search": {
+			"get": {
+				"operationId": "searchRecords",
+				"summary": "Search a user's records by the words of their name and notes, best matches first",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "q",
+						"in": "query",
+						"description": "words the records must all contain, e.g. budget \"client call\" ma*. Quoted phrases match consecutive words, words ending in * match words with the prefix",
+						"required": true,
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "from",
+						"in": "query",
+						"description": "records stopped at or after from, seconds since UNIX epoch",
+						"schema": {
+							"type": "integer",
+							"format": "int64"
+						}
+					},
+					{
+						"name": "to",
+						"in": "query",
+						"description": "records started before to, seconds since UNIX epoch",
+						"schema": {
+							"type": "integer",
+							"format": "int64"
+						}
+					},
+					{
+						"name": "project_id",
+						"in": "query",
+						"description": "records of the project",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "tag",
+						"in": "query",
+						"description": "records with the tag, repeat the parameter for records with all tags",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "limit",
+						"in": "query",
+						"description": "maximum number of results, defaults to 20",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 1,
+							"maximum": 100
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "matching records",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/SearchResults"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/records/{id}": {
+			"put": {
+				"operationId": "updateRecord",
+				"summary": "Update a time record",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/TimeRecordInput"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "updated record",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecord"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "locked, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			},
+			"delete": {
+				"operationId": "deleteRecord",
+				"summary": "Move a time record to the trash",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"responses": {
+					"204": {
+						"description": "deleted"
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "locked, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/records/{id}/restore": {
+			"post": {
+				"operationId": "restoreRecord",
+				"summary": "Restore a time record from the trash",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "restored record",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecord"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "locked, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/trash": {
+			"get": {
+				"operationId": "getTrash",
+				"summary": "Fetch a user's deleted records",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "deleted records",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecords"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/records/{id}/history": {
+			"get": {
+				"operationId": "getRecordHistory",
+				"summary": "Fetch the changes of a time record",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "changes, oldest first",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Changes"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/history": {
+			"get": {
+				"operationId": "getChanges",
+				"summary": "Fetch the change feed of a user",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "since",
+						"in": "query",
+						"description": "id of the last change seen",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "limit",
+						"in": "query",
+						"description": "maximum number of entries, defaults to 100",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 1,
+							"maximum": 1000
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "changes after since",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Changes"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/sync": {
+			"post": {
+				"operationId": "sync",
+				"summary": "Apply the mutations a client made offline",
+				"parameters": [
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/SyncRequest"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "outcome of every mutation",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/SyncResponse"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/changes": {
+			"get": {
+				"operationId": "getRecordFeed",
+				"summary": "Fetch the records of a user changed after a cursor",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "since",
+						"in": "query",
+						"description": "cursor of the last entry seen",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "limit",
+						"in": "query",
+						"description": "maximum number of entries, defaults to 100",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 1,
+							"maximum": 1000
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "records changed after since",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Feed"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/report": {
+			"get": {
+				"operationId": "getReport",
+				"summary": "Report a user's records in a period",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "tz",
+						"in": "query",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin",
+						"required": true,
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "ts",
+						"in": "query",
+						"description": "point in time in the period, seconds since UNIX epoch",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64"
+						}
+					},
+					{
+						"name": "period",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "string",
+							"enum": [
+								"day",
+								"week",
+								"month"
+							]
+						}
+					},
+					{
+						"name": "round",
+						"in": "query",
+						"description": "rounding increment in seconds",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "round_mode",
+						"in": "query",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"up",
+								"down",
+								"nearest"
+							]
+						}
+					},
+					{
+						"name": "round_per",
+						"in": "query",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"record",
+								"day_project",
+								"line"
+							]
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "report",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Report"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/rate": {
+			"post": {
+				"operationId": "createRate",
+				"summary": "Create an hourly rate",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/RateInput"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "created rate",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Rate"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				},
+				"parameters": [
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				]
+			}
+		},
+		"/rates": {
+			"get": {
+				"operationId": "getRates",
+				"summary": "Fetch the rates applying to a user",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "rates",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Rates"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/invoice": {
+			"post": {
+				"operationId": "createInvoice",
+				"summary": "Invoice the unbilled records of a client",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/InvoiceRequest"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "created invoice",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Invoice"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "conflict, records got billed or changed concurrently, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "nothing_to_bill, unrated_records or mixed_currencies, or idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				},
+				"parameters": [
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				]
+			}
+		},
+		"/invoices/{id}": {
+			"get": {
+				"operationId": "getInvoice",
+				"summary": "Fetch an invoice",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "defaults to json, html is formatted in the locale of the Actor-Id",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"json",
+								"html",
+								"ubl"
+							]
+						}
+					},
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "invoice",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Invoice"
+								}
+							},
+							"text/html": {
+								"schema": {
+									"type": "string"
+								}
+							},
+							"application/xml": {
+								"schema": {
+									"type": "string",
+									"description": "UBL 2.1 invoice"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/invoices/{id}/void": {
+			"post": {
+				"operationId": "voidInvoice",
+				"summary": "Void an invoice and unlock its records",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "voided invoice",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Invoice"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "conflict, voided already, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timesheet": {
+			"post": {
+				"operationId": "createTimesheet",
+				"summary": "Create the timesheet of a week",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/TimesheetRequest"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "created or existing timesheet",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timesheet"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				},
+				"parameters": [
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				]
+			}
+		},
+		"/timesheets": {
+			"get": {
+				"operationId": "getTimesheets",
+				"summary": "Fetch the timesheets of a user",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "timesheets",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timesheets"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timesheets/pending": {
+			"get": {
+				"operationId": "getPendingTimesheets",
+				"summary": "Fetch the submitted timesheets waiting for a lead",
+				"parameters": [
+					{
+						"name": "lead_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "timesheets",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timesheets"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timesheets/{id}": {
+			"get": {
+				"operationId": "getTimesheet",
+				"summary": "Fetch a timesheet with its events and records",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "timesheet",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timesheet"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timesheets/{id}/{action}": {
+			"post": {
+				"operationId": "setTimesheetState",
+				"summary": "Submit, approve or reject a timesheet on behalf of the Actor-Id",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "action",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "string",
+							"enum": [
+								"submit",
+								"approve",
+								"reject"
+							]
+						}
+					},
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/StateRequest"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "timesheet",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timesheet"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"403": {
+						"description": "forbidden",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "invalid_transition, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "comment_required, or idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timer": {
+			"get": {
+				"operationId": "getTimer",
+				"summary": "Fetch the running timer of a user",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "running timer",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timer"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timer/start": {
+			"post": {
+				"operationId": "startTimer",
+				"summary": "Start a timer",
+				"parameters": [
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/TimerInput"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "started timer",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Timer"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "timer_running, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/timer/stop": {
+			"post": {
+				"operationId": "stopTimer",
+				"summary": "Stop the running timer and create a time record",
+				"parameters": [
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "format",
+						"in": "query",
+						"description": "format of the records, human by default, takes precedence over the format parameter of the Accept header, e.g. application/json; format=rfc3339",
+						"schema": {
+							"type": "string",
+							"enum": [
+								"human",
+								"rfc3339"
+							]
+						}
+					},
+					{
+						"name": "Accept-Language",
+						"in": "header",
+						"description": "languages the client prefers, decides the locale of users without one in their profile, e.g. da, en;q=0.8. Supported are en, de and da",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/TimerStop"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "created record",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/TimeRecord"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"406": {
+						"description": "not_acceptable, the format of the Accept header is unknown",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "locked, or idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "invalid_stop, or idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/profile": {
+			"get": {
+				"operationId": "getProfile",
+				"summary": "Fetch the preferences of a user, empty if the user never set them",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "profile",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Profile"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			},
+			"put": {
+				"operationId": "setProfile",
+				"summary": "Set the preferences of a user",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/Profile"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "profile",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Profile"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request, e.g. the locale is not supported",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				},
+				"parameters": [
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				]
+			}
+		},
+		"/suggestions/names": {
+			"get": {
+				"operationId": "getNameSuggestions",
+				"summary": "Suggest the names of a user's records completing a prefix, names with the prefix first and then names with a similar word, each by how often and recently they were used",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "prefix",
+						"in": "query",
+						"description": "typed part of the name, all names if empty",
+						"schema": {
+							"type": "string"
+						}
+					},
+					{
+						"name": "limit",
+						"in": "query",
+						"description": "maximum number of suggestions, defaults to 10",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 1,
+							"maximum": 50
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "suggested names",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/NameSuggestions"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/suggestions/names/merge": {
+			"post": {
+				"operationId": "mergeNames",
+				"summary": "Rename a user's records with names similar to a canonical name to it",
+				"parameters": [
+					{
+						"name": "Actor-Id",
+						"in": "header",
+						"description": "user acting on behalf of the request, recorded in the history",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/NameMerge"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "merged names",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/NameMergeResult"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/events": {
+			"get": {
+				"operationId": "getEvents",
+				"summary": "Stream the changes of a user's records and timers as Server-Sent Events, or over a WebSocket if the request asks for an upgrade",
+				"parameters": [
+					{
+						"name": "user_id",
+						"in": "query",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					}
+				],
+				"responses": {
+					"101": {
+						"description": "switched to the WebSocket protocol"
+					},
+					"200": {
+						"description": "event stream",
+						"content": {
+							"text/event-stream": {
+								"schema": {
+									"type": "string"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/webhook": {
+			"post": {
+				"operationId": "createWebhook",
+				"summary": "Subscribe a URL to events",
+				"requestBody": {
+					"required": true,
+					"content": {
+						"application/json": {
+							"schema": {
+								"$ref": "#/components/schemas/WebhookInput"
+							}
+						}
+					}
+				},
+				"responses": {
+					"200": {
+						"description": "subscription",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Webhook"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				},
+				"parameters": [
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				]
+			}
+		},
+		"/webhooks": {
+			"get": {
+				"operationId": "getWebhooks",
+				"summary": "Fetch the webhook subscriptions",
+				"responses": {
+					"200": {
+						"description": "subscriptions",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Webhooks"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/webhooks/{id}": {
+			"delete": {
+				"operationId": "deleteWebhook",
+				"summary": "Delete a webhook subscription",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "Idempotency-Key",
+						"in": "header",
+						"description": "key to safely retry the request, the stored response is replayed to requests with the same key",
+						"schema": {
+							"type": "string",
+							"maxLength": 255
+						}
+					}
+				],
+				"responses": {
+					"204": {
+						"description": "deleted"
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"409": {
+						"description": "idempotency_key_in_use, a request with the key is in progress",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"422": {
+						"description": "idempotency_key_mismatch, the key was used for another request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		},
+		"/webhooks/{id}/deliveries": {
+			"get": {
+				"operationId": "getDeliveries",
+				"summary": "Fetch the delivery log of a subscription, latest attempt first",
+				"parameters": [
+					{
+						"name": "id",
+						"in": "path",
+						"required": true,
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0
+						}
+					},
+					{
+						"name": "limit",
+						"in": "query",
+						"description": "maximum number of entries, defaults to 100",
+						"schema": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 1,
+							"maximum": 1000
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "deliveries",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Deliveries"
+								}
+							}
+						}
+					},
+					"400": {
+						"description": "bad_request",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"404": {
+						"description": "not_found",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"429": {
+						"description": "rate_limited, retry after the seconds of the Retry-After header",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					},
+					"500": {
+						"description": "internal_error",
+						"content": {
+							"application/json": {
+								"schema": {
+									"$ref": "#/components/schemas/Error"
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"Error": {
+				"type": "object",
+				"properties": {
+					"error": {
+						"type": "string",
+						"description": "snake case error code, e.g. not_found"
+					},
+					"message": {
+						"type": "string",
+						"description": "description of the error in the language of the Accept-Language header"
+					}
+				},
+				"required": [
+					"error"
+				]
+			},
+			"Health": {
+				"type": "object",
+				"properties": {
+					"status": {
+						"type": "string",
+						"description": "ok if all checks are ok",
+						"enum": [
+							"ok",
+							"failing",
+							"shutting_down"
+						]
+					},
+					"checked_at": {
+						"type": "string",
+						"description": "time the checks ran, reports are cached for a few seconds",
+						"format": "date-time"
+					},
+					"checks": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"name": {
+									"type": "string",
+									"description": "name of the check, e.g. database, schema or tzdata"
+								},
+								"status": {
+									"type": "string",
+									"enum": [
+										"ok",
+										"failing"
+									]
+								},
+								"error": {
+									"type": "string",
+									"description": "why the check failed"
+								},
+								"duration_ms": {
+									"type": "integer",
+									"format": "int64",
+									"minimum": 0,
+									"description": "time the check took in milliseconds"
+								}
+							},
+							"required": [
+								"name",
+								"status",
+								"duration_ms"
+							]
+						}
+					}
+				},
+				"required": [
+					"status",
+					"checked_at",
+					"checks"
+				]
+			},
+			"Money": {
+				"type": "object",
+				"properties": {
+					"amount": {
+						"type": "string",
+						"description": "exact decimal amount",
+						"pattern": "^-?[0-9]+(\\.[0-9]{1,4})?$"
+					},
+					"currency": {
+						"type": "string",
+						"description": "ISO 4217 currency code",
+						"pattern": "^[A-Z]{3}$"
+					}
+				},
+				"required": [
+					"amount",
+					"currency"
+				],
+				"additionalProperties": false
+			},
+			"Rounding": {
+				"type": "object",
+				"description": "policy to round durations to increments, empty mode and unit if rounding is disabled",
+				"properties": {
+					"increment": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "increment in seconds, 0 disables rounding"
+					},
+					"mode": {
+						"type": "string",
+						"description": "direction to round in, defaults to up",
+						"enum": [
+							"",
+							"up",
+							"down",
+							"nearest"
+						]
+					},
+					"per": {
+						"type": "string",
+						"description": "unit of work to round, defaults to record",
+						"enum": [
+							"",
+							"record",
+							"day_project",
+							"line"
+						]
+					}
+				},
+				"additionalProperties": false
+			},
+			"TimeRecordInput": {
+				"type": "object",
+				"properties": {
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ignored, the id is assigned by the server or taken from the path"
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"name": {
+						"type": "string"
+					},
+					"start_time": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch"
+					},
+					"start_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"stop_time": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch"
+					},
+					"stop_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"duration": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "duration in seconds"
+					},
+					"project_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "0 if not assigned to a project"
+					},
+					"billable": {
+						"type": "boolean",
+						"description": "time is invoiced to the client"
+					},
+					"rate": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"uuid": {
+						"type": "string",
+						"description": "identifies the record across devices, generated by the server if missing",
+						"pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+					},
+					"notes": {
+						"type": "string",
+						"description": "details of the work, searched with the name",
+						"maxLength": 10000
+					},
+					"tags": {
+						"type": "array",
+						"items": {
+							"type": "string",
+							"maxLength": 50
+						},
+						"description": "tags of the record, stored in lower case without duplicates, at most 20"
+					}
+				},
+				"required": [
+					"user_id",
+					"start_time",
+					"stop_time"
+				],
+				"additionalProperties": false
+			},
+			"TimeRecord": {
+				"type": "object",
+				"properties": {
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"name": {
+						"type": "string"
+					},
+					"start_time": {
+						"type": "string",
+						"description": "start time in the user's location, formatted in the user's locale like 02 Jan 2006 15:04:05 or 25.01.2020 3:04:05 PM, or like 2006-01-02T15:04:05+01:00 in the rfc3339 format"
+					},
+					"start_epoch": {
+						"type": "integer",
+						"format": "int64",
+						"description": "start time in seconds since UNIX epoch, rfc3339 format only"
+					},
+					"start_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"stop_time": {
+						"type": "string",
+						"description": "stop time in the user's location, formatted in the user's locale like 02 Jan 2006 15:04:05 or 25.01.2020 3:04:05 PM, or like 2006-01-02T15:04:05+01:00 in the rfc3339 format"
+					},
+					"stop_epoch": {
+						"type": "integer",
+						"format": "int64",
+						"description": "stop time in seconds since UNIX epoch, rfc3339 format only"
+					},
+					"stop_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"duration": {
+						"type": "string",
+						"description": "duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"project_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"client_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "client of the project"
+					},
+					"billable": {
+						"type": "boolean"
+					},
+					"rate": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"invoice_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "invoice the record is locked by"
+					},
+					"deleted_at": {
+						"type": "string",
+						"description": "time the record was moved to the trash",
+						"format": "date-time"
+					},
+					"uuid": {
+						"type": "string",
+						"description": "identifies the record across devices",
+						"pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+					},
+					"version": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "incremented by every change"
+					},
+					"notes": {
+						"type": "string",
+						"description": "details of the work"
+					},
+					"tags": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						},
+						"description": "tags of the record in lower case"
+					}
+				},
+				"required": [
+					"record_id",
+					"user_id",
+					"name",
+					"start_time",
+					"start_loc",
+					"stop_time",
+					"stop_loc",
+					"duration"
+				],
+				"additionalProperties": false
+			},
+			"TimeRecords": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/TimeRecord"
+				},
+				"nullable": true
+			},
+			"SearchResults": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"record": {
+							"$ref": "#/components/schemas/TimeRecord"
+						},
+						"rank": {
+							"type": "number",
+							"description": "relevance of the match, results with higher ranks match better"
+						},
+						"snippet": {
+							"type": "string",
+							"description": "matched part of the name and notes as escaped HTML, matched words are highlighted like <mark>call</mark>"
+						}
+					},
+					"required": [
+						"record",
+						"rank",
+						"snippet"
+					],
+					"additionalProperties": false
+				}
+			},
+			"NameSuggestions": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {
+							"type": "string"
+						},
+						"records": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0,
+							"description": "records with the name"
+						},
+						"last_used": {
+							"type": "string",
+							"description": "start of the latest record with the name",
+							"format": "date-time"
+						},
+						"project_id": {
+							"type": "integer",
+							"format": "int64",
+							"minimum": 0,
+							"description": "project most records with the name are assigned to"
+						},
+						"tags": {
+							"type": "array",
+							"items": {
+								"type": "string"
+							},
+							"description": "tags most records with the name are tagged with"
+						}
+					},
+					"required": [
+						"name",
+						"records",
+						"last_used"
+					],
+					"additionalProperties": false
+				}
+			},
+			"NameMerge": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"name": {
+						"type": "string",
+						"description": "canonical name the records are renamed to",
+						"maxLength": 256
+					},
+					"names": {
+						"type": "array",
+						"items": {
+							"type": "string"
+						},
+						"nullable": true,
+						"description": "names to merge, by default the names similar to the canonical name"
+					},
+					"similarity": {
+						"type": "number",
+						"minimum": 0,
+						"maximum": 1,
+						"description": "minimum trigram similarity of the merged names to the canonical name, defaults to 0.6"
+					},
+					"dry_run": {
+						"type": "boolean",
+						"description": "report what would be renamed without renaming"
+					}
+				},
+				"required": [
+					"user_id",
+					"name"
+				],
+				"additionalProperties": false
+			},
+			"NameMergeResult": {
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "canonical name"
+					},
+					"merged": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"name": {
+									"type": "string"
+								},
+								"renamed": {
+									"type": "integer",
+									"format": "int64",
+									"minimum": 0,
+									"description": "records renamed"
+								},
+								"locked": {
+									"type": "integer",
+									"format": "int64",
+									"minimum": 0,
+									"description": "records locked by an invoice or approved timesheet, not renamed"
+								}
+							},
+							"required": [
+								"name",
+								"renamed",
+								"locked"
+							],
+							"additionalProperties": false
+						}
+					},
+					"dry_run": {
+						"type": "boolean"
+					}
+				},
+				"required": [
+					"name",
+					"merged"
+				],
+				"additionalProperties": false
+			},
+			"Change": {
+				"type": "object",
+				"properties": {
+					"change_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"actor_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"action": {
+						"type": "string",
+						"enum": [
+							"create",
+							"update",
+							"delete",
+							"restore",
+							"purge"
+						]
+					},
+					"changed_at": {
+						"type": "string",
+						"format": "date-time"
+					},
+					"request_id": {
+						"type": "string"
+					},
+					"before": {
+						"description": "stored record before the change, null for creations",
+						"nullable": true
+					},
+					"after": {
+						"description": "stored record after the change, null for purges",
+						"nullable": true
+					}
+				},
+				"required": [
+					"change_id",
+					"record_id",
+					"user_id",
+					"actor_id",
+					"action",
+					"changed_at",
+					"request_id",
+					"before",
+					"after"
+				],
+				"additionalProperties": false
+			},
+			"Changes": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/Change"
+				},
+				"nullable": true
+			},
+			"Mutation": {
+				"type": "object",
+				"properties": {
+					"mutation_id": {
+						"type": "string",
+						"description": "generated by the client, a mutation is applied at most once",
+						"pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+					},
+					"op": {
+						"type": "string",
+						"enum": [
+							"upsert",
+							"delete"
+						]
+					},
+					"uuid": {
+						"type": "string",
+						"description": "uuid of the record",
+						"pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+					},
+					"base_version": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "version of the record the change is based on, 0 for new records"
+					},
+					"record": {
+						"$ref": "#/components/schemas/TimeRecordInput"
+					}
+				},
+				"required": [
+					"mutation_id",
+					"op",
+					"uuid"
+				],
+				"additionalProperties": false
+			},
+			"SyncRequest": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"mutations": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/Mutation"
+						},
+						"description": "applied in order, at most 100"
+					}
+				},
+				"required": [
+					"user_id",
+					"mutations"
+				],
+				"additionalProperties": false
+			},
+			"MutationResult": {
+				"type": "object",
+				"description": "outcome of a mutation with the record as stored, the server's version for conflicts",
+				"properties": {
+					"mutation_id": {
+						"type": "string",
+						"pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+					},
+					"status": {
+						"type": "string",
+						"enum": [
+							"applied",
+							"conflict"
+						]
+					},
+					"reason": {
+						"type": "string",
+						"description": "reason of a conflict",
+						"enum": [
+							"stale",
+							"deleted",
+							"exists",
+							"locked"
+						]
+					},
+					"record": {
+						"$ref": "#/components/schemas/TimeRecord"
+					}
+				},
+				"required": [
+					"mutation_id",
+					"status"
+				],
+				"additionalProperties": false
+			},
+			"SyncResponse": {
+				"type": "object",
+				"properties": {
+					"applied": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"conflicts": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"results": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/MutationResult"
+						},
+						"description": "in the order of the mutations"
+					}
+				},
+				"required": [
+					"applied",
+					"conflicts",
+					"results"
+				],
+				"additionalProperties": false
+			},
+			"FeedEntry": {
+				"type": "object",
+				"properties": {
+					"cursor": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "id of the latest change of the record"
+					},
+					"type": {
+						"type": "string",
+						"enum": [
+							"create",
+							"update",
+							"tombstone"
+						]
+					},
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"uuid": {
+						"type": "string"
+					},
+					"version": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"record": {
+						"$ref": "#/components/schemas/TimeRecord"
+					}
+				},
+				"required": [
+					"cursor",
+					"type",
+					"record_id",
+					"uuid",
+					"version"
+				],
+				"additionalProperties": false
+			},
+			"Feed": {
+				"type": "object",
+				"properties": {
+					"changes": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/FeedEntry"
+						},
+						"description": "records ordered by their latest change"
+					},
+					"cursor": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "cursor of the last entry, pass as since to get the next page"
+					},
+					"more": {
+						"type": "boolean",
+						"description": "the page is full, more entries may follow"
+					}
+				},
+				"required": [
+					"changes",
+					"cursor",
+					"more"
+				],
+				"additionalProperties": false
+			},
+			"ReportLine": {
+				"type": "object",
+				"properties": {
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"name": {
+						"type": "string"
+					},
+					"project_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"duration": {
+						"type": "string",
+						"description": "duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"rounded_duration": {
+						"type": "string",
+						"description": "rounded duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"billable": {
+						"type": "boolean"
+					},
+					"rate": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"amount": {
+						"$ref": "#/components/schemas/Money"
+					}
+				},
+				"required": [
+					"record_id",
+					"name",
+					"duration",
+					"rounded_duration",
+					"billable"
+				],
+				"additionalProperties": false
+			},
+			"Report": {
+				"type": "object",
+				"properties": {
+					"duration": {
+						"type": "string",
+						"description": "duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"rounded_duration": {
+						"type": "string",
+						"description": "rounded duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"billable_duration": {
+						"type": "string",
+						"description": "billable duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"rounded_billable_duration": {
+						"type": "string",
+						"description": "rounded billable duration, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"unrated_duration": {
+						"type": "string",
+						"description": "billable duration without applicable rate, formatted like 01:30:00 or as decimal hours like 1,50 if the user prefers them, or as ISO 8601 duration like PT1H30M in the rfc3339 format"
+					},
+					"amounts": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/Money"
+						},
+						"nullable": true,
+						"description": "billable amounts per currency"
+					},
+					"rounding": {
+						"$ref": "#/components/schemas/Rounding"
+					},
+					"lines": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/ReportLine"
+						}
+					}
+				},
+				"required": [
+					"duration",
+					"rounded_duration",
+					"billable_duration",
+					"rounded_billable_duration",
+					"unrated_duration",
+					"amounts",
+					"rounding",
+					"lines"
+				],
+				"additionalProperties": false
+			},
+			"RateInput": {
+				"type": "object",
+				"properties": {
+					"rate_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ignored on creation"
+					},
+					"scope": {
+						"type": "string",
+						"description": "entity the rate applies to",
+						"enum": [
+							"user",
+							"project",
+							"client"
+						]
+					},
+					"scope_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"hourly": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"effective_from": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, defaults to now"
+					}
+				},
+				"required": [
+					"scope",
+					"scope_id",
+					"hourly"
+				],
+				"additionalProperties": false
+			},
+			"Rate": {
+				"type": "object",
+				"properties": {
+					"rate_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ignored on creation"
+					},
+					"scope": {
+						"type": "string",
+						"description": "entity the rate applies to",
+						"enum": [
+							"user",
+							"project",
+							"client"
+						]
+					},
+					"scope_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"hourly": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"effective_from": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, defaults to now"
+					}
+				},
+				"required": [
+					"rate_id",
+					"scope",
+					"scope_id",
+					"hourly",
+					"effective_from"
+				],
+				"additionalProperties": false
+			},
+			"Rates": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/Rate"
+				},
+				"nullable": true
+			},
+			"InvoiceRequest": {
+				"type": "object",
+				"properties": {
+					"client_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"from": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, start of the period"
+					},
+					"to": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, end of the period, exclusive"
+					},
+					"rounding": {
+						"$ref": "#/components/schemas/Rounding"
+					}
+				},
+				"required": [
+					"client_id",
+					"from",
+					"to"
+				],
+				"additionalProperties": false
+			},
+			"InvoiceLine": {
+				"type": "object",
+				"properties": {
+					"record_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"name": {
+						"type": "string"
+					},
+					"date": {
+						"type": "string",
+						"description": "date in the location the work started",
+						"format": "date"
+					},
+					"hours": {
+						"type": "string",
+						"description": "billed hours"
+					},
+					"duration": {
+						"type": "string",
+						"description": "duration, formatted as hours, minutes and seconds like 01:30:00",
+						"pattern": "^[0-9]{2,}:[0-9]{2}:[0-9]{2}$"
+					},
+					"billed_duration": {
+						"type": "string",
+						"description": "billed duration, formatted as hours, minutes and seconds like 01:30:00",
+						"pattern": "^[0-9]{2,}:[0-9]{2}:[0-9]{2}$"
+					},
+					"rate": {
+						"$ref": "#/components/schemas/Money"
+					},
+					"amount": {
+						"$ref": "#/components/schemas/Money"
+					}
+				},
+				"required": [
+					"record_id",
+					"user_id",
+					"name",
+					"date",
+					"hours",
+					"duration",
+					"billed_duration",
+					"rate",
+					"amount"
+				],
+				"additionalProperties": false
+			},
+			"Invoice": {
+				"type": "object",
+				"properties": {
+					"invoice_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"number": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "sequential invoice number"
+					},
+					"client_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"client_name": {
+						"type": "string"
+					},
+					"period_from": {
+						"type": "string",
+						"format": "date"
+					},
+					"period_to": {
+						"type": "string",
+						"description": "inclusive",
+						"format": "date"
+					},
+					"issue_date": {
+						"type": "string",
+						"format": "date"
+					},
+					"voided": {
+						"type": "boolean"
+					},
+					"rounding": {
+						"$ref": "#/components/schemas/Rounding"
+					},
+					"lines": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/InvoiceLine"
+						},
+						"nullable": true
+					},
+					"total": {
+						"$ref": "#/components/schemas/Money"
+					}
+				},
+				"required": [
+					"invoice_id",
+					"number",
+					"client_id",
+					"client_name",
+					"period_from",
+					"period_to",
+					"issue_date",
+					"voided",
+					"rounding",
+					"lines",
+					"total"
+				],
+				"additionalProperties": false
+			},
+			"TimesheetRequest": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"year": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1,
+						"description": "ISO year",
+						"maximum": 9999
+					},
+					"week": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1,
+						"description": "ISO week",
+						"maximum": 53
+					},
+					"tz": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					}
+				},
+				"required": [
+					"user_id",
+					"year",
+					"week"
+				],
+				"additionalProperties": false
+			},
+			"StateRequest": {
+				"type": "object",
+				"properties": {
+					"comment": {
+						"type": "string",
+						"description": "required to reject a timesheet"
+					}
+				},
+				"additionalProperties": false
+			},
+			"TimesheetEvent": {
+				"type": "object",
+				"properties": {
+					"state": {
+						"type": "string",
+						"enum": [
+							"draft",
+							"submitted",
+							"approved",
+							"rejected"
+						]
+					},
+					"actor_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"comment": {
+						"type": "string"
+					},
+					"created_at": {
+						"type": "string",
+						"format": "date-time"
+					}
+				},
+				"required": [
+					"state",
+					"actor_id",
+					"comment",
+					"created_at"
+				],
+				"additionalProperties": false
+			},
+			"Timesheet": {
+				"type": "object",
+				"properties": {
+					"timesheet_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"year": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ISO year"
+					},
+					"week": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ISO week"
+					},
+					"tz": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"state": {
+						"type": "string",
+						"enum": [
+							"draft",
+							"submitted",
+							"approved",
+							"rejected"
+						]
+					},
+					"start_time": {
+						"type": "string",
+						"description": "first day of the week, formatted like 02 Jan 2006 15:04:05",
+						"pattern": "^[0-9]{2} [A-Z][a-z]{2} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2}$"
+					},
+					"stop_time": {
+						"type": "string",
+						"description": "first day of the next week, formatted like 02 Jan 2006 15:04:05",
+						"pattern": "^[0-9]{2} [A-Z][a-z]{2} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2}$"
+					},
+					"events": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/TimesheetEvent"
+						},
+						"description": "state changes, only returned for a single timesheet"
+					},
+					"records": {
+						"type": "array",
+						"items": {
+							"$ref": "#/components/schemas/TimeRecord"
+						},
+						"description": "records in the week, only returned for a single timesheet"
+					}
+				},
+				"required": [
+					"timesheet_id",
+					"user_id",
+					"year",
+					"week",
+					"tz",
+					"state",
+					"start_time",
+					"stop_time"
+				],
+				"additionalProperties": false
+			},
+			"Timesheets": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/Timesheet"
+				},
+				"nullable": true
+			},
+			"TimerInput": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"name": {
+						"type": "string"
+					},
+					"start_time": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, defaults to now"
+					},
+					"start_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"project_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"billable": {
+						"type": "boolean"
+					}
+				},
+				"required": [
+					"user_id"
+				],
+				"additionalProperties": false
+			},
+			"Timer": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"name": {
+						"type": "string"
+					},
+					"start_time": {
+						"type": "string",
+						"description": "start time in the user's location, formatted like 02 Jan 2006 15:04:05",
+						"pattern": "^[0-9]{2} [A-Z][a-z]{2} [0-9]{4} [0-9]{2}:[0-9]{2}:[0-9]{2}$"
+					},
+					"start_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					},
+					"project_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"billable": {
+						"type": "boolean"
+					}
+				},
+				"required": [
+					"user_id",
+					"name",
+					"start_time",
+					"start_loc"
+				],
+				"additionalProperties": false
+			},
+			"Profile": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"locale": {
+						"type": "string",
+						"description": "language tag of the locale dates, times and durations are shown in, e.g. de or da-DK, the Accept-Language header decides if it is empty"
+					},
+					"clock": {
+						"type": "string",
+						"description": "clock times are shown in, the locale decides if it is empty",
+						"enum": [
+							"",
+							"24h",
+							"12h"
+						]
+					},
+					"decimal_hours": {
+						"type": "boolean",
+						"description": "show durations as decimal hours like 1,50"
+					}
+				},
+				"required": [
+					"user_id"
+				],
+				"additionalProperties": false
+			},
+			"TimerStop": {
+				"type": "object",
+				"properties": {
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 1
+					},
+					"stop_time": {
+						"type": "integer",
+						"format": "int64",
+						"description": "seconds since UNIX epoch, defaults to now"
+					},
+					"stop_loc": {
+						"type": "string",
+						"description": "tz-database name of the user's location, e.g. Europe/Berlin"
+					}
+				},
+				"required": [
+					"user_id"
+				],
+				"additionalProperties": false
+			},
+			"WebhookInput": {
+				"type": "object",
+				"properties": {
+					"subscription_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "ignored"
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "limits the events to a single user"
+					},
+					"url": {
+						"type": "string",
+						"description": "absolute http or https URL"
+					},
+					"secret": {
+						"type": "string",
+						"description": "key of the payload signatures, at least 16 characters"
+					},
+					"events": {
+						"type": "array",
+						"items": {
+							"type": "string",
+							"enum": [
+								"record.created",
+								"record.updated",
+								"record.deleted",
+								"record.restored",
+								"timer.started",
+								"timer.stopped"
+							]
+						}
+					},
+					"created_at": {
+						"type": "string",
+						"description": "ignored",
+						"format": "date-time"
+					}
+				},
+				"required": [
+					"url",
+					"secret",
+					"events"
+				],
+				"additionalProperties": false
+			},
+			"Webhook": {
+				"type": "object",
+				"description": "webhook subscription, the secret is never returned",
+				"properties": {
+					"subscription_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"user_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"url": {
+						"type": "string"
+					},
+					"events": {
+						"type": "array",
+						"items": {
+							"type": "string",
+							"enum": [
+								"record.created",
+								"record.updated",
+								"record.deleted",
+								"record.restored",
+								"timer.started",
+								"timer.stopped"
+							]
+						}
+					},
+					"created_at": {
+						"type": "string",
+						"format": "date-time"
+					}
+				},
+				"required": [
+					"subscription_id",
+					"url",
+					"events",
+					"created_at"
+				],
+				"additionalProperties": false
+			},
+			"Webhooks": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/Webhook"
+				},
+				"nullable": true
+			},
+			"Delivery": {
+				"type": "object",
+				"properties": {
+					"delivery_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"message_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"subscription_id": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"event": {
+						"type": "string",
+						"enum": [
+							"record.created",
+							"record.updated",
+							"record.deleted",
+							"record.restored",
+							"timer.started",
+							"timer.stopped"
+						]
+					},
+					"attempt": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"status_code": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0,
+						"description": "status of the response, missing if the request failed"
+					},
+					"error": {
+						"type": "string"
+					},
+					"duration_ms": {
+						"type": "integer",
+						"format": "int64",
+						"minimum": 0
+					},
+					"delivered_at": {
+						"type": "string",
+						"format": "date-time"
+					}
+				},
+				"required": [
+					"delivery_id",
+					"message_id",
+					"subscription_id",
+					"event",
+					"attempt",
+					"duration_ms",
+					"delivered_at"
+				],
+				"additionalProperties": false
+			},
+			"Deliveries": {
+				"type": "array",
+				"items": {
+					"$ref": "#/components/schemas/Delivery"
+				},
+				"nullable": true
+			}
+		}
+	}
+}
+`
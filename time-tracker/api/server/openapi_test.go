@@ -0,0 +1,446 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/openapi"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// mockDatastore returns realistic data for all stores and fails with the
+// error of the user, client, record, invoice, timesheet or subscription id.
+type mockDatastore struct {
+	*mockRPCStore
+	mockKeyStore
+	mockHealthStore
+	mockProfileStore
+	mockSuggestionStore
+}
+
+func (ms *mockDatastore) Search(ctx context.Context, q store.SearchQuery) ([]store.SearchResult, error) {
+	rec := store.TimeRecord{RecordID: 1, UserID: q.UserID, Name: "client call", Start: q.From, StartLoc: "UTC", Stop: q.From.Add(time.Hour), StopLoc: "UTC", Duration: 3600, Notes: "budget", Tags: q.Tags}
+	return []store.SearchResult{{Record: rec, Rank: 0.6, Snippet: "<mark>client</mark> <mark>call</mark> – <mark>budget</mark>"}}, ms.errs[q.UserID]
+}
+func (ms *mockDatastore) UnbilledRecords(ctx context.Context, clientID uint64, from, to time.Time) ([]store.TimeRecord, error) {
+	rate, _ := billing.ParseMoney("80", "EUR")
+	return []store.TimeRecord{
+		{RecordID: 1, UserID: 1, Name: "foo", Start: from, StartLoc: "UTC", Stop: from.Add(time.Hour), StopLoc: "UTC", Duration: 3600, ClientID: clientID, Billable: true, Rate: &rate},
+	}, ms.errs[clientID]
+}
+func (ms *mockDatastore) ClientRates(ctx context.Context, clientID uint64) ([]billing.Rate, error) {
+	return nil, ms.errs[clientID]
+}
+func (ms *mockDatastore) CreateInvoice(ctx context.Context, inv store.Invoice) (*store.Invoice, error) {
+	inv.InvoiceID, inv.Number, inv.Created = 1, 1, time.Unix(1577833200, 0)
+	return &inv, nil
+}
+func (ms *mockDatastore) Invoice(ctx context.Context, id uint64) (*store.Invoice, error) {
+	return ms.invoice(id), ms.errs[id]
+}
+func (ms *mockDatastore) VoidInvoice(ctx context.Context, id uint64) (*store.Invoice, error) {
+	inv := ms.invoice(id)
+	inv.Voided = &inv.Created
+	return inv, ms.errs[id]
+}
+func (ms *mockDatastore) invoice(id uint64) *store.Invoice {
+	rate, _ := billing.ParseMoney("80", "EUR")
+	return &store.Invoice{
+		InvoiceID:  id,
+		Number:     id,
+		ClientID:   1,
+		ClientName: "ACME",
+		From:       time.Unix(1577833200, 0),
+		To:         time.Unix(1580511600, 0),
+		Created:    time.Unix(1580511600, 0),
+		Lines: []store.InvoiceLine{
+			{RecordID: 1, UserID: 1, Name: "foo", Start: time.Unix(1577833200, 0), StartLoc: "UTC", Duration: 3600, Billed: 3600, Rate: rate, Amount: rate},
+		},
+		Total: rate,
+	}
+}
+func (ms *mockDatastore) History(ctx context.Context, recordID uint64) ([]store.Change, error) {
+	return []store.Change{
+		{ChangeID: 1, RecordID: recordID, UserID: 1, Action: "create", Changed: time.Unix(1577833200, 0), After: json.RawMessage(`{"record_id":1}`)},
+	}, ms.errs[recordID]
+}
+func (ms *mockDatastore) Changes(ctx context.Context, userID, since uint64, limit int) ([]store.Change, error) {
+	return nil, ms.errs[userID]
+}
+func (ms *mockDatastore) CreateTimesheet(ctx context.Context, t store.Timesheet) (*store.Timesheet, error) {
+	t.TimesheetID = 1
+	return &t, ms.errs[t.UserID]
+}
+func (ms *mockDatastore) Timesheet(ctx context.Context, id uint64) (*store.Timesheet, error) {
+	t := ms.timesheet(id, store.TimesheetSubmitted)
+	t.Events = []store.TimesheetEvent{{State: store.TimesheetSubmitted, ActorID: 1, Created: time.Unix(1577833200, 0)}}
+	t.Records = []store.TimeRecord{{RecordID: 1, UserID: 1, Start: t.Start, StartLoc: "UTC", Stop: t.Start, StopLoc: "UTC"}}
+	return t, ms.errs[id]
+}
+func (ms *mockDatastore) Timesheets(ctx context.Context, userID uint64) ([]store.Timesheet, error) {
+	return []store.Timesheet{*ms.timesheet(1, store.TimesheetDraft)}, ms.errs[userID]
+}
+func (ms *mockDatastore) PendingTimesheets(ctx context.Context, leadID uint64) ([]store.Timesheet, error) {
+	return nil, ms.errs[leadID]
+}
+func (ms *mockDatastore) SetTimesheetState(ctx context.Context, id uint64, to store.TimesheetState, actorID uint64, comment string) (*store.Timesheet, error) {
+	return ms.timesheet(id, to), ms.errs[id]
+}
+func (ms *mockDatastore) timesheet(id uint64, state store.TimesheetState) *store.Timesheet {
+	start := firstDayOfISOWeek(2020, 1, time.UTC)
+	return &store.Timesheet{TimesheetID: id, UserID: 1, Year: 2020, Week: 1, Loc: "UTC", Start: start, Stop: start.AddDate(0, 0, 7), State: state}
+}
+func (ms *mockDatastore) CreateWebhook(ctx context.Context, s webhook.Subscription) (*webhook.Subscription, error) {
+	s.SubscriptionID, s.Created, s.Secret = 1, time.Unix(1577833200, 0), ""
+	return &s, ms.errs[s.UserID]
+}
+func (ms *mockDatastore) Webhooks(ctx context.Context) ([]webhook.Subscription, error) {
+	return []webhook.Subscription{
+		{SubscriptionID: 1, URL: "https://example.com/hook", Events: []string{webhook.RecordCreated}, Created: time.Unix(1577833200, 0)},
+	}, nil
+}
+func (ms *mockDatastore) DeleteWebhook(ctx context.Context, id uint64) error {
+	return ms.errs[id]
+}
+func (ms *mockDatastore) Sync(ctx context.Context, userID uint64, mutations []store.Mutation) ([]store.MutationResult, error) {
+	results := make([]store.MutationResult, 0, len(mutations))
+	for _, m := range mutations {
+		res := store.MutationResult{MutationID: m.MutationID, Status: store.MutationApplied}
+		rec := &store.TimeRecord{RecordID: 1, UserID: userID, Start: time.Unix(1577833200, 0), StartLoc: "UTC", Stop: time.Unix(1577836800, 0), StopLoc: "UTC", Duration: 3600, UUID: m.UUID, Version: m.BaseVersion + 1}
+		switch {
+		case m.BaseVersion == 3:
+			// the record changed on the server
+			res.Status, res.Reason = store.MutationConflict, store.ConflictStale
+			rec.Version = 4
+			res.Record = rec
+		case m.Op == store.MutationUpsert:
+			res.Record = rec
+		}
+		results = append(results, res)
+	}
+	return results, ms.errs[userID]
+}
+func (ms *mockDatastore) Feed(ctx context.Context, userID, since uint64, limit int) ([]store.FeedEntry, error) {
+	uuid := "9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a41"
+	return []store.FeedEntry{
+		{Cursor: since + 1, Kind: store.FeedCreate, RecordID: 1, UUID: uuid, Version: 1, Record: &store.TimeRecord{RecordID: 1, UserID: userID, Start: time.Unix(1577833200, 0), StartLoc: "UTC", Stop: time.Unix(1577836800, 0), StopLoc: "UTC", Duration: 3600, UUID: uuid, Version: 1}},
+		{Cursor: since + 2, Kind: store.FeedTombstone, RecordID: 2, UUID: "0c6d2b7e-4a8c-4d2e-9f1a-9b2f5f3e8a41", Version: 3},
+	}, ms.errs[userID]
+}
+func (ms *mockDatastore) Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]webhook.Delivery, error) {
+	return []webhook.Delivery{
+		{DeliveryID: 1, MessageID: 1, SubscriptionID: subscriptionID, Event: webhook.RecordCreated, Attempt: 1, StatusCode: 200, Duration: time.Millisecond, Delivered: time.Unix(1577833200, 0)},
+	}, ms.errs[subscriptionID]
+}
+
+// openAPITests exercise every operation of the OpenAPI document, errors are
+// triggered by the ids the mock store fails for.
+var openAPITests = []struct {
+	d string // description of test case
+	m string // HTTP method
+	u string // route of test request
+	p string // request payload
+	k string // idempotency key
+	s int    // expected http status code
+}{
+	{d: "liveness", m: "GET", u: "/live", s: http.StatusOK},
+	{d: "readiness", m: "GET", u: "/ready", s: http.StatusOK},
+	{d: "openapi document", m: "GET", u: "/openapi.json", s: http.StatusOK},
+
+	{
+		d: "create record",
+		m: "POST",
+		u: "/record",
+		p: `{"user_id":1,"name":"foo","start_time":1577833200,"start_loc":"Europe/Berlin","stop_time":1577836800,"stop_loc":"Europe/Berlin","duration":3600,"billable":true,"rate":{"amount":"80.5","currency":"EUR"}}`,
+		s: http.StatusOK,
+	},
+	{d: "create locked record", m: "POST", u: "/record", p: `{"user_id":3,"start_time":1577833200,"stop_time":1577836800}`, s: http.StatusConflict},
+	{d: "create record with idempotency key", m: "POST", u: "/record", p: `{"user_id":1,"start_time":1577833200,"stop_time":1577836800}`, k: "retry-1", s: http.StatusOK},
+	{d: "replay record creation", m: "POST", u: "/record", p: `{"user_id":1,"start_time":1577833200,"stop_time":1577836800}`, k: "retry-1", s: http.StatusOK},
+	{d: "reuse idempotency key", m: "POST", u: "/record", p: `{"user_id":1,"start_time":1577833200,"stop_time":1577840400}`, k: "retry-1", s: http.StatusUnprocessableEntity},
+	{d: "get records", m: "GET", u: "/records?user_id=1&tz=Europe/Berlin&ts=1577833200&period=week", s: http.StatusOK},
+	{d: "get records in rfc3339 format", m: "GET", u: "/records?user_id=1&tz=Europe/Berlin&ts=1577833200&period=week&format=rfc3339", s: http.StatusOK},
+	{d: "get records with store error", m: "GET", u: "/records?user_id=6&tz=Europe/Berlin&ts=1577833200&period=day", s: http.StatusInternalServerError},
+	{d: "update record", m: "PUT", u: "/records/1", p: `{"user_id":1,"start_time":1577833200,"stop_time":1577836800}`, s: http.StatusOK},
+	{d: "update missing record", m: "PUT", u: "/records/2", p: `{"user_id":1,"start_time":1577833200,"stop_time":1577836800}`, s: http.StatusNotFound},
+	{d: "delete record", m: "DELETE", u: "/records/1", s: http.StatusNoContent},
+	{d: "delete locked record", m: "DELETE", u: "/records/3", s: http.StatusConflict},
+	{d: "restore record", m: "POST", u: "/records/1/restore", s: http.StatusOK},
+	{d: "restore missing record", m: "POST", u: "/records/2/restore", s: http.StatusNotFound},
+	{d: "get trash", m: "GET", u: "/trash?user_id=1", s: http.StatusOK},
+	{d: "search records", m: "GET", u: `/records/search?user_id=1&q="client+call"+budg*&from=1577833200&tag=call&limit=5`, s: http.StatusOK},
+	{d: "search without words", m: "GET", u: "/records/search?user_id=1&q=*", s: http.StatusBadRequest},
+	{d: "get record history", m: "GET", u: "/records/1/history", s: http.StatusOK},
+	{d: "get change feed", m: "GET", u: "/history?user_id=1&since=3&limit=10", s: http.StatusOK},
+	{
+		d: "sync mutations",
+		m: "POST",
+		u: "/sync",
+		p: `{"user_id":1,"mutations":[` +
+			`{"mutation_id":"6f1c2a9e-1b7d-4c3e-8a5f-2d9e0b4c7a11","op":"upsert","uuid":"9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a41","base_version":0,"record":{"user_id":1,"name":"offline","start_time":1577833200,"start_loc":"UTC","stop_time":1577836800,"stop_loc":"UTC"}},` +
+			`{"mutation_id":"6f1c2a9e-1b7d-4c3e-8a5f-2d9e0b4c7a12","op":"upsert","uuid":"0c6d2b7e-4a8c-4d2e-9f1a-9b2f5f3e8a41","base_version":3,"record":{"user_id":1,"start_time":1577833200,"stop_time":1577836800}},` +
+			`{"mutation_id":"6f1c2a9e-1b7d-4c3e-8a5f-2d9e0b4c7a13","op":"delete","uuid":"0c6d2b7e-4a8c-4d2e-9f1a-9b2f5f3e8a42","base_version":1}]}`,
+		s: http.StatusOK,
+	},
+	{d: "sync with store error", m: "POST", u: "/sync", p: `{"user_id":6,"mutations":[]}`, s: http.StatusInternalServerError},
+	{d: "get record feed", m: "GET", u: "/changes?user_id=1&since=3&limit=10", s: http.StatusOK},
+	{d: "get report", m: "GET", u: "/report?user_id=1&tz=Europe/Berlin&ts=1577833200&period=month&round=900&round_mode=nearest&round_per=day_project", s: http.StatusOK},
+
+	{d: "get report in rfc3339 format", m: "GET", u: "/report?user_id=1&tz=Europe/Berlin&ts=1577833200&period=month&format=rfc3339", s: http.StatusOK},
+
+	{d: "create rate", m: "POST", u: "/rate", p: `{"scope":"user","scope_id":1,"hourly":{"amount":"60","currency":"EUR"}}`, s: http.StatusOK},
+	{d: "get rates", m: "GET", u: "/rates?user_id=1", s: http.StatusOK},
+
+	{d: "create invoice", m: "POST", u: "/invoice", p: `{"client_id":1,"from":1577833200,"to":1580511600,"rounding":{"increment":900,"mode":"up","per":"line"}}`, s: http.StatusOK},
+	{d: "get invoice", m: "GET", u: "/invoices/1", s: http.StatusOK},
+	{d: "get invoice as html", m: "GET", u: "/invoices/1?format=html", s: http.StatusOK},
+	{d: "get invoice as ubl", m: "GET", u: "/invoices/1?format=ubl", s: http.StatusOK},
+	{d: "get missing invoice", m: "GET", u: "/invoices/2", s: http.StatusNotFound},
+	{d: "void invoice", m: "POST", u: "/invoices/1/void", s: http.StatusOK},
+
+	{d: "create timesheet", m: "POST", u: "/timesheet", p: `{"user_id":1,"year":2020,"week":1,"tz":"Europe/Berlin"}`, s: http.StatusOK},
+	{d: "get timesheets", m: "GET", u: "/timesheets?user_id=1", s: http.StatusOK},
+	{d: "get pending timesheets", m: "GET", u: "/timesheets/pending?lead_id=1", s: http.StatusOK},
+	{d: "get timesheet", m: "GET", u: "/timesheets/1", s: http.StatusOK},
+	{d: "submit timesheet", m: "POST", u: "/timesheets/1/submit", s: http.StatusOK},
+	{d: "reject timesheet without comment", m: "POST", u: "/timesheets/1/reject", p: `{"comment":""}`, s: http.StatusUnprocessableEntity},
+
+	{d: "start timer", m: "POST", u: "/timer/start", p: `{"user_id":1,"name":"foo","start_time":1577833200,"start_loc":"Europe/Berlin"}`, s: http.StatusOK},
+	{d: "start running timer", m: "POST", u: "/timer/start", p: `{"user_id":4,"start_loc":"Europe/Berlin"}`, s: http.StatusConflict},
+	{d: "get timer", m: "GET", u: "/timer?user_id=1", s: http.StatusOK},
+	{d: "get missing timer", m: "GET", u: "/timer?user_id=2", s: http.StatusNotFound},
+	{d: "stop timer", m: "POST", u: "/timer/stop", p: `{"user_id":1,"stop_time":1577836800,"stop_loc":"Europe/Berlin"}`, s: http.StatusOK},
+	{d: "stop timer in rfc3339 format", m: "POST", u: "/timer/stop?format=rfc3339", p: `{"user_id":1,"stop_time":1577836800,"stop_loc":"Europe/Berlin"}`, s: http.StatusOK},
+	{d: "stop timer before start", m: "POST", u: "/timer/stop", p: `{"user_id":5}`, s: http.StatusUnprocessableEntity},
+
+	{d: "set profile", m: "PUT", u: "/profile", p: `{"user_id":1,"locale":"da","clock":"12h","decimal_hours":true}`, s: http.StatusOK},
+	{d: "get profile", m: "GET", u: "/profile?user_id=1", s: http.StatusOK},
+	{d: "set unsupported locale", m: "PUT", u: "/profile", p: `{"user_id":1,"locale":"fr"}`, s: http.StatusBadRequest},
+
+	{d: "suggest names", m: "GET", u: "/suggestions/names?user_id=1&prefix=cl&limit=5", s: http.StatusOK},
+	{d: "merge names", m: "POST", u: "/suggestions/names/merge", p: `{"user_id":1,"name":"Client call","similarity":0.5,"dry_run":true}`, s: http.StatusOK},
+	{d: "merge names into no name", m: "POST", u: "/suggestions/names/merge", p: `{"user_id":1,"name":""}`, s: http.StatusBadRequest},
+
+	{d: "create webhook", m: "POST", u: "/webhook", p: `{"url":"https://example.com/hook","secret":"0123456789abcdef","events":["record.created"]}`, s: http.StatusOK},
+	{d: "get webhooks", m: "GET", u: "/webhooks", s: http.StatusOK},
+	{d: "delete webhook", m: "DELETE", u: "/webhooks/1", s: http.StatusNoContent},
+	{d: "delete missing webhook", m: "DELETE", u: "/webhooks/2", s: http.StatusNotFound},
+	{d: "get deliveries", m: "GET", u: "/webhooks/1/deliveries?limit=10", s: http.StatusOK},
+}
+
+// newAPIServer returns a test server of the API with requests validated
+// against the OpenAPI document.
+func newAPIServer(t *testing.T) (*httptest.Server, *openapi.Spec) {
+	ms := &mockDatastore{mockRPCStore: &mockRPCStore{errs: map[uint64]error{
+		2: store.ErrNotFound,
+		3: store.ErrRecordLocked,
+		4: store.ErrTimerRunning,
+		5: store.ErrInvalidStop,
+		6: errInternal,
+	}}}
+	h, err := newHandler(ms, events.NewHub(), 200*time.Millisecond, config.NewLive(config.Settings{ValidateRequests: true}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := openapi.Load([]byte(openAPISpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(h), spec
+}
+
+// operation returns the operation of a request to the API.
+func operation(t *testing.T, spec *openapi.Spec, r *http.Request) (*openapi.Operation, map[string]string) {
+	var match mux.RouteMatch
+	h, err := newRouter(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Match(r, &match) || match.MatchErr != nil {
+		t.Fatalf("no route matches %s %s", r.Method, r.URL)
+	}
+	tpl, err := match.Route.GetPathTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, ok := spec.Operation(openapi.PathTemplate(tpl), r.Method)
+	if !ok {
+		t.Fatalf("%s %s is not documented", r.Method, tpl)
+	}
+	return op, match.Vars
+}
+
+func TestOpenAPI(t *testing.T) {
+	srv, spec := newAPIServer(t)
+	defer srv.Close()
+
+	tested := map[string]bool{}
+	for _, tc := range openAPITests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			req, err := http.NewRequest(tt.m, srv.URL+tt.u, strings.NewReader(tt.p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.k != "" {
+				req.Header.Set("Idempotency-Key", tt.k)
+			}
+			op, vars := operation(t, spec, req)
+			tested[op.OperationID] = true
+			if err := spec.ValidateRequest(op, req, vars); err != nil {
+				t.Fatalf("invalid request: %v", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, got := tt.s, resp.StatusCode; want != got {
+				t.Errorf("want status code %d got %d: %s", want, got, body)
+			}
+			if err := spec.ValidateResponse(op, resp.StatusCode, resp.Header.Get("Content-Type"), body); err != nil {
+				t.Errorf("invalid response: %v\n%s", err, body)
+			}
+		})
+	}
+
+	// the event stream does not end, only its header is validated
+	t.Run("event stream", func(t *testing.T) {
+		req, err := http.NewRequest("GET", srv.URL+"/events?user_id=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		op, _ := operation(t, spec, req)
+		tested[op.OperationID] = true
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+			t.Fatal(err)
+		}
+		if err := spec.ValidateResponse(op, resp.StatusCode, resp.Header.Get("Content-Type"), nil); err != nil {
+			t.Errorf("invalid response: %v", err)
+		}
+	})
+
+	var untested []string
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			if !tested[op.OperationID] {
+				untested = append(untested, fmt.Sprintf("%s %s", strings.ToUpper(method), path))
+			}
+		}
+	}
+	sort.Strings(untested)
+	if len(untested) > 0 {
+		t.Errorf("operations without tests: %s", strings.Join(untested, ", "))
+	}
+}
+
+// TestOpenAPIRoutes checks that every route of the handler is documented and
+// that the document has no stale paths.
+func TestOpenAPIRoutes(t *testing.T) {
+	h, err := newRouter(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := openapi.Load([]byte(openAPISpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routed := map[string]bool{}
+	err = h.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		path := openapi.PathTemplate(tpl)
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			if m == "OPTIONS" {
+				continue // CORS preflight requests
+			}
+			routed[m+" "+path] = true
+			if _, ok := spec.Operation(path, m); !ok {
+				t.Errorf("route %s %s is not documented", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, item := range spec.Paths {
+		for method := range item {
+			if m := strings.ToUpper(method); !routed[m+" "+path] {
+				t.Errorf("documented operation %s %s has no route", m, path)
+			}
+		}
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	srv, _ := newAPIServer(t)
+	defer srv.Close()
+
+	tests := []struct {
+		d string // description of test case
+		m string // HTTP method
+		u string // route of test request
+		p string // request payload
+	}{
+		{d: "expect unknown field to be rejected", m: "POST", u: "/record", p: `{"user_id":1,"start_time":1,"stop":2}`},
+		{d: "expect missing field to be rejected", m: "POST", u: "/rate", p: `{"scope":"user","scope_id":1}`},
+		{d: "expect wrong type to be rejected", m: "POST", u: "/timer/start", p: `{"user_id":"1"}`},
+		{d: "expect negative id to be rejected", m: "POST", u: "/timesheet", p: `{"user_id":-1,"year":2020,"week":1}`},
+		{d: "expect invalid enum to be rejected", m: "POST", u: "/webhook", p: `{"url":"https://example.com","secret":"0123456789abcdef","events":["record.purged"]}`},
+		{d: "expect query parameter out of range to be rejected", m: "GET", u: "/history?user_id=1&limit=5000"},
+		{d: "expect unknown rate scope to be rejected", m: "POST", u: "/rate", p: `{"scope":"team","scope_id":1,"hourly":{"amount":"60","currency":"EUR"}}`},
+		{d: "expect invalid invoice format to be rejected", m: "GET", u: "/invoices/1?format=pdf"},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			req, err := http.NewRequest(tt.m, srv.URL+tt.u, strings.NewReader(tt.p))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if want, got := http.StatusBadRequest, resp.StatusCode; want != got {
+				t.Errorf("want status code %d got %d", want, got)
+			}
+			if want, got := string(errorBody(errBadRequest)), strings.TrimSpace(string(body)); want != got {
+				t.Errorf("want response %s got %s", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// profileStore handles operations on the profiles of users.
+type profileStore interface {
+	Profile(ctx context.Context, userID uint64) (*store.Profile, error)
+	SetProfile(ctx context.Context, p store.Profile) (*store.Profile, error)
+}
+
+// localizer chooses how dates, times and durations are shown to a user.
+type localizer struct {
+	profiles profileStore
+}
+
+// formatter returns the formatter of the user, see locale.Negotiate. The
+// preferences of the user's profile take precedence over the Accept-Language
+// header of r. Users without profile and failures to load it fall back to
+// the header, formatting in a language the user did not choose is better
+// than failing the request. A user id of 0 is an unknown user.
+func (l localizer) formatter(ctx context.Context, r *http.Request, userID uint64) locale.Formatter {
+	var prefs locale.Preferences
+	if userID != 0 {
+		p, err := l.profiles.Profile(ctx, userID)
+		switch err {
+		case nil:
+			prefs = p.Preferences
+		case store.ErrNotFound:
+		default:
+			loggerFromRequest(r).Error().Err(err).Uint64("user_id", userID).Msg("failed to load profile")
+		}
+	}
+	return locale.Negotiate(prefs, r.Header.Get("Accept-Language"))
+}
+
+// formatRecord returns the record of a user in the format. Records in
+// store.FormatHuman are formatted in the user's locale.
+func (l localizer) formatRecord(ctx context.Context, r *http.Request, rec *store.TimeRecord, format store.Format) store.FormattedRecord {
+	fr := store.FormattedRecord{TimeRecord: rec, Format: format}
+	if format == store.FormatHuman {
+		fr.Locale = l.formatter(ctx, r, rec.UserID)
+	}
+	return fr
+}
+
+// formatRecords returns the records of a user in the format, see
+// formatRecord.
+func (l localizer) formatRecords(ctx context.Context, r *http.Request, userID uint64, recs []store.TimeRecord, format store.Format) []store.FormattedRecord {
+	var f locale.Formatter
+	if format == store.FormatHuman {
+		f = l.formatter(ctx, r, userID)
+	}
+	return store.FormatRecords(recs, format, f)
+}
+
+// profileService provides API methods to read and change the profiles of
+// users.
+type profileService struct {
+	profileStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the profile endpoint.
+func (ps *profileService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ps.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch r.Method {
+	case "GET":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		ps.getProfile(ctx, w, r, userID)
+		return
+
+	case "PUT":
+		var p store.Profile
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&p); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if p.UserID == 0 {
+			writeError(w, r, errBadRequest, http.StatusBadRequest)
+			return
+		}
+		if err := p.Validate(); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ps.setProfile(ctx, w, r, p)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+// getProfile responds with the profile of a user, users who never set their
+// preferences get empty preferences.
+func (ps *profileService) getProfile(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64) {
+	p, err := ps.Profile(ctx, userID)
+	switch err {
+	case nil:
+		encodeJSON(w, r, p, http.StatusOK)
+	case store.ErrNotFound:
+		encodeJSON(w, r, &store.Profile{UserID: userID}, http.StatusOK)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (ps *profileService) setProfile(ctx context.Context, w http.ResponseWriter, r *http.Request, p store.Profile) {
+	saved, err := ps.SetProfile(ctx, p)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, saved, http.StatusOK)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/zerolog"
+)
+
+// keeps the profiles in memory.
+type mockProfileStore struct {
+	mu       sync.Mutex
+	profiles map[uint64]store.Profile
+}
+
+func (ps *mockProfileStore) Profile(ctx context.Context, userID uint64) (*store.Profile, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.profiles[userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &p, nil
+}
+func (ps *mockProfileStore) SetProfile(ctx context.Context, p store.Profile) (*store.Profile, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.profiles == nil {
+		ps.profiles = map[uint64]store.Profile{}
+	}
+	ps.profiles[p.UserID] = p
+	return &p, nil
+}
+
+func TestLocalization(t *testing.T) {
+	ds := &mockDatastore{mockRPCStore: &mockRPCStore{errs: map[uint64]error{2: store.ErrNotFound}}}
+	h, err := newHandler(ds, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, u, body, lang string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, u, strings.NewReader(body))
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	record := func(w *httptest.ResponseRecorder) (start, duration string) {
+		var recs []struct {
+			Start    string `json:"start_time"`
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&recs); err != nil || len(recs) != 1 {
+			t.Fatalf("want one record got %d, %v", len(recs), err)
+		}
+		return recs[0].Start, recs[0].Duration
+	}
+	const records = "/records?user_id=1&tz=UTC&ts=1579939200&period=day"
+
+	tests := []struct {
+		d        string // description of test case
+		lang     string // Accept-Language of the request
+		profile  string // profile of the user, none if empty
+		start    string // expected start time
+		duration string // expected duration
+	}{
+		{d: "default", start: "25 Jan 2020 00:00:00", duration: "01:30:00"},
+		{d: "accepted language", lang: "fr, da;q=0.9", start: "25.01.2020 00:00:00", duration: "01:30:00"},
+		{d: "unsupported language", lang: "fr", start: "25 Jan 2020 00:00:00", duration: "01:30:00"},
+		{
+			d:        "profile before accepted language",
+			lang:     "da",
+			profile:  `{"user_id":1,"locale":"de-AT","clock":"12h","decimal_hours":true}`,
+			start:    "25.01.2020 12:00:00 AM",
+			duration: "1,50",
+		},
+		{d: "preferences without locale", lang: "da", profile: `{"user_id":1,"decimal_hours":true}`, start: "25.01.2020 00:00:00", duration: "1,50"},
+	}
+	for _, tt := range tests {
+		ds.mockProfileStore = mockProfileStore{}
+		if tt.profile != "" {
+			if w := do("PUT", "/profile", tt.profile, ""); w.Code != http.StatusOK {
+				t.Fatalf("%s: want status 200 for profile got %d", tt.d, w.Code)
+			}
+		}
+		w := do("GET", records, "", tt.lang)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: want status 200 got %d", tt.d, w.Code)
+		}
+		if vary := w.Header()["Vary"]; !contains(vary, "Accept-Language") {
+			t.Errorf("%s: want vary Accept-Language got %v", tt.d, vary)
+		}
+		start, duration := record(w)
+		if start != tt.start || duration != tt.duration {
+			t.Errorf("%s: want %s and %s got %s and %s", tt.d, tt.start, tt.duration, start, duration)
+		}
+	}
+
+	// the etag of the records changes with the locale
+	ds.mockProfileStore = mockProfileStore{}
+	en, da := do("GET", records, "", "en").Header().Get("ETag"), do("GET", records, "", "da").Header().Get("ETag")
+	if en == "" || en == da {
+		t.Errorf("want different etags by locale got %q and %q", en, da)
+	}
+
+	// reports are formatted like records
+	do("PUT", "/profile", `{"user_id":1,"locale":"de","decimal_hours":true}`, "")
+	w := do("GET", "/report?user_id=1&tz=UTC&ts=1579939200&period=day", "", "")
+	var rep struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "1,50", rep.Duration; want != got {
+		t.Errorf("report: want duration %s got %s", want, got)
+	}
+
+	// error messages are translated, the error code stays the same
+	w = do("GET", "/timer?user_id=2", "", "de")
+	var e struct {
+		Err     string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Err != "not_found" || e.Message != locale.German.Message("not_found") {
+		t.Errorf("want not_found in german got %s: %s", e.Err, e.Message)
+	}
+}
+
+func TestServeHTTPProfile(t *testing.T) {
+	ps := &profileService{&mockProfileStore{}, 200 * time.Millisecond}
+	tests := []struct {
+		d string // description of test case
+		m string // method of test request
+		u string // route of test request
+		b string // request body
+		s int    // expected http status code
+		r string // expected response
+	}{
+		{d: "expect empty profile of unknown user", m: "GET", u: "/profile?user_id=1", s: http.StatusOK, r: `{"user_id":1}`},
+		{d: "expect missing user to result in 400", m: "PUT", u: "/profile", b: `{"locale":"da"}`, s: http.StatusBadRequest},
+		{d: "expect unsupported locale to result in 400", m: "PUT", u: "/profile", b: `{"user_id":1,"locale":"fr"}`, s: http.StatusBadRequest},
+		{d: "expect unknown clock to result in 400", m: "PUT", u: "/profile", b: `{"user_id":1,"clock":"10h"}`, s: http.StatusBadRequest},
+		{d: "expect profile to be saved", m: "PUT", u: "/profile", b: `{"user_id":1,"locale":"da","clock":"24h"}`, s: http.StatusOK, r: `{"user_id":1,"locale":"da","clock":"24h"}`},
+		{d: "expect saved profile", m: "GET", u: "/profile?user_id=1", s: http.StatusOK, r: `{"user_id":1,"locale":"da","clock":"24h"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		ps.ServeHTTP(w, httptest.NewRequest(tt.m, tt.u, strings.NewReader(tt.b)))
+		if w.Code != tt.s {
+			t.Errorf("%s: want status %d got %d", tt.d, tt.s, w.Code)
+		}
+		if got := strings.TrimSpace(w.Body.String()); tt.r != "" && got != tt.r {
+			t.Errorf("%s: want %s got %s", tt.d, tt.r, got)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+var errRetroactiveRate = errors.New("rate must not take effect in the past")
+
+// rateStore handles operations on hourly rates.
+type rateStore interface {
+	CreateRate(ctx context.Context, r billing.Rate) (*billing.Rate, error)
+	Rates(ctx context.Context, userID uint64) ([]billing.Rate, error)
+}
+
+// rateService provides API methods to operate on hourly rates.
+type rateService struct {
+	rateStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the rate endpoints.
+func (rs *rateService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), rs.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "rate":
+		var rate billing.Rate
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&rate); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		rs.createRate(ctx, w, r, rate)
+		return
+
+	case "rates":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		rs.getRates(ctx, w, r, userID)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+// createRate stores a new rate. Rates only take effect from now on, so adding
+// a rate never changes the amounts of work that has been done already.
+func (rs *rateService) createRate(ctx context.Context, w http.ResponseWriter, r *http.Request, rate billing.Rate) {
+	if err := rate.Validate(); err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	if rate.EffectiveFrom.IsZero() {
+		rate.EffectiveFrom = now
+	}
+	if rate.EffectiveFrom.Before(now.Add(-time.Minute)) { // allow for clock skew
+		writeError(w, r, errRetroactiveRate, http.StatusBadRequest)
+		return
+	}
+	created, err := rs.CreateRate(ctx, rate)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, created, http.StatusOK)
+}
+
+func (rs *rateService) getRates(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64) {
+	rates, err := rs.Rates(ctx, userID)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, rates, http.StatusOK)
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// rateLimitKey returns the rate limit bucket of the client of a request, its
+// IP. Behind a proxy setting ipHeader, it is the last address of the header,
+// the one added by the proxy; the addresses before it are sent by the client
+// and could be anything. Otherwise it is the remote address. The Actor-Id
+// header is not authenticated, so it does not select the bucket.
+func rateLimitKey(r *http.Request, ipHeader string) string {
+	if ipHeader != "" {
+		hops := strings.Split(r.Header.Get(ipHeader), ",")
+		if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/rs/zerolog"
+)
+
+// failingLimiter fails to take tokens.
+type failingLimiter struct{}
+
+func (failingLimiter) TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	live := config.NewLive(config.Settings{RateLimits: middleware.RateLimits{
+		Read:     middleware.RateLimit{Rate: 1, Burst: 1},
+		Write:    middleware.RateLimit{Rate: 1, Burst: 2},
+		IPHeader: "X-Real-IP",
+	}})
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, live, nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tests run in order and share the buckets
+	tests := []struct {
+		d         string // description of test case
+		m         string // HTTP method
+		u         string // route of test request
+		addr      string // remote address
+		ip        string // X-Real-IP header
+		actor     string // Actor-Id header
+		s         int    // expected http status code
+		remaining string // expected RateLimit-Remaining header
+		retry     string // expected Retry-After header
+	}{
+		{d: "first write", m: "DELETE", u: "/records/1", addr: "10.0.0.1:1234", s: http.StatusNoContent, remaining: "1"},
+		{d: "second write", m: "DELETE", u: "/records/1", addr: "10.0.0.1:1235", s: http.StatusNoContent, remaining: "0"},
+		{d: "write over limit", m: "DELETE", u: "/records/1", addr: "10.0.0.1:1236", s: http.StatusTooManyRequests, remaining: "0", retry: "1"},
+		{d: "read has its own limit", m: "GET", u: "/timer?user_id=1", addr: "10.0.0.1:1237", s: http.StatusOK, remaining: "0"},
+		{d: "read over limit", m: "GET", u: "/timer?user_id=1", addr: "10.0.0.1:1237", s: http.StatusTooManyRequests, remaining: "0", retry: "1"},
+		{d: "other client", m: "DELETE", u: "/records/1", addr: "10.0.0.2:1234", s: http.StatusNoContent, remaining: "1"},
+		{d: "client ip of proxy", m: "DELETE", u: "/records/1", addr: "10.0.0.1:1238", ip: "192.0.2.1", s: http.StatusNoContent, remaining: "1"},
+		{d: "last hop added by proxy", m: "DELETE", u: "/records/1", addr: "10.0.0.1:1239", ip: "198.51.100.7, 192.0.2.1", s: http.StatusNoContent, remaining: "0"},
+		{d: "actor does not select the bucket", m: "DELETE", u: "/records/1", addr: "10.0.0.2:1235", actor: "7", s: http.StatusNoContent, remaining: "0"},
+		{d: "other actor of the same client", m: "DELETE", u: "/records/1", addr: "10.0.0.2:1236", actor: "8", s: http.StatusTooManyRequests, remaining: "0", retry: "1"},
+		{d: "probes are not limited", m: "GET", u: "/ready", addr: "10.0.0.1:1240", s: http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.m, tt.u, nil)
+		r.RemoteAddr = tt.addr
+		if tt.ip != "" {
+			r.Header.Set("X-Real-IP", tt.ip)
+		}
+		if tt.actor != "" {
+			r.Header.Set("Actor-Id", tt.actor)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if want, got := tt.s, w.Code; want != got {
+			t.Errorf("%s: want status code %d got %d", tt.d, want, got)
+		}
+		if want, got := tt.remaining, w.Header().Get("RateLimit-Remaining"); want != got {
+			t.Errorf("%s: want remaining %q got %q", tt.d, want, got)
+		}
+		if want, got := tt.retry, w.Header().Get("Retry-After"); want != got {
+			t.Errorf("%s: want retry after %q got %q", tt.d, want, got)
+		}
+		if tt.s == http.StatusTooManyRequests && !strings.Contains(w.Body.String(), "rate_limited") {
+			t.Errorf("%s: want rate_limited error got %s", tt.d, w.Body)
+		}
+	}
+
+	// requests pass if the limiter fails
+	h, err = newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, live, failingLimiter{}, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("DELETE", "/records/1", nil))
+		if want, got := http.StatusNoContent, w.Code; want != got {
+			t.Errorf("failing limiter: want status code %d got %d", want, got)
+		}
+	}
+}
@@ -1,8 +1,20 @@
 package server
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"sync/atomic"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/health"
+)
+
+// Caching and timeout of the readiness checks. Probes within healthTTL get
+// the cached report.
+const (
+	healthTTL     = 2 * time.Second
+	healthTimeout = time.Second
 )
 
 var healthCode = int32(http.StatusOK)
@@ -12,12 +24,53 @@ func HealthCheckShutDown() {
 	atomic.StoreInt32(&healthCode, http.StatusServiceUnavailable)
 }
 
-func health() int {
+func healthStatus() int {
 	return int(atomic.LoadInt32(&healthCode))
 }
 
-type readinessHandler struct{}
+// healthStore checks the database the services operate on.
+type healthStore interface {
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
+}
+
+// newChecker returns the readiness checks of the service: the database is
+// reachable, its schema has the version of the store and the time zone
+// database, which the locations of records are looked up in, is available.
+func newChecker(hs healthStore) *health.Checker {
+	c := health.NewChecker(healthTTL, healthTimeout)
+	c.Register("database", hs.Ping)
+	c.Register("schema", hs.CheckSchema)
+	c.Register("tzdata", health.TZData("Europe/Berlin", "America/New_York"))
+	return c
+}
+
+// readinessHandler reports whether the service is ready to serve requests,
+// with the outcome of every check in the body.
+type readinessHandler struct {
+	checker *health.Checker
+}
 
 func (h *readinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(health())
+	if healthStatus() != http.StatusOK {
+		report := health.Report{Status: health.StatusShuttingDown, Checked: time.Now(), Checks: []health.Result{}}
+		encodeJSON(w, r, report, healthStatus())
+		return
+	}
+	report := h.checker.Check()
+	status := http.StatusOK
+	if !report.OK() {
+		status = http.StatusServiceUnavailable
+	}
+	encodeJSON(w, r, report, status)
+}
+
+// livenessHandler reports that the process is able to serve requests. It
+// does not check dependencies, a failing dependency is no reason to restart
+// the service.
+type livenessHandler struct{}
+
+func (livenessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"status":"ok"}`+"\n")
 }
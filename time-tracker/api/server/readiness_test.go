@@ -1,31 +1,109 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+
+	"github.com/fgrimme/time-tracker/time-tracker/health"
 )
 
-var healthtest = struct {
-	d string // description of test
-	p string // URL path of test requests
-	m string // HTTP method of test requests
-	s int    // expected response status code
+// mockHealthStore fails the checks with its errors and counts the pings.
+type mockHealthStore struct {
+	pingErr   error
+	schemaErr error
+	pings     int32
+}
+
+func (hs *mockHealthStore) Ping(ctx context.Context) error {
+	atomic.AddInt32(&hs.pings, 1)
+	return hs.pingErr
+}
+func (hs *mockHealthStore) CheckSchema(ctx context.Context) error {
+	return hs.schemaErr
+}
+
+var healthtests = []struct {
+	d      string           // description of test
+	hs     *mockHealthStore // store checked
+	s      int              // expected response status code
+	status string           // expected status of the report
+	failed string           // name of the check expected to fail
 }{
-	d: "expect status code 200",
-	p: "/ready",
-	m: "GET",
-	s: 200,
+	{d: "expect status code 200", hs: &mockHealthStore{}, s: http.StatusOK, status: health.StatusOK},
+	{d: "expect 503 if the database is down", hs: &mockHealthStore{pingErr: errors.New("connection refused")}, s: http.StatusServiceUnavailable, status: health.StatusFailing, failed: "database"},
+	{d: "expect 503 if the schema is outdated", hs: &mockHealthStore{schemaErr: errors.New("schema version 0, want 1")}, s: http.StatusServiceUnavailable, status: health.StatusFailing, failed: "schema"},
 }
 
 func TestHealth(t *testing.T) {
-	h := readinessHandler{}
-	tt := healthtest
-	t.Run(tt.d, func(t *testing.T) {
+	for _, tc := range healthtests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			h := &readinessHandler{newChecker(tt.hs)}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+			if w, g := tt.s, w.Code; w != g {
+				t.Errorf("want %d got %d", w, g)
+			}
+			var report health.Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if w, g := tt.status, report.Status; w != g {
+				t.Errorf("want status %s got %s", w, g)
+			}
+			if w, g := 3, len(report.Checks); w != g {
+				t.Fatalf("want %d checks got %d", w, g)
+			}
+			for _, res := range report.Checks {
+				if failed := res.Name == tt.failed; failed != (res.Status == health.StatusFailing) {
+					t.Errorf("check %s: unexpected status %s: %s", res.Name, res.Status, res.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestHealthCached(t *testing.T) {
+	hs := &mockHealthStore{}
+	h := &readinessHandler{newChecker(hs)}
+	for i := 0; i < 3; i++ {
 		w := httptest.NewRecorder()
-		r := httptest.NewRequest(tt.m, tt.p, nil)
-		h.ServeHTTP(w, r)
-		if w, g := tt.s, w.Code; w != g {
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+		if w, g := http.StatusOK, w.Code; w != g {
 			t.Errorf("want %d got %d", w, g)
 		}
-	})
+	}
+	if w, g := int32(1), atomic.LoadInt32(&hs.pings); w != g {
+		t.Errorf("want %d pings got %d", w, g)
+	}
+}
+
+func TestHealthShutDown(t *testing.T) {
+	defer atomic.StoreInt32(&healthCode, http.StatusOK)
+	HealthCheckShutDown()
+
+	h := &readinessHandler{newChecker(&mockHealthStore{})}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/ready", nil))
+	if w, g := http.StatusServiceUnavailable, w.Code; w != g {
+		t.Errorf("want %d got %d", w, g)
+	}
+	var report health.Report
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if w, g := health.StatusShuttingDown, report.Status; w != g {
+		t.Errorf("want status %s got %s", w, g)
+	}
+
+	w = httptest.NewRecorder()
+	livenessHandler{}.ServeHTTP(w, httptest.NewRequest("GET", "/live", nil))
+	if w, g := http.StatusOK, w.Code; w != g {
+		t.Errorf("liveness: want %d got %d", w, g)
+	}
 }
@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// report summarizes the time records of a user in a period. Durations are
+// reported exact and rounded according to the rounding policy, so the
+// difference is auditable. Amounts are calculated from rounded durations.
+type report struct {
+	Duration                seconds          `json:"duration"`
+	RoundedDuration         seconds          `json:"rounded_duration"`
+	BillableDuration        seconds          `json:"billable_duration"`
+	RoundedBillableDuration seconds          `json:"rounded_billable_duration"`
+	UnratedDuration         seconds          `json:"unrated_duration"` // billable without applicable rate
+	Amounts                 []billing.Money  `json:"amounts"`          // billable amounts per currency
+	Rounding                billing.Rounding `json:"rounding"`
+	Lines                   []reportLine     `json:"lines"`
+
+	durations durationFormat
+}
+
+// reportLine is the entry of a time record in a report.
+type reportLine struct {
+	RecordID        uint64         `json:"record_id"`
+	Name            string         `json:"name"`
+	ProjectID       uint64         `json:"project_id,omitempty"`
+	Duration        seconds        `json:"duration"`
+	RoundedDuration seconds        `json:"rounded_duration"`
+	Billable        bool           `json:"billable"`
+	Rate            *billing.Money `json:"rate,omitempty"`
+	Amount          *billing.Money `json:"amount,omitempty"`
+
+	durations durationFormat
+}
+
+// reportService provides API methods to create reports of time records.
+type reportService struct {
+	records timeRecordStore
+	rates   rateStore
+	localizer
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the report endpoint.
+func (rs *reportService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), rs.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "report":
+		pq, code, err := parsePeriodQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		q := r.URL.Query()
+		rounding, err := billing.ParseRounding(q.Get("round"), q.Get("round_mode"), q.Get("round_per"))
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		// durations are formatted like the durations of records
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Accept-Language")
+		format, code, err := recordFormat(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		rs.getReport(ctx, w, r, pq, rounding, format)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (rs *reportService) getReport(ctx context.Context, w http.ResponseWriter, r *http.Request, pq periodQuery, rounding billing.Rounding, format store.Format) {
+	rep, err := rs.report(ctx, pq, rounding)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	if format == store.FormatRFC3339 {
+		rep.formatDurations(store.FormatISODuration)
+	} else {
+		rep.formatDurations(rs.formatter(ctx, r, pq.userID).Duration)
+	}
+	encodeJSON(w, r, rep, http.StatusOK)
+}
+
+// report creates the report of the user's records in the queried period.
+func (rs *reportService) report(ctx context.Context, pq periodQuery, rounding billing.Rounding) (report, error) {
+	day, err := getStartOfPeriod(pq.t, pq.loc, pq.period)
+	if err != nil {
+		return report{}, err
+	}
+	recs, err := rs.records.Get(ctx, pq.userID, day)
+	if err != nil {
+		return report{}, err
+	}
+	rates, err := rs.rates.Rates(ctx, pq.userID)
+	if err != nil {
+		return report{}, err
+	}
+	return newReport(recs, rates, rounding), nil
+}
+
+// newReport sums up the durations and billable amounts of the records. The
+// amount of a billable record is calculated from the record's own rate if it
+// has one or else from the rate that was effective when the work started.
+func newReport(recs []store.TimeRecord, rates []billing.Rate, rounding billing.Rounding) report {
+	// billable work is rounded on its own like on invoices, so the rounding
+	// of a group never lands on non-billable work
+	rounded := make([]int64, len(recs))
+	for _, billable := range []bool{true, false} {
+		var idx []int
+		var work []billing.Work
+		for i, rec := range recs {
+			if rec.Billable != billable {
+				continue
+			}
+			idx = append(idx, i)
+			work = append(work, billing.Work{
+				Seconds:   rec.Duration,
+				Day:       rec.Start.Format("2006-01-02"),
+				ProjectID: rec.ProjectID,
+			})
+		}
+		for j, r := range rounding.RoundAll(work) {
+			rounded[idx[j]] = r
+		}
+	}
+
+	var total, totalRounded, billable, billableRounded, unrated int64
+	totals := make(billing.Totals)
+	lines := make([]reportLine, 0, len(recs))
+	for i, rec := range recs {
+		total += rec.Duration
+		totalRounded += rounded[i]
+		line := reportLine{
+			RecordID:        rec.RecordID,
+			Name:            rec.Name,
+			ProjectID:       rec.ProjectID,
+			Duration:        seconds(rec.Duration),
+			RoundedDuration: seconds(rounded[i]),
+			Billable:        rec.Billable,
+		}
+		if rec.Billable {
+			billable += rec.Duration
+			billableRounded += rounded[i]
+			line.Rate = rec.Rate
+			if line.Rate == nil {
+				// rates take effect at a point in time, not at a wall clock
+				rate, ok := billing.RateFor(rates, billing.Subject{
+					UserID:    rec.UserID,
+					ProjectID: rec.ProjectID,
+					ClientID:  rec.ClientID,
+				}, store.InLocation(rec.Start, rec.StartLoc))
+				if ok {
+					line.Rate = &rate.Hourly
+				}
+			}
+			if line.Rate != nil {
+				amount := billing.Amount(*line.Rate, rounded[i])
+				totals.Add(amount)
+				line.Amount = &amount
+			} else {
+				unrated += rec.Duration
+			}
+		}
+		lines = append(lines, line)
+	}
+	return report{
+		Duration:                seconds(total),
+		RoundedDuration:         seconds(totalRounded),
+		BillableDuration:        seconds(billable),
+		RoundedBillableDuration: seconds(billableRounded),
+		UnratedDuration:         seconds(unrated),
+		Amounts:                 totals.List(),
+		Rounding:                rounding,
+		Lines:                   lines,
+	}
+}
+
+// seconds is a duration in seconds, which is formatted as hours, minutes and
+// seconds, see store.FormatDuration.
+type seconds int64
+
+func (s seconds) String() string {
+	return store.FormatDuration(time.Duration(s) * time.Second)
+}
+
+// durationFormat formats the durations of a report, seconds.String if it
+// is nil.
+type durationFormat func(time.Duration) string
+
+func (f durationFormat) format(s seconds) string {
+	if f == nil {
+		return s.String()
+	}
+	return f(time.Duration(s) * time.Second)
+}
+
+// formatDurations sets the format of the durations of the report and its
+// lines.
+func (rep *report) formatDurations(f durationFormat) {
+	rep.durations = f
+	for i := range rep.Lines {
+		rep.Lines[i].durations = f
+	}
+}
+
+// MarshalJSON formats the durations of the report.
+func (rep report) MarshalJSON() ([]byte, error) {
+	type plain report // prevents recursion
+	f := rep.durations
+	return json.Marshal(struct {
+		plain
+		Duration                string `json:"duration"`
+		RoundedDuration         string `json:"rounded_duration"`
+		BillableDuration        string `json:"billable_duration"`
+		RoundedBillableDuration string `json:"rounded_billable_duration"`
+		UnratedDuration         string `json:"unrated_duration"`
+	}{
+		plain:                   plain(rep),
+		Duration:                f.format(rep.Duration),
+		RoundedDuration:         f.format(rep.RoundedDuration),
+		BillableDuration:        f.format(rep.BillableDuration),
+		RoundedBillableDuration: f.format(rep.RoundedBillableDuration),
+		UnratedDuration:         f.format(rep.UnratedDuration),
+	})
+}
+
+// MarshalJSON formats the durations of the line.
+func (l reportLine) MarshalJSON() ([]byte, error) {
+	type plain reportLine // prevents recursion
+	return json.Marshal(struct {
+		plain
+		Duration        string `json:"duration"`
+		RoundedDuration string `json:"rounded_duration"`
+	}{
+		plain:           plain(l),
+		Duration:        l.durations.format(l.Duration),
+		RoundedDuration: l.durations.format(l.RoundedDuration),
+	})
+}
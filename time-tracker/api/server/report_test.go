@@ -0,0 +1,133 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+func TestNewReport(t *testing.T) {
+	jan := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	eur := func(a string) billing.Money {
+		m, err := billing.ParseMoney(a, "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	override := eur("120")
+	rates := []billing.Rate{
+		{Scope: billing.ScopeUser, ScopeID: 42, Hourly: eur("60"), EffectiveFrom: jan},
+		{Scope: billing.ScopeProject, ScopeID: 7, Hourly: eur("80"), EffectiveFrom: jan},
+	}
+	recs := []store.TimeRecord{
+		// user rate
+		{RecordID: 1, UserID: 42, Start: jan, Duration: 1800, Billable: true},
+		// project rate
+		{RecordID: 2, UserID: 42, ProjectID: 7, Start: jan, Duration: 3600, Billable: true},
+		// record override
+		{RecordID: 3, UserID: 42, ProjectID: 7, Start: jan, Duration: 900, Billable: true, Rate: &override},
+		// not billable
+		{RecordID: 4, UserID: 42, Start: jan, Duration: 600},
+		// no rate effective yet
+		{RecordID: 5, UserID: 42, Start: jan.Add(-time.Hour), Duration: 60, Billable: true},
+	}
+	rep := newReport(recs, rates, billing.Rounding{})
+
+	if got, want := rep.Duration.String(), "01:56:00"; got != want {
+		t.Errorf("want duration %s got %s", want, got)
+	}
+	if got, want := rep.BillableDuration.String(), "01:46:00"; got != want {
+		t.Errorf("want billable duration %s got %s", want, got)
+	}
+	if got, want := rep.UnratedDuration.String(), "00:01:00"; got != want {
+		t.Errorf("want unrated duration %s got %s", want, got)
+	}
+	if len(rep.Amounts) != 1 {
+		t.Fatalf("want 1 amount got %d", len(rep.Amounts))
+	}
+	// 30 + 80 + 30
+	if got, want := rep.Amounts[0].String(), "140.00"; got != want {
+		t.Errorf("want amount %s got %s", want, got)
+	}
+	if rep.Lines[3].Amount != nil || rep.Lines[4].Amount != nil {
+		t.Errorf("want no amount for records without billable rate")
+	}
+}
+
+func TestNewReportRoundsBillableWork(t *testing.T) {
+	jan := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rate, err := billing.ParseMoney("60", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := []billing.Rate{{Scope: billing.ScopeUser, ScopeID: 42, Hourly: rate, EffectiveFrom: jan}}
+	// billable and non-billable work of the same day and project, the
+	// non-billable record is the last of the group
+	recs := []store.TimeRecord{
+		{RecordID: 1, UserID: 42, ProjectID: 7, Start: jan, Duration: 600, Billable: true},
+		{RecordID: 2, UserID: 42, ProjectID: 7, Start: jan, Duration: 600},
+	}
+	rep := newReport(recs, rates, billing.Rounding{Increment: 900, Mode: billing.RoundUp, Per: billing.PerDayProject})
+	if got, want := rep.RoundedBillableDuration.String(), "00:15:00"; got != want {
+		t.Errorf("want rounded billable duration %s got %s", want, got)
+	}
+	if got, want := rep.RoundedDuration.String(), "00:30:00"; got != want {
+		t.Errorf("want rounded duration %s got %s", want, got)
+	}
+	if got, want := rep.Amounts[0].String(), "15.00"; got != want {
+		t.Errorf("want amount %s got %s", want, got)
+	}
+}
+
+func TestNewReportEffectiveRate(t *testing.T) {
+	eur := func(a string) billing.Money {
+		m, err := billing.ParseMoney(a, "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	rates := []billing.Rate{
+		{Scope: billing.ScopeUser, ScopeID: 42, Hourly: eur("60"), EffectiveFrom: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{Scope: billing.ScopeUser, ScopeID: 42, Hourly: eur("90"), EffectiveFrom: time.Date(2020, time.January, 25, 8, 30, 0, 0, time.UTC)},
+	}
+	// 09:00 in Berlin is 08:00 UTC, before the second rate took effect. The
+	// store reads the wall clock in the user's location with a +00 offset.
+	recs := []store.TimeRecord{
+		{RecordID: 1, UserID: 42, Start: time.Date(2020, time.January, 25, 9, 0, 0, 0, time.FixedZone("", 0)), StartLoc: "Europe/Berlin", Duration: 3600, Billable: true},
+	}
+	rep := newReport(recs, rates, billing.Rounding{})
+	if got, want := rep.Amounts[0].String(), "60.00"; got != want {
+		t.Errorf("want amount %s got %s", want, got)
+	}
+}
+
+func TestNewReportRounded(t *testing.T) {
+	jan := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rate, err := billing.ParseMoney("60", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := []store.TimeRecord{
+		{RecordID: 1, UserID: 42, Start: jan, Duration: 400, Billable: true, Rate: &rate},
+		{RecordID: 2, UserID: 42, Start: jan, Duration: 100},
+	}
+	rep := newReport(recs, nil, billing.Rounding{Increment: 360, Mode: billing.RoundUp, Per: billing.PerRecord})
+
+	if got, want := rep.Duration.String(), "00:08:20"; got != want {
+		t.Errorf("want raw duration %s got %s", want, got)
+	}
+	if got, want := rep.RoundedDuration.String(), "00:18:00"; got != want {
+		t.Errorf("want rounded duration %s got %s", want, got)
+	}
+	if got, want := rep.RoundedBillableDuration.String(), "00:12:00"; got != want {
+		t.Errorf("want rounded billable duration %s got %s", want, got)
+	}
+	// 12 minutes at 60 per hour
+	if got, want := rep.Amounts[0].String(), "12.00"; got != want {
+		t.Errorf("want amount of rounded duration %s got %s", want, got)
+	}
+}
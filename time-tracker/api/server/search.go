@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+const (
+	// defaultSearchLimit is the number of search results returned if the
+	// request does not specify a limit.
+	defaultSearchLimit = 20
+	// maxSearchLimit is the maximum number of search results of a request.
+	maxSearchLimit = 100
+)
+
+// recordSearcher searches the records of users by full-text search. Datastores
+// without it are searched in memory, see store.SearchRecords.
+type recordSearcher interface {
+	Search(ctx context.Context, q store.SearchQuery) ([]store.SearchResult, error)
+}
+
+// searchResult is a record matching a search, see store.SearchResult.
+type searchResult struct {
+	Record  store.FormattedRecord `json:"record"`
+	Rank    float64               `json:"rank"`
+	Snippet string                `json:"snippet"`
+}
+
+// parseSearchQuery parses the search of the request. The user id and the
+// search q are required, the period from and to is given as UNIX timestamps.
+// Records can be filtered by project_id and by tags, given as repeated tag
+// parameters.
+func parseSearchQuery(r *http.Request) (store.SearchQuery, int, error) {
+	userID, code, err := parseUserQuery(r)
+	if err != nil {
+		return store.SearchQuery{}, code, err
+	}
+	q := r.URL.Query()
+	sq := store.SearchQuery{UserID: userID, Limit: defaultSearchLimit}
+	if sq.Terms, err = store.ParseSearch(q.Get("q")); err != nil {
+		return store.SearchQuery{}, http.StatusBadRequest, errBadRequest
+	}
+	for param, t := range map[string]*time.Time{"from": &sq.From, "to": &sq.To} {
+		if v := q.Get(param); v != "" {
+			ts, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return store.SearchQuery{}, http.StatusBadRequest, errBadRequest
+			}
+			*t = time.Unix(ts, 0)
+		}
+	}
+	if v := q.Get("project_id"); v != "" {
+		if sq.ProjectID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return store.SearchQuery{}, http.StatusBadRequest, errBadRequest
+		}
+	}
+	if sq.Tags, err = store.NormalizeTags(q["tag"]); err != nil {
+		return store.SearchQuery{}, http.StatusBadRequest, errBadRequest
+	}
+	if v := q.Get("limit"); v != "" {
+		if sq.Limit, err = strconv.Atoi(v); err != nil || sq.Limit <= 0 || sq.Limit > maxSearchLimit {
+			return store.SearchQuery{}, http.StatusBadRequest, errBadRequest
+		}
+	}
+	return sq, 0, nil
+}
+
+func (rs *timeRecordService) searchRecords(ctx context.Context, w http.ResponseWriter, r *http.Request, sq store.SearchQuery, format store.Format) {
+	ctx, span := tracer.Start(ctx, "timeRecordService.searchRecords")
+	defer span.End()
+	var results []store.SearchResult
+	if s, ok := rs.timeRecordStore.(recordSearcher); ok {
+		var err error
+		if results, err = s.Search(ctx, sq); err != nil {
+			writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+	} else {
+		recs, err := rs.Get(ctx, sq.UserID, sq.From)
+		if err != nil {
+			writeError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		results = store.SearchRecords(recs, sq)
+	}
+
+	recs := make([]store.TimeRecord, len(results))
+	for i := range results {
+		recs[i] = results[i].Record
+	}
+	formatted := rs.formatRecords(ctx, r, sq.UserID, recs, format)
+	resp := make([]searchResult, len(results))
+	for i := range results {
+		resp[i] = searchResult{formatted[i], results[i].Rank, results[i].Snippet}
+	}
+	encodeJSON(w, r, resp, http.StatusOK)
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// searchFallbackStore has no full-text search, its records are searched in
+// memory.
+type searchFallbackStore struct {
+	*mockRPCStore
+}
+
+func (ss searchFallbackStore) Get(ctx context.Context, userID uint64, t time.Time) ([]store.TimeRecord, error) {
+	day := func(d int) time.Time { return time.Date(2020, time.March, d, 9, 0, 0, 0, time.UTC) }
+	return []store.TimeRecord{
+		{RecordID: 1, UserID: userID, Name: "Client call", Notes: "budget", Start: day(2), StartLoc: "UTC", Stop: day(2), StopLoc: "UTC", Tags: []string{"call"}},
+		{RecordID: 2, UserID: userID, Name: "Planning", Notes: "client call prep", Start: day(3), StartLoc: "UTC", Stop: day(3), StopLoc: "UTC", ProjectID: 7},
+		{RecordID: 3, UserID: userID, Name: "Review", Start: day(4), StartLoc: "UTC", Stop: day(4), StopLoc: "UTC"},
+	}, ss.errs[userID]
+}
+
+func TestServeHTTPSearch(t *testing.T) {
+	rs := &timeRecordService{
+		searchFallbackStore{&mockRPCStore{errs: map[uint64]error{2: errInternal}}},
+		localizer{&mockProfileStore{}},
+		200 * time.Millisecond,
+	}
+	tests := []struct {
+		d   string   // description of test case
+		u   string   // route of test request
+		s   int      // expected http status code
+		ids []uint64 // expected records
+	}{
+		{d: "expect missing search to result in 400", u: "/records/search?user_id=1", s: http.StatusBadRequest},
+		{d: "expect invalid period to result in 400", u: "/records/search?user_id=1&q=call&from=yesterday", s: http.StatusBadRequest},
+		{d: "expect invalid limit to result in 400", u: "/records/search?user_id=1&q=call&limit=1000", s: http.StatusBadRequest},
+		{d: "expect store error to result in 500", u: "/records/search?user_id=2&q=call", s: http.StatusInternalServerError},
+		{d: "expect names to rank higher", u: "/records/search?user_id=1&q=call", s: http.StatusOK, ids: []uint64{1, 2}},
+		{d: "expect phrases and prefixes", u: `/records/search?user_id=1&q="client+ca*"`, s: http.StatusOK, ids: []uint64{1, 2}},
+		{d: "expect records of the project", u: "/records/search?user_id=1&q=call&project_id=7", s: http.StatusOK, ids: []uint64{2}},
+		{d: "expect records with the tags", u: "/records/search?user_id=1&q=call&tag=Call", s: http.StatusOK, ids: []uint64{1}},
+		{d: "expect records in the period", u: "/records/search?user_id=1&q=call&to=1583226000", s: http.StatusOK, ids: []uint64{1}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		rs.ServeHTTP(w, httptest.NewRequest("GET", tt.u, nil))
+		if w.Code != tt.s {
+			t.Errorf("%s: want status %d got %d", tt.d, tt.s, w.Code)
+			continue
+		}
+		if tt.s != http.StatusOK {
+			continue
+		}
+		var results []struct {
+			Record struct {
+				RecordID uint64 `json:"record_id"`
+			} `json:"record"`
+			Snippet string `json:"snippet"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]uint64, len(results))
+		for i, res := range results {
+			ids[i] = res.Record.RecordID
+		}
+		if !reflect.DeepEqual(tt.ids, ids) {
+			t.Errorf("%s: want records %v got %v", tt.d, tt.ids, ids)
+		}
+		if len(results) > 0 && results[0].Snippet == "" {
+			t.Errorf("%s: want snippet", tt.d)
+		}
+	}
+}
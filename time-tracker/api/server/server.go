@@ -5,18 +5,30 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 )
 
+// writeTimeout is the deadline for ServeHTTP. Event streams apply it to each
+// write instead.
+const writeTimeout = 5 * time.Second
+
 // HTTPServer represents an http-server.
 type HTTPServer struct {
 	server *http.Server
 	logger zerolog.Logger
 }
 
-// New returns an HTTPServer instance with a handler attached.
-func New(httpAddr string, timeout time.Duration, ts timeRecordStore, logger zerolog.Logger) (*HTTPServer, error) {
-	handler, err := newHandler(ts, timeout, logger)
+// New returns an HTTPServer instance with a handler attached. Clients
+// subscribe to the events of broker, which is closed on shutdown to end the
+// event streams. Requests are validated against the OpenAPI document while
+// the settings of live say so. Rate limits are kept by limiter, in memory if
+// it is nil. Request metrics are registered with reg unless it is nil.
+func New(httpAddr string, timeout time.Duration, ds datastore, broker events.Broker, live *config.Live, limiter middleware.Limiter, reg prometheus.Registerer, logger zerolog.Logger) (*HTTPServer, error) {
+	handler, err := newHandler(ds, broker, timeout, live, limiter, reg, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -24,8 +36,11 @@ func New(httpAddr string, timeout time.Duration, ts timeRecordStore, logger zero
 		Addr:         httpAddr,
 		Handler:      handler,
 		ReadTimeout:  5 * time.Second, // deadline for reading request body
-		WriteTimeout: 5 * time.Second, // deadline for ServeHTTP
+		WriteTimeout: writeTimeout,    // deadline for ServeHTTP
 	}
+	// event streams take over their connections, which are not closed by
+	// the server on shutdown
+	server.RegisterOnShutdown(broker.Close)
 	return &HTTPServer{
 		server: server,
 		logger: logger,
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+const (
+	// defaultSuggestionLimit is the number of suggestions returned if the
+	// request does not specify a limit.
+	defaultSuggestionLimit = 10
+	// maxSuggestionLimit is the maximum number of suggestions of a request.
+	maxSuggestionLimit = 50
+)
+
+// suggestionStore suggests the names of records and merges similar names.
+type suggestionStore interface {
+	NameSuggestions(ctx context.Context, userID uint64, prefix string, limit int) ([]store.NameSuggestion, error)
+	MergeNames(ctx context.Context, m store.NameMerge) (*store.NameMergeResult, error)
+}
+
+// suggestionService provides API methods to complete the names of records
+// and to merge names spelled differently.
+type suggestionService struct {
+	suggestionStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the suggestion endpoints.
+func (ss *suggestionService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ss.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+	// renamed records are recorded with the actor and request id
+	ctx = store.WithAudit(ctx, auditFromRequest(r))
+
+	switch routeName(r) {
+	case "name_suggestions":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		q := r.URL.Query()
+		limit := defaultSuggestionLimit
+		if l := q.Get("limit"); l != "" {
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > maxSuggestionLimit {
+				writeError(w, r, errBadRequest, http.StatusBadRequest)
+				return
+			}
+		}
+		ss.getNameSuggestions(ctx, w, r, userID, q.Get("prefix"), limit)
+		return
+
+	case "merge_names":
+		var m store.NameMerge
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&m); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if err := m.Validate(); err != nil {
+			writeError(w, r, errBadRequest, http.StatusBadRequest)
+			return
+		}
+		ss.mergeNames(ctx, w, r, m)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (ss *suggestionService) getNameSuggestions(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64, prefix string, limit int) {
+	ctx, span := tracer.Start(ctx, "suggestionService.getNameSuggestions")
+	defer span.End()
+	suggestions, err := ss.NameSuggestions(ctx, userID, prefix, limit)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, suggestions, http.StatusOK)
+}
+
+func (ss *suggestionService) mergeNames(ctx context.Context, w http.ResponseWriter, r *http.Request, m store.NameMerge) {
+	ctx, span := tracer.Start(ctx, "suggestionService.mergeNames")
+	defer span.End()
+	result, err := ss.MergeNames(ctx, m)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, result, http.StatusOK)
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+)
+
+// suggests the names starting with the prefix and records the merges.
+type mockSuggestionStore struct {
+	merged store.NameMerge
+}
+
+func (ss *mockSuggestionStore) NameSuggestions(ctx context.Context, userID uint64, prefix string, limit int) ([]store.NameSuggestion, error) {
+	if userID == 2 {
+		return nil, errInternal
+	}
+	suggestions := make([]store.NameSuggestion, 0)
+	for _, s := range []store.NameSuggestion{
+		{Name: "Client call", Records: 12, LastUsed: time.Unix(1577833200, 0).UTC(), ProjectID: 7, Tags: []string{"call"}},
+		{Name: "Code review", Records: 3, LastUsed: time.Unix(1577833200, 0).UTC()},
+	} {
+		if strings.HasPrefix(strings.ToLower(s.Name), strings.ToLower(prefix)) && len(suggestions) < limit {
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions, nil
+}
+func (ss *mockSuggestionStore) MergeNames(ctx context.Context, m store.NameMerge) (*store.NameMergeResult, error) {
+	ss.merged = m
+	return &store.NameMergeResult{
+		Name:   m.Name,
+		Merged: []store.MergedName{{Name: "client cal", Renamed: 2}, {Name: "Clinet call", Renamed: 1, Locked: 1}},
+		DryRun: m.DryRun,
+	}, nil
+}
+
+func TestServeHTTPSuggestions(t *testing.T) {
+	ms := &mockSuggestionStore{}
+	ss := &suggestionService{ms, 200 * time.Millisecond}
+	router := mux.NewRouter()
+	router.Handle("/suggestions/names", ss).Methods("GET").Name("name_suggestions")
+	router.Handle("/suggestions/names/merge", ss).Methods("POST").Name("merge_names")
+	tests := []struct {
+		d string // description of test case
+		m string // method of test request
+		u string // route of test request
+		b string // request body
+		s int    // expected http status code
+		r string // expected response
+	}{
+		{d: "expect invalid limit to result in 400", m: "GET", u: "/suggestions/names?user_id=1&limit=100", s: http.StatusBadRequest},
+		{d: "expect store error to result in 500", m: "GET", u: "/suggestions/names?user_id=2", s: http.StatusInternalServerError},
+		{
+			d: "expect names completing the prefix",
+			m: "GET",
+			u: "/suggestions/names?user_id=1&prefix=cl",
+			s: http.StatusOK,
+			r: `[{"name":"Client call","records":12,"last_used":"2019-12-31T23:00:00Z","project_id":7,"tags":["call"]}]`,
+		},
+		{d: "expect limited suggestions", m: "GET", u: "/suggestions/names?user_id=1&limit=1", s: http.StatusOK, r: `[{"name":"Client call","records":12,"last_used":"2019-12-31T23:00:00Z","project_id":7,"tags":["call"]}]`},
+		{d: "expect missing name to result in 400", m: "POST", u: "/suggestions/names/merge", b: `{"user_id":1,"name":" "}`, s: http.StatusBadRequest},
+		{d: "expect invalid similarity to result in 400", m: "POST", u: "/suggestions/names/merge", b: `{"user_id":1,"name":"Client call","similarity":2}`, s: http.StatusBadRequest},
+		{d: "expect unknown field to result in 400", m: "POST", u: "/suggestions/names/merge", b: `{"user_id":1,"name":"Client call","force":true}`, s: http.StatusBadRequest},
+		{
+			d: "expect merged names",
+			m: "POST",
+			u: "/suggestions/names/merge",
+			b: `{"user_id":1,"name":"Client call","dry_run":true}`,
+			s: http.StatusOK,
+			r: `{"name":"Client call","merged":[{"name":"client cal","renamed":2,"locked":0},{"name":"Clinet call","renamed":1,"locked":1}],"dry_run":true}`,
+		},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.m, tt.u, strings.NewReader(tt.b)))
+		if w.Code != tt.s {
+			t.Errorf("%s: want status %d got %d", tt.d, tt.s, w.Code)
+		}
+		if got := strings.TrimSpace(w.Body.String()); tt.r != "" && got != tt.r {
+			t.Errorf("%s: want %s got %s", tt.d, tt.r, got)
+		}
+	}
+	if want, got := "Client call", ms.merged.Name; want != got || !ms.merged.DryRun {
+		t.Errorf("want dry run merge into %s got %+v", want, ms.merged)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// maxMutations is the maximum number of mutations synced in one request.
+const maxMutations = 100
+
+// syncStore syncs the records changed by clients while they were offline.
+type syncStore interface {
+	Sync(ctx context.Context, userID uint64, mutations []store.Mutation) ([]store.MutationResult, error)
+	Feed(ctx context.Context, userID, since uint64, limit int) ([]store.FeedEntry, error)
+}
+
+// syncRequest is the payload of requests to sync the mutations of a user.
+type syncRequest struct {
+	UserID    uint64           `json:"user_id"`
+	Mutations []store.Mutation `json:"mutations"`
+}
+
+// validate returns an error if the request or one of its mutations is
+// malformed. Records of mutations are assigned to the user of the request.
+func (req syncRequest) validate() error {
+	if req.UserID == 0 {
+		return errors.New("missing user id")
+	}
+	if len(req.Mutations) > maxMutations {
+		return fmt.Errorf("more than %d mutations", maxMutations)
+	}
+	for _, m := range req.Mutations {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		if m.Record != nil && m.Record.UserID != 0 && m.Record.UserID != req.UserID {
+			return fmt.Errorf("mutation %s changes a record of another user", m.MutationID)
+		}
+	}
+	return nil
+}
+
+// syncResponse reports the outcome of every mutation in the order of the
+// request.
+type syncResponse struct {
+	Applied   int                    `json:"applied"`
+	Conflicts int                    `json:"conflicts"`
+	Results   []store.MutationResult `json:"results"`
+}
+
+// feedResponse is a page of the record feed. Cursor is the cursor of the
+// last entry, or since if there is none, and passed as since to get the next
+// page.
+type feedResponse struct {
+	Entries []store.FeedEntry `json:"changes"`
+	Cursor  uint64            `json:"cursor"`
+	More    bool              `json:"more"` // the page is full, more entries may follow
+}
+
+// syncService provides API methods for clients to sync records they changed
+// while offline and to catch up with changes made elsewhere.
+type syncService struct {
+	syncStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the sync endpoints.
+func (ss *syncService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ss.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+	ctx = store.WithAudit(ctx, auditFromRequest(r))
+
+	switch routeName(r) {
+	case "sync":
+		var req syncRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if err := req.validate(); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ss.sync(ctx, w, r, req)
+		return
+
+	case "changes":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		q := r.URL.Query()
+		var since uint64
+		if s := q.Get("since"); s != "" {
+			if since, err = strconv.ParseUint(s, 10, 64); err != nil {
+				writeError(w, r, err, http.StatusBadRequest)
+				return
+			}
+		}
+		limit := defaultChangeLimit
+		if l := q.Get("limit"); l != "" {
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > 1000 {
+				writeError(w, r, errBadRequest, http.StatusBadRequest)
+				return
+			}
+		}
+		ss.getFeed(ctx, w, r, userID, since, limit)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (ss *syncService) sync(ctx context.Context, w http.ResponseWriter, r *http.Request, req syncRequest) {
+	results, err := ss.Sync(ctx, req.UserID, req.Mutations)
+	if err != nil {
+		// applied mutations are kept, the client retries the batch
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	resp := syncResponse{Results: results}
+	for _, res := range results {
+		if res.Status == store.MutationConflict {
+			resp.Conflicts++
+		} else {
+			resp.Applied++
+		}
+	}
+	encodeJSON(w, r, resp, http.StatusOK)
+}
+
+func (ss *syncService) getFeed(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, since uint64, limit int) {
+	entries, err := ss.Feed(ctx, userID, since, limit)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	resp := feedResponse{Entries: entries, Cursor: since, More: len(entries) == limit}
+	if n := len(entries); n > 0 {
+		resp.Cursor = entries[n-1].Cursor
+	}
+	encodeJSON(w, r, resp, http.StatusOK)
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+)
+
+const (
+	mutationID = "6f1c2a9e-1b7d-4c3e-8a5f-2d9e0b4c7a11"
+	recordUUID = "9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a41"
+	recordJSON = `{"start_time":1577833200,"start_loc":"UTC","stop_time":1577836800,"stop_loc":"UTC"}`
+)
+
+func TestSync(t *testing.T) {
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutation := func(op string, base int, record string) string {
+		m := `{"mutation_id":"` + mutationID + `","op":"` + op + `","uuid":"` + recordUUID + `","base_version":` + strconv.Itoa(base)
+		if record != "" {
+			m += `,"record":` + record
+		}
+		return m + `}`
+	}
+	tests := []struct {
+		d         string // description of test case
+		p         string // request payload
+		s         int    // expected http status code
+		applied   int
+		conflicts int
+	}{
+		{d: "expect mutations applied", p: `{"user_id":1,"mutations":[` + mutation("upsert", 0, recordJSON) + `,` + mutation("delete", 1, "") + `]}`, s: http.StatusOK, applied: 2},
+		{d: "expect stale mutation reported", p: `{"user_id":1,"mutations":[` + mutation("upsert", 3, recordJSON) + `,` + mutation("delete", 1, "") + `]}`, s: http.StatusOK, applied: 1, conflicts: 1},
+		{d: "expect empty batch", p: `{"user_id":1,"mutations":[]}`, s: http.StatusOK},
+		{d: "expect bad request without user", p: `{"mutations":[]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for unknown op", p: `{"user_id":1,"mutations":[` + mutation("merge", 0, recordJSON) + `]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for upsert without record", p: `{"user_id":1,"mutations":[` + mutation("upsert", 0, "") + `]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for invalid uuid", p: `{"user_id":1,"mutations":[{"mutation_id":"` + mutationID + `","op":"delete","uuid":"42"}]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for record of other user", p: `{"user_id":1,"mutations":[` + mutation("upsert", 0, `{"user_id":2,"start_time":1577833200,"stop_time":1577836800}`) + `]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for mismatching uuid", p: `{"user_id":1,"mutations":[` + mutation("upsert", 0, `{"uuid":"0c6d2b7e-4a8c-4d2e-9f1a-9b2f5f3e8a41","start_time":1577833200,"stop_time":1577836800}`) + `]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for too many mutations", p: `{"user_id":1,"mutations":[` + strings.Repeat(mutation("delete", 1, "")+",", maxMutations) + mutation("delete", 1, "") + `]}`, s: http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/sync", strings.NewReader(tc.p)))
+		if w.Code != tc.s {
+			t.Errorf("%s: want status code %d got %d: %s", tc.d, tc.s, w.Code, w.Body)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Applied   int `json:"applied"`
+			Conflicts int `json:"conflicts"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", tc.d, err)
+		}
+		if resp.Applied != tc.applied || resp.Conflicts != tc.conflicts {
+			t.Errorf("%s: want %d applied and %d conflicts got %d and %d", tc.d, tc.applied, tc.conflicts, resp.Applied, resp.Conflicts)
+		}
+	}
+}
+
+func TestFeedCursor(t *testing.T) {
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		d      string // description of test case
+		u      string // route of test request
+		cursor uint64
+		more   bool
+	}{
+		{d: "expect cursor of last entry", u: "/changes?user_id=1&since=5", cursor: 7, more: false},
+		{d: "expect more for full page", u: "/changes?user_id=1&since=5&limit=2", cursor: 7, more: true},
+		{d: "expect cursor from start", u: "/changes?user_id=1", cursor: 2, more: false},
+	}
+	for _, tc := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tc.u, nil))
+		var resp struct {
+			Cursor uint64 `json:"cursor"`
+			More   bool   `json:"more"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v: %s", tc.d, err, w.Body)
+		}
+		if resp.Cursor != tc.cursor || resp.More != tc.more {
+			t.Errorf("%s: want cursor %d and more %v got %d and %v", tc.d, tc.cursor, tc.more, resp.Cursor, resp.More)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// Timer errors exposed to clients.
+var (
+	errTimerRunning = errors.New("timer_running")
+	errInvalidStop  = errors.New("invalid_stop")
+)
+
+// timerStore handles operations on running timers.
+type timerStore interface {
+	StartTimer(ctx context.Context, t store.Timer) (*store.Timer, error)
+	Timer(ctx context.Context, userID uint64) (*store.Timer, error)
+	StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*store.TimeRecord, error)
+}
+
+// timerStop is the payload of requests to stop a timer. The stop time is
+// given as seconds since UNIX epoch, the time of the request is used if it
+// is missing.
+type timerStop struct {
+	UserID  uint64 `json:"user_id"`
+	Stop    int64  `json:"stop_time"`
+	StopLoc string `json:"stop_loc"`
+}
+
+// stopTime returns the stop time in the user's location.
+func (req timerStop) stopTime() (time.Time, error) {
+	loc, err := time.LoadLocation(req.StopLoc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	stop := time.Now()
+	if req.Stop != 0 {
+		stop = time.Unix(req.Stop, 0)
+	}
+	return stop.In(loc), nil
+}
+
+// startNow validates a timer to start and starts it at the time of the
+// request if the start time is missing.
+func startNow(t *store.Timer) error {
+	if t.UserID == 0 {
+		return errors.New("missing user id")
+	}
+	if t.Start.IsZero() {
+		loc, err := time.LoadLocation(t.StartLoc)
+		if err != nil {
+			return err
+		}
+		t.Start = time.Now().In(loc)
+	}
+	return nil
+}
+
+// timerService provides API methods to start and stop timers.
+type timerService struct {
+	timerStore
+	localizer
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the timer endpoints.
+func (ts *timerService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ts.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+	ctx = store.WithAudit(ctx, auditFromRequest(r))
+
+	switch routeName(r) {
+	case "timer":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		ts.getTimer(ctx, w, r, userID)
+		return
+
+	case "timer_start":
+		var t store.Timer
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&t); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		if err := startNow(&t); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ts.startTimer(ctx, w, r, t)
+		return
+
+	case "timer_stop":
+		var req timerStop
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		stop, err := req.stopTime()
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Vary", "Accept-Language")
+		format, code, err := recordFormat(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		ts.stopTimer(ctx, w, r, req.UserID, stop, req.StopLoc, format)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (ts *timerService) getTimer(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64) {
+	t, err := ts.Timer(ctx, userID)
+	switch err {
+	case nil:
+		encodeJSON(w, r, t, http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (ts *timerService) startTimer(ctx context.Context, w http.ResponseWriter, r *http.Request, t store.Timer) {
+	started, err := ts.StartTimer(ctx, t)
+	switch err {
+	case nil:
+		encodeJSON(w, r, started, http.StatusOK)
+	case store.ErrTimerRunning:
+		writeError(w, r, errTimerRunning, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+// stopTimer stops the running timer of a user and responds with the created
+// time record in the format and the user's locale.
+func (ts *timerService) stopTimer(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64, stop time.Time, stopLoc string, format store.Format) {
+	rec, err := ts.StopTimer(ctx, userID, stop, stopLoc)
+	switch err {
+	case nil:
+		encodeJSON(w, r, ts.formatRecord(ctx, r, rec, format), http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrInvalidStop:
+		writeError(w, r, errInvalidStop, http.StatusUnprocessableEntity)
+	case store.ErrRecordLocked:
+		writeError(w, r, errLocked, http.StatusConflict)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
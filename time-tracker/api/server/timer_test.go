@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+)
+
+func TestTimers(t *testing.T) {
+	h, err := newHandler(newMemStore(), events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		d string // description of test case
+		m string // http method of test request
+		u string // route of test request
+		p string // request payload
+		s int    // expected http status code
+		b string // expected part of the response body
+	}{
+		{d: "expect timer started", m: "POST", u: "/timer/start", p: `{"user_id":7,"name":"standup","start_time":1579939200,"start_loc":"Europe/Berlin"}`, s: http.StatusOK, b: `"start_time":"25 Jan 2020 09:00:00"`},
+		{d: "expect conflict for running timer", m: "POST", u: "/timer/start", p: `{"user_id":7,"name":"review","start_loc":"Europe/Berlin"}`, s: http.StatusConflict, b: `"timer_running"`},
+		{d: "expect timer of other user started now", m: "POST", u: "/timer/start", p: `{"user_id":8,"name":"review","start_loc":"UTC"}`, s: http.StatusOK, b: `"name":"review"`},
+		{d: "expect bad request for missing user", m: "POST", u: "/timer/start", p: `{"name":"standup","start_loc":"UTC"}`, s: http.StatusBadRequest},
+		{d: "expect bad request for unknown start location", m: "POST", u: "/timer/start", p: `{"user_id":9,"start_loc":"Mars/Olympus"}`, s: http.StatusBadRequest},
+		{d: "expect bad request for invalid start", m: "POST", u: "/timer/start", p: `{"user_id":9,"start_time":"now"}`, s: http.StatusBadRequest},
+		{d: "expect running timer", m: "GET", u: "/timer?user_id=7", s: http.StatusOK, b: `"name":"standup"`},
+		{d: "expect no timer of other user", m: "GET", u: "/timer?user_id=9", s: http.StatusNotFound},
+		{d: "expect unprocessable stop before start", m: "POST", u: "/timer/stop", p: `{"user_id":7,"stop_time":1579935600,"stop_loc":"Europe/Berlin"}`, s: http.StatusUnprocessableEntity, b: `"invalid_stop"`},
+		{d: "expect timer still running", m: "GET", u: "/timer?user_id=7", s: http.StatusOK},
+		{d: "expect bad request for unknown stop location", m: "POST", u: "/timer/stop", p: `{"user_id":7,"stop_time":1579944600,"stop_loc":"Mars/Olympus"}`, s: http.StatusBadRequest},
+		{d: "expect bad request for unknown stop field", m: "POST", u: "/timer/stop", p: `{"user_id":7,"stop_time":1579944600,"stop_loc":"Europe/Berlin","name":"standup"}`, s: http.StatusBadRequest},
+		{d: "expect record of stopped timer", m: "POST", u: "/timer/stop", p: `{"user_id":7,"stop_time":1579944600,"stop_loc":"Europe/Berlin"}`, s: http.StatusOK, b: `"stop_time":"25 Jan 2020 10:30:00","stop_loc":"Europe/Berlin","duration":"01:30:00"`},
+		{d: "expect no timer to stop", m: "POST", u: "/timer/stop", p: `{"user_id":7,"stop_time":1579944600,"stop_loc":"Europe/Berlin"}`, s: http.StatusNotFound},
+		{d: "expect no timer after stop", m: "GET", u: "/timer?user_id=7", s: http.StatusNotFound},
+		{d: "expect timer started again", m: "POST", u: "/timer/start", p: `{"user_id":7,"name":"review","start_loc":"Europe/Berlin"}`, s: http.StatusOK, b: `"name":"review"`},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.m, tc.u, strings.NewReader(tc.p))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.s {
+			t.Errorf("%s: want status code %d got %d: %s", tc.d, tc.s, w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), tc.b) {
+			t.Errorf("%s: want body containing %s got %s", tc.d, tc.b, w.Body)
+		}
+	}
+}
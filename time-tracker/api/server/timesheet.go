@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+)
+
+// Timesheet errors exposed to clients.
+var (
+	errInvalidTransition = errors.New("invalid_transition")
+	errForbidden         = errors.New("forbidden")
+	errCommentRequired   = errors.New("comment_required")
+)
+
+// timesheetStore handles operations on timesheets.
+type timesheetStore interface {
+	CreateTimesheet(ctx context.Context, t store.Timesheet) (*store.Timesheet, error)
+	Timesheet(ctx context.Context, id uint64) (*store.Timesheet, error)
+	Timesheets(ctx context.Context, userID uint64) ([]store.Timesheet, error)
+	PendingTimesheets(ctx context.Context, leadID uint64) ([]store.Timesheet, error)
+	SetTimesheetState(ctx context.Context, id uint64, to store.TimesheetState, actorID uint64, comment string) (*store.Timesheet, error)
+}
+
+// timesheetRequest is the payload of requests to create a timesheet for an
+// ISO week. The week starts on Monday midnight in the given location.
+type timesheetRequest struct {
+	UserID uint64 `json:"user_id"`
+	Year   int    `json:"year"`
+	Week   int    `json:"week"`
+	Tz     string `json:"tz"`
+}
+
+// stateRequest is the optional payload of requests to change the state of a
+// timesheet.
+type stateRequest struct {
+	Comment string `json:"comment"`
+}
+
+// timesheetActions maps the actions of the state change endpoint to the
+// resulting states.
+var timesheetActions = map[string]store.TimesheetState{
+	"submit":  store.TimesheetSubmitted,
+	"approve": store.TimesheetApproved,
+	"reject":  store.TimesheetRejected,
+}
+
+// timesheetService provides API methods to operate on timesheets.
+type timesheetService struct {
+	timesheetStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the timesheet endpoints.
+func (ss *timesheetService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ss.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "timesheet":
+		var req timesheetRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		t, err := newTimesheet(req)
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ss.createTimesheet(ctx, w, r, t)
+		return
+
+	case "timesheets":
+		userID, code, err := parseUserQuery(r)
+		if err != nil {
+			writeError(w, r, err, code)
+			return
+		}
+		ss.getTimesheets(ctx, w, r, userID)
+		return
+
+	case "pending":
+		leadID, err := strconv.ParseUint(r.URL.Query().Get("lead_id"), 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ss.getPending(ctx, w, r, leadID)
+		return
+
+	case "timesheet_id":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ss.getTimesheet(ctx, w, r, id)
+		return
+
+	case "timesheet_state":
+		vars := mux.Vars(r)
+		id, err := strconv.ParseUint(vars["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		to, ok := timesheetActions[vars["action"]]
+		if !ok {
+			writeError(w, r, errNotFound, http.StatusNotFound)
+			return
+		}
+		var req stateRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+
+		// the body is optional
+		if err := decoder.Decode(&req); err != nil && err != io.EOF {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ss.setState(ctx, w, r, id, to, req.Comment)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+// newTimesheet returns a draft timesheet for the requested ISO week.
+func newTimesheet(req timesheetRequest) (store.Timesheet, error) {
+	if req.UserID == 0 {
+		return store.Timesheet{}, errors.New("missing user id")
+	}
+	loc, err := time.LoadLocation(req.Tz)
+	if err != nil {
+		return store.Timesheet{}, err
+	}
+	if req.Year < 1 || req.Year > 9999 {
+		return store.Timesheet{}, errors.New("invalid year")
+	}
+	// the 28th of December is always in the last week of the ISO year
+	if _, weeks := time.Date(req.Year, time.December, 28, 0, 0, 0, 0, loc).ISOWeek(); req.Week < 1 || req.Week > weeks {
+		return store.Timesheet{}, errors.New("invalid week")
+	}
+	start := firstDayOfISOWeek(req.Year, req.Week, loc)
+	return store.Timesheet{
+		UserID: req.UserID,
+		Year:   req.Year,
+		Week:   req.Week,
+		Loc:    loc.String(),
+		Start:  start,
+		Stop:   start.AddDate(0, 0, 7),
+		State:  store.TimesheetDraft,
+	}, nil
+}
+
+func (ss *timesheetService) createTimesheet(ctx context.Context, w http.ResponseWriter, r *http.Request, t store.Timesheet) {
+	created, err := ss.CreateTimesheet(ctx, t)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, created, http.StatusOK)
+}
+
+func (ss *timesheetService) getTimesheet(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64) {
+	t, err := ss.Timesheet(ctx, id)
+	switch err {
+	case nil:
+		encodeJSON(w, r, t, http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+func (ss *timesheetService) getTimesheets(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uint64) {
+	sheets, err := ss.Timesheets(ctx, userID)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, sheets, http.StatusOK)
+}
+
+// getPending returns the queue of submitted timesheets waiting for the
+// approval of a lead.
+func (ss *timesheetService) getPending(ctx context.Context, w http.ResponseWriter, r *http.Request, leadID uint64) {
+	sheets, err := ss.PendingTimesheets(ctx, leadID)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, sheets, http.StatusOK)
+}
+
+// setState moves a timesheet to another state on behalf of the actor of the
+// request. Rejections must explain what needs to be fixed.
+func (ss *timesheetService) setState(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64, to store.TimesheetState, comment string) {
+	if to == store.TimesheetRejected && comment == "" {
+		writeError(w, r, errCommentRequired, http.StatusUnprocessableEntity)
+		return
+	}
+	t, err := ss.SetTimesheetState(ctx, id, to, auditFromRequest(r).ActorID, comment)
+	switch err {
+	case nil:
+		encodeJSON(w, r, t, http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	case store.ErrTransition:
+		writeError(w, r, errInvalidTransition, http.StatusConflict)
+	case store.ErrForbidden:
+		writeError(w, r, errForbidden, http.StatusForbidden)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
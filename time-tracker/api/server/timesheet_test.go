@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/gorilla/mux"
+)
+
+// uses the timesheet id to get the mock store error.
+type mockTimesheetStore struct{}
+
+func (ms *mockTimesheetStore) CreateTimesheet(ctx context.Context, t store.Timesheet) (*store.Timesheet, error) {
+	return &t, nil
+}
+func (ms *mockTimesheetStore) Timesheet(ctx context.Context, id uint64) (*store.Timesheet, error) {
+	return &store.Timesheet{TimesheetID: id, Loc: "UTC"}, timesheetTests[id].e
+}
+func (ms *mockTimesheetStore) Timesheets(ctx context.Context, userID uint64) ([]store.Timesheet, error) {
+	return nil, nil
+}
+func (ms *mockTimesheetStore) PendingTimesheets(ctx context.Context, leadID uint64) ([]store.Timesheet, error) {
+	return nil, nil
+}
+func (ms *mockTimesheetStore) SetTimesheetState(ctx context.Context, id uint64, to store.TimesheetState, actorID uint64, comment string) (*store.Timesheet, error) {
+	return &store.Timesheet{TimesheetID: id, Loc: "UTC", State: to}, timesheetTests[id].e
+}
+
+// test cases indexed by timesheet id
+var timesheetTests = map[uint64]struct {
+	d string // description of test case
+	e error  // mock store error
+	u string // route of test request
+	b string // request body
+	s int    // expected http status code
+}{
+	1: {
+		d: "expect unknown timesheet to result in 404",
+		e: store.ErrNotFound,
+		u: "/timesheets/1/submit",
+		s: http.StatusNotFound,
+	},
+	2: {
+		d: "expect invalid transition to result in 409",
+		e: store.ErrTransition,
+		u: "/timesheets/2/approve",
+		s: http.StatusConflict,
+	},
+	3: {
+		d: "expect approval by someone other than the lead to result in 403",
+		e: store.ErrForbidden,
+		u: "/timesheets/3/approve",
+		s: http.StatusForbidden,
+	},
+	4: {
+		d: "expect rejection without comment to result in 422",
+		u: "/timesheets/4/reject",
+		b: `{"comment":""}`,
+		s: http.StatusUnprocessableEntity,
+	},
+	5: {
+		d: "expect rejection with comment to succeed",
+		u: "/timesheets/5/reject",
+		b: `{"comment":"missing friday"}`,
+		s: http.StatusOK,
+	},
+	6: {
+		d: "expect submission without body to succeed",
+		u: "/timesheets/6/submit",
+		s: http.StatusOK,
+	},
+	7: {
+		d: "expect unknown field to result in 400",
+		u: "/timesheets/7/submit",
+		b: `{"note":"foo"}`,
+		s: http.StatusBadRequest,
+	},
+}
+
+func TestServeHTTPTimesheet(t *testing.T) {
+	ss := &timesheetService{&mockTimesheetStore{}, 200 * time.Millisecond}
+	router := mux.NewRouter()
+	router.Handle("/timesheets/{id:[0-9]+}/{action:(?:submit|approve|reject)}", ss).
+		Methods("POST").
+		Name("timesheet_state")
+
+	for _, tc := range timesheetTests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", tt.u, strings.NewReader(tt.b))
+			router.ServeHTTP(w, r)
+			if want, got := tt.s, w.Code; want != got {
+				t.Errorf("want status code %d got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestNewTimesheet(t *testing.T) {
+	tests := []struct {
+		d     string           // description of test case
+		req   timesheetRequest // request payload
+		start string           // expected start of the week in RFC 3339
+		e     bool             // expect error
+	}{
+		{
+			d:     "expect week to start on monday in the given location",
+			req:   timesheetRequest{UserID: 1, Year: 2020, Week: 2, Tz: "Europe/Berlin"},
+			start: "2020-01-06T00:00:00+01:00",
+		},
+		{
+			d:     "expect first week to start in the previous year",
+			req:   timesheetRequest{UserID: 1, Year: 2020, Week: 1, Tz: "UTC"},
+			start: "2019-12-30T00:00:00Z",
+		},
+		{
+			d:     "expect week 53 in a long year",
+			req:   timesheetRequest{UserID: 1, Year: 2020, Week: 53, Tz: "UTC"},
+			start: "2020-12-28T00:00:00Z",
+		},
+		{
+			d:   "expect error for week 53 in a short year",
+			req: timesheetRequest{UserID: 1, Year: 2019, Week: 53, Tz: "UTC"},
+			e:   true,
+		},
+		{
+			d:   "expect error for unknown location",
+			req: timesheetRequest{UserID: 1, Year: 2020, Week: 1, Tz: "Foo/Bar"},
+			e:   true,
+		},
+		{
+			d:   "expect error for missing user",
+			req: timesheetRequest{Year: 2020, Week: 1, Tz: "UTC"},
+			e:   true,
+		},
+	}
+	for _, tc := range tests {
+		ts, err := newTimesheet(tc.req)
+		if tc.e {
+			if err == nil {
+				t.Errorf("%s: want error", tc.d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.d, err)
+			continue
+		}
+		if got := ts.Start.Format(time.RFC3339); got != tc.start {
+			t.Errorf("%s: want start %s got %s", tc.d, tc.start, got)
+		}
+		if got := ts.Stop.Sub(ts.Start); got != 7*24*time.Hour {
+			t.Errorf("%s: want week of 7 days got %v", tc.d, got)
+		}
+		if ts.State != store.TimesheetDraft {
+			t.Errorf("%s: want draft got %s", tc.d, ts.State)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	h, err := newHandler(&mockDatastore{mockRPCStore: &mockRPCStore{}}, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest("GET", "/records?user_id=1&tz=UTC&ts=1577833200&period=day", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	server, ok := spans["GET /records"]
+	if !ok {
+		t.Fatalf("want span of the request got %v", spans)
+	}
+	if got := server.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("want trace id %s of the traceparent got %s", traceID, got)
+	}
+	if got := server.Parent().SpanID().String(); got != spanID {
+		t.Errorf("want parent span %s got %s", spanID, got)
+	}
+	var requestID string
+	for _, kv := range server.Attributes() {
+		if kv.Key == "request.id" {
+			requestID = kv.Value.AsString()
+		}
+	}
+	if want := w.Header().Get("Request-Id"); requestID == "" || requestID != want {
+		t.Errorf("want request id %q in span got %q", want, requestID)
+	}
+	handler, ok := spans["timeRecordService.getRecords"]
+	if !ok {
+		t.Fatalf("want span of the handler got %v", spans)
+	}
+	if handler.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("want handler span to be a child of the request span")
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/gorilla/mux"
+)
+
+// defaultDeliveryLimit is the number of deliveries returned by the delivery
+// log if the request does not specify a limit.
+const defaultDeliveryLimit = 100
+
+// webhookStore handles operations on webhook subscriptions.
+type webhookStore interface {
+	CreateWebhook(ctx context.Context, s webhook.Subscription) (*webhook.Subscription, error)
+	Webhooks(ctx context.Context) ([]webhook.Subscription, error)
+	DeleteWebhook(ctx context.Context, id uint64) error
+	Deliveries(ctx context.Context, subscriptionID uint64, limit int) ([]webhook.Delivery, error)
+}
+
+// webhookService provides API methods to manage webhook subscriptions.
+type webhookService struct {
+	webhookStore
+	timeout time.Duration
+}
+
+// ServeHTTP serves requests to the webhook endpoints.
+func (ws *webhookService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), ws.timeout)
+	defer cancel()
+	ctx = loggerFromRequest(r).WithContext(ctx)
+
+	switch routeName(r) {
+	case "webhook":
+		var s webhook.Subscription
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields() // catch unwanted fields
+		if err := decoder.Decode(&s); err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ws.createWebhook(ctx, w, r, s)
+		return
+
+	case "webhooks":
+		ws.getWebhooks(ctx, w, r)
+		return
+
+	case "webhook_id":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		ws.deleteWebhook(ctx, w, r, id)
+		return
+
+	case "deliveries":
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64) // mux validates type
+		if err != nil {
+			writeError(w, r, err, http.StatusBadRequest)
+			return
+		}
+		limit := defaultDeliveryLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if limit, err = strconv.Atoi(l); err != nil || limit <= 0 || limit > 1000 {
+				writeError(w, r, errBadRequest, http.StatusBadRequest)
+				return
+			}
+		}
+		ws.getDeliveries(ctx, w, r, id, limit)
+		return
+	}
+	writeError(w, r, errNotFound, http.StatusNotFound)
+}
+
+func (ws *webhookService) createWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request, s webhook.Subscription) {
+	if err := s.Validate(); err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	created, err := ws.CreateWebhook(ctx, s)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, created, http.StatusOK)
+}
+
+func (ws *webhookService) getWebhooks(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	subs, err := ws.Webhooks(ctx)
+	if err != nil {
+		writeError(w, r, err, http.StatusInternalServerError)
+		return
+	}
+	encodeJSON(w, r, subs, http.StatusOK)
+}
+
+func (ws *webhookService) deleteWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64) {
+	switch err := ws.DeleteWebhook(ctx, id); err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
+
+// getDeliveries returns the delivery log of a subscription.
+func (ws *webhookService) getDeliveries(ctx context.Context, w http.ResponseWriter, r *http.Request, id uint64, limit int) {
+	deliveries, err := ws.Deliveries(ctx, id, limit)
+	switch err {
+	case nil:
+		encodeJSON(w, r, deliveries, http.StatusOK)
+	case store.ErrNotFound:
+		writeError(w, r, errNotFound, http.StatusNotFound)
+	default:
+		writeError(w, r, err, http.StatusInternalServerError)
+	}
+}
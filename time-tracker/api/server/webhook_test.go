@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/config"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/rs/zerolog"
+)
+
+func TestWebhooks(t *testing.T) {
+	ms := newMemStore()
+	h, err := newHandler(ms, events.NewHub(), time.Second, config.NewLive(config.Settings{}), nil, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const secret = "0123456789abcdef"
+	subscription := func(url, secret, events string) string {
+		return `{"url":"` + url + `","secret":"` + secret + `","events":[` + events + `]}`
+	}
+	tests := []struct {
+		d string // description of test case
+		m string // http method of test request
+		u string // route of test request
+		p string // request payload
+		s int    // expected http status code
+		b string // expected part of the response body
+	}{
+		{d: "expect subscription created", m: "POST", u: "/webhook", p: subscription("https://example.com/hook", secret, `"record.created","timer.stopped"`), s: http.StatusOK, b: `"subscription_id":1`},
+		{d: "expect bad request for relative url", m: "POST", u: "/webhook", p: subscription("/hook", secret, `"record.created"`), s: http.StatusBadRequest},
+		{d: "expect bad request for other scheme", m: "POST", u: "/webhook", p: subscription("ftp://example.com/hook", secret, `"record.created"`), s: http.StatusBadRequest},
+		{d: "expect bad request for invalid url", m: "POST", u: "/webhook", p: subscription("https://exa mple.com/%zz", secret, `"record.created"`), s: http.StatusBadRequest},
+		{d: "expect bad request for short secret", m: "POST", u: "/webhook", p: subscription("https://example.com/hook", "0123456789", `"record.created"`), s: http.StatusBadRequest},
+		{d: "expect bad request for missing secret", m: "POST", u: "/webhook", p: `{"url":"https://example.com/hook","events":["record.created"]}`, s: http.StatusBadRequest},
+		{d: "expect bad request for missing events", m: "POST", u: "/webhook", p: subscription("https://example.com/hook", secret, ""), s: http.StatusBadRequest},
+		{d: "expect bad request for unknown event", m: "POST", u: "/webhook", p: subscription("https://example.com/hook", secret, `"record.created","record.billed"`), s: http.StatusBadRequest},
+		{d: "expect bad request for unknown field", m: "POST", u: "/webhook", p: `{"url":"https://example.com/hook","secret":"` + secret + `","events":["record.created"],"active":true}`, s: http.StatusBadRequest},
+		{d: "expect second subscription created", m: "POST", u: "/webhook", p: subscription("http://example.com/other", secret, `"timer.started"`), s: http.StatusOK, b: `"subscription_id":2`},
+		{d: "expect subscriptions listed", m: "GET", u: "/webhooks", s: http.StatusOK, b: `"url":"http://example.com/other"`},
+		{d: "expect deliveries listed", m: "GET", u: "/webhooks/1/deliveries?limit=10", s: http.StatusOK, b: `[]`},
+		{d: "expect bad request for zero limit", m: "GET", u: "/webhooks/1/deliveries?limit=0", s: http.StatusBadRequest},
+		{d: "expect bad request for limit too high", m: "GET", u: "/webhooks/1/deliveries?limit=1001", s: http.StatusBadRequest},
+		{d: "expect bad request for invalid limit", m: "GET", u: "/webhooks/1/deliveries?limit=ten", s: http.StatusBadRequest},
+		{d: "expect deliveries of unknown subscription not found", m: "GET", u: "/webhooks/9/deliveries", s: http.StatusNotFound},
+		{d: "expect subscription deleted", m: "DELETE", u: "/webhooks/1", s: http.StatusNoContent},
+		{d: "expect deleted subscription not found", m: "DELETE", u: "/webhooks/1", s: http.StatusNotFound},
+		{d: "expect deliveries of deleted subscription not found", m: "GET", u: "/webhooks/1/deliveries", s: http.StatusNotFound},
+		{d: "expect remaining subscription listed", m: "GET", u: "/webhooks", s: http.StatusOK, b: `[{"subscription_id":2,`},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.m, tc.u, strings.NewReader(tc.p))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.s {
+			t.Errorf("%s: want status code %d got %d: %s", tc.d, tc.s, w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), tc.b) {
+			t.Errorf("%s: want body containing %s got %s", tc.d, tc.b, w.Body)
+		}
+		// the secret signs deliveries and is never returned
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: want no secret in the response got %s", tc.d, w.Body)
+		}
+	}
+	if s, ok := ms.webhooks[2]; !ok || s.Secret != secret {
+		t.Errorf("want secret of subscription 2 stored got %+v", s)
+	}
+}
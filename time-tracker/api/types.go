@@ -7,7 +7,8 @@ import (
 
 type Error struct {
 	Err      string         `json:"error"`
-	Response *http.Response `json:"-"` // Will not be marshalled
+	Message  string         `json:"message,omitempty"` // Err in the language of the client
+	Response *http.Response `json:"-"`                 // Will not be marshalled
 }
 
 func (e Error) Error() string {
@@ -0,0 +1,219 @@
+package billing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+func mustParse(t *testing.T, amount, currency string) billing.Money {
+	m, err := billing.ParseMoney(amount, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+var amountTests = []struct {
+	d   string // description of test case
+	r   string // hourly rate
+	c   string // currency
+	s   int64  // seconds
+	out string // expected formatted amount
+}{
+	{d: "expect full hour to equal the rate", r: "100", c: "EUR", s: 3600, out: "100.00"},
+	{d: "expect one second to round to zero", r: "100", c: "EUR", s: 1, out: "0.03"},
+	{d: "expect halves to be rounded away from zero", r: "0.09", c: "EUR", s: 1800, out: "0.05"},
+	{d: "expect currency without minor units", r: "1000", c: "JPY", s: 5400, out: "1500"},
+	{d: "expect currency with three minor units", r: "10.005", c: "KWD", s: 3600, out: "10.005"},
+}
+
+func TestAmount(t *testing.T) {
+	for _, tc := range amountTests {
+		got := billing.Amount(mustParse(t, tc.r, tc.c), tc.s)
+		if want := tc.out; got.String() != want {
+			t.Errorf("%s: want %s got %s", tc.d, want, got.String())
+		}
+	}
+}
+
+func TestTotalsAreExact(t *testing.T) {
+	// a third of an hour at 0.10 is 0.0333..., three of them must sum up to
+	// exactly 0.10 instead of 0.09 when rounding each amount.
+	rate := mustParse(t, "0.10", "EUR")
+	totals := make(billing.Totals)
+	for i := 0; i < 3; i++ {
+		totals.Add(billing.Amount(rate, 1200))
+	}
+	totals.Add(billing.Amount(mustParse(t, "1", "USD"), 3600))
+	l := totals.List()
+	if len(l) != 2 {
+		t.Fatalf("want 2 currencies got %d", len(l))
+	}
+	if got, want := l[0].Currency+" "+l[0].String(), "EUR 0.10"; got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+	if got, want := l[1].Currency+" "+l[1].String(), "USD 1.00"; got != want {
+		t.Errorf("want %s got %s", want, got)
+	}
+}
+
+var parseMoneyTests = []struct {
+	d string // description of test case
+	a string // amount
+	c string // currency
+}{
+	{d: "expect fractions to be rejected", a: "1/3", c: "EUR"},
+	{d: "expect exponents to be rejected", a: "1e3", c: "EUR"},
+	{d: "expect more than four decimals to be rejected", a: "0.00001", c: "EUR"},
+	{d: "expect lower case currency to be rejected", a: "1", c: "eur"},
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	for _, tc := range parseMoneyTests {
+		if _, err := billing.ParseMoney(tc.a, tc.c); err == nil {
+			t.Errorf("%s: expected err", tc.d)
+		}
+	}
+}
+
+func TestRateFor(t *testing.T) {
+	jan := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)
+	rates := []billing.Rate{
+		{RateID: 1, Scope: billing.ScopeUser, ScopeID: 42, Hourly: mustParse(t, "50", "EUR"), EffectiveFrom: jan},
+		{RateID: 2, Scope: billing.ScopeUser, ScopeID: 42, Hourly: mustParse(t, "60", "EUR"), EffectiveFrom: feb},
+		{RateID: 3, Scope: billing.ScopeProject, ScopeID: 7, Hourly: mustParse(t, "80", "EUR"), EffectiveFrom: jan},
+		{RateID: 4, Scope: billing.ScopeClient, ScopeID: 3, Hourly: mustParse(t, "90", "EUR"), EffectiveFrom: feb},
+	}
+	tests := []struct {
+		d  string          // description of test case
+		s  billing.Subject // subject to get the rate for
+		t  time.Time       // time the work started
+		id uint64          // expected rate id, 0 if none applies
+	}{
+		{
+			d: "expect no rate before the first rate is effective",
+			s: billing.Subject{UserID: 42},
+			t: jan.Add(-time.Second),
+		},
+		{
+			d:  "expect the user rate effective at the time of work",
+			s:  billing.Subject{UserID: 42},
+			t:  jan.Add(time.Hour),
+			id: 1,
+		},
+		{
+			d:  "expect a rate change to not affect earlier work",
+			s:  billing.Subject{UserID: 42},
+			t:  feb.Add(time.Hour),
+			id: 2,
+		},
+		{
+			d:  "expect the project rate to override the user rate",
+			s:  billing.Subject{UserID: 42, ProjectID: 7},
+			t:  feb.Add(time.Hour),
+			id: 3,
+		},
+		{
+			d:  "expect the client rate to override the project rate",
+			s:  billing.Subject{UserID: 42, ProjectID: 7, ClientID: 3},
+			t:  feb.Add(time.Hour),
+			id: 4,
+		},
+		{
+			d:  "expect the project rate before the client rate is effective",
+			s:  billing.Subject{UserID: 42, ProjectID: 7, ClientID: 3},
+			t:  jan.Add(time.Hour),
+			id: 3,
+		},
+	}
+	for _, tc := range tests {
+		r, ok := billing.RateFor(rates, tc.s, tc.t)
+		if tc.id == 0 {
+			if ok {
+				t.Errorf("%s: want no rate got %d", tc.d, r.RateID)
+			}
+			continue
+		}
+		if !ok || r.RateID != tc.id {
+			t.Errorf("%s: want rate %d got %d", tc.d, tc.id, r.RateID)
+		}
+	}
+}
+
+var roundTests = []struct {
+	d   string           // description of test case
+	r   billing.Rounding // rounding policy
+	in  int64            // seconds
+	out int64            // expected seconds
+}{
+	{d: "expect no rounding without increment", r: billing.Rounding{}, in: 61, out: 61},
+	{d: "expect rounding up to 6 minutes", r: billing.Rounding{Increment: 360, Mode: billing.RoundUp}, in: 361, out: 720},
+	{d: "expect exact increments to be kept", r: billing.Rounding{Increment: 900, Mode: billing.RoundUp}, in: 1800, out: 1800},
+	{d: "expect rounding down to 15 minutes", r: billing.Rounding{Increment: 900, Mode: billing.RoundDown}, in: 1799, out: 900},
+	{d: "expect rounding to nearest down", r: billing.Rounding{Increment: 900, Mode: billing.RoundNearest}, in: 1349, out: 900},
+	{d: "expect halves to be rounded up", r: billing.Rounding{Increment: 900, Mode: billing.RoundNearest}, in: 1350, out: 1800},
+}
+
+func TestRound(t *testing.T) {
+	for _, tc := range roundTests {
+		if got := tc.r.Round(tc.in); got != tc.out {
+			t.Errorf("%s: want %d got %d", tc.d, tc.out, got)
+		}
+	}
+}
+
+func TestRoundAll(t *testing.T) {
+	work := []billing.Work{
+		{Seconds: 600, Day: "2020-01-01", ProjectID: 1},
+		{Seconds: 600, Day: "2020-01-01", ProjectID: 1},
+		{Seconds: 600, Day: "2020-01-01", ProjectID: 2},
+		{Seconds: 1000, Day: "2020-01-02", ProjectID: 1},
+		{Seconds: 100, Day: "2020-01-02", ProjectID: 1},
+	}
+	tests := []struct {
+		d   string           // description of test case
+		r   billing.Rounding // rounding policy
+		out []int64          // expected seconds
+	}{
+		{
+			d:   "expect each record to be rounded",
+			r:   billing.Rounding{Increment: 900, Mode: billing.RoundUp, Per: billing.PerRecord},
+			out: []int64{900, 900, 900, 1800, 900},
+		},
+		{
+			d:   "expect the difference of a group to be added to its last entry",
+			r:   billing.Rounding{Increment: 900, Mode: billing.RoundUp, Per: billing.PerDayProject},
+			out: []int64{600, 1200, 900, 1000, 800},
+		},
+		{
+			d:   "expect entries to never be rounded below zero",
+			r:   billing.Rounding{Increment: 900, Mode: billing.RoundDown, Per: billing.PerDayProject},
+			out: []int64{600, 300, 0, 900, 0},
+		},
+	}
+	for _, tc := range tests {
+		got := tc.r.RoundAll(work)
+		for i := range got {
+			if got[i] != tc.out[i] {
+				t.Errorf("%s: want %v got %v", tc.d, tc.out, got)
+				break
+			}
+		}
+	}
+}
+
+func TestParseRounding(t *testing.T) {
+	r, err := billing.ParseRounding("360", "", "")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if r.Mode != billing.RoundUp || r.Per != billing.PerRecord {
+		t.Errorf("want defaults up per record got %+v", r)
+	}
+	if _, err := billing.ParseRounding("360", "sideways", ""); err == nil {
+		t.Errorf("expected err for invalid mode")
+	}
+}
@@ -0,0 +1,164 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+)
+
+// Amounts of money are represented as exact rational numbers and only get
+// rounded to the minor unit of their currency when they are formatted. This
+// way, sums over many records do not accumulate rounding errors and amounts
+// computed from hourly rates and durations in seconds stay exact.
+
+var (
+	errInvalidAmount   = errors.New("invalid amount")
+	errInvalidCurrency = errors.New("invalid currency code")
+)
+
+// MaxDecimals is the maximum number of decimal places of parsed amounts,
+// which matches the scale of amounts stored in the database.
+const MaxDecimals = 4
+
+var (
+	decimalRegexp  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]{1,4})?$`)
+	currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// minorUnits holds the number of decimal places of currencies which do not
+// use two decimal places according to ISO 4217.
+var minorUnits = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// Money is an exact decimal amount of money in a ISO 4217 currency.
+type Money struct {
+	Amount   *big.Rat
+	Currency string
+}
+
+// ParseMoney parses a decimal amount, e.g. "120.50", in the given currency.
+func ParseMoney(amount, currency string) (Money, error) {
+	if !decimalRegexp.MatchString(amount) {
+		return Money{}, errInvalidAmount
+	}
+	if !currencyRegexp.MatchString(currency) {
+		return Money{}, errInvalidCurrency
+	}
+	a, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return Money{}, errInvalidAmount
+	}
+	return Money{Amount: a, Currency: currency}, nil
+}
+
+// String returns the amount rounded to the minor unit of the currency.
+// Halves are rounded away from zero.
+func (m Money) String() string {
+	if m.Amount == nil {
+		return new(big.Rat).FloatString(decimals(m.Currency))
+	}
+	return m.Amount.FloatString(decimals(m.Currency))
+}
+
+// Round returns the amount rounded to the minor unit of the currency.
+// Halves are rounded away from zero.
+func (m Money) Round() Money {
+	r, _ := new(big.Rat).SetString(m.String())
+	return Money{Amount: r, Currency: m.Currency}
+}
+
+// Decimal returns the amount with MaxDecimals decimal places, which is exact
+// for parsed amounts.
+func (m Money) Decimal() string {
+	return m.Amount.FloatString(MaxDecimals)
+}
+
+// MarshalJSON formats the amount as decimal string to avoid loss of precision
+// in clients parsing JSON numbers as floating point numbers.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{
+		Amount:   m.String(),
+		Currency: m.Currency,
+	})
+}
+
+// UnmarshalJSON parses a decimal string amount and a currency code.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	p, err := ParseMoney(v.Amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = p
+	return nil
+}
+
+// Amount returns the amount of money earned in seconds at the given hourly rate.
+func Amount(hourly Money, seconds int64) Money {
+	a := new(big.Rat).SetInt64(seconds)
+	a.Mul(a, hourly.Amount)
+	a.Quo(a, big.NewRat(3600, 1))
+	return Money{Amount: a, Currency: hourly.Currency}
+}
+
+// Totals sums up amounts of money per currency.
+type Totals map[string]*big.Rat
+
+// Add adds m to the total of its currency.
+func (t Totals) Add(m Money) {
+	total, ok := t[m.Currency]
+	if !ok {
+		total = new(big.Rat)
+		t[m.Currency] = total
+	}
+	total.Add(total, m.Amount)
+}
+
+// List returns the totals ordered by currency code.
+func (t Totals) List() []Money {
+	l := make([]Money, 0, len(t))
+	for c, a := range t {
+		l = append(l, Money{Amount: new(big.Rat).Set(a), Currency: c})
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Currency < l[j].Currency })
+	return l
+}
+
+func decimals(currency string) int {
+	if d, ok := minorUnits[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// Validate returns an error if m is not a valid, non-negative amount of money.
+func (m Money) Validate() error {
+	if m.Amount == nil || m.Amount.Sign() < 0 {
+		return errInvalidAmount
+	}
+	if !currencyRegexp.MatchString(m.Currency) {
+		return fmt.Errorf("%v: %q", errInvalidCurrency, m.Currency)
+	}
+	return nil
+}
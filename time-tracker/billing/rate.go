@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var errUnknownScope = errors.New("unknown rate scope")
+
+// Scope is the entity an hourly rate applies to.
+type Scope string
+
+// Rate scopes in ascending order of precedence. A rate of a scope overrides
+// the rates of all scopes before it. Per-record overrides are stored with the
+// time record itself, see store.TimeRecord.
+const (
+	ScopeUser    Scope = "user"
+	ScopeProject Scope = "project"
+	ScopeClient  Scope = "client"
+)
+
+var precedence = []Scope{ScopeClient, ScopeProject, ScopeUser}
+
+// Rate is an hourly rate which is effective from a certain point in time
+// until it is superseded by a rate of the same scope with a later date.
+// Rates are never changed in place, a rate change is a new rate. This
+// preserves the history of rates and makes the amount of a record depend on
+// the rate that was effective at the time the work was done.
+type Rate struct {
+	RateID        uint64
+	Scope         Scope
+	ScopeID       uint64
+	Hourly        Money
+	EffectiveFrom time.Time
+}
+
+// rateJSON is the JSON representation of a rate with the effective date as
+// seconds since UNIX epoch.
+type rateJSON struct {
+	RateID        uint64 `json:"rate_id"`
+	Scope         Scope  `json:"scope"`
+	ScopeID       uint64 `json:"scope_id"`
+	Hourly        Money  `json:"hourly"`
+	EffectiveFrom int64  `json:"effective_from"`
+}
+
+// MarshalJSON formats the effective date as seconds since UNIX epoch.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rateJSON{
+		RateID:        r.RateID,
+		Scope:         r.Scope,
+		ScopeID:       r.ScopeID,
+		Hourly:        r.Hourly,
+		EffectiveFrom: r.EffectiveFrom.Unix(),
+	})
+}
+
+// UnmarshalJSON parses a rate with the effective date as seconds since UNIX
+// epoch. The rate id is ignored since it must be created by the datastore.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var rj rateJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+	r.Scope = rj.Scope
+	r.ScopeID = rj.ScopeID
+	r.Hourly = rj.Hourly
+	if rj.EffectiveFrom != 0 {
+		r.EffectiveFrom = time.Unix(rj.EffectiveFrom, 0)
+	}
+	return nil
+}
+
+// Validate returns an error if r is not a valid rate.
+func (r Rate) Validate() error {
+	switch r.Scope {
+	case ScopeUser, ScopeProject, ScopeClient:
+	default:
+		return errUnknownScope
+	}
+	return r.Hourly.Validate()
+}
+
+// Subject identifies the entities a unit of work is billed to.
+type Subject struct {
+	UserID    uint64
+	ProjectID uint64 // 0 if the work is not assigned to a project
+	ClientID  uint64 // 0 if the project has no client
+}
+
+// RateFor returns the rate with the highest precedence which is effective at
+// time t for the given subject. Returns false if no rate is applicable.
+func RateFor(rates []Rate, s Subject, t time.Time) (Rate, bool) {
+	ids := map[Scope]uint64{
+		ScopeUser:    s.UserID,
+		ScopeProject: s.ProjectID,
+		ScopeClient:  s.ClientID,
+	}
+	for _, scope := range precedence {
+		id := ids[scope]
+		if id == 0 {
+			continue
+		}
+		if r, ok := effective(rates, scope, id, t); ok {
+			return r, true
+		}
+	}
+	return Rate{}, false
+}
+
+// effective returns the latest rate of the given scope and id that became
+// effective before or at time t.
+func effective(rates []Rate, scope Scope, id uint64, t time.Time) (Rate, bool) {
+	var found bool
+	var rate Rate
+	for _, r := range rates {
+		if r.Scope != scope || r.ScopeID != id || r.EffectiveFrom.After(t) {
+			continue
+		}
+		if !found || r.EffectiveFrom.After(rate.EffectiveFrom) {
+			rate = r
+			found = true
+		}
+	}
+	return rate, found
+}
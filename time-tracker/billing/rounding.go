@@ -0,0 +1,158 @@
+package billing
+
+import (
+	"errors"
+	"strconv"
+)
+
+var errInvalidRounding = errors.New("invalid rounding policy")
+
+// RoundingMode is the direction durations are rounded in.
+type RoundingMode string
+
+// Rounding modes.
+const (
+	RoundUp      RoundingMode = "up"
+	RoundDown    RoundingMode = "down"
+	RoundNearest RoundingMode = "nearest" // halves are rounded up
+)
+
+// RoundingScope is the unit of work durations are rounded for.
+type RoundingScope string
+
+// Rounding scopes. Each invoice line bills exactly one record, thus rounding
+// per line and per record result in the same durations.
+const (
+	PerRecord     RoundingScope = "record"
+	PerDayProject RoundingScope = "day_project"
+	PerLine       RoundingScope = "line"
+)
+
+// Rounding is a policy to round durations to increments, as contractually
+// required by clients. Durations are only rounded for reports and invoices,
+// stored durations are always exact.
+type Rounding struct {
+	Increment int64         `json:"increment"` // seconds, 0 disables rounding
+	Mode      RoundingMode  `json:"mode"`
+	Per       RoundingScope `json:"per"`
+}
+
+// ParseRounding parses a rounding policy from an increment in seconds, a mode
+// and a scope. An empty increment disables rounding. Mode defaults to up and
+// scope to record.
+func ParseRounding(increment, mode, per string) (Rounding, error) {
+	if increment == "" {
+		return Rounding{}, nil
+	}
+	i, err := strconv.ParseInt(increment, 10, 64)
+	if err != nil {
+		return Rounding{}, errInvalidRounding
+	}
+	r := Rounding{Increment: i, Mode: RoundingMode(mode), Per: RoundingScope(per)}
+	if r.Mode == "" {
+		r.Mode = RoundUp
+	}
+	if r.Per == "" {
+		r.Per = PerRecord
+	}
+	return r, r.Validate()
+}
+
+// Validate returns an error if r is not a valid rounding policy.
+func (r Rounding) Validate() error {
+	if r.Increment < 0 {
+		return errInvalidRounding
+	}
+	if r.Increment == 0 {
+		return nil
+	}
+	switch r.Mode {
+	case RoundUp, RoundDown, RoundNearest:
+	default:
+		return errInvalidRounding
+	}
+	switch r.Per {
+	case PerRecord, PerDayProject, PerLine:
+	default:
+		return errInvalidRounding
+	}
+	return nil
+}
+
+// Round rounds a duration in seconds to the policy's increment.
+func (r Rounding) Round(seconds int64) int64 {
+	if r.Increment <= 0 {
+		return seconds
+	}
+	rest := seconds % r.Increment
+	if rest == 0 {
+		return seconds
+	}
+	down := seconds - rest
+	switch r.Mode {
+	case RoundDown:
+		return down
+	case RoundNearest:
+		if 2*rest < r.Increment {
+			return down
+		}
+	}
+	return down + r.Increment
+}
+
+// Work is a unit of work to be rounded.
+type Work struct {
+	Seconds   int64
+	Day       string // local date the work started in, e.g. 2020-01-31
+	ProjectID uint64
+}
+
+// RoundAll returns the rounded durations of the given work in the same order.
+// When rounding per day and project, the durations of each group are summed
+// up and rounded and the difference to the exact sum is attributed to the
+// last entries of the group, so the rounded durations of a group always add
+// up to its rounded total.
+func (r Rounding) RoundAll(work []Work) []int64 {
+	rounded := make([]int64, len(work))
+	if r.Per != PerDayProject {
+		for i, w := range work {
+			rounded[i] = r.Round(w.Seconds)
+		}
+		return rounded
+	}
+
+	type group struct {
+		day       string
+		projectID uint64
+	}
+	sums := make(map[group]int64)
+	members := make(map[group][]int)
+	var order []group
+	for i, w := range work {
+		g := group{w.Day, w.ProjectID}
+		if _, ok := sums[g]; !ok {
+			order = append(order, g)
+		}
+		sums[g] += w.Seconds
+		members[g] = append(members[g], i)
+		rounded[i] = w.Seconds
+	}
+	for _, g := range order {
+		delta := r.Round(sums[g]) - sums[g]
+		idx := members[g]
+		if delta > 0 {
+			rounded[idx[len(idx)-1]] += delta
+			continue
+		}
+		// never round an entry below zero
+		for j := len(idx) - 1; j >= 0 && delta < 0; j-- {
+			d := -delta
+			if d > rounded[idx[j]] {
+				d = rounded[idx[j]]
+			}
+			rounded[idx[j]] -= d
+			delta += d
+		}
+	}
+	return rounded
+}
@@ -4,34 +4,75 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/fgrimme/time-tracker/time-tracker/api/server"
+	"github.com/fgrimme/time-tracker/time-tracker/config"
 	"github.com/fgrimme/time-tracker/time-tracker/database"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/fgrimme/time-tracker/time-tracker/purger"
 	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/fgrimme/time-tracker/time-tracker/tracing"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
+// rateLimitRetention is the time unused rate limit buckets are kept. Buckets
+// are full after it unless the rate is less than the burst per hour.
+const rateLimitRetention = time.Hour
+
 var (
 	version = "unkown" // version gets built into the binary, see Makefile
 
 	// provide the configuration via env parameters or arguments
 	httpAddr      = kingpin.Flag("http-addr", "address of HTTP server").Envar("HTTP_ADDR").Required().String()
+	grpcAddr      = kingpin.Flag("grpc-addr", "address of gRPC server, disabled if empty").Envar("GRPC_ADDR").String()
+	metricsAddr   = kingpin.Flag("metrics-addr", "address of the Prometheus metrics server, disabled if empty").Envar("METRICS_ADDR").String()
+	metricsDrain  = kingpin.Flag("metrics-drain", "time to wait on shutdown for a final scrape, longer than the scrape interval").Envar("METRICS_DRAIN").Default("20s").Duration()
 	serviceName   = kingpin.Flag("service", "service name").Envar("SERVICE").Default("time-record-service").String()
 	timeRecDBDSN  = kingpin.Flag("timerec-db-dsn", "time record db DSN").Envar("TIME_REC_DB_DSN").Required().String()
 	timeout       = kingpin.Flag("timeout", "timeout to handle incoming requests").Envar("REQ_TIMEOUT").Default("900ms").Duration()
 	shutdownDelay = kingpin.Flag("shutdown-delay", "shutdown delay in ms").Envar("SHUTDOWN_DELAY").Default("5000ms").Duration()
+	retention     = kingpin.Flag("trash-retention", "time deleted records are kept in the trash").Envar("TRASH_RETENTION").Default("720h").Duration()
+	purgeInterval = kingpin.Flag("purge-interval", "interval to purge records from the trash").Envar("PURGE_INTERVAL").Default("1h").Duration()
+	keyRetention  = kingpin.Flag("idempotency-retention", "time responses are kept for retries with the same idempotency key").Envar("IDEMPOTENCY_RETENTION").Default("24h").Duration()
+	hookInterval  = kingpin.Flag("webhook-interval", "interval to poll the webhook outbox").Envar("WEBHOOK_INTERVAL").Default("1s").Duration()
+	hookTimeout   = kingpin.Flag("webhook-timeout", "timeout to deliver a webhook").Envar("WEBHOOK_TIMEOUT").Default("10s").Duration()
+	traceExporter = kingpin.Flag("trace-exporter", "exporter of OpenTelemetry traces").Envar("TRACE_EXPORTER").Default(tracing.None).Enum(tracing.Exporters...)
+	traceFile     = kingpin.Flag("trace-file", "file spans are appended to by the file exporter").Envar("TRACE_FILE").Default("traces.json").String()
+	traceZipkin   = kingpin.Flag("trace-zipkin-url", "span endpoint of the Zipkin collector").Envar("TRACE_ZIPKIN_URL").Default("http://localhost:9411/api/v2/spans").String()
+	traceRatio    = kingpin.Flag("trace-sample-ratio", "ratio of traces sampled, traces of callers follow their sampling decision").Envar("TRACE_SAMPLE_RATIO").Default("1").Float64()
+	limitStore    = kingpin.Flag("rate-limit-store", "store of rate limits, postgres shares the limits between replicas").Envar("RATE_LIMIT_STORE").Default("memory").Enum("memory", "postgres")
+	eventBroker   = kingpin.Flag("event-broker", "broker of live events, postgres distributes events between replicas").Envar("EVENT_BROKER").Default("memory").Enum("memory", "postgres")
 )
 
 func main() {
 	kingpin.Version(version)
+	// the configuration file is layered under the flags and env parameters
+	src, err := config.Open(kingpin.CommandLine, os.Args[1:])
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
 	kingpin.Parse()
+	settings, err := src.Settings()
+	if err == nil {
+		err = check()
+	}
+	if err != nil {
+		kingpin.Fatalf("%v", err)
+	}
+	zerolog.SetGlobalLevel(settings.LogLevel)
+	live := config.NewLive(settings)
 
-	// we use the default log level debug and write to stderr.
+	// we use the log level of the settings, debug by default, and write to stderr.
 	// note, we log in (inefficient) human friendly format to console here since it
 	// is a coding challenge. in a production environment we would prefer structured,
 	// machine parsable format so we could make use of automated log analysis.
@@ -45,6 +86,23 @@ func main() {
 		Interface("version", version).
 		Logger()
 
+	if src.Path() != "" {
+		logger.Info().Msgf("loaded configuration from %s", src.Path())
+	}
+
+	tracer, err := tracing.Setup(tracing.Config{
+		Service:     *serviceName,
+		Version:     version,
+		Exporter:    *traceExporter,
+		File:        *traceFile,
+		ZipkinURL:   *traceZipkin,
+		SampleRatio: *traceRatio,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *serviceName, err)
+		os.Exit(1)
+	}
+
 	// connect to databases
 	ds, err := database.Connect("postgres", *timeRecDBDSN, "time_record_db", *timeout)
 	if err != nil {
@@ -59,11 +117,50 @@ func main() {
 
 	// we use dependency injection throughout the whole application to either create
 	// working instances or fail early on instantiation
-	httpSrv, err := server.New(*httpAddr, *timeout, store.New(ds), logger)
+	var broker events.Broker = events.NewHub()
+	if *eventBroker == "postgres" {
+		if broker, err = events.NewPostgres(ds, *timeRecDBDSN, logger); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *serviceName, err)
+			os.Exit(1)
+		}
+	}
+	ts := store.New(ds)
+	ts.PublishTo(broker)
+	var reg prometheus.Registerer
+	var metricsSrv *server.MetricsServer
+	if *metricsAddr != "" {
+		r := prometheus.NewRegistry()
+		r.MustRegister(prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+		if err := ts.RegisterMetrics(r); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *serviceName, err)
+			os.Exit(1)
+		}
+		metricsSrv = server.NewMetricsServer(*metricsAddr, r, logger)
+		reg = r
+	}
+	var limiter middleware.Limiter
+	var limitPurger *purger.Purger
+	if *limitStore == "postgres" {
+		limiter = ts
+		limitPurger = purger.New(purger.PurgeFunc(ts.PurgeRateLimits), rateLimitRetention, *purgeInterval, logger.With().Str("purge", "rate_limits").Logger())
+	}
+	httpSrv, err := server.New(*httpAddr, *timeout, ts, broker, live, limiter, reg, logger)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %v\n", *serviceName, err)
 		os.Exit(1)
 	}
+	var grpcSrv *server.GRPCServer
+	if *grpcAddr != "" {
+		grpcSrv = server.NewGRPC(*grpcAddr, *timeout, ts, logger)
+	}
+	trashPurger := purger.New(ts, *retention, *purgeInterval, logger.With().Str("purge", "trash").Logger())
+	keyPurger := purger.New(purger.PurgeFunc(ts.PurgeKeys), *keyRetention, *purgeInterval, logger.With().Str("purge", "idempotency_keys").Logger())
+	// without a timeout deliveries may outlast the lease of their messages
+	if *hookTimeout <= 0 {
+		fmt.Fprintf(os.Stderr, "%s: webhook timeout must be positive, got %s\n", *serviceName, *hookTimeout)
+		os.Exit(1)
+	}
+	dispatcher := webhook.New(ts, &http.Client{Timeout: *hookTimeout}, *hookInterval, logger)
 
 	// run and handle shutdown gracefully
 	ctx, cancel := context.WithCancel(context.Background())
@@ -75,8 +172,29 @@ func main() {
 		<-quit
 		cancel()
 	}()
+	if src.Path() != "" {
+		go func() {
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			for range hup {
+				reload(src, live, logger)
+			}
+		}()
+	}
 
+	if metricsSrv != nil {
+		go metricsSrv.Run()
+	}
 	go httpSrv.Run()
+	if grpcSrv != nil {
+		go grpcSrv.Run()
+	}
+	go trashPurger.Run()
+	go keyPurger.Run()
+	if limitPurger != nil {
+		go limitPurger.Run()
+	}
+	go dispatcher.Run()
 
 	<-ctx.Done()
 
@@ -87,10 +205,66 @@ func main() {
 	defer cancel()
 
 	// when shutting down, we first gracefully shutting down the main http
-	// server, waiting for it to finish processing all the running requests.
+	// and grpc servers, waiting for them to finish processing all the running
+	// requests.
 	httpSrv.Shutdown(ctx)
-	// here we would also shut down the metrics server so we would set the
-	// shutdown timeout to something higher than the prometheus scrape interval.
-	// we would use a counter to check if a scrape happened to shutdown
-	// asap though
+	if grpcSrv != nil {
+		grpcSrv.Shutdown(ctx)
+	}
+	// background workers are stopped after the http server so requests in
+	// flight are not affected.
+	trashPurger.Shutdown(ctx)
+	keyPurger.Shutdown(ctx)
+	if limitPurger != nil {
+		limitPurger.Shutdown(ctx)
+	}
+	dispatcher.Shutdown(ctx)
+	// spans of the drained requests are exported before the exit
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Error().Err(err).Msg("trace export shutdown error")
+	}
+	// the metrics server is shut down last, after a final scrape collected
+	// the metrics of the requests drained above. it gets its own timeout
+	// which is longer than the prometheus scrape interval.
+	if metricsSrv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), *metricsDrain)
+		defer cancel()
+		metricsSrv.Shutdown(ctx)
+	}
+}
+
+// check validates the configuration beyond the types of the flags.
+func check() error {
+	for name, d := range map[string]time.Duration{
+		"timeout":               *timeout,
+		"trash-retention":       *retention,
+		"purge-interval":        *purgeInterval,
+		"idempotency-retention": *keyRetention,
+		"webhook-interval":      *hookInterval,
+		"webhook-timeout":       *hookTimeout,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("--%s must be positive, got %s", name, d)
+		}
+	}
+	if *traceRatio < 0 || *traceRatio > 1 {
+		return fmt.Errorf("--trace-sample-ratio must be between 0 and 1, got %g", *traceRatio)
+	}
+	return nil
+}
+
+// reload applies the settings of the configuration file. Invalid files are
+// rejected and the current settings are kept.
+func reload(src *config.Source, live *config.Live, logger zerolog.Logger) {
+	settings, restart, err := src.Reload()
+	if err != nil {
+		logger.Error().Err(err).Msg("rejected configuration, keeping the current one")
+		return
+	}
+	live.Store(settings)
+	zerolog.SetGlobalLevel(settings.LogLevel)
+	for _, name := range restart {
+		logger.Warn().Msgf("changes of %s take effect after a restart", name)
+	}
+	logger.Info().Msgf("reloaded configuration from %s", src.Path())
 }
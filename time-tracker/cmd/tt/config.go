@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// errNoConfig is returned if the user did not log in yet.
+var errNoConfig = errors.New("not logged in, run tt login first")
+
+// config holds the credentials of the user, stored in the XDG config dir.
+type config struct {
+	URL    string `json:"url"`     // base URL of the API
+	UserID uint64 `json:"user_id"` // user to track time for
+}
+
+// xdgDir returns the directory of tt in the XDG base directory of the
+// environment variable, or in the default relative to the home directory.
+func xdgDir(env, def string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "tt"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, def, "tt"), nil
+}
+
+// configPath returns the path of the config file.
+func configPath() (string, error) {
+	dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+func loadConfig(path string) (config, error) {
+	var c config
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, errNoConfig
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(data, &c)
+}
+
+// save writes the config readable only by the user.
+func (c config) save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces the file at path atomically, so it is never left half
+// written.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
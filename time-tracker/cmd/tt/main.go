@@ -0,0 +1,291 @@
+// Command tt tracks time from the terminal.
+//
+//	tt login http://localhost:8080 --user 42
+//	tt start "code review"
+//	tt status
+//	tt stop
+//	tt report --week
+//
+// Starts and stops issued while the API is not reachable are queued in the
+// XDG data dir and synced before the next command, or with tt sync.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api/client"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	version = "unkown" // version gets built into the binary, see Makefile
+
+	app     = kingpin.New("tt", "Track your time from the terminal.")
+	timeout = app.Flag("timeout", "timeout of a command").Envar("TT_TIMEOUT").Default("10s").Duration()
+
+	loginCmd  = app.Command("login", "Store the API and the user to track time for.")
+	loginURL  = loginCmd.Arg("url", "base URL of the API").Required().String()
+	loginUser = loginCmd.Flag("user", "id of the user").Required().Uint64()
+
+	startCmd      = app.Command("start", "Start a timer.")
+	startName     = startCmd.Arg("name", "what you are working on").String()
+	startProject  = startCmd.Flag("project", "id of the project").Uint64()
+	startBillable = startCmd.Flag("billable", "time is invoiced to the client").Bool()
+
+	stopCmd   = app.Command("stop", "Stop the running timer.")
+	statusCmd = app.Command("status", "Show the running timer.")
+	syncCmd   = app.Command("sync", "Sync the starts and stops queued while offline.")
+
+	reportCmd   = app.Command("report", "Show the records of today, this week or this month.")
+	reportWeek  = reportCmd.Flag("week", "report this week").Bool()
+	reportMonth = reportCmd.Flag("month", "report this month").Bool()
+)
+
+func main() {
+	app.Version(version)
+	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := run(ctx, cmd, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "tt: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, cmd string, out io.Writer) error {
+	cfgPath, err := configPath()
+	if err != nil {
+		return err
+	}
+	if cmd == loginCmd.FullCommand() {
+		return login(cfgPath, *loginURL, *loginUser, out)
+	}
+
+	cfg, err := loadConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+	api, err := client.New(cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+	qPath, err := queuePath()
+	if err != nil {
+		return err
+	}
+	q, err := loadQueue(qPath)
+	if err != nil {
+		return err
+	}
+	t := &tt{
+		userID: cfg.UserID,
+		api:    api,
+		queue:  q,
+		zone:   localZone(),
+		format: localFormatter(),
+		now:    time.Now,
+		out:    out,
+	}
+	if err := t.sync(ctx, cmd == syncCmd.FullCommand()); err != nil && cmd == syncCmd.FullCommand() {
+		return err
+	}
+
+	switch cmd {
+	case startCmd.FullCommand():
+		return t.start(ctx, *startName, *startProject, *startBillable)
+	case stopCmd.FullCommand():
+		return t.stop(ctx)
+	case statusCmd.FullCommand():
+		return t.status(ctx)
+	case reportCmd.FullCommand():
+		period := client.Day
+		if *reportWeek {
+			period = client.Week
+		}
+		if *reportMonth {
+			period = client.Month
+		}
+		return t.report(ctx, period)
+	}
+	return nil
+}
+
+// login stores the credentials of the user.
+func login(path, url string, userID uint64, out io.Writer) error {
+	if _, err := client.New(url, nil); err != nil {
+		return err
+	}
+	if err := (config{URL: url, UserID: userID}).save(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "tracking time of user %d at %s\n", userID, url)
+	return nil
+}
+
+// tt runs the commands of a user.
+type tt struct {
+	userID uint64
+	api    *client.Client
+	queue  *queue
+	zone   string           // tz-database name of the local zone
+	format locale.Formatter // formats dates and times in the local locale
+	now    func() time.Time
+	out    io.Writer
+}
+
+// sync syncs the queued commands. Commands rejected by the API are reported
+// and an unreachable API only if verbose is set.
+func (t *tt) sync(ctx context.Context, verbose bool) error {
+	if len(t.queue.commands) == 0 {
+		if verbose {
+			fmt.Fprintln(t.out, "nothing to sync")
+		}
+		return nil
+	}
+	synced, rejected, err := t.queue.sync(ctx, t.api, t.userID)
+	for _, r := range rejected {
+		fmt.Fprintf(t.out, "dropped queued %v\n", r)
+	}
+	if synced > 0 || verbose {
+		fmt.Fprintf(t.out, "synced %d queued commands, %d left\n", synced, len(t.queue.commands))
+	}
+	return err
+}
+
+// start starts a timer, or queues the start if the API is not reachable.
+func (t *tt) start(ctx context.Context, name string, projectID uint64, billable bool) error {
+	loc, err := time.LoadLocation(t.zone)
+	if err != nil {
+		return err
+	}
+	c := command{
+		Op:        opStart,
+		Name:      name,
+		ProjectID: projectID,
+		Billable:  billable,
+		Time:      t.now().In(loc).Truncate(time.Second),
+		Zone:      t.zone,
+	}
+	if len(t.queue.commands) == 0 {
+		_, err := t.api.StartTimer(ctx, client.Timer{
+			UserID:    t.userID,
+			Name:      c.Name,
+			Start:     c.Time,
+			StartLoc:  c.Zone,
+			ProjectID: c.ProjectID,
+			Billable:  c.Billable,
+		})
+		if err == nil || !offline(err) {
+			if client.ErrorCode(err) == "timer_running" {
+				return errors.New("a timer is running already, stop it first")
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(t.out, "started %q at %s\n", name, c.Time.Format("15:04"))
+			return nil
+		}
+	}
+	// commands are synced in order, so once one is queued all are
+	if err := t.queue.push(c); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "offline: queued start of %q at %s\n", name, c.Time.Format("15:04"))
+	return nil
+}
+
+// stop stops the running timer, or queues the stop if the API is not
+// reachable.
+func (t *tt) stop(ctx context.Context) error {
+	loc, err := time.LoadLocation(t.zone)
+	if err != nil {
+		return err
+	}
+	c := command{Op: opStop, Time: t.now().In(loc).Truncate(time.Second), Zone: t.zone}
+	if len(t.queue.commands) == 0 {
+		rec, err := t.api.StopTimer(ctx, t.userID, c.Time, c.Zone)
+		if err == nil || !offline(err) {
+			if client.ErrorCode(err) == "not_found" {
+				return errors.New("no timer is running")
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(t.out, "stopped %q after %s\n", rec.Name, formatDuration(rec.Duration))
+			return nil
+		}
+	}
+	if err := t.queue.push(c); err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "offline: queued stop at %s\n", c.Time.Format("15:04"))
+	return nil
+}
+
+// status shows the running timer. The timer is taken from the queue if
+// commands are waiting to be synced.
+func (t *tt) status(ctx context.Context) error {
+	if len(t.queue.commands) > 0 {
+		c, ok := t.queue.running()
+		if !ok {
+			fmt.Fprintf(t.out, "no timer running (offline, %d commands not synced)\n", len(t.queue.commands))
+			return nil
+		}
+		fmt.Fprintf(t.out, "%q running for %s since %s (offline, %d commands not synced)\n",
+			c.Name, formatDuration(t.now().Sub(c.Time)), c.Time.Format("15:04"), len(t.queue.commands))
+		return nil
+	}
+	timer, err := t.api.Timer(ctx, t.userID)
+	if client.ErrorCode(err) == "not_found" {
+		fmt.Fprintln(t.out, "no timer running")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "%q running for %s since %s\n",
+		timer.Name, formatDuration(t.now().Sub(timer.Start)), t.format.Day(timer.Start)+" "+t.format.ShortTime(timer.Start))
+	return nil
+}
+
+// report shows the records of the day, week or month in the local zone.
+func (t *tt) report(ctx context.Context, period string) error {
+	loc, err := time.LoadLocation(t.zone)
+	if err != nil {
+		return err
+	}
+	from, to := periodOf(t.now().In(loc), period)
+	recs, err := t.api.RecordsBetween(ctx, t.userID, from, to)
+	if err != nil {
+		if offline(err) {
+			return fmt.Errorf("reports need the API, which is not reachable: %v", err)
+		}
+		return err
+	}
+	fmt.Fprintf(t.out, "%s to %s\n\n", t.format.Date(from), t.format.Date(to.AddDate(0, 0, -1)))
+	return renderRecords(t.out, recs, t.format)
+}
+
+// periodOf returns the start and end of the day, ISO week or month
+// containing now.
+func periodOf(now time.Time, period string) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch period {
+	case client.Week:
+		// weeks start on Monday
+		monday := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return monday, monday.AddDate(0, 0, 7)
+	case client.Month:
+		first := day.AddDate(0, 0, 1-d)
+		return first, first.AddDate(0, 1, 0)
+	}
+	return day, day.AddDate(0, 0, 1)
+}
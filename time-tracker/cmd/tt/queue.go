@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api/client"
+)
+
+// Operations of queued commands.
+const (
+	opStart = "start"
+	opStop  = "stop"
+)
+
+// command is a start or stop of a timer issued while the API was not
+// reachable. The time is taken when the command is issued, so the timer
+// starts and stops at the right time when the command is synced later.
+type command struct {
+	Op        string    `json:"op"`
+	Name      string    `json:"name,omitempty"`
+	ProjectID uint64    `json:"project_id,omitempty"`
+	Billable  bool      `json:"billable,omitempty"`
+	Time      time.Time `json:"time"`
+	Zone      string    `json:"zone"`
+}
+
+// timerAPI is the part of the API commands are synced with.
+type timerAPI interface {
+	StartTimer(ctx context.Context, t client.Timer) (*client.Timer, error)
+	StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*client.Record, error)
+}
+
+// queue holds the commands waiting to be synced, oldest first. It is stored
+// in the XDG data dir.
+type queue struct {
+	path     string
+	commands []command
+}
+
+// queuePath returns the path of the queue file.
+func queuePath() (string, error) {
+	dir, err := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "queue.json"), nil
+}
+
+func loadQueue(path string) (*queue, error) {
+	q := &queue{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.commands); err != nil {
+		return nil, fmt.Errorf("reading queue %s: %v", path, err)
+	}
+	return q, nil
+}
+
+// push appends a command to the queue.
+func (q *queue) push(c command) error {
+	q.commands = append(q.commands, c)
+	return q.save()
+}
+
+func (q *queue) save() error {
+	if len(q.commands) == 0 {
+		if err := os.Remove(q.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(q.commands, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(q.path, data)
+}
+
+// sync sends the queued commands of the user in order. It stops at the first
+// command the API is not reachable for and returns its error. Commands the
+// API rejects, like a start while a timer is running, can never succeed and
+// are dropped, their errors are returned as rejected.
+func (q *queue) sync(ctx context.Context, api timerAPI, userID uint64) (synced int, rejected []error, err error) {
+	defer func() {
+		if saveErr := q.save(); err == nil {
+			err = saveErr
+		}
+	}()
+	for len(q.commands) > 0 {
+		c := q.commands[0]
+		var cerr error
+		switch c.Op {
+		case opStart:
+			_, cerr = api.StartTimer(ctx, client.Timer{
+				UserID:    userID,
+				Name:      c.Name,
+				Start:     c.Time,
+				StartLoc:  c.Zone,
+				ProjectID: c.ProjectID,
+				Billable:  c.Billable,
+			})
+		case opStop:
+			_, cerr = api.StopTimer(ctx, userID, c.Time, c.Zone)
+		default:
+			rejected = append(rejected, fmt.Errorf("unknown operation %q", c.Op))
+			q.commands = q.commands[1:]
+			continue
+		}
+		if cerr != nil && offline(cerr) {
+			return synced, rejected, cerr
+		}
+		if cerr != nil {
+			rejected = append(rejected, fmt.Errorf("%s at %s: %v", c.Op, c.Time.Format("Mon 02 Jan 15:04"), cerr))
+		} else {
+			synced++
+		}
+		q.commands = q.commands[1:]
+	}
+	return synced, rejected, nil
+}
+
+// running returns the timer started by the queued commands, if the last of
+// them is a start.
+func (q *queue) running() (command, bool) {
+	if n := len(q.commands); n > 0 && q.commands[n-1].Op == opStart {
+		return q.commands[n-1], true
+	}
+	return command{}, false
+}
+
+// offline reports whether a request failed because the API is not reachable,
+// temporarily failing, timed out or rate limited, so it may succeed later.
+func offline(err error) bool {
+	code := client.StatusCode(err)
+	return code == 0 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
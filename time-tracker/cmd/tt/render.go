@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api/client"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+)
+
+// localFormatter returns the formatter of the locale of the environment, the
+// first set of LC_ALL, LC_TIME and LANG, e.g. de_DE.UTF-8. English is assumed
+// if the locale is not supported.
+func localFormatter() locale.Formatter {
+	for _, env := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if name := os.Getenv(env); name != "" {
+			return locale.Negotiate(locale.Preferences{Locale: name}, "")
+		}
+	}
+	return locale.Formatter{}
+}
+
+// renderRecords writes the records as table, oldest first, followed by the
+// total and billable durations. Times are shown in the location of the
+// record, dates and times are formatted by f.
+func renderRecords(w io.Writer, recs []client.Record, f locale.Formatter) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tSTART\tSTOP\tDURATION\tPROJECT\tBILLABLE\tNAME")
+	var total, billable time.Duration
+	for i := len(recs) - 1; i >= 0; i-- {
+		r := recs[i]
+		project := "-"
+		if r.ProjectID != 0 {
+			project = fmt.Sprint(r.ProjectID)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			f.Day(r.Start),
+			f.ShortTime(r.Start),
+			f.ShortTime(r.Stop),
+			formatDuration(r.Duration),
+			project,
+			yesNo(r.Billable),
+			r.Name,
+		)
+		total += r.Duration
+		if r.Billable {
+			billable += r.Duration
+		}
+	}
+	fmt.Fprintf(tw, "\t\t\t\t\t\t\n")
+	fmt.Fprintf(tw, "TOTAL\t\t\t%s\t\t\t%d records\n", formatDuration(total), len(recs))
+	fmt.Fprintf(tw, "BILLABLE\t\t\t%s\t\t\t\n", formatDuration(billable))
+	return tw.Flush()
+}
+
+// formatDuration formats d in hours and minutes, e.g. 1:05.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%d:%02d", d/time.Hour, d%time.Hour/time.Minute)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
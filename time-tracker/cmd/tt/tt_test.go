@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api"
+	"github.com/fgrimme/time-tracker/time-tracker/api/client"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+)
+
+// mockTimerAPI fails with the status queued for the call, 0 meaning the API
+// is not reachable.
+type mockTimerAPI struct {
+	status []int
+	calls  []string
+}
+
+func (m *mockTimerAPI) call(op string) error {
+	m.calls = append(m.calls, op)
+	s := m.status[0]
+	m.status = m.status[1:]
+	switch {
+	case s == 0:
+		return context.DeadlineExceeded
+	case s >= 300:
+		return &api.Error{Err: "failed", Response: &http.Response{StatusCode: s}}
+	}
+	return nil
+}
+
+func (m *mockTimerAPI) StartTimer(ctx context.Context, t client.Timer) (*client.Timer, error) {
+	return &t, m.call(opStart)
+}
+
+func (m *mockTimerAPI) StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (*client.Record, error) {
+	return &client.Record{}, m.call(opStop)
+}
+
+func TestQueueSync(t *testing.T) {
+	now := time.Date(2020, 2, 3, 9, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		d        string
+		ops      []string
+		status   []int
+		synced   int
+		rejected int
+		left     int
+		err      bool
+	}{
+		{d: "all synced", ops: []string{opStart, opStop}, status: []int{201, 200}, synced: 2},
+		{d: "offline", ops: []string{opStart, opStop}, status: []int{0}, left: 2, err: true},
+		{d: "offline after first", ops: []string{opStart, opStop}, status: []int{201, 503}, synced: 1, left: 1, err: true},
+		{d: "rate limited kept", ops: []string{opStart, opStop}, status: []int{201, 429}, synced: 1, left: 1, err: true},
+		{d: "timed out kept", ops: []string{opStart, opStop}, status: []int{408}, left: 2, err: true},
+		{d: "rejected dropped", ops: []string{opStart, opStop, opStart}, status: []int{409, 200, 201}, synced: 2, rejected: 1},
+		{d: "unknown op", ops: []string{"pause", opStart}, status: []int{201}, synced: 1, rejected: 1},
+	} {
+		dir, err := ioutil.TempDir("", "tt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "queue.json")
+
+		q := &queue{path: path}
+		for _, op := range tt.ops {
+			if err := q.push(command{Op: op, Time: now, Zone: "UTC"}); err != nil {
+				t.Fatalf("%s: %v", tt.d, err)
+			}
+		}
+		m := &mockTimerAPI{status: tt.status}
+		synced, rejected, err := q.sync(context.Background(), m, 1)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %v, got %v", tt.d, tt.err, err)
+		}
+		if synced != tt.synced || len(rejected) != tt.rejected {
+			t.Errorf("%s: expected %d synced and %d rejected, got %d and %v", tt.d, tt.synced, tt.rejected, synced, rejected)
+		}
+
+		// the queue left is stored
+		q, err = loadQueue(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.d, err)
+		}
+		if len(q.commands) != tt.left {
+			t.Errorf("%s: expected %d commands left, got %d", tt.d, tt.left, len(q.commands))
+		}
+		if _, err := os.Stat(path); tt.left == 0 && !os.IsNotExist(err) {
+			t.Errorf("%s: expected empty queue to be removed, got %v", tt.d, err)
+		}
+	}
+}
+
+func TestDetectZone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	zoneinfo := filepath.Join(dir, "zoneinfo", "America", "New_York")
+	if err := os.MkdirAll(filepath.Dir(zoneinfo), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(zoneinfo, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	localtime := filepath.Join(dir, "localtime")
+	if err := os.Symlink(zoneinfo, localtime); err != nil {
+		t.Fatal(err)
+	}
+	timezone := filepath.Join(dir, "timezone")
+	if err := ioutil.WriteFile(timezone, []byte("Asia/Tokyo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	for _, tt := range []struct {
+		d, tz, localtime, timezone, e string
+	}{
+		{d: "TZ", tz: "Europe/Berlin", localtime: localtime, timezone: timezone, e: "Europe/Berlin"},
+		{d: "TZ with colon", tz: ":Europe/Berlin", localtime: localtime, timezone: timezone, e: "Europe/Berlin"},
+		{d: "TZ path", tz: "/etc/localtime", localtime: localtime, timezone: timezone, e: "America/New_York"},
+		{d: "localtime", localtime: localtime, timezone: timezone, e: "America/New_York"},
+		{d: "timezone", localtime: missing, timezone: timezone, e: "Asia/Tokyo"},
+		{d: "unknown", tz: "Mars/Olympus", localtime: missing, timezone: missing, e: "UTC"},
+	} {
+		if zone := detectZone(tt.tz, tt.localtime, tt.timezone); zone != tt.e {
+			t.Errorf("%s: expected %s, got %s", tt.d, tt.e, zone)
+		}
+	}
+}
+
+func TestPeriodOf(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a Sunday
+	now := time.Date(2020, 3, 29, 18, 30, 0, 0, berlin)
+	for _, tt := range []struct {
+		period   string
+		from, to time.Time
+	}{
+		{client.Day, time.Date(2020, 3, 29, 0, 0, 0, 0, berlin), time.Date(2020, 3, 30, 0, 0, 0, 0, berlin)},
+		{client.Week, time.Date(2020, 3, 23, 0, 0, 0, 0, berlin), time.Date(2020, 3, 30, 0, 0, 0, 0, berlin)},
+		{client.Month, time.Date(2020, 3, 1, 0, 0, 0, 0, berlin), time.Date(2020, 4, 1, 0, 0, 0, 0, berlin)},
+	} {
+		from, to := periodOf(now, tt.period)
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%s: expected %s to %s, got %s to %s", tt.period, tt.from, tt.to, from, to)
+		}
+	}
+}
+
+func TestRenderRecords(t *testing.T) {
+	start := time.Date(2020, 3, 25, 14, 0, 0, 0, time.UTC)
+	recs := []client.Record{{Name: "review", Start: start, Stop: start.Add(90 * time.Minute), Duration: 90 * time.Minute}}
+	for _, tt := range []struct {
+		f    locale.Formatter
+		want string
+	}{
+		{locale.Formatter{}, "Wed 25 Mar  14:00  15:30  1:30"},
+		{locale.Negotiate(locale.Preferences{Locale: "de_DE.UTF-8"}, ""), "Mi 25.03.  14:00  15:30  1:30"},
+		{locale.Formatter{Clock: locale.Clock12}, "Wed 25 Mar  2:00 PM  3:30 PM  1:30"},
+	} {
+		var b bytes.Buffer
+		if err := renderRecords(&b, recs, tt.f); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(b.String(), tt.want) {
+			t.Errorf("%s: expected %q in\n%s", tt.f.Key(), tt.want, b.String())
+		}
+	}
+}
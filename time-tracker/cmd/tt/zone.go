@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localZone returns the tz-database name of the local time zone, which is
+// sent as location of starts and stops. It is taken from the TZ variable, the
+// /etc/localtime link or /etc/timezone, in that order, UTC is assumed if none
+// names a known zone.
+func localZone() string {
+	return detectZone(os.Getenv("TZ"), "/etc/localtime", "/etc/timezone")
+}
+
+func detectZone(tz, localtime, timezone string) string {
+	// TZ may be prefixed with a colon, see tzset(3)
+	if zone := strings.TrimPrefix(tz, ":"); knownZone(zone) {
+		return zone
+	}
+	// /etc/localtime links to the zone file, e.g. /usr/share/zoneinfo/Europe/Berlin
+	if target, err := filepath.EvalSymlinks(localtime); err == nil {
+		if i := strings.LastIndex(target, "zoneinfo/"); i >= 0 {
+			if zone := target[i+len("zoneinfo/"):]; knownZone(zone) {
+				return zone
+			}
+		}
+	}
+	if data, err := ioutil.ReadFile(timezone); err == nil {
+		if zone := strings.TrimSpace(string(data)); knownZone(zone) {
+			return zone
+		}
+	}
+	return "UTC"
+}
+
+// knownZone reports whether zone is the name of a zone of the tz database.
+// Paths to zone files and the pseudo zone Local are not.
+func knownZone(zone string) bool {
+	if zone == "" || zone == "Local" || filepath.IsAbs(zone) {
+		return false
+	}
+	_, err := time.LoadLocation(zone)
+	return err == nil
+}
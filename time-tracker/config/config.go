@@ -0,0 +1,300 @@
+// Package config layers a YAML or TOML configuration file under the flags and
+// environment variables of the service and reloads the settings which can
+// change at runtime.
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/middleware"
+	"github.com/rs/zerolog"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// Settings are the part of the configuration which is reloaded on SIGHUP.
+type Settings struct {
+	LogLevel         zerolog.Level
+	ValidateRequests bool // reject requests not conforming to the OpenAPI document
+	CORS             middleware.CORSPolicy
+	RateLimits       middleware.RateLimits
+}
+
+// Live holds the current settings. They are replaced as a whole, so readers
+// never see a mix of old and new settings.
+type Live struct {
+	v atomic.Value
+}
+
+// NewLive returns a Live holding s.
+func NewLive(s Settings) *Live {
+	l := &Live{}
+	l.Store(s)
+	return l
+}
+
+// Load returns the current settings.
+func (l *Live) Load() Settings {
+	return l.v.Load().(Settings)
+}
+
+// Store replaces the current settings.
+func (l *Live) Store(s Settings) {
+	l.v.Store(s)
+}
+
+// settingsFlags are the flags of the settings.
+type settingsFlags struct {
+	logLevel        *string
+	validate        *bool
+	corsOrigins     *[]string
+	corsMethods     *[]string
+	corsHeaders     *[]string
+	corsExpose      *[]string
+	corsCredentials *bool
+	corsMaxAge      *time.Duration
+	readRate        *float64
+	readBurst       *int
+	writeRate       *float64
+	writeBurst      *int
+	ipHeader        *string
+}
+
+// registerSettings registers the flags of the settings with app. Flags with
+// lists of values take comma separated values.
+func registerSettings(app *kingpin.Application) *settingsFlags {
+	return &settingsFlags{
+		logLevel:        app.Flag("log-level", "minimum level of log messages").Envar("LOG_LEVEL").Default("debug").Enum("debug", "info", "warn", "error"),
+		validate:        app.Flag("validate-requests", "reject requests not conforming to the OpenAPI document").Envar("VALIDATE_REQUESTS").Bool(),
+		corsOrigins:     app.Flag("cors-allowed-origins", "origins allowed to make cross-origin requests, * matches any part of an origin").Envar("CORS_ALLOWED_ORIGINS").Default("*").Strings(),
+		corsMethods:     app.Flag("cors-allowed-methods", "methods allowed in cross-origin requests").Envar("CORS_ALLOWED_METHODS").Default("GET,POST,PUT,DELETE").Strings(),
+		corsHeaders:     app.Flag("cors-allowed-headers", "request headers allowed in cross-origin requests").Envar("CORS_ALLOWED_HEADERS").Default("Content-Type,Authorization,Idempotency-Key,Actor-Id,traceparent,tracestate").Strings(),
+		corsExpose:      app.Flag("cors-exposed-headers", "response headers exposed to cross-origin requests").Envar("CORS_EXPOSED_HEADERS").Default("Request-Id,Idempotent-Replayed").Strings(),
+		corsCredentials: app.Flag("cors-allow-credentials", "allow cross-origin requests with credentials, not allowed for all origins").Envar("CORS_ALLOW_CREDENTIALS").Bool(),
+		corsMaxAge:      app.Flag("cors-max-age", "time browsers may cache the answer to a preflight").Envar("CORS_MAX_AGE").Default("10m").Duration(),
+		readRate:        app.Flag("rate-limit-read", "GET requests per second of a client, 0 disables the limit").Envar("RATE_LIMIT_READ").Default("20").Float64(),
+		readBurst:       app.Flag("rate-limit-read-burst", "GET requests a client may make at once").Envar("RATE_LIMIT_READ_BURST").Default("40").Int(),
+		writeRate:       app.Flag("rate-limit-write", "POST, PUT and DELETE requests per second of a client, 0 disables the limit").Envar("RATE_LIMIT_WRITE").Default("5").Float64(),
+		writeBurst:      app.Flag("rate-limit-write-burst", "POST, PUT and DELETE requests a client may make at once").Envar("RATE_LIMIT_WRITE_BURST").Default("20").Int(),
+		ipHeader:        app.Flag("rate-limit-ip-header", "header holding the client IP set by a proxy, e.g. X-Real-IP, the last address of the header is used, the remote address if empty").Envar("RATE_LIMIT_IP_HEADER").String(),
+	}
+}
+
+func (f *settingsFlags) settings() (Settings, error) {
+	level, err := zerolog.ParseLevel(*f.logLevel)
+	if err != nil {
+		return Settings{}, err
+	}
+	cors := middleware.CORSPolicy{
+		AllowedOrigins:   split(*f.corsOrigins),
+		AllowedMethods:   split(*f.corsMethods),
+		AllowedHeaders:   split(*f.corsHeaders),
+		ExposedHeaders:   split(*f.corsExpose),
+		AllowCredentials: *f.corsCredentials,
+		MaxAge:           *f.corsMaxAge,
+	}
+	if err := cors.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid CORS policy: %v", err)
+	}
+	limits := middleware.RateLimits{
+		Read:     middleware.RateLimit{Rate: *f.readRate, Burst: *f.readBurst},
+		Write:    middleware.RateLimit{Rate: *f.writeRate, Burst: *f.writeBurst},
+		IPHeader: *f.ipHeader,
+	}
+	if err := limits.Read.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid read rate limit: %v", err)
+	}
+	if err := limits.Write.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid write rate limit: %v", err)
+	}
+	return Settings{LogLevel: level, ValidateRequests: *f.validate, CORS: cors, RateLimits: limits}, nil
+}
+
+// split splits comma separated values.
+func split(values []string) []string {
+	var s []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				s = append(s, item)
+			}
+		}
+	}
+	return s
+}
+
+// Source is the configuration file of an application. The keys of the file
+// are the names of the flags, nested keys are joined by a dash, so
+//
+//	trace:
+//	  exporter: zipkin
+//
+// sets --trace-exporter. Lists set flags which can be repeated. Flags set on
+// the command line or by their environment variable take precedence over the
+// file.
+type Source struct {
+	app      *kingpin.Application
+	settings *settingsFlags
+	path     string
+	values   map[string][]string // values of the file at startup
+	args     map[string][]string // values of the flags set on the command line
+}
+
+// Open registers the --config flag and the flags of the settings with app,
+// reads the file given by args or CONFIG_FILE and sets its values as defaults
+// of the flags. It must be called before app is parsed.
+func Open(app *kingpin.Application, args []string) (*Source, error) {
+	file := app.Flag("config", "YAML or TOML configuration file, overridden by flags and environment").Envar("CONFIG_FILE").String()
+	s := &Source{
+		app:      app,
+		settings: registerSettings(app),
+		args:     map[string][]string{},
+	}
+
+	// the values are set when app is parsed, the flags on the command line
+	// are picked up here to find the file. errors, like unknown flags, are
+	// reported by the parse.
+	if ctx, _ := app.ParseContext(args); ctx != nil {
+		for _, e := range ctx.Elements {
+			if f, ok := e.Clause.(*kingpin.FlagClause); ok && e.Value != nil {
+				s.args[f.Model().Name] = append(s.args[f.Model().Name], *e.Value)
+			}
+		}
+	}
+	if v := s.args["config"]; len(v) > 0 {
+		s.path = v[len(v)-1]
+	} else {
+		s.path = os.Getenv("CONFIG_FILE")
+	}
+	if s.path == "" {
+		return s, nil
+	}
+	*file = s.path
+
+	values, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	s.values = values
+	// defaults of required flags are accepted since the flags have been
+	// checked by ParseContext already. a required flag missing in the file is
+	// reported by the parse.
+	for name, v := range values {
+		app.GetFlag(name).Default(v...)
+	}
+	return s, nil
+}
+
+// Path returns the path of the configuration file, empty if there is none.
+func (s *Source) Path() string {
+	return s.path
+}
+
+// Settings returns the settings the application was started with. It must be
+// called after app is parsed.
+func (s *Source) Settings() (Settings, error) {
+	return s.settings.settings()
+}
+
+// Reload reads the file again and returns the settings, which are validated
+// like on startup. Flags set on the command line or by the environment keep
+// their values. Changes of other flags are not applied, their names are
+// returned as restart.
+func (s *Source) Reload() (_ Settings, restart []string, err error) {
+	if s.path == "" {
+		return Settings{}, nil, fmt.Errorf("no configuration file")
+	}
+	values, err := s.read()
+	if err != nil {
+		return Settings{}, nil, err
+	}
+
+	app := kingpin.New("reload", "")
+	settings := registerSettings(app)
+	reloaded := map[string]bool{}
+	for _, f := range app.Model().Flags {
+		reloaded[f.Name] = true
+		flag := app.GetFlag(f.Name)
+		if v, ok := s.args[f.Name]; ok {
+			// the command line overrides the environment
+			flag.NoEnvar().Default(v...)
+		} else if v, ok := values[f.Name]; ok {
+			flag.Default(v...)
+		}
+	}
+	if _, err := app.Parse(nil); err != nil {
+		return Settings{}, nil, err
+	}
+	reload, err := settings.settings()
+	if err != nil {
+		return Settings{}, nil, err
+	}
+
+	for name := range union(s.values, values) {
+		if !reloaded[name] && !s.explicit(name) && !equal(s.values[name], values[name]) {
+			restart = append(restart, name)
+		}
+	}
+	sort.Strings(restart)
+	return reload, restart, nil
+}
+
+// read reads the file and checks that its keys are flags of the application.
+func (s *Source) read() (map[string][]string, error) {
+	values, err := Load(s.path)
+	if err != nil {
+		return nil, err
+	}
+	for name, v := range values {
+		flag := s.app.GetFlag(name)
+		if name == "config" || flag == nil {
+			return nil, fmt.Errorf("%s: unknown key %q", s.path, name)
+		}
+		if r, ok := flag.Model().Value.(repeatable); len(v) > 1 && (!ok || !r.IsCumulative()) {
+			return nil, fmt.Errorf("%s: key %q takes a single value", s.path, name)
+		}
+	}
+	return values, nil
+}
+
+// repeatable is implemented by the values of flags which can be repeated.
+type repeatable interface {
+	IsCumulative() bool
+}
+
+// explicit reports whether the flag is set on the command line or by its
+// environment variable.
+func (s *Source) explicit(name string) bool {
+	if _, ok := s.args[name]; ok {
+		return true
+	}
+	flag := s.app.GetFlag(name)
+	return flag != nil && flag.Model().Envar != "" && os.Getenv(flag.Model().Envar) != ""
+}
+
+func union(a, b map[string][]string) map[string]bool {
+	u := map[string]bool{}
+	for k := range a {
+		u[k] = true
+	}
+	for k := range b {
+		u[k] = true
+	}
+	return u
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// testApp returns an application with flags like the service's.
+func testApp() (*kingpin.Application, *string, *string, *[]string) {
+	app := kingpin.New("test", "")
+	addr := app.Flag("http-addr", "").Envar("TEST_HTTP_ADDR").Required().String()
+	exporter := app.Flag("trace-exporter", "").Default("none").Enum("none", "zipkin")
+	origins := app.Flag("origin", "").Strings()
+	return app, addr, exporter, origins
+}
+
+// writeFile writes a file to dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := map[string][]string{
+		"http-addr":          {":8080"},
+		"timeout":            {"900ms"},
+		"validate-requests":  {"true"},
+		"trace-exporter":     {"zipkin"},
+		"trace-sample-ratio": {"0.5"},
+		"origin":             {"https://a.example", "https://b.example"},
+	}
+	tests := []struct {
+		d    string // description of test case
+		name string // name of the file
+		c    string // content of the file
+	}{
+		{
+			d:    "yaml",
+			name: "config.yaml",
+			c: `http-addr: ":8080"
+timeout: 900ms
+validate-requests: true
+trace:
+  exporter: zipkin
+  sample-ratio: 0.5
+origin: [https://a.example, https://b.example]
+`,
+		},
+		{
+			d:    "toml",
+			name: "config.toml",
+			c: `http-addr = ":8080"
+timeout = "900ms"
+validate-requests = true
+origin = ["https://a.example", "https://b.example"]
+
+[trace]
+exporter = "zipkin"
+sample-ratio = 0.5
+`,
+		},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			values, err := Load(writeFile(t, dir, tt.name, tt.c))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(want, values) {
+				t.Errorf("want %v got %v", want, values)
+			}
+		})
+	}
+
+	for _, name := range []string{"config.json", "invalid.yaml"} {
+		if _, err := Load(writeFile(t, dir, name, "{")); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.yaml", `http-addr: ":8080"
+log-level: info
+trace:
+  exporter: zipkin
+origin:
+- https://a.example
+- https://b.example
+`)
+
+	tests := []struct {
+		d        string   // description of test case
+		args     []string // command line
+		env      string   // value of TEST_HTTP_ADDR
+		addr     string   // expected address
+		exporter string   // expected exporter
+		level    zerolog.Level
+	}{
+		{d: "file", args: []string{"--config", path}, addr: ":8080", exporter: "zipkin", level: zerolog.InfoLevel},
+		{d: "flags override the file", args: []string{"--config=" + path, "--http-addr=:9090", "--trace-exporter=none", "--log-level=warn"}, addr: ":9090", exporter: "none", level: zerolog.WarnLevel},
+		{d: "env overrides the file", args: []string{"--config", path}, env: ":7070", addr: ":7070", exporter: "zipkin", level: zerolog.InfoLevel},
+		{d: "flags override env", args: []string{"--config", path, "--http-addr", ":9090"}, env: ":7070", addr: ":9090", exporter: "zipkin", level: zerolog.InfoLevel},
+		{d: "no file", args: []string{"--http-addr", ":9090"}, addr: ":9090", exporter: "none", level: zerolog.DebugLevel},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			os.Setenv("TEST_HTTP_ADDR", tt.env)
+			defer os.Unsetenv("TEST_HTTP_ADDR")
+
+			app, addr, exporter, origins := testApp()
+			src, err := Open(app, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := app.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			settings, err := src.Settings()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w, g := tt.addr, *addr; w != g {
+				t.Errorf("want address %s got %s", w, g)
+			}
+			if w, g := tt.exporter, *exporter; w != g {
+				t.Errorf("want exporter %s got %s", w, g)
+			}
+			if w, g := tt.level, settings.LogLevel; w != g {
+				t.Errorf("want log level %s got %s", w, g)
+			}
+			if src.Path() != "" && len(*origins) != 2 {
+				t.Errorf("want 2 origins got %v", *origins)
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		d string // description of test case
+		c string // content of the file
+	}{
+		{d: "unknown key", c: "http-adr: \":8080\"\n"},
+		{d: "list of a single value", c: "http-addr: [\":8080\", \":9090\"]\n"},
+		{d: "config file", c: "config: other.yaml\n"},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			path := writeFile(t, dir, "config.yaml", tt.c)
+			app, _, _, _ := testApp()
+			if _, err := Open(app, []string{"--config", path}); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+
+	path := writeFile(t, dir, "config.yaml", "log-level: verbose\nhttp-addr: \":8080\"\n")
+	app, _, _, _ := testApp()
+	if _, err := Open(app, []string{"--config", path}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Parse([]string{"--config", path}); err == nil {
+		t.Error("invalid log level: want error")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.yml", "http-addr: \":8080\"\nlog-level: info\n")
+
+	app, _, _, _ := testApp()
+	args := []string{"--config", path, "--validate-requests"}
+	src, err := Open(app, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		d       string // description of test case
+		c       string // content of the file
+		level   zerolog.Level
+		origins []string // expected origins of the CORS policy
+		restart []string // expected flags which changed but are not reloaded
+		err     bool     // whether the reload is rejected
+	}{
+		{d: "log level", c: "http-addr: \":8080\"\nlog-level: error\n", level: zerolog.ErrorLevel, origins: []string{"*"}},
+		{d: "cors origins", c: "http-addr: \":8080\"\ncors:\n  allowed-origins: [https://a.example, \"https://*.b.example\"]\n", level: zerolog.DebugLevel, origins: []string{"https://a.example", "https://*.b.example"}},
+		{d: "comma separated cors origins", c: "http-addr: \":8080\"\ncors-allowed-origins: https://a.example, https://c.example\n", level: zerolog.DebugLevel, origins: []string{"https://a.example", "https://c.example"}},
+		{d: "default log level", c: "http-addr: \":8080\"\n", level: zerolog.DebugLevel, origins: []string{"*"}},
+		{d: "flags keep their value", c: "http-addr: \":8080\"\nvalidate-requests: false\n", level: zerolog.DebugLevel, origins: []string{"*"}},
+		{d: "restart required", c: "http-addr: \":9090\"\ntrace-exporter: zipkin\n", level: zerolog.DebugLevel, origins: []string{"*"}, restart: []string{"http-addr", "trace-exporter"}},
+		{d: "invalid log level", c: "log-level: verbose\n", err: true},
+		{d: "credentials for all origins", c: "cors-allow-credentials: true\n", err: true},
+		{d: "unknown key", c: "log-levl: info\n", err: true},
+		{d: "invalid file", c: "log-level: [", err: true},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			writeFile(t, dir, "config.yml", tt.c)
+			settings, restart, err := src.Reload()
+			if tt.err {
+				if err == nil {
+					t.Error("want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w, g := tt.level, settings.LogLevel; w != g {
+				t.Errorf("want log level %s got %s", w, g)
+			}
+			if !settings.ValidateRequests {
+				t.Error("want requests validated as set by flag")
+			}
+			if w, g := tt.origins, settings.CORS.AllowedOrigins; !reflect.DeepEqual(w, g) {
+				t.Errorf("want origins %v got %v", w, g)
+			}
+			if !reflect.DeepEqual(tt.restart, restart) {
+				t.Errorf("want restart %v got %v", tt.restart, restart)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Load reads a YAML or TOML file, chosen by its extension, and returns its
+// values by key. Keys of nested tables are joined by a dash and lists have a
+// value per item.
+func Load(path string) (map[string][]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("%s: unknown format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	values := map[string][]string{}
+	if err := flatten(values, "", doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return values, nil
+}
+
+// flatten adds the values of v to values, keyed by their path below key.
+func flatten(values map[string][]string, key string, v interface{}) error {
+	join := func(k string) string {
+		if key == "" {
+			return k
+		}
+		return key + "-" + k
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if err := flatten(values, join(k), item); err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		// yaml decodes nested mappings with keys of any type
+		for k, item := range v {
+			if err := flatten(values, join(fmt.Sprint(k)), item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			values[key] = []string{}
+		}
+		for _, item := range v {
+			if isTable(item) {
+				return fmt.Errorf("key %q: lists of tables are not supported", key)
+			}
+			if err := flatten(values, key, item); err != nil {
+				return err
+			}
+		}
+	case []map[string]interface{}:
+		return fmt.Errorf("key %q: lists of tables are not supported", key)
+	case nil:
+		return fmt.Errorf("key %q has no value", key)
+	default:
+		values[key] = append(values[key], fmt.Sprint(v))
+	}
+	return nil
+}
+
+func isTable(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
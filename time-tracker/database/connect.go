@@ -21,12 +21,16 @@ func New(db *sql.DB, name string, timeout time.Duration) *DB {
 	}
 }
 
-// Connect creates a connection to a database
+// Connect creates a connection to a database. Its statements are traced.
 func Connect(driverName, dsn, name string, reqTimeout time.Duration) (*DB, error) {
-	db, err := sql.Open(driverName, dsn)
+	// the registered driver is looked up by opening a database, which does
+	// not connect yet
+	opened, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(newTracedConnector(opened.Driver(), driverName, dsn))
+	opened.Close()
 	d := &DB{
 		db:   db,
 		name: name,
@@ -52,6 +56,13 @@ func (db *DB) RequestContext(ctx context.Context) (context.Context, context.Canc
 	return context.WithTimeout(ctx, db.reqTimeout)
 }
 
+// Ping checks that the database is reachable within the request timeout.
+func (db *DB) Ping(ctx context.Context) error {
+	ctx, cancel := db.RequestContext(ctx)
+	defer cancel()
+	return db.db.PingContext(ctx)
+}
+
 // Close closes a DB, and returns any error generated during closing the connection.
 func (db *DB) Close() error {
 	return db.db.Close()
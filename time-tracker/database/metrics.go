@@ -0,0 +1,62 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsCollector exports the connection pool statistics of a database.
+type statsCollector struct {
+	db *DB
+
+	openConns     *prometheus.Desc
+	inUseConns    *prometheus.Desc
+	idleConns     *prometheus.Desc
+	maxOpenConns  *prometheus.Desc
+	waitCount     *prometheus.Desc
+	waitDuration  *prometheus.Desc
+	maxIdleClosed *prometheus.Desc
+	lifeClosed    *prometheus.Desc
+}
+
+// Collector returns a collector of the connection pool statistics of the
+// database, see sql.DB.Stats. The metrics are labeled with the database name.
+func (db *DB) Collector() prometheus.Collector {
+	labels := prometheus.Labels{"db": db.name}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("db_"+name, help, nil, labels)
+	}
+	return &statsCollector{
+		db:            db,
+		openConns:     desc("open_connections", "Number of established connections, in use and idle."),
+		inUseConns:    desc("in_use_connections", "Number of connections in use."),
+		idleConns:     desc("idle_connections", "Number of idle connections."),
+		maxOpenConns:  desc("max_open_connections", "Maximum number of open connections, 0 if unlimited."),
+		waitCount:     desc("wait_count_total", "Number of connections waited for."),
+		waitDuration:  desc("wait_duration_seconds_total", "Time blocked waiting for a connection."),
+		maxIdleClosed: desc("max_idle_closed_total", "Number of connections closed due to the maximum of idle connections."),
+		lifeClosed:    desc("max_lifetime_closed_total", "Number of connections closed due to their maximum lifetime."),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.openConns
+	ch <- c.inUseConns
+	ch <- c.idleConns
+	ch <- c.maxOpenConns
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.lifeClosed
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.openConns, prometheus.GaugeValue, float64(s.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUseConns, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.maxOpenConns, prometheus.GaugeValue, float64(s.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(s.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, s.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(s.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.lifeClosed, prometheus.CounterValue, float64(s.MaxLifetimeClosed))
+}
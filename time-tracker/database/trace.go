@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/fgrimme/time-tracker/time-tracker/database")
+
+// tracedConnector opens connections which trace queries and statements as
+// spans with the SQL statement as attribute. The spans of queries end when
+// their rows are closed, so they include fetching the rows. The time waited
+// for a connection of the pool is not included, it is the gap between the
+// span of the caller and the span of the statement.
+type tracedConnector struct {
+	driver driver.Driver
+	dsn    string
+	system attribute.KeyValue
+}
+
+func newTracedConnector(d driver.Driver, driverName, dsn string) *tracedConnector {
+	system := semconv.DBSystemKey.String(driverName)
+	if driverName == "postgres" {
+		system = semconv.DBSystemPostgreSQL
+	}
+	return &tracedConnector{driver: d, dsn: dsn, system: system}
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, system: c.system}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// tracedConn traces the queries and statements executed on a connection.
+// Prepared statements are not traced.
+type tracedConn struct {
+	driver.Conn
+	system attribute.KeyValue
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.start(ctx, query)
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != nil {
+		end(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.start(ctx, query)
+	res, err := e.ExecContext(ctx, query, args)
+	end(span, err)
+	return res, err
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// start starts the span of a statement, named by its operation, e.g. SELECT.
+func (c *tracedConn) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := "sql"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		name = strings.ToUpper(fields[0])
+	}
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(c.system, semconv.DBStatementKey.String(strings.TrimSpace(query))))
+}
+
+// tracedRows ends the span of a query when the rows are closed.
+type tracedRows struct {
+	driver.Rows
+	span trace.Span
+	err  error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = err
+	}
+	end(r.span, r.err)
+	return err
+}
+
+// end ends a span, recording the error if it is not nil.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
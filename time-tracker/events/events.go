@@ -0,0 +1,130 @@
+// Package events publishes changes to time records and timers to the clients
+// of a user while they happen.
+//
+// The Hub distributes events within a single process. When running multiple
+// replicas, Postgres distributes events between them using LISTEN/NOTIFY, so
+// clients receive the changes made through any replica.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// bufferSize is the number of events buffered per subscription. Subscribers
+// which fall behind by more events are dropped.
+const bufferSize = 64
+
+// Event is a change of a user's time records or timer. The types are the
+// event types of webhooks, e.g. "record.created".
+type Event struct {
+	Type   string          `json:"event"`
+	UserID uint64          `json:"user_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Broker publishes events to the subscriptions of the event's user.
+type Broker interface {
+	Publisher
+	// Subscribe returns a subscription to the events of a user.
+	Subscribe(userID uint64) *Subscription
+	// Close ends all subscriptions.
+	Close()
+}
+
+// Subscription receives the events of a user. The channel is closed when the
+// subscription ends, because it was closed, the subscriber fell behind or the
+// broker was closed.
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	userID uint64
+	hub    *Hub
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub is a Broker distributing events to the subscriptions in this process.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[uint64]map[*Subscription]struct{}
+	closed bool
+}
+
+// NewHub returns a Hub without subscriptions.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[uint64]map[*Subscription]struct{})}
+}
+
+// Publish sends the event to the subscriptions of its user. It never blocks,
+// subscriptions which can not keep up are ended.
+func (h *Hub) Publish(ctx context.Context, e Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[e.UserID] {
+		select {
+		case s.c <- e:
+		default:
+			h.remove(s)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a subscription to the events of a user. The
+// subscription is ended right away if the hub is closed.
+func (h *Hub) Subscribe(userID uint64) *Subscription {
+	c := make(chan Event, bufferSize)
+	s := &Subscription{C: c, c: c, userID: userID, hub: h}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(c)
+		return s
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][s] = struct{}{}
+	return s
+}
+
+// Close ends all subscriptions. Later subscriptions end right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for s := range subs {
+			h.remove(s)
+		}
+	}
+	h.closed = true
+}
+
+func (h *Hub) unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+// remove ends a subscription if it has not ended yet. The lock must be held.
+func (h *Hub) remove(s *Subscription) {
+	subs := h.subs[s.userID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.userID)
+	}
+	close(s.c)
+}
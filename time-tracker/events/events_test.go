@@ -0,0 +1,63 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+)
+
+func TestHub(t *testing.T) {
+	ctx := context.Background()
+	h := events.NewHub()
+	a := h.Subscribe(1)
+	b := h.Subscribe(1)
+	other := h.Subscribe(2)
+
+	h.Publish(ctx, events.Event{Type: "record.created", UserID: 1})
+	for _, s := range []*events.Subscription{a, b} {
+		if e := <-s.C; e.Type != "record.created" {
+			t.Errorf("want record.created got %s", e.Type)
+		}
+	}
+	select {
+	case e := <-other.C:
+		t.Errorf("want no event for other user got %+v", e)
+	default:
+	}
+
+	// closed subscriptions do not receive events
+	a.Close()
+	a.Close() // closing twice is fine
+	h.Publish(ctx, events.Event{Type: "record.updated", UserID: 1})
+	if _, ok := <-a.C; ok {
+		t.Error("want closed subscription")
+	}
+	if e := <-b.C; e.Type != "record.updated" {
+		t.Errorf("want record.updated got %s", e.Type)
+	}
+
+	h.Close()
+	for _, s := range []*events.Subscription{b, other, h.Subscribe(3)} {
+		if _, ok := <-s.C; ok {
+			t.Error("want subscriptions ended by close")
+		}
+	}
+}
+
+func TestHubSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	h := events.NewHub()
+	s := h.Subscribe(1)
+	// publishing never blocks, the subscriber is dropped instead
+	for i := 0; i < 1000; i++ {
+		h.Publish(ctx, events.Event{UserID: 1})
+	}
+	n := 0
+	for range s.C {
+		n++
+	}
+	if n == 0 || n == 1000 {
+		t.Errorf("want buffered events only got %d", n)
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/database"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// channel is the Postgres notification channel events are published on.
+const channel = "time_tracker_events"
+
+// Postgres is a Broker distributing events between replicas with Postgres
+// LISTEN/NOTIFY. Events are published to the listeners of all replicas, which
+// distribute them to their subscriptions with a Hub. Events published while a
+// listener reconnects are lost, clients catch up with the change feed.
+type Postgres struct {
+	*Hub
+	db       *database.DB
+	listener *pq.Listener
+	logger   zerolog.Logger
+	done     chan struct{}
+}
+
+// NewPostgres returns a Broker which listens for events on a dedicated
+// connection to the database with the given DSN and publishes events with db.
+func NewPostgres(db *database.DB, dsn string, logger zerolog.Logger) (*Postgres, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Warn().Err(err).Msg("event listener connection")
+		}
+	})
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	p := &Postgres{
+		Hub:      NewHub(),
+		db:       db,
+		listener: listener,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+	go p.receive()
+	return p, nil
+}
+
+// Publish notifies the listeners of all replicas of the event. Notifications
+// are limited to 8000 bytes by Postgres.
+func (p *Postgres) Publish(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	db := p.db.GetDB()
+	ctx, cancel := p.db.RequestContext(ctx)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, string(b))
+	return err
+}
+
+// receive distributes notifications to the subscriptions until the listener
+// is closed.
+func (p *Postgres) receive() {
+	defer close(p.done)
+	for n := range p.listener.Notify {
+		if n == nil { // reconnected, notifications may have been lost
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+			p.logger.Error().Err(err).Msg("invalid event notification")
+			continue
+		}
+		p.Hub.Publish(context.Background(), e)
+	}
+}
+
+// Close stops listening and ends all subscriptions.
+func (p *Postgres) Close() {
+	if err := p.listener.Close(); err != nil {
+		p.logger.Warn().Err(err).Msg("closing event listener")
+	}
+	<-p.done
+	p.Hub.Close()
+}
@@ -0,0 +1,136 @@
+// Package health checks the dependencies of the service for readiness
+// probes.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Statuses of checks and reports.
+const (
+	StatusOK           = "ok"
+	StatusFailing      = "failing"
+	StatusShuttingDown = "shutting_down" // reported by the service, not by checks
+)
+
+// Check returns an error if a dependency is not healthy. It must return when
+// ctx is done.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a check.
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of all checks. It is ok if all checks are ok.
+type Report struct {
+	Status  string    `json:"status"`
+	Checked time.Time `json:"checked_at"`
+	Checks  []Result  `json:"checks"`
+}
+
+// OK reports whether the service is ready.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered checks concurrently. The report is cached for
+// a while so frequent probes do not put load on the dependencies.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks []namedCheck
+	report *Report
+}
+
+// NewChecker returns a Checker caching reports for ttl. Checks which take
+// longer than timeout fail.
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Register adds a check which is reported with the name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name, check})
+	c.report = nil
+}
+
+// Check returns the report of the checks, which are run at most once per
+// ttl. Concurrent callers wait for the running checks and get their report.
+// The checks do not run with the context of a caller, the report is shared
+// and must not fail because the caller that ran the checks gave up.
+func (c *Checker) Check() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.report != nil && time.Since(c.report.Checked) < c.ttl {
+		return *c.report
+	}
+	report := c.run(context.Background())
+	c.report = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	report := Report{Status: StatusOK, Checked: time.Now(), Checks: make([]Result, len(c.checks))}
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, nc)
+		}(i, nc)
+	}
+	wg.Wait()
+	for _, res := range report.Checks {
+		if res.Status != StatusOK {
+			report.Status = StatusFailing
+		}
+	}
+	return report
+}
+
+// run runs a check, recovering from panics.
+func run(ctx context.Context, nc namedCheck) (res Result) {
+	start := time.Now()
+	res = Result{Name: nc.name, Status: StatusOK}
+	defer func() {
+		if p := recover(); p != nil {
+			res.Status, res.Error = StatusFailing, fmt.Sprintf("panic: %v", p)
+		}
+		res.Duration = time.Since(start).Milliseconds()
+	}()
+	if err := nc.check(ctx); err != nil {
+		res.Status, res.Error = StatusFailing, err.Error()
+	}
+	return res
+}
+
+// TZData returns a check that the time zone database is available by loading
+// the given locations.
+func TZData(names ...string) Check {
+	return func(ctx context.Context) error {
+		for _, name := range names {
+			if _, err := time.LoadLocation(name); err != nil {
+				return fmt.Errorf("time zone database: %v", err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	c := NewChecker(time.Minute, 50*time.Millisecond)
+	c.Register("ok", func(ctx context.Context) error { return nil })
+	c.Register("error", func(ctx context.Context) error { return errors.New("down") })
+	c.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c.Register("panic", func(ctx context.Context) error { panic("boom") })
+	c.Register("tzdata", TZData("Europe/Berlin", "UTC"))
+
+	report := c.Check()
+	if report.OK() {
+		t.Errorf("want failing report got %s", report.Status)
+	}
+	want := map[string]string{
+		"ok":     StatusOK,
+		"error":  StatusFailing,
+		"slow":   StatusFailing,
+		"panic":  StatusFailing,
+		"tzdata": StatusOK,
+	}
+	if w, g := len(want), len(report.Checks); w != g {
+		t.Fatalf("want %d results got %d", w, g)
+	}
+	for _, res := range report.Checks {
+		if w, g := want[res.Name], res.Status; w != g {
+			t.Errorf("%s: want %s got %s: %s", res.Name, w, g, res.Error)
+		}
+	}
+}
+
+func TestCheckerCache(t *testing.T) {
+	runs := 0
+	c := NewChecker(time.Minute, time.Second)
+	c.Register("count", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+	first := c.Check()
+	second := c.Check()
+	if w, g := 1, runs; w != g {
+		t.Errorf("want %d runs got %d", w, g)
+	}
+	if !first.Checked.Equal(second.Checked) {
+		t.Errorf("want cached report checked at %s got %s", first.Checked, second.Checked)
+	}
+
+	c = NewChecker(0, time.Second)
+	c.Register("count", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+	c.Check()
+	c.Check()
+	if w, g := 3, runs; w != g {
+		t.Errorf("without cache: want %d runs got %d", w, g)
+	}
+}
+
+func TestCheckerContext(t *testing.T) {
+	c := NewChecker(time.Minute, time.Second)
+	c.Register("context", func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no timeout")
+		}
+		return ctx.Err()
+	})
+	if report := c.Check(); !report.OK() {
+		t.Errorf("want checks run with their own timeout got %+v", report)
+	}
+}
+
+func TestTZData(t *testing.T) {
+	if err := TZData("Nowhere/Atlantis")(context.Background()); err == nil {
+		t.Error("want error for unknown location")
+	}
+}
@@ -0,0 +1,85 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// Errors returned when an invoice can not be built.
+var (
+	ErrNoRecords       = errors.New("no billable records")
+	ErrMixedCurrencies = errors.New("records are billed in different currencies")
+	ErrUnrated         = errors.New("no rate applies to record")
+)
+
+// Build creates an invoice for the billable records of a client in the
+// period [from, to). The rate of each record is fixed on its invoice line so
+// later rate changes do not alter the invoice. Durations are billed rounded
+// according to the rounding policy. Line amounts are rounded to the minor
+// unit of the currency and the total is the sum of the lines.
+// The number is assigned when the invoice is stored.
+func Build(clientID uint64, from, to time.Time, recs []store.TimeRecord, rates []billing.Rate, rounding billing.Rounding) (store.Invoice, error) {
+	if len(recs) == 0 {
+		return store.Invoice{}, ErrNoRecords
+	}
+	inv := store.Invoice{
+		ClientID: clientID,
+		From:     from,
+		To:       to,
+		Rounding: rounding,
+		Lines:    make([]store.InvoiceLine, 0, len(recs)),
+	}
+	work := make([]billing.Work, len(recs))
+	for i, rec := range recs {
+		work[i] = billing.Work{
+			Seconds:   rec.Duration,
+			Day:       rec.Start.Format("2006-01-02"),
+			ProjectID: rec.ProjectID,
+		}
+	}
+	billed := rounding.RoundAll(work)
+	total := new(big.Rat)
+	var currency string
+	for i, rec := range recs {
+		rate := rec.Rate
+		if rate == nil {
+			// rates take effect at a point in time, not at a wall clock
+			r, ok := billing.RateFor(rates, billing.Subject{
+				UserID:    rec.UserID,
+				ProjectID: rec.ProjectID,
+				ClientID:  rec.ClientID,
+			}, store.InLocation(rec.Start, rec.StartLoc))
+			if !ok {
+				return store.Invoice{}, fmt.Errorf("%v: %d", ErrUnrated, rec.RecordID)
+			}
+			rate = &r.Hourly
+		}
+		if currency == "" {
+			currency = rate.Currency
+		}
+		if rate.Currency != currency {
+			return store.Invoice{}, ErrMixedCurrencies
+		}
+		amount := billing.Amount(*rate, billed[i]).Round()
+		total.Add(total, amount.Amount)
+		inv.Lines = append(inv.Lines, store.InvoiceLine{
+			RecordID: rec.RecordID,
+			UserID:   rec.UserID,
+			Name:     rec.Name,
+			Start:    rec.Start,
+			StartLoc: rec.StartLoc,
+			Duration: rec.Duration,
+			Billed:   billed[i],
+			Rate:     *rate,
+			Amount:   amount,
+			Version:  rec.Version,
+		})
+	}
+	inv.Total = billing.Money{Amount: total, Currency: currency}
+	return inv, nil
+}
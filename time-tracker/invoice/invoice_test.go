@@ -0,0 +1,154 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/invoice"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+func money(t *testing.T, amount, currency string) billing.Money {
+	m, err := billing.ParseMoney(amount, currency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+var (
+	jan = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	feb = time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func TestBuild(t *testing.T) {
+	usd := money(t, "100", "USD")
+	rates := []billing.Rate{
+		{Scope: billing.ScopeClient, ScopeID: 3, Hourly: money(t, "0.10", "EUR"), EffectiveFrom: jan},
+		{Scope: billing.ScopeClient, ScopeID: 3, Hourly: money(t, "0.20", "EUR"), EffectiveFrom: time.Date(2020, time.January, 25, 8, 30, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		d     string             // description of test case
+		recs  []store.TimeRecord // unbilled records
+		total string             // expected total
+		e     error              // expected error
+	}{
+		{
+			d: "expect error without records",
+			e: invoice.ErrNoRecords,
+		},
+		{
+			d: "expect line amounts to be rounded before summing up",
+			recs: []store.TimeRecord{
+				{RecordID: 1, UserID: 42, ClientID: 3, Start: jan, Duration: 1200},
+				{RecordID: 2, UserID: 42, ClientID: 3, Start: jan, Duration: 1200},
+				{RecordID: 3, UserID: 42, ClientID: 3, Start: jan, Duration: 1200},
+			},
+			total: "0.09",
+		},
+		{
+			// 09:00 in Berlin is 08:00 UTC, the store reads the wall clock
+			// in the user's location with a +00 offset
+			d: "expect the rate effective at the start",
+			recs: []store.TimeRecord{
+				{RecordID: 1, UserID: 42, ClientID: 3, Start: time.Date(2020, time.January, 25, 9, 0, 0, 0, time.FixedZone("", 0)), StartLoc: "Europe/Berlin", Duration: 3600},
+			},
+			total: "0.10",
+		},
+		{
+			d: "expect error for records billed in different currencies",
+			recs: []store.TimeRecord{
+				{RecordID: 1, UserID: 42, ClientID: 3, Start: jan, Duration: 3600},
+				{RecordID: 2, UserID: 42, ClientID: 3, Start: jan, Duration: 3600, Rate: &usd},
+			},
+			e: invoice.ErrMixedCurrencies,
+		},
+		{
+			d: "expect error for records without applicable rate",
+			recs: []store.TimeRecord{
+				{RecordID: 1, UserID: 42, ClientID: 3, Start: jan.Add(-time.Hour), Duration: 3600},
+			},
+			e: invoice.ErrUnrated,
+		},
+	}
+	for _, tc := range tests {
+		inv, err := invoice.Build(3, jan, feb, tc.recs, rates, billing.Rounding{})
+		if tc.e != nil {
+			if err == nil || !strings.HasPrefix(err.Error(), tc.e.Error()) {
+				t.Errorf("%s: want err %v got %v", tc.d, tc.e, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", tc.d, err)
+		}
+		if got := inv.Total.String(); got != tc.total {
+			t.Errorf("%s: want total %s got %s", tc.d, tc.total, got)
+		}
+		if got, want := len(inv.Lines), len(tc.recs); got != want {
+			t.Errorf("%s: want %d lines got %d", tc.d, want, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	eur := money(t, "80", "EUR")
+	inv, err := invoice.Build(3, jan, feb, []store.TimeRecord{
+		{RecordID: 1, UserID: 42, Name: "<review>", Start: jan, StartLoc: "UTC", Duration: 5400, Rate: &eur},
+	}, nil, billing.Rounding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv.Number = 7
+	inv.ClientName = "ACME"
+	inv.Created = feb
+
+	doc := invoice.NewDocument(inv)
+	if got, want := doc.To, "2020-01-31"; got != want {
+		t.Errorf("want inclusive period end %s got %s", want, got)
+	}
+	if got, want := doc.Lines[0].Hours, "1.50"; got != want {
+		t.Errorf("want hours %s got %s", want, got)
+	}
+
+	var html bytes.Buffer
+	if err := invoice.WriteHTML(&html, inv, locale.Formatter{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "&lt;review&gt;") {
+		t.Errorf("want escaped line name in html\n%s", html.String())
+	}
+	if !strings.Contains(html.String(), "120.00 EUR") {
+		t.Errorf("want line amount in html\n%s", html.String())
+	}
+
+	html.Reset()
+	if err := invoice.WriteHTML(&html, inv, locale.Formatter{Locale: locale.German}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Rechnung 7", "Leistungszeitraum: 01.01.2020 &ndash; 31.01.2020", "<td>1,50</td>", "120,00 EUR"} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("want %q in german html\n%s", want, html.String())
+		}
+	}
+
+	var ubl bytes.Buffer
+	if err := invoice.WriteUBL(&ubl, inv); err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		ID      string `xml:"ID"`
+		Payable string `xml:"LegalMonetaryTotal>PayableAmount"`
+	}
+	if err := xml.Unmarshal(ubl.Bytes(), &v); err != nil {
+		t.Fatalf("invalid xml: %v\n%s", err, ubl.String())
+	}
+	if v.ID != "7" || v.Payable != "120.00" {
+		t.Errorf("want invoice 7 with payable amount 120.00 got %+v\n%s", v, ubl.String())
+	}
+}
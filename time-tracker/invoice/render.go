@@ -0,0 +1,249 @@
+package invoice
+
+import (
+	"encoding/xml"
+	"html/template"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+const dateFormat = "2006-01-02"
+
+// Document is the structured JSON representation of an invoice.
+type Document struct {
+	InvoiceID  uint64           `json:"invoice_id"`
+	Number     uint64           `json:"number"`
+	ClientID   uint64           `json:"client_id"`
+	ClientName string           `json:"client_name"`
+	From       string           `json:"period_from"`
+	To         string           `json:"period_to"` // inclusive
+	Issued     string           `json:"issue_date"`
+	Voided     bool             `json:"voided"`
+	Rounding   billing.Rounding `json:"rounding"`
+	Lines      []DocumentLine   `json:"lines"`
+	Total      billing.Money    `json:"total"`
+}
+
+// DocumentLine is the structured JSON representation of an invoice line.
+type DocumentLine struct {
+	RecordID uint64        `json:"record_id"`
+	UserID   uint64        `json:"user_id"`
+	Name     string        `json:"name"`
+	Date     string        `json:"date"`  // date in the location the work started
+	Hours    string        `json:"hours"` // billed hours
+	Duration string        `json:"duration"`
+	Billed   string        `json:"billed_duration"`
+	Rate     billing.Money `json:"rate"`
+	Amount   billing.Money `json:"amount"`
+}
+
+// NewDocument returns the structured representation of an invoice. Dates
+// are ISO 8601 dates. The period end is inclusive, in contrast to the
+// stored, exclusive end.
+func NewDocument(inv store.Invoice) Document {
+	doc := Document{
+		InvoiceID:  inv.InvoiceID,
+		Number:     inv.Number,
+		ClientID:   inv.ClientID,
+		ClientName: inv.ClientName,
+		From:       inv.From.UTC().Format(dateFormat),
+		To:         inv.To.UTC().Add(-time.Nanosecond).Format(dateFormat),
+		Issued:     inv.Created.UTC().Format(dateFormat),
+		Voided:     inv.Voided != nil,
+		Rounding:   inv.Rounding,
+		Lines:      make([]DocumentLine, 0, len(inv.Lines)),
+		Total:      inv.Total,
+	}
+	for _, l := range inv.Lines {
+		doc.Lines = append(doc.Lines, DocumentLine{
+			RecordID: l.RecordID,
+			UserID:   l.UserID,
+			Name:     l.Name,
+			Date:     l.Start.Format(dateFormat),
+			Hours:    hours(l.Billed),
+			Duration: store.FormatDuration(time.Duration(l.Duration) * time.Second),
+			Billed:   store.FormatDuration(time.Duration(l.Billed) * time.Second),
+			Rate:     l.Rate,
+			Amount:   l.Amount,
+		})
+	}
+	return doc
+}
+
+// hours returns the duration in seconds as decimal hours with two decimal
+// places.
+func hours(seconds int64) string {
+	return big.NewRat(seconds, 3600).FloatString(2)
+}
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.T "invoice"}} {{.Number}}</title>
+</head>
+<body>
+<h1>{{.T "invoice"}} {{.Number}}{{if .Voided}} ({{.T "invoice.void"}}){{end}}</h1>
+<p>
+{{.T "invoice.client"}}: {{.ClientName}}<br>
+{{.T "invoice.issue_date"}}: {{.Issued}}<br>
+{{.T "invoice.period"}}: {{.From}} &ndash; {{.To}}
+</p>
+<table>
+<thead>
+<tr><th>{{.T "invoice.date"}}</th><th>{{.T "invoice.description"}}</th><th>{{.T "invoice.duration"}}</th><th>{{.T "invoice.hours"}}</th><th>{{.T "invoice.rate"}}</th><th>{{.T "invoice.amount"}}</th></tr>
+</thead>
+<tbody>
+{{range .Lines}}<tr><td>{{.Date}}</td><td>{{.Name}}</td><td>{{.Duration}}</td><td>{{.Hours}}</td><td>{{.Rate}}</td><td>{{.Amount}}</td></tr>
+{{end}}</tbody>
+<tfoot>
+<tr><th colspan="5">{{.T "invoice.total"}}</th><th>{{.Total}}</th></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// htmlDocument is an invoice with dates, durations and amounts formatted
+// for the reader of the HTML document.
+type htmlDocument struct {
+	f          locale.Formatter
+	Lang       string
+	Number     uint64
+	ClientName string
+	From       string
+	To         string
+	Issued     string
+	Voided     bool
+	Lines      []htmlLine
+	Total      string
+}
+
+type htmlLine struct {
+	Date     string
+	Name     string
+	Duration string
+	Hours    string
+	Rate     string
+	Amount   string
+}
+
+// T returns the translation of a label.
+func (d htmlDocument) T(key string) string {
+	return d.f.Message(key)
+}
+
+// WriteHTML renders the invoice as HTML document to w. Labels, dates,
+// durations and amounts are formatted by f.
+func WriteHTML(w io.Writer, inv store.Invoice, f locale.Formatter) error {
+	money := func(m billing.Money) string {
+		return f.Decimal(m.String()) + " " + m.Currency
+	}
+	doc := htmlDocument{
+		f:          f,
+		Lang:       f.Lang(),
+		Number:     inv.Number,
+		ClientName: inv.ClientName,
+		From:       f.Date(inv.From.UTC()),
+		To:         f.Date(inv.To.UTC().Add(-time.Nanosecond)),
+		Issued:     f.Date(inv.Created.UTC()),
+		Voided:     inv.Voided != nil,
+		Lines:      make([]htmlLine, 0, len(inv.Lines)),
+		Total:      money(inv.Total),
+	}
+	for _, l := range inv.Lines {
+		doc.Lines = append(doc.Lines, htmlLine{
+			Date:     f.Date(l.Start),
+			Name:     l.Name,
+			Duration: f.Duration(time.Duration(l.Duration) * time.Second),
+			Hours:    f.Hours(time.Duration(l.Billed) * time.Second),
+			Rate:     money(l.Rate),
+			Amount:   money(l.Amount),
+		})
+	}
+	return htmlTemplate.Execute(w, doc)
+}
+
+// ublInvoice is a minimal UBL 2.1 invoice for e-invoicing. Time is billed in
+// hours, see UN/ECE recommendation 20 unit code HUR.
+type ublInvoice struct {
+	XMLName         xml.Name  `xml:"urn:oasis:names:specification:ubl:schema:xsd:Invoice-2 Invoice"`
+	CAC             string    `xml:"xmlns:cac,attr"`
+	CBC             string    `xml:"xmlns:cbc,attr"`
+	ID              uint64    `xml:"cbc:ID"`
+	IssueDate       string    `xml:"cbc:IssueDate"`
+	InvoiceTypeCode string    `xml:"cbc:InvoiceTypeCode"`
+	Currency        string    `xml:"cbc:DocumentCurrencyCode"`
+	StartDate       string    `xml:"cac:InvoicePeriod>cbc:StartDate"`
+	EndDate         string    `xml:"cac:InvoicePeriod>cbc:EndDate"`
+	Customer        string    `xml:"cac:AccountingCustomerParty>cac:Party>cac:PartyName>cbc:Name"`
+	Total           ublTotal  `xml:"cac:LegalMonetaryTotal"`
+	Lines           []ublLine `xml:"cac:InvoiceLine"`
+}
+
+type ublAmount struct {
+	Currency string `xml:"currencyID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type ublQuantity struct {
+	Unit  string `xml:"unitCode,attr"`
+	Value string `xml:",chardata"`
+}
+
+type ublTotal struct {
+	LineExtension ublAmount `xml:"cbc:LineExtensionAmount"`
+	Payable       ublAmount `xml:"cbc:PayableAmount"`
+}
+
+type ublLine struct {
+	ID            uint64      `xml:"cbc:ID"`
+	Quantity      ublQuantity `xml:"cbc:InvoicedQuantity"`
+	LineExtension ublAmount   `xml:"cbc:LineExtensionAmount"`
+	Item          string      `xml:"cac:Item>cbc:Name"`
+	Price         ublAmount   `xml:"cac:Price>cbc:PriceAmount"`
+}
+
+func newUBLAmount(m billing.Money) ublAmount {
+	return ublAmount{Currency: m.Currency, Value: m.String()}
+}
+
+// WriteUBL renders the invoice as UBL 2.1 XML document to w.
+func WriteUBL(w io.Writer, inv store.Invoice) error {
+	doc := NewDocument(inv)
+	u := ublInvoice{
+		CAC:             "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2",
+		CBC:             "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2",
+		ID:              doc.Number,
+		IssueDate:       doc.Issued,
+		InvoiceTypeCode: "380", // commercial invoice
+		Currency:        doc.Total.Currency,
+		StartDate:       doc.From,
+		EndDate:         doc.To,
+		Customer:        doc.ClientName,
+		Total: ublTotal{
+			LineExtension: newUBLAmount(doc.Total),
+			Payable:       newUBLAmount(doc.Total),
+		},
+	}
+	for i, l := range doc.Lines {
+		u.Lines = append(u.Lines, ublLine{
+			ID:            uint64(i + 1),
+			Quantity:      ublQuantity{Unit: "HUR", Value: l.Hours},
+			LineExtension: newUBLAmount(l.Amount),
+			Item:          l.Name,
+			Price:         newUBLAmount(l.Rate),
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(u)
+}
@@ -0,0 +1,302 @@
+// Package locale formats dates, times and durations for the locale and
+// preferences of a user and translates the messages of the API.
+package locale
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock is the clock times are shown in.
+type Clock string
+
+// Clocks of times, the locale decides if none is preferred.
+const (
+	Clock24 Clock = "24h"
+	Clock12 Clock = "12h"
+)
+
+// Preferences are the preferences of a user how dates, times and durations
+// are shown. Empty preferences fall back to the locale.
+type Preferences struct {
+	// Locale is the language tag of the locale, e.g. de or da-DK.
+	Locale string `json:"locale,omitempty"`
+	// Clock is 24h or 12h.
+	Clock Clock `json:"clock,omitempty"`
+	// DecimalHours shows durations as decimal hours, e.g. 1.50 instead of
+	// 01:30:00.
+	DecimalHours bool `json:"decimal_hours,omitempty"`
+}
+
+// Validate returns an error if the locale is not supported or the clock is
+// unknown.
+func (p Preferences) Validate() error {
+	if p.Locale != "" {
+		if _, ok := Lookup(p.Locale); !ok {
+			return fmt.Errorf("unsupported locale %q", p.Locale)
+		}
+	}
+	switch p.Clock {
+	case "", Clock24, Clock12:
+	default:
+		return fmt.Errorf("unknown clock %q", p.Clock)
+	}
+	return nil
+}
+
+// Locale holds the names and layouts of a language. Layouts are layouts of
+// the time package, in which {wd}, {mon} and {month} are replaced by the
+// short weekday, the short and the full month name of the language.
+type Locale struct {
+	Tag         string // language tag, e.g. de
+	weekdays    [7]string
+	shortMonths [12]string
+	months      [12]string
+	date        string // e.g. 02.01.2006
+	longDate    string // date with the month name, e.g. 2. {month} 2006
+	day         string // weekday and day without year, e.g. {wd} 02.01.
+	clock       Clock  // clock if the user prefers none
+	decimal     string // decimal separator
+	messages    map[string]string
+}
+
+// English is the default locale. Its dates and times are formatted the way
+// the API always did, like 02 Jan 2006 15:04:05.
+var English = &Locale{
+	Tag:         "en",
+	weekdays:    [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	shortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	date:        "02 {mon} 2006",
+	longDate:    "2 {month} 2006",
+	day:         "{wd} 02 {mon}",
+	clock:       Clock24,
+	decimal:     ".",
+	messages:    english,
+}
+
+// German is the locale of Germany, Austria and Switzerland.
+var German = &Locale{
+	Tag:         "de",
+	weekdays:    [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	shortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+	months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	date:        "02.01.2006",
+	longDate:    "2. {month} 2006",
+	day:         "{wd} 02.01.",
+	clock:       Clock24,
+	decimal:     ",",
+	messages:    german,
+}
+
+// Danish is the locale of Denmark.
+var Danish = &Locale{
+	Tag:         "da",
+	weekdays:    [7]string{"søn", "man", "tir", "ons", "tor", "fre", "lør"},
+	shortMonths: [12]string{"jan", "feb", "mar", "apr", "maj", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+	months:      [12]string{"januar", "februar", "marts", "april", "maj", "juni", "juli", "august", "september", "oktober", "november", "december"},
+	date:        "02.01.2006",
+	longDate:    "2. {month} 2006",
+	day:         "{wd} 02.01.",
+	clock:       Clock24,
+	decimal:     ",",
+	messages:    danish,
+}
+
+// locales are the supported locales by language.
+var locales = map[string]*Locale{
+	English.Tag: English,
+	German.Tag:  German,
+	Danish.Tag:  Danish,
+}
+
+// Lookup returns the supported locale of a language tag, e.g. de-AT, or a
+// POSIX locale name, e.g. de_DE.UTF-8. Regions are not told apart.
+func Lookup(tag string) (*Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_.@"); i >= 0 {
+		tag = tag[:i]
+	}
+	l, ok := locales[tag]
+	return l, ok
+}
+
+// Match returns the supported locale preferred by an Accept-Language header,
+// e.g. da, en-GB;q=0.8, de;q=0.7, or English if none is supported.
+func Match(acceptLanguage string) *Locale {
+	type language struct {
+		tag string
+		q   float64
+	}
+	var langs []language
+	for _, s := range strings.Split(acceptLanguage, ",") {
+		parts := strings.Split(s, ";")
+		lang := language{tag: strings.TrimSpace(parts[0]), q: 1}
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				q, err := strconv.ParseFloat(p[2:], 64)
+				if err != nil {
+					q = 0
+				}
+				lang.q = q
+			}
+		}
+		if lang.tag != "" && lang.q > 0 {
+			langs = append(langs, lang)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	for _, lang := range langs {
+		if l, ok := Lookup(lang.tag); ok {
+			return l
+		}
+	}
+	return English
+}
+
+// Negotiate returns the formatter of a user with the preferences p. The
+// locale of the preferences takes precedence over the Accept-Language
+// header, it is chosen by the user on purpose.
+func Negotiate(p Preferences, acceptLanguage string) Formatter {
+	l, ok := Lookup(p.Locale)
+	if !ok {
+		l = Match(acceptLanguage)
+	}
+	clock := p.Clock
+	if clock == "" {
+		clock = l.clock
+	}
+	return Formatter{Locale: l, Clock: clock, DecimalHours: p.DecimalHours}
+}
+
+// Formatter formats dates, times and durations in a locale. The zero value
+// formats in English on a 24 hour clock.
+type Formatter struct {
+	Locale       *Locale
+	Clock        Clock
+	DecimalHours bool
+}
+
+func (f Formatter) locale() *Locale {
+	if f.Locale == nil {
+		return English
+	}
+	return f.Locale
+}
+
+// Lang returns the language tag of the locale of f.
+func (f Formatter) Lang() string {
+	return f.locale().Tag
+}
+
+// Key identifies the formatter, formatters with the same key format alike.
+func (f Formatter) Key() string {
+	key := f.locale().Tag
+	if f.Clock == Clock12 {
+		key += "-12h"
+	}
+	if f.DecimalHours {
+		key += "-decimal"
+	}
+	return key
+}
+
+// format formats t with the layout and replaces the names of the locale.
+func (f Formatter) format(t time.Time, layout string) string {
+	l := f.locale()
+	return strings.NewReplacer(
+		"{wd}", l.weekdays[t.Weekday()],
+		"{month}", l.months[t.Month()-1],
+		"{mon}", l.shortMonths[t.Month()-1],
+	).Replace(t.Format(layout))
+}
+
+// Date formats the date of t, e.g. 25.01.2020.
+func (f Formatter) Date(t time.Time) string {
+	return f.format(t, f.locale().date)
+}
+
+// LongDate formats the date of t with the month name, e.g. 25. Januar 2020.
+func (f Formatter) LongDate(t time.Time) string {
+	return f.format(t, f.locale().longDate)
+}
+
+// Day formats the weekday and day of t without the year, e.g. Sa 25.01.
+func (f Formatter) Day(t time.Time) string {
+	return f.format(t, f.locale().day)
+}
+
+// Time formats the time of t in seconds, e.g. 15:04:05 or 3:04:05 PM.
+func (f Formatter) Time(t time.Time) string {
+	if f.Clock == Clock12 {
+		return t.Format("3:04:05 PM")
+	}
+	return t.Format("15:04:05")
+}
+
+// ShortTime formats the time of t in minutes, e.g. 15:04 or 3:04 PM.
+func (f Formatter) ShortTime(t time.Time) string {
+	if f.Clock == Clock12 {
+		return t.Format("3:04 PM")
+	}
+	return t.Format("15:04")
+}
+
+// DateTime formats the date and time of t, e.g. 25.01.2020 15:04:05.
+func (f Formatter) DateTime(t time.Time) string {
+	return f.Date(t) + " " + f.Time(t)
+}
+
+// Duration formats d as hours, minutes and seconds, e.g. 01:30:00, or as
+// decimal hours if they are preferred, e.g. 1,50.
+func (f Formatter) Duration(d time.Duration) string {
+	if f.DecimalHours {
+		return f.Hours(d)
+	}
+	neg := d < 0
+	if neg {
+		d = -d
+	}
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	hms := fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	if neg {
+		return "-" + hms
+	}
+	return hms
+}
+
+// Hours formats d as decimal hours with two decimal places, e.g. 1,50.
+func (f Formatter) Hours(d time.Duration) string {
+	return f.Decimal(big.NewRat(int64(d/time.Second), 3600).FloatString(2))
+}
+
+// Decimal replaces the decimal point of a decimal number, e.g. an amount
+// of money, with the separator of the locale.
+func (f Formatter) Decimal(s string) string {
+	return strings.Replace(s, ".", f.locale().decimal, 1)
+}
+
+// Message returns the translation of a message in the locale of f, see
+// Locale.Message.
+func (f Formatter) Message(key string) string {
+	return f.locale().Message(key)
+}
+
+// Message returns the translation of a message, e.g. of the error code
+// not_found, falling back to English. It returns an empty string if there is
+// no translation.
+func (l *Locale) Message(key string) string {
+	if m, ok := l.messages[key]; ok {
+		return m
+	}
+	return english[key]
+}
@@ -0,0 +1,97 @@
+package locale_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		h string // Accept-Language header
+		l *locale.Locale
+	}{
+		{h: "", l: locale.English},
+		{h: "de-DE", l: locale.German},
+		{h: "fr, da;q=0.5, de;q=0.4", l: locale.Danish},
+		{h: "de;q=0.4, da-DK;q=0.5", l: locale.Danish},
+		{h: "da;q=0, de", l: locale.German},
+		{h: "fr, es", l: locale.English},
+	}
+	for _, tt := range tests {
+		if got := locale.Match(tt.h); got != tt.l {
+			t.Errorf("%q: want %s got %s", tt.h, tt.l.Tag, got.Tag)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	f := locale.Negotiate(locale.Preferences{Locale: "de_AT.UTF-8"}, "da")
+	if f.Locale != locale.German || f.Clock != locale.Clock24 {
+		t.Errorf("want german on a 24h clock got %s", f.Key())
+	}
+	f = locale.Negotiate(locale.Preferences{Clock: locale.Clock12, DecimalHours: true}, "da")
+	if want, got := "da-12h-decimal", f.Key(); want != got {
+		t.Errorf("want %s got %s", want, got)
+	}
+	if err := (locale.Preferences{Locale: "fr"}).Validate(); err == nil {
+		t.Error("want error for unsupported locale")
+	}
+	if err := (locale.Preferences{Clock: "10h"}).Validate(); err == nil {
+		t.Error("want error for unknown clock")
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	ts := time.Date(2020, time.March, 25, 15, 4, 5, 0, time.UTC)
+	d := 90*time.Minute + 5*time.Second
+
+	tests := []struct {
+		f                                  locale.Formatter
+		date, longDate, day, dateTime, dur string
+	}{
+		{
+			f:    locale.Formatter{},
+			date: "25 Mar 2020", longDate: "25 March 2020", day: "Wed 25 Mar", dateTime: "25 Mar 2020 15:04:05", dur: "01:30:05",
+		},
+		{
+			f:    locale.Formatter{Locale: locale.German},
+			date: "25.03.2020", longDate: "25. März 2020", day: "Mi 25.03.", dateTime: "25.03.2020 15:04:05", dur: "01:30:05",
+		},
+		{
+			f:    locale.Formatter{Locale: locale.Danish, Clock: locale.Clock12, DecimalHours: true},
+			date: "25.03.2020", longDate: "25. marts 2020", day: "ons 25.03.", dateTime: "25.03.2020 3:04:05 PM", dur: "1,50",
+		},
+	}
+	for _, tt := range tests {
+		key := tt.f.Key()
+		if got := tt.f.Date(ts); got != tt.date {
+			t.Errorf("%s: want date %s got %s", key, tt.date, got)
+		}
+		if got := tt.f.LongDate(ts); got != tt.longDate {
+			t.Errorf("%s: want long date %s got %s", key, tt.longDate, got)
+		}
+		if got := tt.f.Day(ts); got != tt.day {
+			t.Errorf("%s: want day %s got %s", key, tt.day, got)
+		}
+		if got := tt.f.DateTime(ts); got != tt.dateTime {
+			t.Errorf("%s: want date and time %s got %s", key, tt.dateTime, got)
+		}
+		if got := tt.f.Duration(d); got != tt.dur {
+			t.Errorf("%s: want duration %s got %s", key, tt.dur, got)
+		}
+	}
+	if want, got := "-00:30:00", (locale.Formatter{}).Duration(-30*time.Minute); want != got {
+		t.Errorf("want negative duration %s got %s", want, got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := locale.German.Message("not_found"); got != "Nicht gefunden." {
+		t.Errorf("want german message got %q", got)
+	}
+	if got := locale.Danish.Message("unknown_code"); got != "" {
+		t.Errorf("want no message for unknown code got %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package locale
+
+// english are the English messages by key. Error codes of the API are their
+// own keys, texts of documents are prefixed by the document, e.g. invoice.
+var english = map[string]string{
+	"bad_request":              "The request is malformed.",
+	"comment_required":         "A comment is required to reject a timesheet.",
+	"conflict":                 "The request conflicts with the current state.",
+	"forbidden":                "You are not allowed to do this.",
+	"idempotency_key_in_use":   "A request with this idempotency key is still in progress.",
+	"idempotency_key_mismatch": "The idempotency key was used for a different request.",
+	"internal_error":           "Something went wrong on our side.",
+	"invalid_stop":             "The stop time is before the start time.",
+	"invalid_transition":       "The timesheet can not change to this state.",
+	"locked":                   "The record is locked by an invoice or an approved timesheet.",
+	"mixed_currencies":         "The records are billed in different currencies.",
+	"not_acceptable":           "The requested format is not supported.",
+	"not_found":                "Not found.",
+	"nothing_to_bill":          "There are no billable records.",
+	"rate_limited":             "Too many requests, please retry later.",
+	"record_exists":            "A record with this uuid exists.",
+	"timer_running":            "A timer is running already.",
+	"unrated_records":          "No rate applies to some records.",
+
+	"invoice":             "Invoice",
+	"invoice.void":        "void",
+	"invoice.client":      "Client",
+	"invoice.issue_date":  "Issue date",
+	"invoice.period":      "Period",
+	"invoice.date":        "Date",
+	"invoice.description": "Description",
+	"invoice.duration":    "Duration",
+	"invoice.hours":       "Hours",
+	"invoice.rate":        "Rate",
+	"invoice.amount":      "Amount",
+	"invoice.total":       "Total",
+}
+
+var german = map[string]string{
+	"bad_request":              "Die Anfrage ist fehlerhaft.",
+	"comment_required":         "Zum Ablehnen eines Stundenzettels ist ein Kommentar nötig.",
+	"conflict":                 "Die Anfrage widerspricht dem aktuellen Zustand.",
+	"forbidden":                "Das ist dir nicht erlaubt.",
+	"idempotency_key_in_use":   "Eine Anfrage mit diesem Idempotenzschlüssel wird noch bearbeitet.",
+	"idempotency_key_mismatch": "Der Idempotenzschlüssel wurde für eine andere Anfrage verwendet.",
+	"internal_error":           "Bei uns ist etwas schiefgelaufen.",
+	"invalid_stop":             "Das Ende liegt vor dem Beginn.",
+	"invalid_transition":       "Der Stundenzettel kann nicht in diesen Zustand wechseln.",
+	"locked":                   "Der Eintrag ist durch eine Rechnung oder einen genehmigten Stundenzettel gesperrt.",
+	"mixed_currencies":         "Die Einträge werden in verschiedenen Währungen abgerechnet.",
+	"not_acceptable":           "Das angefragte Format wird nicht unterstützt.",
+	"not_found":                "Nicht gefunden.",
+	"nothing_to_bill":          "Es gibt keine abrechenbaren Einträge.",
+	"rate_limited":             "Zu viele Anfragen, bitte versuche es später erneut.",
+	"record_exists":            "Ein Eintrag mit dieser UUID existiert bereits.",
+	"timer_running":            "Es läuft bereits ein Timer.",
+	"unrated_records":          "Für einige Einträge gilt kein Stundensatz.",
+
+	"invoice":             "Rechnung",
+	"invoice.void":        "storniert",
+	"invoice.client":      "Kunde",
+	"invoice.issue_date":  "Rechnungsdatum",
+	"invoice.period":      "Leistungszeitraum",
+	"invoice.date":        "Datum",
+	"invoice.description": "Beschreibung",
+	"invoice.duration":    "Dauer",
+	"invoice.hours":       "Stunden",
+	"invoice.rate":        "Satz",
+	"invoice.amount":      "Betrag",
+	"invoice.total":       "Summe",
+}
+
+var danish = map[string]string{
+	"bad_request":              "Forespørgslen er ugyldig.",
+	"comment_required":         "Der kræves en kommentar for at afvise en timeseddel.",
+	"conflict":                 "Forespørgslen er i konflikt med den nuværende tilstand.",
+	"forbidden":                "Det har du ikke lov til.",
+	"idempotency_key_in_use":   "En forespørgsel med denne idempotensnøgle er stadig i gang.",
+	"idempotency_key_mismatch": "Idempotensnøglen blev brugt til en anden forespørgsel.",
+	"internal_error":           "Noget gik galt hos os.",
+	"invalid_stop":             "Sluttidspunktet ligger før starttidspunktet.",
+	"invalid_transition":       "Timesedlen kan ikke skifte til denne tilstand.",
+	"locked":                   "Registreringen er låst af en faktura eller en godkendt timeseddel.",
+	"mixed_currencies":         "Registreringerne faktureres i forskellige valutaer.",
+	"not_acceptable":           "Det ønskede format understøttes ikke.",
+	"not_found":                "Ikke fundet.",
+	"nothing_to_bill":          "Der er ingen fakturerbare registreringer.",
+	"rate_limited":             "For mange forespørgsler, prøv igen senere.",
+	"record_exists":            "Der findes allerede en registrering med dette uuid.",
+	"timer_running":            "Der kører allerede et ur.",
+	"unrated_records":          "Der gælder ingen sats for nogle registreringer.",
+
+	"invoice":             "Faktura",
+	"invoice.void":        "annulleret",
+	"invoice.client":      "Kunde",
+	"invoice.issue_date":  "Fakturadato",
+	"invoice.period":      "Periode",
+	"invoice.date":        "Dato",
+	"invoice.description": "Beskrivelse",
+	"invoice.duration":    "Varighed",
+	"invoice.hours":       "Timer",
+	"invoice.rate":        "Sats",
+	"invoice.amount":      "Beløb",
+	"invoice.total":       "I alt",
+}
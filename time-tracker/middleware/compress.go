@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the size of bodies below which responses are sent
+// uncompressed, compressing them does not pay off.
+const compressMinSize = 1024
+
+// compressor compresses the data written to it.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoding is a content encoding. Its compressors are pooled since they are
+// expensive to allocate.
+type encoding struct {
+	name string
+	pool sync.Pool
+}
+
+func (e *encoding) get(w io.Writer) compressor {
+	c := e.pool.Get().(compressor)
+	c.Reset(w)
+	return c
+}
+
+// encodings are the supported content encodings in order of preference.
+var encodings = []*encoding{
+	{name: "br", pool: sync.Pool{New: func() interface{} {
+		// level 5 still beats gzip at a similar speed, the default of 6
+		// is considerably slower
+		return brotli.NewWriterLevel(nil, 5)
+	}}},
+	{name: "gzip", pool: sync.Pool{New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}}},
+}
+
+// negotiateEncoding returns the encoding preferred by the Accept-Encoding
+// header of a request or nil if the response is sent unencoded. Encodings of
+// the same quality are chosen in the order of encodings.
+func negotiateEncoding(header string) *encoding {
+	q := map[string]float64{}
+	for _, s := range splitList(header) {
+		name, quality := s, 1.0
+		if i := strings.IndexByte(s, ';'); i >= 0 {
+			name = strings.TrimSpace(s[:i])
+			param := strings.TrimSpace(s[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				quality = v
+			}
+		}
+		q[strings.ToLower(name)] = quality
+	}
+
+	var best *encoding
+	var bestQ float64
+	for _, e := range encodings {
+		v, found := q[e.name]
+		if !found {
+			v = q["*"]
+		}
+		if v > bestQ {
+			best, bestQ = e, v
+		}
+	}
+	return best
+}
+
+// compressible reports whether responses of the media type are compressed.
+// Images, archives and the like are compressed already.
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		mt == "application/json",
+		strings.HasSuffix(mt, "+json"),
+		mt == "application/javascript",
+		mt == "application/xml",
+		strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+	return false
+}
+
+// NewCompression returns middleware that compresses responses with brotli or
+// gzip, whichever the client prefers by its Accept-Encoding header. Bodies are
+// buffered until they exceed compressMinSize, smaller ones are sent as they
+// are. Responses which are encoded already, have no body or a media type that
+// does not compress well are passed through, so are WebSocket upgrades and
+// hijacked connections.
+func NewCompression() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Upgrade") != "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if enc == nil || r.Method == http.MethodHead {
+				h.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, enc: enc}
+			defer cw.close()
+			h.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter holds back the header and the body until it knows whether
+// the response is compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	enc    *encoding
+	status int
+	buf    []byte
+	w      io.Writer  // destination of the body once the header is written
+	c      compressor // compressor of the body if it is compressed
+
+	hijacked bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = code
+	header := cw.Header()
+	if code < 200 || code == http.StatusNoContent || code == http.StatusNotModified ||
+		header.Get("Content-Encoding") != "" {
+		cw.start(false)
+		return
+	}
+	if ct := header.Get("Content-Type"); ct != "" && !compressible(ct) {
+		cw.start(false)
+		return
+	}
+	if n, err := strconv.Atoi(header.Get("Content-Length")); err == nil && n < compressMinSize {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.w != nil {
+		return cw.w.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what is written so far, compressed if the media type allows it,
+// so event streams are not held back.
+func (cw *compressWriter) Flush() {
+	if cw.w == nil {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.start(true)
+	}
+	if cw.c != nil {
+		cw.c.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands over the connection, event streams write to it uncompressed.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		cw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// CloseNotify and ReadFrom complete the interfaces of the net/http writer,
+// without them writers wrapping this one do not offer hijacking.
+func (cw *compressWriter) CloseNotify() <-chan bool {
+	if cn, ok := cw.ResponseWriter.(http.CloseNotifier); ok {
+		return cn.CloseNotify()
+	}
+	return nil
+}
+
+func (cw *compressWriter) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(writerOnly{cw}, r)
+}
+
+// writerOnly hides the ReadFrom of a writer from io.Copy.
+type writerOnly struct {
+	io.Writer
+}
+
+// start writes the header and the buffered body, compressed if compress is
+// set and the media type allows it.
+func (cw *compressWriter) start(compress bool) error {
+	header := cw.Header()
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// net/http would sniff the compressed body
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if compress && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", cw.enc.name)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// the compressed body is not byte for byte the same
+			header.Set("ETag", "W/"+etag)
+		}
+		cw.c = cw.enc.get(cw.ResponseWriter)
+		cw.w = cw.c
+	} else {
+		cw.w = cw.ResponseWriter
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.w.Write(buf)
+	return err
+}
+
+// close sends the rest of the response. Bodies smaller than compressMinSize
+// are still buffered and sent uncompressed.
+func (cw *compressWriter) close() error {
+	if cw.hijacked {
+		return nil
+	}
+	if cw.w == nil {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// nothing was written, which net/http answers with 200
+			return nil
+		}
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.c == nil {
+		return nil
+	}
+	err := cw.c.Close()
+	cw.c.Reset(nil)
+	cw.enc.pool.Put(cw.c)
+	return err
+}
@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy is the policy of cross-origin requests made by browsers.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make requests, e.g.
+	// https://app.example.com. A * matches any part of an origin, e.g.
+	// https://*.example.com, and an origin of * allows all origins.
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in preflight requests.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight requests.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers browsers let clients read,
+	// besides the simple ones like Content-Type.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization headers.
+	AllowCredentials bool
+	// MaxAge is the time browsers may cache the answer to a preflight.
+	MaxAge time.Duration
+}
+
+// Validate returns an error if the policy is invalid. Credentials must not be
+// allowed for all origins.
+func (p CORSPolicy) Validate() error {
+	for _, o := range p.AllowedOrigins {
+		if strings.Count(o, "*") > 1 {
+			return fmt.Errorf("origin %q has more than one wildcard", o)
+		}
+		if o == "*" && p.AllowCredentials {
+			return fmt.Errorf("credentials must not be allowed for all origins")
+		}
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("max age must not be negative, got %s", p.MaxAge)
+	}
+	return nil
+}
+
+// AllowOrigin reports whether requests of the origin are allowed.
+func (p CORSPolicy) AllowOrigin(origin string) bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		if i := strings.IndexByte(o, '*'); i >= 0 {
+			prefix, suffix := strings.ToLower(o[:i]), strings.ToLower(o[i+1:])
+			lo := strings.ToLower(origin)
+			if len(lo) > len(prefix)+len(suffix) && strings.HasPrefix(lo, prefix) && strings.HasSuffix(lo, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowAll() bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowMethod(method string) bool {
+	for _, m := range p.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowHeaders(headers []string) bool {
+next:
+	for _, h := range headers {
+		for _, a := range p.AllowedHeaders {
+			if strings.EqualFold(a, h) {
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// NewCORS returns middleware that applies the CORS policy returned by policy
+// to each request. It answers preflight requests itself, so it must wrap the
+// router. Preflights of origins, methods or headers not allowed are rejected
+// with 403, other requests of origins not allowed are served without CORS
+// headers, so browsers hide the response.
+func NewCORS(policy func() CORSPolicy) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := policy()
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			header := w.Header()
+			if !p.allowAll() || p.AllowCredentials {
+				// the response depends on the origin, caches must not
+				// serve it to other origins
+				header.Add("Vary", "Origin")
+			}
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" || !p.AllowOrigin(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if p.allowAll() && !p.AllowCredentials {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if len(p.ExposedHeaders) > 0 {
+					header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			requested := splitList(r.Header.Get("Access-Control-Request-Headers"))
+			if !p.allowMethod(method) || !p.allowHeaders(requested) {
+				header.Del("Access-Control-Allow-Origin")
+				header.Del("Access-Control-Allow-Credentials")
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			header.Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+			if len(p.AllowedHeaders) > 0 {
+				header.Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+			}
+			if p.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// splitList splits a comma separated header value.
+func splitList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
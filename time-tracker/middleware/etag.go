@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/hlog"
+)
+
+// NewETag returns middleware that sets weak ETags on GET and HEAD requests
+// and answers them with 304 if the If-None-Match header holds the current
+// tag. tag computes the tag of the response to a request without building
+// the response, which is what makes 304s cheap. If it returns an empty tag or
+// fails, the request is served without an ETag.
+func NewETag(tag func(r *http.Request) (string, error)) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				h.ServeHTTP(w, r)
+				return
+			}
+			t, err := tag(r)
+			if err != nil {
+				hlog.FromRequest(r).Error().Err(err).Msg("failed to compute etag")
+			}
+			if t == "" || err != nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+			etag := `W/"` + t + `"`
+			w.Header().Set("ETag", etag)
+			if matchETag(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			h.ServeHTTP(&etagWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// matchETag reports whether the If-None-Match header matches etag. The
+// comparison is weak, W/"x" matches "x".
+func matchETag(header, etag string) bool {
+	for _, t := range splitList(header) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter removes the ETag of responses other than 200, it is the tag
+// of the successful response.
+type etagWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	if code != http.StatusOK {
+		ew.Header().Del("ETag")
+	}
+	ew.ResponseWriter.WriteHeader(code)
+}
+
+func (ew *etagWriter) Write(p []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	return ew.ResponseWriter.Write(p)
+}
+
+// Flush keeps the flusher of the wrapped writer.
+func (ew *etagWriter) Flush() {
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/fgrimme/time-tracker/time-tracker/api"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+	"github.com/rs/zerolog/hlog"
+)
+
+// IdempotencyKeyHeader is the header of requests carrying an idempotency key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxKeyLength is the maximum length of idempotency keys.
+const maxKeyLength = 255
+
+// IdempotencyStore stores the responses of requests by idempotency key.
+type IdempotencyStore interface {
+	// ReserveKey reserves the key for a request with the hash. It returns
+	// the response stored for the key if the request was made before,
+	// store.ErrKeyMismatch if the key was used for a request with another
+	// hash and store.ErrKeyInUse if the request is still in progress.
+	ReserveKey(ctx context.Context, key store.IdempotencyKey, hash string) (*store.StoredResponse, error)
+	// SaveResponse stores the response of the request the key is reserved
+	// for.
+	SaveResponse(ctx context.Context, key store.IdempotencyKey, resp store.StoredResponse) error
+	// ReleaseKey removes the reservation of a key whose request has no
+	// response stored, so the request can be retried.
+	ReleaseKey(ctx context.Context, key store.IdempotencyKey) error
+}
+
+// NewIdempotency returns middleware that makes POST, PUT, PATCH and DELETE
+// requests with an Idempotency-Key header safe to retry. Keys are scoped by
+// the actor of the request returned by actor and by the method and path, so
+// clients cannot collide with or replay the keys of others. The response of
+// the first request with a key is stored and replayed to requests with the
+// same key, query and body in the scope. Requests with the same key but a
+// different query or body are rejected with 422, requests made while the
+// first one is in progress with 409. Server errors are not stored, the
+// request is executed again on retry.
+func NewIdempotency(s IdempotencyStore, actor func(r *http.Request) uint64) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(IdempotencyKeyHeader)
+			if header == "" || !mutating(r.Method) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			logger := hlog.FromRequest(r).With().Str("idempotency_key", header).Logger()
+			if !validKey(header) {
+				logger.Error().Msg("bad request: invalid idempotency key")
+				writeAPIError(w, r, "bad_request", http.StatusBadRequest)
+				return
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				logger.Error().Err(err).Msg("bad request")
+				writeAPIError(w, r, "bad_request", http.StatusBadRequest)
+				return
+			}
+			r.Body.Close()
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			key := store.IdempotencyKey{ActorID: actor(r), Method: r.Method, Path: r.URL.EscapedPath(), Key: header}
+
+			stored, err := s.ReserveKey(r.Context(), key, requestHash(r, body))
+			switch err {
+			case nil:
+			case store.ErrKeyMismatch:
+				logger.Debug().Msg("idempotency key reused for another request")
+				writeAPIError(w, r, "idempotency_key_mismatch", http.StatusUnprocessableEntity)
+				return
+			case store.ErrKeyInUse:
+				logger.Debug().Msg("idempotency key in use")
+				writeAPIError(w, r, "idempotency_key_in_use", http.StatusConflict)
+				return
+			default:
+				logger.Error().Err(err).Msg("failed to reserve idempotency key")
+				writeAPIError(w, r, "internal_error", http.StatusInternalServerError)
+				return
+			}
+			if stored != nil {
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			saved := false
+			defer func() {
+				// release the key if the handler failed or panicked so
+				// the request can be retried
+				if saved {
+					return
+				}
+				if err := s.ReleaseKey(context.Background(), key); err != nil {
+					logger.Error().Err(err).Msg("failed to release idempotency key")
+				}
+			}()
+			h.ServeHTTP(rec, r)
+			if rec.status >= 500 {
+				return
+			}
+			resp := store.StoredResponse{
+				StatusCode:  rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			}
+			if err := s.SaveResponse(context.Background(), key, resp); err != nil {
+				logger.Error().Err(err).Msg("failed to store response of idempotency key")
+				return
+			}
+			saved = true
+		})
+	}
+}
+
+// mutating reports whether requests with the method change resources.
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// validKey reports whether a key consists of up to 255 printable ASCII
+// characters.
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestHash returns the hex encoded SHA-256 hash of the method, URL and
+// body of a request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeAPIError writes an error in the JSON format of the API with the
+// message in the language accepted by the client.
+func writeAPIError(w http.ResponseWriter, r *http.Request, code string, status int) {
+	msg := locale.Match(r.Header.Get("Accept-Language")).Message(code)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&api.Error{Err: code, Message: msg})
+}
+
+// responseRecorder passes a response on and keeps its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status, rec.wroteHeader = status, true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
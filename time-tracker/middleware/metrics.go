@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zenazn/goji/web/mutil"
+)
+
+// NewMetrics returns middleware that counts requests and observes their
+// latency by method, route and status. Routes are the path templates of the
+// router, so requests to /records/1 and /records/2 share a route. The
+// collectors are registered with reg.
+func NewMetrics(reg prometheus.Registerer) (Middleware, error) {
+	labels := []string{"method", "route", "status"}
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Number of HTTP requests by method, route and status.",
+	}, labels)
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, labels)
+	for _, c := range []prometheus.Collector{requests, latency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			// the wrapper keeps the flusher and hijacker of event streams
+			lw := mutil.WrapWriter(w)
+			h.ServeHTTP(lw, r)
+
+			status := lw.Status()
+			if status == 0 {
+				// nothing was written, which is answered with 200, or
+				// the connection was hijacked by a WebSocket
+				status = http.StatusOK
+			}
+			values := []string{r.Method, routeTemplate(r), strconv.Itoa(status)}
+			requests.WithLabelValues(values...).Inc()
+			latency.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+		})
+	}, nil
+}
+
+// routeTemplate returns the path template of the route matching the request
+// or "unmatched".
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unmatched"
+}
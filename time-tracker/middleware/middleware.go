@@ -23,6 +23,21 @@ func Use(h http.Handler, mws ...Middleware) http.Handler {
 	return h
 }
 
+// NewToggle returns middleware that applies m only while enabled returns
+// true, so m can be switched on and off at runtime.
+func NewToggle(enabled func() bool, m Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		on := m(h)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enabled() {
+				on.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
 // NewRecoverHandler produces middleware for use as the last request handler
 // in order to avoid the service completely crashing when there's a runtime
 // panic.
@@ -40,17 +55,6 @@ func NewRecoverHandler() Middleware {
 	}
 }
 
-func NewCORSHandler() Middleware {
-	return func(h http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Headers", "X-Requested-With")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-			h.ServeHTTP(w, r)
-		})
-	}
-}
-
 // NewContextLog returns middleware that adds logger to request context.
 func NewContextLog(logger zerolog.Logger) []Middleware {
 	var mw []Middleware
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+)
+
+// RateLimit is a token bucket. It holds up to Burst tokens and is refilled
+// with Rate tokens per second, every request takes a token.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether requests are limited.
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Validate returns an error if the limit is invalid.
+func (l RateLimit) Validate() error {
+	if l.Rate < 0 || l.Burst < 0 {
+		return fmt.Errorf("rate and burst must not be negative, got %g and %d", l.Rate, l.Burst)
+	}
+	if l.Rate > 0 && l.Burst < 1 {
+		return fmt.Errorf("burst must be at least 1, got %d", l.Burst)
+	}
+	return nil
+}
+
+// refill returns the tokens of a bucket which had tokens elapsed ago.
+func (l RateLimit) refill(tokens float64, elapsed time.Duration) float64 {
+	return math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate)
+}
+
+// RateLimits are the limits of requests which read, GET and HEAD, and those
+// which write, all others.
+type RateLimits struct {
+	Read  RateLimit
+	Write RateLimit
+	// IPHeader is the header holding the client IP set by a proxy, e.g.
+	// X-Real-IP. The last address of the header, the one added by the
+	// proxy, is used. The remote address is used if it is empty.
+	IPHeader string
+}
+
+// Limiter takes tokens from buckets.
+type Limiter interface {
+	// TakeToken takes a token from the bucket of key, holding up to burst
+	// tokens refilled with rate tokens per second, if it has one and
+	// returns the tokens left.
+	TakeToken(ctx context.Context, key string, rate float64, burst int) (ok bool, tokens float64, err error)
+}
+
+// NewRateLimit returns middleware that limits the requests of each client to
+// the limits returned by limits. Clients are told their limit by RateLimit-*
+// headers. Requests exceeding it are rejected with 429 and a Retry-After
+// header. Requests pass if the limiter fails, a failing limiter must not take
+// the service down. key returns the bucket of the client of a request.
+func NewRateLimit(l Limiter, limits func() RateLimits, key func(r *http.Request, ipHeader string) string) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			all := limits()
+			limit, class := all.Write, "write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				limit, class = all.Read, "read"
+			}
+			if !limit.Enabled() {
+				h.ServeHTTP(w, r)
+				return
+			}
+			ok, tokens, err := l.TakeToken(r.Context(), class+":"+key(r, all.IPHeader), limit.Rate, limit.Burst)
+			if err != nil {
+				hlog.FromRequest(r).Error().Err(err).Msg("failed to take rate limit token")
+				h.ServeHTTP(w, r)
+				return
+			}
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			header.Set("RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+			header.Set("RateLimit-Reset", strconv.Itoa(seconds((float64(limit.Burst)-tokens)/limit.Rate)))
+			if !ok {
+				header.Set("Retry-After", strconv.Itoa(seconds((1-tokens)/limit.Rate)))
+				writeAPIError(w, r, "rate_limited", http.StatusTooManyRequests)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds s up to whole seconds.
+func seconds(s float64) int {
+	return int(math.Ceil(math.Max(0, s)))
+}
+
+// MemoryLimiter keeps buckets in memory. The limits hold per instance of the
+// service.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // time the bucket is full again
+}
+
+// sweepInterval is the interval full buckets are removed in.
+const sweepInterval = time.Minute
+
+// NewMemoryLimiter returns a Limiter keeping buckets in memory.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: map[string]*bucket{}}
+}
+
+// TakeToken takes a token from the bucket of key.
+func (m *MemoryLimiter) TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	limit := RateLimit{Rate: rate, Burst: burst}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.swept) > sweepInterval {
+		// full buckets are like new ones
+		for k, b := range m.buckets {
+			if !now.Before(b.full) {
+				delete(m.buckets, k)
+			}
+		}
+		m.swept = now
+	}
+
+	b, found := m.buckets[key]
+	if !found {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.tokens = limit.refill(b.tokens, now.Sub(b.last))
+	b.last = now
+	ok := b.tokens >= 1
+	if ok {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / limit.Rate * float64(time.Second)))
+	return ok, b.tokens, nil
+}
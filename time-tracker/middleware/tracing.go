@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/hlog"
+	"github.com/zenazn/goji/web/mutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/fgrimme/time-tracker/time-tracker/middleware")
+
+// NewTracing returns middleware that starts a server span for every request,
+// continuing the trace of the traceparent header. The span is named by the
+// method and route. The trace id is added to the request's logger and the
+// request id to the span, so logs and traces of a request can be linked. It
+// must be used inside the middleware of NewContextLog.
+func NewTracing() Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			route := routeTemplate(r)
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", route, r)...))
+			defer span.End()
+
+			if id, ok := hlog.IDFromRequest(r); ok {
+				span.SetAttributes(attribute.String("request.id", id.String()))
+			}
+			if sc := span.SpanContext(); sc.IsValid() {
+				hlog.FromRequest(r).UpdateContext(func(c zerolog.Context) zerolog.Context {
+					return c.Str("trace_id", sc.TraceID().String())
+				})
+			}
+
+			lw := mutil.WrapWriter(w)
+			h.ServeHTTP(lw, r.WithContext(ctx))
+			status := lw.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(status))
+		})
+	}
+}
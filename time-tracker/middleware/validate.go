@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/fgrimme/time-tracker/time-tracker/openapi"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/hlog"
+)
+
+// NewRequestValidator returns middleware that rejects requests which do not
+// conform to the OpenAPI document with a bad request error. Requests to
+// undocumented operations are passed on.
+func NewRequestValidator(spec *openapi.Spec) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+			tpl, err := route.GetPathTemplate()
+			if err != nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+			op, ok := spec.Operation(openapi.PathTemplate(tpl), r.Method)
+			if !ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+			if err := spec.ValidateRequest(op, r, mux.Vars(r)); err != nil {
+				// like all bad requests, the reason is logged but hidden
+				hlog.FromRequest(r).Error().Err(err).Msg("bad request")
+				writeAPIError(w, r, "bad_request", http.StatusBadRequest)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+// Package openapi validates HTTP requests and responses against an OpenAPI 3
+// document. Only the subset of OpenAPI used to describe the time tracker API
+// is supported: JSON bodies, path and query parameters and schemas with
+// types, formats, enums, patterns, minimums, required and additional
+// properties and references to the schema components.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Spec is an OpenAPI 3 document.
+type Spec struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info holds the metadata of the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations on a path by lower case HTTP method.
+type PathItem map[string]*Operation
+
+// Components holds the reusable schemas of the document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation describes an API operation on a path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter describes a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request.
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes the response with a status code.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body with a content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Load parses an OpenAPI document and checks that all references resolve.
+func Load(data []byte) (*Spec, error) {
+	var s Spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", s.OpenAPI)
+	}
+	for path, item := range s.Paths {
+		for method, op := range item {
+			schemas := op.schemas()
+			for _, schema := range schemas {
+				if err := s.checkRefs(schema); err != nil {
+					return nil, fmt.Errorf("%s %s: %v", strings.ToUpper(method), path, err)
+				}
+			}
+		}
+	}
+	for name, schema := range s.Components.Schemas {
+		if err := s.checkRefs(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %v", name, err)
+		}
+	}
+	return &s, nil
+}
+
+// Operation returns the operation of the method on the path template, e.g.
+// "/records/{id}".
+func (s *Spec) Operation(path, method string) (*Operation, bool) {
+	op, ok := s.Paths[path][strings.ToLower(method)]
+	return op, ok
+}
+
+// ValidateRequest validates the parameters and body of a request to the
+// operation. The path parameters are given by name. The body is read and
+// replaced, so it can be read again by the handler.
+func (s *Spec) ValidateRequest(op *Operation, r *http.Request, vars map[string]string) error {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			if v, ok := vars[p.Name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = query[p.Name]
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			if p.Required {
+				return fmt.Errorf("missing %s parameter %s", p.In, p.Name)
+			}
+			continue
+		}
+		for _, v := range values {
+			if err := s.validateParameter(p.Schema, v); err != nil {
+				return fmt.Errorf("%s parameter %s: %v", p.In, p.Name, err)
+			}
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return errors.New("missing request body")
+		}
+		return nil
+	}
+	mt, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return errors.New("request body is not JSON")
+	}
+	return s.ValidateJSON(mt.Schema, body)
+}
+
+// ValidateResponse validates the status code, content type and body of a
+// response of the operation.
+func (s *Spec) ValidateResponse(op *Operation, status int, contentType string, body []byte) error {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("undocumented status %d", status)
+	}
+	if len(resp.Content) == 0 {
+		if len(bytes.TrimSpace(body)) != 0 {
+			return fmt.Errorf("undocumented body of status %d", status)
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	mt, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %s of status %d", mediaType, status)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+	return s.ValidateJSON(mt.Schema, body)
+}
+
+// ValidateJSON validates a JSON document against the schema.
+func (s *Spec) ValidateJSON(schema *Schema, data []byte) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return err
+	}
+	return s.validate(schema, v, "")
+}
+
+// schemas returns the schemas of the parameters and bodies of an operation.
+func (op *Operation) schemas() []*Schema {
+	var schemas []*Schema
+	for _, p := range op.Parameters {
+		schemas = append(schemas, p.Schema)
+	}
+	if op.RequestBody != nil {
+		for _, mt := range op.RequestBody.Content {
+			schemas = append(schemas, mt.Schema)
+		}
+	}
+	for _, resp := range op.Responses {
+		for _, mt := range resp.Content {
+			schemas = append(schemas, mt.Schema)
+		}
+	}
+	return schemas
+}
+
+// PathTemplate converts a gorilla/mux path template to an OpenAPI path
+// template by removing the patterns of variables, e.g. "/records/{id:[0-9]+}"
+// to "/records/{id}".
+func PathTemplate(tpl string) string {
+	var b strings.Builder
+	depth := 0
+	skip := false
+	for _, c := range tpl {
+		switch {
+		case c == '{':
+			depth++
+			if depth > 1 {
+				continue
+			}
+		case c == '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			skip = false
+		case c == ':' && depth == 1:
+			skip = true
+		}
+		if !skip {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
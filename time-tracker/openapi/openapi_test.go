@@ -0,0 +1,90 @@
+package openapi_test
+
+import (
+	"testing"
+
+	"github.com/fgrimme/time-tracker/time-tracker/openapi"
+)
+
+const testSpec = `{
+	"openapi": "3.0.3",
+	"info": {"title": "test", "version": "1"},
+	"paths": {},
+	"components": {"schemas": {
+		"Record": {
+			"type": "object",
+			"properties": {
+				"id": {"type": "integer", "minimum": 1},
+				"name": {"type": "string", "pattern": "^[a-z]+$"},
+				"state": {"type": "string", "enum": ["draft", "done"]},
+				"created_at": {"type": "string", "format": "date-time"},
+				"tags": {"type": "array", "items": {"type": "string"}, "nullable": true}
+			},
+			"required": ["id"],
+			"additionalProperties": false
+		}
+	}}
+}`
+
+func TestValidateJSON(t *testing.T) {
+	spec, err := openapi.Load([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := &openapi.Schema{Ref: "#/components/schemas/Record"}
+
+	tests := []struct {
+		d string // description of test case
+		j string // JSON document
+		e string // expected error
+	}{
+		{d: "expect valid document", j: `{"id":1,"name":"foo","state":"done","created_at":"2020-01-01T00:00:00Z","tags":["a"]}`},
+		{d: "expect null to be allowed if nullable", j: `{"id":1,"tags":null}`},
+		{d: "expect missing property", j: `{"name":"foo"}`, e: "id is required"},
+		{d: "expect unknown property", j: `{"id":1,"stop":2}`, e: "stop is unknown"},
+		{d: "expect integer", j: `{"id":1.5}`, e: "id must be an integer"},
+		{d: "expect minimum", j: `{"id":0}`, e: "id must be at least 1"},
+		{d: "expect pattern", j: `{"id":1,"name":"Foo"}`, e: "name must match ^[a-z]+$"},
+		{d: "expect enum", j: `{"id":1,"state":"open"}`, e: "state must be one of draft, done"},
+		{d: "expect date-time", j: `{"id":1,"created_at":"01 Jan 2020"}`, e: "created_at must be a RFC 3339 date-time"},
+		{d: "expect item type", j: `{"id":1,"tags":[1]}`, e: "tags[0] must be a string"},
+		{d: "expect object", j: `[]`, e: "value must be an object"},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			err := spec.ValidateJSON(schema, []byte(tt.j))
+			var got string
+			if err != nil {
+				got = err.Error()
+			}
+			if want := tt.e; want != got {
+				t.Errorf("want error %q got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := openapi.Load([]byte(`{"openapi":"2.0"}`)); err == nil {
+		t.Error("want error for unsupported version")
+	}
+	spec := `{"openapi":"3.0.3","paths":{"/":{"get":{"responses":{"200":{"description":"ok","content":{"application/json":{"schema":{"$ref":"#/components/schemas/Missing"}}}}}}}}}`
+	if _, err := openapi.Load([]byte(spec)); err == nil {
+		t.Error("want error for unresolved reference")
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	tests := map[string]string{
+		"/records":             "/records",
+		"/records/{id:[0-9]+}": "/records/{id}",
+		"/timesheets/{id:[0-9]+}/{action:(?:submit|approve)}": "/timesheets/{id}/{action}",
+		"/x/{n:[0-9]{2}}/y": "/x/{n}/y",
+	}
+	for tpl, want := range tests {
+		if got := openapi.PathTemplate(tpl); want != got {
+			t.Errorf("want path template %s got %s", want, got)
+		}
+	}
+}
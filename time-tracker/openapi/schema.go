@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refPrefix is the prefix of references to the schema components.
+const refPrefix = "#/components/schemas/"
+
+// resolve returns the schema a reference refers to.
+func (s *Spec) resolve(schema *Schema) (*Schema, error) {
+	for schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, refPrefix)
+		ref, ok := s.Components.Schemas[name]
+		if !ok || name == schema.Ref {
+			return nil, fmt.Errorf("unresolved reference %s", schema.Ref)
+		}
+		schema = ref
+	}
+	return schema, nil
+}
+
+// checkRefs checks that all references in the schema resolve.
+func (s *Spec) checkRefs(schema *Schema) error {
+	if schema == nil {
+		return errors.New("missing schema")
+	}
+	if schema.Ref != "" {
+		_, err := s.resolve(schema)
+		return err
+	}
+	for _, p := range schema.Properties {
+		if err := s.checkRefs(p); err != nil {
+			return err
+		}
+	}
+	if schema.Items != nil {
+		return s.checkRefs(schema.Items)
+	}
+	if schema.Pattern != "" {
+		if _, err := regexp.Compile(schema.Pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateParameter validates the string value of a path or query parameter.
+func (s *Spec) validateParameter(schema *Schema, value string) error {
+	schema, err := s.resolve(schema)
+	if err != nil {
+		return err
+	}
+	var v interface{} = value
+	switch schema.Type {
+	case "integer", "number":
+		v = json.Number(value)
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("not a boolean")
+		}
+		v = b
+	}
+	return s.validate(schema, v, "")
+}
+
+// validate validates a value decoded from JSON with numbers as json.Number.
+// The path of the value is used in errors.
+func (s *Spec) validate(schema *Schema, v interface{}, path string) error {
+	schema, err := s.resolve(schema)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		if schema.Nullable {
+			return nil
+		}
+		return fieldError(path, "must not be null")
+	}
+	switch schema.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fieldError(path, "must be an object")
+		}
+		return s.validateObject(schema, obj, path)
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fieldError(path, "must be an array")
+		}
+		for i, item := range arr {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fieldError(path, "must be a string")
+		}
+		return validateString(schema, str, path)
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fieldError(path, "must be a number")
+		}
+		return validateNumber(schema, n, path)
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fieldError(path, "must be a boolean")
+		}
+		return nil
+	case "":
+		return nil // any value
+	}
+	return fieldError(path, "has unknown type "+schema.Type)
+}
+
+func (s *Spec) validateObject(schema *Schema, obj map[string]interface{}, path string) error {
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			return fieldError(join(path, name), "is required")
+		}
+	}
+	// iterate in order so errors are deterministic
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, ok := schema.Properties[name]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				return fieldError(join(path, name), "is unknown")
+			}
+			continue
+		}
+		if err := s.validate(p, obj[name], join(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(schema *Schema, str string, path string) error {
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, e := range schema.Enum {
+			found = found || e == str
+		}
+		if !found {
+			return fieldError(path, fmt.Sprintf("must be one of %s", strings.Join(schema.Enum, ", ")))
+		}
+	}
+	if schema.Pattern != "" {
+		if !regexp.MustCompile(schema.Pattern).MatchString(str) {
+			return fieldError(path, "must match "+schema.Pattern)
+		}
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return fieldError(path, "must be a RFC 3339 date-time")
+		}
+	}
+	return nil
+}
+
+func validateNumber(schema *Schema, n json.Number, path string) error {
+	f, err := n.Float64()
+	if err != nil {
+		return fieldError(path, "must be a number")
+	}
+	if schema.Type == "integer" {
+		if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+			if _, err := strconv.ParseUint(n.String(), 10, 64); err != nil {
+				return fieldError(path, "must be an integer")
+			}
+		}
+	}
+	if schema.Minimum != nil && f < *schema.Minimum {
+		return fieldError(path, fmt.Sprintf("must be at least %v", *schema.Minimum))
+	}
+	if schema.Maximum != nil && f > *schema.Maximum {
+		return fieldError(path, fmt.Sprintf("must be at most %v", *schema.Maximum))
+	}
+	return nil
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func fieldError(path, msg string) error {
+	if path == "" {
+		return errors.New("value " + msg)
+	}
+	return fmt.Errorf("%s %s", path, msg)
+}
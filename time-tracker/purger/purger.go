@@ -0,0 +1,100 @@
+package purger
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// purgeStore permanently deletes records from the trash.
+type purgeStore interface {
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PurgeFunc adapts a function to the store of a Purger, e.g. to purge
+// entries other than the records in the trash.
+type PurgeFunc func(ctx context.Context, before time.Time) (int64, error)
+
+// Purge calls f(ctx, before).
+func (f PurgeFunc) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return f(ctx, before)
+}
+
+// Purger periodically deletes records permanently which have been in the
+// trash for longer than the retention period. The logger of a purger of other
+// entries should name them.
+type Purger struct {
+	store     purgeStore
+	retention time.Duration
+	interval  time.Duration
+	logger    zerolog.Logger
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New returns a Purger which purges records deleted longer than retention
+// ago every interval.
+func New(s purgeStore, retention, interval time.Duration, logger zerolog.Logger) *Purger {
+	return &Purger{
+		store:     s,
+		retention: retention,
+		interval:  interval,
+		logger:    logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Run purges records until Shutdown is called. It purges once on start.
+func (p *Purger) Run() {
+	defer close(p.done)
+	p.logger.Info().Msgf("purging entries older than %s every %s", p.retention, p.interval)
+
+	// a running purge is canceled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	n, err := p.store.Purge(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logger.Error().Err(err).Msg("failed to purge")
+		}
+		return
+	}
+	if n > 0 {
+		p.logger.Info().Int64("count", n).Msg("purged")
+	}
+}
+
+// Shutdown stops the purger and waits for a running purge to finish or to be
+// canceled, at the latest until ctx is done.
+func (p *Purger) Shutdown(ctx context.Context) {
+	p.logger.Info().Msg("shutting down purger")
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.logger.Error().Err(ctx.Err()).Msg("purger shutdown error")
+	}
+}
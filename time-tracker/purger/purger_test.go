@@ -0,0 +1,61 @@
+package purger
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// records the cut-off times the store was called with.
+type mockPurgeStore struct {
+	sync.Mutex
+	calls []time.Time
+}
+
+func (s *mockPurgeStore) Purge(ctx context.Context, before time.Time) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.calls = append(s.calls, before)
+	return 1, nil
+}
+
+func (s *mockPurgeStore) count() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.calls)
+}
+
+func TestPurger(t *testing.T) {
+	s := &mockPurgeStore{}
+	retention := 24 * time.Hour
+	p := New(s, retention, 10*time.Millisecond, zerolog.Nop())
+
+	start := time.Now()
+	go p.Run()
+	// wait for a few purges
+	for i := 0; s.count() < 3; i++ {
+		if i > 100 {
+			t.Fatalf("want at least 3 purges got %d", s.count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	p.Shutdown(ctx)
+	if ctx.Err() != nil {
+		t.Fatalf("want purger to stop before the shutdown deadline")
+	}
+
+	n := s.count()
+	time.Sleep(30 * time.Millisecond)
+	if got := s.count(); got != n {
+		t.Errorf("want no purges after shutdown got %d", got-n)
+	}
+	if cutoff := s.calls[0]; cutoff.After(start.Add(-retention + time.Second)) {
+		t.Errorf("want records deleted before %v to be purged got %v", start.Add(-retention), cutoff)
+	}
+}
@@ -0,0 +1,17 @@
+package store
+
+import "errors"
+
+// Errors returned by the datastore.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrRecordLocked  = errors.New("time record is locked")
+	ErrInvoiceVoided = errors.New("invoice is voided")
+	ErrTransition    = errors.New("invalid timesheet state transition")
+	ErrForbidden     = errors.New("actor is not allowed to change the timesheet")
+	ErrTimerRunning  = errors.New("timer is running already")
+	ErrInvalidStop   = errors.New("stop time is before start time")
+	ErrRecordExists  = errors.New("record with the uuid exists already")
+	ErrKeyMismatch   = errors.New("idempotency key is used by another request")
+	ErrKeyInUse      = errors.New("idempotency key is used by a request in progress")
+)
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaVersion is the version of the database schema the store requires, see
+// the table schema_version of initdb/schema.sql.
+const SchemaVersion = 7
+
+// Ping checks that the database is reachable.
+func (ts *TimeRecordStore) Ping(ctx context.Context) error {
+	return ts.db.Ping(ctx)
+}
+
+// CheckSchema returns an error if the version of the database schema is not
+// SchemaVersion, e.g. because the schema is not set up yet.
+func (ts *TimeRecordStore) CheckSchema(ctx context.Context) error {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	var version int
+	err := db.QueryRowContext(ctx, `
+  SELECT COALESCE(max(version), 0)
+  FROM schema_version
+  `).Scan(&version)
+	if isUndefinedTable(err) {
+		return fmt.Errorf("schema version %d required, the database has no schema version", SchemaVersion)
+	}
+	if err != nil {
+		return err
+	}
+	if version != SchemaVersion {
+		return fmt.Errorf("schema version %d required, the database has version %d", SchemaVersion, version)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Actions recorded in the history of time records.
+const (
+	actionCreate  = "create"
+	actionUpdate  = "update"
+	actionDelete  = "delete"
+	actionRestore = "restore"
+	actionPurge   = "purge"
+)
+
+type auditKey struct{}
+
+// Audit identifies who changed time records in which request.
+type Audit struct {
+	ActorID   uint64 // 0 if unknown, the record's user is recorded then
+	RequestID string
+}
+
+// WithAudit returns a context carrying the audit information which is
+// recorded with every change made with the context.
+func WithAudit(ctx context.Context, a Audit) context.Context {
+	return context.WithValue(ctx, auditKey{}, a)
+}
+
+func auditFromContext(ctx context.Context) Audit {
+	a, _ := ctx.Value(auditKey{}).(Audit)
+	return a
+}
+
+// insertHistory appends a change of a record to the history. It must be
+// called in the transaction changing the record, so a change is never made
+// without being recorded. Values are the JSON representations of the table
+// row before and after the change, nil if there is none.
+func insertHistory(ctx context.Context, tx *sql.Tx, action string, recordID, userID uint64, before, after []byte) error {
+	a := auditFromContext(ctx)
+	actorID := a.ActorID
+	if actorID == 0 {
+		actorID = userID
+	}
+	_, err := tx.ExecContext(ctx, `
+  INSERT INTO record_history(
+    record_id,
+	user_id,
+	actor_id,
+	action,
+	request_id,
+	before,
+	after)
+  VALUES($1,$2,$3,$4,$5,$6,$7)
+  `,
+		recordID,
+		userID,
+		actorID,
+		action,
+		a.RequestID,
+		jsonValue(before),
+		jsonValue(after))
+	return err
+}
+
+// jsonValue returns a nullable JSON column value. JSON is passed as string
+// since lib/pq sends byte slices in binary format.
+func jsonValue(v []byte) sql.NullString {
+	return sql.NullString{String: string(v), Valid: v != nil}
+}
+
+// changeColumns are the columns of changes selected from record_history.
+const changeColumns = `
+	id,
+	record_id,
+	user_id,
+	actor_id,
+	action,
+	changed_at,
+	request_id,
+	before,
+	after`
+
+// History returns the changes of the record with the given id, oldest first.
+func (ts *TimeRecordStore) History(ctx context.Context, recordID uint64) (_ []Change, err error) {
+	ctx, end := ts.instrument(ctx, "History")
+	defer end(&err)
+	query := `
+  SELECT` + changeColumns + `
+  FROM record_history
+  WHERE record_id = $1
+  ORDER BY id;
+  `
+	return ts.queryChanges(ctx, query, recordID)
+}
+
+// Changes returns at most limit changes of the records of a user which were
+// made after the change with the id since, oldest first. Pass the id of the
+// last change received as since to get the following changes.
+func (ts *TimeRecordStore) Changes(ctx context.Context, userID, since uint64, limit int) (_ []Change, err error) {
+	ctx, end := ts.instrument(ctx, "Changes")
+	defer end(&err)
+	query := `
+  SELECT` + changeColumns + `
+  FROM record_history
+  WHERE user_id = $1
+  AND id > $2
+  ORDER BY id
+  LIMIT $3;
+  `
+	return ts.queryChanges(ctx, query, userID, since, limit)
+}
+
+// queryChanges runs a query selecting changeColumns and returns the scanned
+// changes.
+func (ts *TimeRecordStore) queryChanges(ctx context.Context, query string, args ...interface{}) ([]Change, error) {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]Change, 0)
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(
+			&c.ChangeID,
+			&c.RecordID,
+			&c.UserID,
+			&c.ActorID,
+			&c.Action,
+			&c.Changed,
+			&c.RequestID,
+			&c.Before,
+			&c.After); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdempotencyKey is an idempotency key in the scope it is sent in, the
+// requests of an actor to a method and path. Requests without an actor share
+// the scope of actor 0.
+type IdempotencyKey struct {
+	ActorID uint64
+	Method  string
+	Path    string
+	Key     string
+}
+
+// StoredResponse is a response stored for an idempotency key.
+type StoredResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// keyLease is the time after which the reservation of an idempotency key
+// without a response is considered abandoned, e.g. by a crashed replica, and
+// can be taken over by a retry.
+const keyLease = time.Minute
+
+// ReserveKey reserves an idempotency key for a request with the given hash.
+// Returns the stored response if the key has one, ErrKeyMismatch if the key
+// was reserved for another request and ErrKeyInUse if the request is in
+// progress.
+func (ts *TimeRecordStore) ReserveKey(ctx context.Context, key IdempotencyKey, hash string) (_ *StoredResponse, err error) {
+	ctx, end := ts.instrument(ctx, "ReserveKey")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `
+  INSERT INTO idempotency_keys(
+    actor_id,
+    method,
+    path,
+    key,
+    request_hash)
+  VALUES($1,$2,$3,$4,$5)
+  ON CONFLICT (actor_id, method, path, key) DO UPDATE
+  SET request_hash = EXCLUDED.request_hash, created_at = now()
+  WHERE idempotency_keys.status_code IS NULL
+  AND idempotency_keys.created_at < now() - $6 * interval '1 second'
+  `, key.ActorID, key.Method, key.Path, key.Key, hash, keyLease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return nil, err
+	}
+
+	var (
+		storedHash string
+		status     sql.NullInt64
+		resp       StoredResponse
+	)
+	err = db.QueryRowContext(ctx, `
+  SELECT request_hash, status_code, content_type, body
+  FROM idempotency_keys
+  WHERE actor_id = $1 AND method = $2 AND path = $3 AND key = $4
+  `, key.ActorID, key.Method, key.Path, key.Key).Scan(&storedHash, &status, &resp.ContentType, &resp.Body)
+	switch {
+	case err == sql.ErrNoRows:
+		// released or purged since the insert, the retry tries again
+		return nil, ErrKeyInUse
+	case err != nil:
+		return nil, err
+	case storedHash != hash:
+		return nil, ErrKeyMismatch
+	case !status.Valid:
+		return nil, ErrKeyInUse
+	}
+	resp.StatusCode = int(status.Int64)
+	return &resp, nil
+}
+
+// SaveResponse stores the response of the request an idempotency key is
+// reserved for.
+func (ts *TimeRecordStore) SaveResponse(ctx context.Context, key IdempotencyKey, resp StoredResponse) (err error) {
+	ctx, end := ts.instrument(ctx, "SaveResponse")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, `
+  UPDATE idempotency_keys
+  SET status_code = $5, content_type = $6, body = $7
+  WHERE actor_id = $1 AND method = $2 AND path = $3 AND key = $4
+  `, key.ActorID, key.Method, key.Path, key.Key, resp.StatusCode, resp.ContentType, resp.Body)
+	return err
+}
+
+// ReleaseKey deletes the reservation of an idempotency key which has no
+// response stored.
+func (ts *TimeRecordStore) ReleaseKey(ctx context.Context, key IdempotencyKey) (err error) {
+	ctx, end := ts.instrument(ctx, "ReleaseKey")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, `
+  DELETE FROM idempotency_keys
+  WHERE actor_id = $1 AND method = $2 AND path = $3 AND key = $4
+  AND status_code IS NULL
+  `, key.ActorID, key.Method, key.Path, key.Key)
+	return err
+}
+
+// PurgeKeys deletes the idempotency keys reserved before the given time.
+// Returns the number of purged keys.
+func (ts *TimeRecordStore) PurgeKeys(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, end := ts.instrument(ctx, "PurgeKeys")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,365 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+// UnbilledRecords returns the billable records of the projects of a client
+// which started in the period [from, to) and are not billed yet, oldest
+// first. Deleted records are excluded.
+func (ts *TimeRecordStore) UnbilledRecords(ctx context.Context, clientID uint64, from, to time.Time) (_ []TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "UnbilledRecords")
+	defer end(&err)
+	query := `
+  SELECT` + recordColumns + `
+  FROM time_records
+  AS tr
+  JOIN projects AS p ON p.id = tr.project_id
+  WHERE p.client_id = $1
+  AND tr.billable
+  AND tr.invoice_id IS NULL
+  AND tr.deleted_at IS NULL
+  AND tr.start_time >= $2
+  AND tr.start_time < $3
+  ORDER BY tr.start_time, tr.id;
+  `
+	return ts.queryRecords(ctx, query, clientID, from, to)
+}
+
+// ClientRates returns the history of rates which may apply to records of the
+// projects of a client: the rates of the client, of its projects and of the
+// users who recorded time for its projects.
+func (ts *TimeRecordStore) ClientRates(ctx context.Context, clientID uint64) (_ []billing.Rate, err error) {
+	ctx, end := ts.instrument(ctx, "ClientRates")
+	defer end(&err)
+	query := `
+  SELECT
+    id,
+	scope,
+	scope_id,
+	amount::text,
+	currency,
+	effective_from
+  FROM rates
+  WHERE (scope = 'client' AND scope_id = $1)
+  OR (scope = 'project' AND scope_id IN (
+    SELECT id FROM projects WHERE client_id = $1))
+  OR (scope = 'user' AND scope_id IN (
+    SELECT tr.user_id FROM time_records AS tr
+    JOIN projects AS p ON p.id = tr.project_id
+    WHERE p.client_id = $1))
+  ORDER BY effective_from;
+  `
+	return ts.queryRates(ctx, query, clientID)
+}
+
+// CreateInvoice inserts a new invoice with a sequential number and locks its
+// records, which is recorded in their history. Invoice numbers have no gaps since a failed transaction also rolls
+// back the number counter. Returns ErrRecordLocked if any of the records has
+// been billed, deleted, made non-billable or changed since the version its
+// line is built from.
+func (ts *TimeRecordStore) CreateInvoice(ctx context.Context, inv Invoice) (_ *Invoice, err error) {
+	ctx, end := ts.instrument(ctx, "CreateInvoice")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	// the row lock on the counter serializes concurrent invoice creation
+	var number uint64
+	if err := tx.QueryRowContext(ctx, `
+  UPDATE invoice_numbers
+  SET last_number = last_number + 1
+  RETURNING last_number
+  `).Scan(&number); err != nil {
+		return nil, err
+	}
+
+	var id uint64
+	if err := tx.QueryRowContext(ctx, `
+  INSERT INTO invoices(
+    number,
+	client_id,
+	period_from,
+	period_to,
+	total_amount,
+	currency,
+	rounding_increment,
+	rounding_mode,
+	rounding_per)
+  VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)
+  RETURNING id
+  `,
+		number,
+		inv.ClientID,
+		inv.From,
+		inv.To,
+		inv.Total.Decimal(),
+		inv.Total.Currency,
+		inv.Rounding.Increment,
+		inv.Rounding.Mode,
+		inv.Rounding.Per).Scan(&id); err != nil {
+		return nil, err
+	}
+
+	for _, l := range inv.Lines {
+		// the start is copied from the record, the start of the line is the
+		// wall clock in the user's location and no point in time
+		if _, err := tx.ExecContext(ctx, `
+  INSERT INTO invoice_lines(
+    invoice_id,
+	record_id,
+	user_id,
+	name,
+	start_time,
+	start_time_loc,
+	duration,
+	billed_duration,
+	rate_amount,
+	amount)
+  SELECT $1, tr.id, $3, $4, tr.start_time, tr.start_time_loc, $5, $6, $7, $8
+  FROM time_records AS tr
+  WHERE tr.id = $2
+  `,
+			id,
+			l.RecordID,
+			l.UserID,
+			l.Name,
+			l.Duration,
+			l.Billed,
+			l.Rate.Decimal(),
+			l.Amount.Decimal()); err != nil {
+			return nil, err
+		}
+		var before []byte
+		err := tx.QueryRowContext(ctx, `
+  SELECT to_jsonb(tr)
+  FROM time_records AS tr
+  WHERE id = $1
+  AND invoice_id IS NULL
+  AND deleted_at IS NULL
+  AND billable
+  AND version = $2
+  FOR UPDATE
+  `, l.RecordID, l.Version).Scan(&before)
+		if err == sql.ErrNoRows {
+			return nil, ErrRecordLocked
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := setInvoice(ctx, tx, l.RecordID, sql.NullInt64{Int64: int64(id), Valid: true}, before); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ts.Invoice(ctx, id)
+}
+
+// Invoice returns the invoice with the given id including its lines. Returns
+// ErrNotFound if the invoice does not exist.
+func (ts *TimeRecordStore) Invoice(ctx context.Context, id uint64) (_ *Invoice, err error) {
+	ctx, end := ts.instrument(ctx, "Invoice")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	var inv Invoice
+	var total, currency, roundingMode, roundingPer string
+	var voided sql.NullTime
+	err = db.QueryRowContext(ctx, `
+  SELECT
+    i.id,
+	i.number,
+	i.client_id,
+	c.name,
+	i.period_from,
+	i.period_to,
+	i.created_at,
+	i.voided_at,
+	i.total_amount::text,
+	i.currency,
+	i.rounding_increment,
+	i.rounding_mode,
+	i.rounding_per
+  FROM invoices AS i
+  JOIN clients AS c ON c.id = i.client_id
+  WHERE i.id = $1
+  `, id).Scan(
+		&inv.InvoiceID,
+		&inv.Number,
+		&inv.ClientID,
+		&inv.ClientName,
+		&inv.From,
+		&inv.To,
+		&inv.Created,
+		&voided,
+		&total,
+		&currency,
+		&inv.Rounding.Increment,
+		&roundingMode,
+		&roundingPer)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if voided.Valid {
+		inv.Voided = &voided.Time
+	}
+	inv.Rounding.Mode = billing.RoundingMode(roundingMode)
+	inv.Rounding.Per = billing.RoundingScope(roundingPer)
+	if inv.Total, err = billing.ParseMoney(total, currency); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT
+    record_id,
+	user_id,
+	name,
+	start_time AT TIME ZONE start_time_loc,
+	start_time_loc,
+	duration,
+	billed_duration,
+	rate_amount::text,
+	amount::text
+  FROM invoice_lines
+  WHERE invoice_id = $1
+  ORDER BY start_time, record_id;
+  `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inv.Lines = make([]InvoiceLine, 0)
+	for rows.Next() {
+		var l InvoiceLine
+		var rate, amount string
+		if err := rows.Scan(
+			&l.RecordID,
+			&l.UserID,
+			&l.Name,
+			&l.Start,
+			&l.StartLoc,
+			&l.Duration,
+			&l.Billed,
+			&rate,
+			&amount); err != nil {
+			return nil, err
+		}
+		if l.Rate, err = billing.ParseMoney(rate, currency); err != nil {
+			return nil, err
+		}
+		if l.Amount, err = billing.ParseMoney(amount, currency); err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, l)
+	}
+	return &inv, rows.Err()
+}
+
+// VoidInvoice marks an invoice as voided and releases its records so they
+// can be changed and billed again. The releases are recorded in the history
+// of the records. The invoice and its number are kept.
+// Returns ErrNotFound if the invoice does not exist and ErrInvoiceVoided if it
+// has been voided already.
+func (ts *TimeRecordStore) VoidInvoice(ctx context.Context, id uint64) (_ *Invoice, err error) {
+	ctx, end := ts.instrument(ctx, "VoidInvoice")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	var voided sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+  SELECT voided_at FROM invoices WHERE id = $1 FOR UPDATE
+  `, id).Scan(&voided)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if voided.Valid {
+		return nil, ErrInvoiceVoided
+	}
+	if _, err := tx.ExecContext(ctx, `
+  UPDATE invoices SET voided_at = now() WHERE id = $1
+  `, id); err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+  SELECT id, to_jsonb(tr) FROM time_records AS tr WHERE invoice_id = $1 ORDER BY id FOR UPDATE
+  `, id)
+	if err != nil {
+		return nil, err
+	}
+	type billed struct {
+		id     uint64
+		before []byte
+	}
+	var recs []billed
+	for rows.Next() {
+		var rec billed
+		if err := rows.Scan(&rec.id, &rec.before); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		if err := setInvoice(ctx, tx, rec.id, sql.NullInt64{}, rec.before); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ts.Invoice(ctx, id)
+}
+
+// setInvoice sets the invoice of a record locked by the transaction, NULL
+// releases it, and records the change in the record's history. before is the
+// record read with the lock. The invoice is part of the record, a new version
+// tells clients polling or syncing the record.
+func setInvoice(ctx context.Context, tx *sql.Tx, recordID uint64, invoiceID sql.NullInt64, before []byte) error {
+	var userID uint64
+	var after []byte
+	err := tx.QueryRowContext(ctx, `
+  UPDATE time_records AS tr
+  SET invoice_id = $2, version = version + 1
+  WHERE id = $1
+  RETURNING user_id, to_jsonb(tr)
+  `, recordID, invoiceID).Scan(&userID, &after)
+	if err != nil {
+		return err
+	}
+	return insertHistory(ctx, tx, actionUpdate, recordID, userID, before, after)
+}
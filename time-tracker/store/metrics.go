@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// storeMetrics are the latencies and errors of the store's methods.
+type storeMetrics struct {
+	latency *prometheus.HistogramVec
+	errors  *prometheus.CounterVec
+}
+
+// RegisterMetrics registers the latencies and errors of the store's methods,
+// the connection pool statistics of its database and the number of running
+// timers with reg. Errors like ErrNotFound, which are answers rather than
+// failures, are not counted.
+func (ts *TimeRecordStore) RegisterMetrics(reg prometheus.Registerer) error {
+	m := &storeMetrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "store_query_duration_seconds",
+			Help:    "Latency of the time record store's methods.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "store_query_errors_total",
+			Help: "Number of failed calls of the time record store's methods.",
+		}, []string{"method"}),
+	}
+	running := &timerCollector{
+		ts: ts,
+		desc: prometheus.NewDesc("running_timers",
+			"Number of timers running.", nil, nil),
+	}
+	for _, c := range []prometheus.Collector{m.latency, m.errors, ts.db.Collector(), running} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	ts.metrics = m
+	return nil
+}
+
+// observe records the latency and error of a call of the method started at
+// start, see instrument.
+func (ts *TimeRecordStore) observe(method string, start time.Time, err error) {
+	if ts.metrics == nil {
+		return
+	}
+	ts.metrics.latency.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil && !expected(err) {
+		ts.metrics.errors.WithLabelValues(method).Inc()
+	}
+}
+
+// expected reports whether the error is returned by the store as an answer,
+// like ErrNotFound, rather than because of a failure.
+func expected(err error) bool {
+	switch err {
+	case ErrNotFound, ErrRecordLocked, ErrInvoiceVoided, ErrTransition, ErrForbidden,
+		ErrTimerRunning, ErrInvalidStop, ErrRecordExists, ErrKeyMismatch, ErrKeyInUse:
+		return true
+	}
+	return false
+}
+
+// timerCollector counts the running timers on every scrape.
+type timerCollector struct {
+	ts   *TimeRecordStore
+	desc *prometheus.Desc
+}
+
+func (c *timerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *timerCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := c.ts.db.RequestContext(context.Background())
+	defer cancel()
+	var n int64
+	err := c.ts.db.GetDB().QueryRowContext(ctx, `SELECT count(*) FROM timers`).Scan(&n)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
@@ -0,0 +1,209 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/database"
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+// testDSNEnv names the variable holding the DSN of a Postgres database
+// initialized with initdb/schema.sql, e.g. the database of docker-compose.
+// The tests of the store against Postgres are skipped if it is not set.
+const testDSNEnv = "TEST_TIME_REC_DB_DSN"
+
+// newTestStore returns a store on the test database, the database to set up
+// fixtures and a function to close it.
+func newTestStore(t *testing.T) (*store.TimeRecordStore, *sql.DB, func()) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	db, err := database.Connect("postgres", dsn, "time_record_db", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store.New(db), db.GetDB(), func() { db.Close() }
+}
+
+func TestCreateInvoiceStart(t *testing.T) {
+	ts, db, done := newTestStore(t)
+	defer done()
+	ctx := context.Background()
+
+	var clientID, projectID uint64
+	if err := db.QueryRow(`INSERT INTO clients(name) VALUES('Acme') RETURNING id`).Scan(&clientID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`INSERT INTO projects(client_id, name) VALUES($1, 'Web') RETURNING id`, clientID).Scan(&projectID); err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	// shortly after midnight in Berlin, the day before in UTC
+	start := time.Date(2020, time.January, 25, 0, 30, 0, 0, berlin)
+	if _, err := ts.Create(ctx, store.TimeRecord{
+		UserID:    42,
+		Name:      "late call",
+		Start:     start,
+		StartLoc:  "Europe/Berlin",
+		Stop:      start.Add(time.Hour),
+		StopLoc:   "Europe/Berlin",
+		Duration:  3600,
+		ProjectID: projectID,
+		Billable:  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// lines are built from the records as the store reads them
+	recs, err := ts.UnbilledRecords(ctx, clientID, start.Add(-time.Hour), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("want 1 unbilled record got %d", len(recs))
+	}
+	r := recs[0]
+	rate, _ := billing.ParseMoney("80", "EUR")
+	inv, err := ts.CreateInvoice(ctx, store.Invoice{
+		ClientID: clientID,
+		From:     start.Add(-time.Hour),
+		To:       start.Add(time.Hour),
+		Total:    rate,
+		Lines: []store.InvoiceLine{{
+			RecordID: r.RecordID,
+			UserID:   r.UserID,
+			Name:     r.Name,
+			Start:    r.Start,
+			StartLoc: r.StartLoc,
+			Duration: r.Duration,
+			Billed:   r.Duration,
+			Rate:     rate,
+			Amount:   rate,
+			Version:  r.Version,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv.Lines) != 1 {
+		t.Fatalf("want 1 line got %d", len(inv.Lines))
+	}
+	l := inv.Lines[0]
+	if want, got := "25 Jan 2020 00:30:00", l.Start.Format("02 Jan 2006 15:04:05"); want != got {
+		t.Errorf("want line start %s got %s", want, got)
+	}
+	if got := store.InLocation(l.Start, l.StartLoc); !got.Equal(start) {
+		t.Errorf("want line start at %s got %s", start, got)
+	}
+	rec, err := ts.Record(ctx, r.RecordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.InvoiceID != inv.InvoiceID || rec.Version != r.Version+1 {
+		t.Errorf("want record in invoice %d in version %d got %+v", inv.InvoiceID, r.Version+1, rec)
+	}
+	changes, err := ts.History(ctx, r.RecordID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(changes); n != 2 || changes[n-1].Action != "update" {
+		t.Errorf("want creation and invoicing in the history got %+v", changes)
+	}
+}
+
+func TestMergeNamesKeepsTimes(t *testing.T) {
+	ts, db, done := newTestStore(t)
+	defer done()
+	ctx := context.Background()
+
+	const userID = 9050
+	if _, err := db.Exec(`INSERT INTO users(id) VALUES($1) ON CONFLICT DO NOTHING`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM time_records WHERE user_id = $1`, userID); err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2020, time.January, 25, 9, 0, 0, 0, berlin)
+	var ids []uint64
+	for _, name := range []string{"Client call", "Clinet call"} {
+		rec, err := ts.Create(ctx, store.TimeRecord{
+			UserID:   userID,
+			Name:     name,
+			Start:    start,
+			StartLoc: "Europe/Berlin",
+			Stop:     start.Add(90 * time.Minute),
+			StopLoc:  "Europe/Berlin",
+			Duration: 5400,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.RecordID)
+	}
+
+	res, err := ts.MergeNames(ctx, store.NameMerge{UserID: userID, Name: "Client call", Names: []string{"Clinet call"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Merged) != 1 || res.Merged[0].Name != "Clinet call" || res.Merged[0].Renamed != 1 {
+		t.Fatalf("unexpected merge %+v", res)
+	}
+	rec, err := ts.Record(ctx, ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Name != "Client call" || rec.Version != 2 {
+		t.Errorf("want renamed record in version 2 got %+v", rec)
+	}
+	if got := store.InLocation(rec.Start, rec.StartLoc); !got.Equal(start) {
+		t.Errorf("want start %s got %s", start, got)
+	}
+	if got := store.InLocation(rec.Stop, rec.StopLoc); !got.Equal(start.Add(90 * time.Minute)) {
+		t.Errorf("want stop %s got %s", start.Add(90*time.Minute), got)
+	}
+}
+
+func TestSubmitTimesheetRequiresActor(t *testing.T) {
+	ts, db, done := newTestStore(t)
+	defer done()
+	ctx := context.Background()
+
+	const userID = 9031
+	if _, err := db.Exec(`INSERT INTO users(id) VALUES($1) ON CONFLICT DO NOTHING`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM timesheet_events WHERE timesheet_id IN (SELECT id FROM timesheets WHERE user_id = $1)`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM timesheets WHERE user_id = $1`, userID); err != nil {
+		t.Fatal(err)
+	}
+	sheet, err := ts.CreateTimesheet(ctx, store.Timesheet{
+		UserID: userID,
+		Year:   2020,
+		Week:   5,
+		Loc:    "UTC",
+		Start:  time.Date(2020, time.January, 27, 0, 0, 0, 0, time.UTC),
+		Stop:   time.Date(2020, time.February, 3, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.SetTimesheetState(ctx, sheet.TimesheetID, store.TimesheetSubmitted, 0, ""); err != store.ErrForbidden {
+		t.Errorf("want anonymous submission forbidden got %v", err)
+	}
+	submitted, err := ts.SetTimesheetState(ctx, sheet.TimesheetID, store.TimesheetSubmitted, userID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submitted.State != store.TimesheetSubmitted {
+		t.Errorf("want timesheet submitted got %s", submitted.State)
+	}
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Profile returns the profile of a user. Returns ErrNotFound if the user has
+// not set any preferences.
+func (ts *TimeRecordStore) Profile(ctx context.Context, userID uint64) (_ *Profile, err error) {
+	ctx, end := ts.instrument(ctx, "Profile")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	p := Profile{UserID: userID}
+	err = db.QueryRowContext(ctx, `
+  SELECT locale, clock, decimal_hours
+  FROM user_profiles
+  WHERE user_id = $1
+  `, userID).Scan(&p.Locale, &p.Clock, &p.DecimalHours)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetProfile creates or replaces the profile of a user.
+func (ts *TimeRecordStore) SetProfile(ctx context.Context, p Profile) (_ *Profile, err error) {
+	ctx, end := ts.instrument(ctx, "SetProfile")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, `
+  INSERT INTO user_profiles(user_id, locale, clock, decimal_hours)
+  VALUES($1,$2,$3,$4)
+  ON CONFLICT (user_id) DO UPDATE SET
+    locale = EXCLUDED.locale,
+    clock = EXCLUDED.clock,
+    decimal_hours = EXCLUDED.decimal_hours,
+    updated_at = now()
+  `, p.UserID, p.Locale, p.Clock, p.DecimalHours)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
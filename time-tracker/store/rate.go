@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+)
+
+// CreateRate inserts a new hourly rate. Rates are never updated or deleted,
+// a rate change is inserted as a new rate with a later effective date.
+func (ts *TimeRecordStore) CreateRate(ctx context.Context, r billing.Rate) (_ *billing.Rate, err error) {
+	ctx, end := ts.instrument(ctx, "CreateRate")
+	defer end(&err)
+	query := `
+  INSERT INTO rates(
+    scope,
+	scope_id,
+	amount,
+	currency,
+	effective_from)
+  VALUES($1,$2,$3,$4,$5)
+  RETURNING
+    id,
+	scope,
+	scope_id,
+	amount::text,
+	currency,
+	effective_from
+  `
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	row := db.QueryRowContext(ctx, query,
+		r.Scope,
+		r.ScopeID,
+		r.Hourly.Decimal(),
+		r.Hourly.Currency,
+		r.EffectiveFrom)
+	return scanBillingRate(row.Scan)
+}
+
+// Rates returns the history of rates which may apply to records of the given
+// user, which are the user's rates and the rates of all projects and clients.
+func (ts *TimeRecordStore) Rates(ctx context.Context, userID uint64) (_ []billing.Rate, err error) {
+	ctx, end := ts.instrument(ctx, "Rates")
+	defer end(&err)
+	query := `
+  SELECT
+    id,
+	scope,
+	scope_id,
+	amount::text,
+	currency,
+	effective_from
+  FROM rates
+  WHERE (scope = 'user' AND scope_id = $1)
+  OR scope IN ('project', 'client')
+  ORDER BY effective_from;
+  `
+	return ts.queryRates(ctx, query, userID)
+}
+
+// queryRates runs a query selecting the columns of rates and returns the
+// scanned rates.
+func (ts *TimeRecordStore) queryRates(ctx context.Context, query string, args ...interface{}) ([]billing.Rate, error) {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]billing.Rate, 0)
+	for rows.Next() {
+		r, err := scanBillingRate(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *r)
+	}
+	return rates, rows.Err()
+}
+
+// scanBillingRate scans a rate with the columns selected by the rate queries.
+func scanBillingRate(scan func(dest ...interface{}) error) (*billing.Rate, error) {
+	var r billing.Rate
+	var scope, amount, currency string
+	var effectiveFrom time.Time
+	if err := scan(
+		&r.RateID,
+		&scope,
+		&r.ScopeID,
+		&amount,
+		&currency,
+		&effectiveFrom); err != nil {
+		return nil, err
+	}
+	hourly, err := billing.ParseMoney(amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	r.Scope = billing.Scope(scope)
+	r.Hourly = hourly
+	r.EffectiveFrom = effectiveFrom
+	return &r, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// refillTokens are the tokens of a bucket refilled since its last update,
+// limited to the burst $2 with the rate $3.
+const refillTokens = `LEAST($2, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3)`
+
+// TakeToken takes a token from the bucket of key, holding up to burst tokens
+// refilled with rate tokens per second, if it has one and returns the tokens
+// left, so the limits hold across all instances of the service.
+// The bucket is refilled and taken from in a single statement, concurrent
+// requests wait for the row lock.
+func (ts *TimeRecordStore) TakeToken(ctx context.Context, key string, rate float64, burst int) (ok bool, tokens float64, err error) {
+	ctx, end := ts.instrument(ctx, "TakeToken")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	err = db.QueryRowContext(ctx, `
+  INSERT INTO rate_limits AS b (key, tokens, allowed, updated_at)
+  VALUES ($1, $2 - 1, true, now())
+  ON CONFLICT (key) DO UPDATE SET
+    allowed = `+refillTokens+` >= 1,
+    tokens = `+refillTokens+` - CASE WHEN `+refillTokens+` >= 1 THEN 1 ELSE 0 END,
+    updated_at = now()
+  RETURNING allowed, tokens
+  `, key, float64(burst), rate).Scan(&ok, &tokens)
+	if err != nil {
+		return false, 0, err
+	}
+	return ok, tokens, nil
+}
+
+// PurgeRateLimits deletes the buckets not used since before. They are full,
+// so this does not change the limits if the retention is long enough to
+// refill them.
+func (ts *TimeRecordStore) PurgeRateLimits(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, end := ts.instrument(ctx, "PurgeRateLimits")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `DELETE FROM rate_limits WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// SearchQuery is a full-text search of the names and notes of the records of
+// a user, see ParseSearch. Records in the trash are not searched.
+type SearchQuery struct {
+	UserID    uint64
+	Terms     []SearchTerm // all terms must match
+	From      time.Time    // records stopped at or after From, all if zero
+	To        time.Time    // records started before To, all if zero
+	ProjectID uint64       // 0 for all projects
+	Tags      []string     // records with all of the tags
+	Limit     int          // maximum number of results, 0 for all
+}
+
+// SearchTerm is a word, the prefix of a word or a phrase of consecutive
+// words.
+type SearchTerm struct {
+	Words  []string // in lower case
+	Prefix bool     // the last word is a prefix
+}
+
+// SearchResult is a record matching a search. Results with a higher rank
+// match better, ranks are comparable within a search only. The snippet is
+// the matched part of the name and notes as HTML, matched words are
+// highlighted as <mark>word</mark>.
+type SearchResult struct {
+	Record  TimeRecord
+	Rank    float64
+	Snippet string
+}
+
+// errEmptySearch is returned by ParseSearch for searches without words.
+var errEmptySearch = errors.New("search without words")
+
+// ParseSearch parses the terms of a search like `"client call" budg* march`.
+// Quoted phrases match consecutive words, words ending in * match all words
+// with the prefix. Words are split like the database splits them, e.g.
+// client-call is the phrase "client call". Returns an error if there are no
+// words.
+func ParseSearch(q string) ([]SearchTerm, error) {
+	var terms []SearchTerm
+	add := func(s string) {
+		t := SearchTerm{Prefix: strings.HasSuffix(s, "*")}
+		for _, w := range splitWords(s) {
+			t.Words = append(t.Words, strings.ToLower(s[w[0]:w[1]]))
+		}
+		if len(t.Words) > 0 {
+			terms = append(terms, t)
+		}
+	}
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			add(part) // quoted, an unclosed quote ends with the search
+			continue
+		}
+		for _, s := range strings.Fields(part) {
+			add(s)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, errEmptySearch
+	}
+	return terms, nil
+}
+
+// splitWords returns the start and end offsets of the words of s, words are
+// letters and digits.
+func splitWords(s string) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(s)})
+	}
+	return words
+}
+
+// tsquery returns the terms as text search query, e.g. 'client' <-> 'ca':*
+// for the phrase "client ca*". Words consist of letters and digits, so they
+// need no escaping.
+func tsquery(terms []SearchTerm) string {
+	and := make([]string, len(terms))
+	for i, t := range terms {
+		phrase := make([]string, len(t.Words))
+		for j, w := range t.Words {
+			phrase[j] = "'" + w + "'"
+		}
+		if t.Prefix {
+			phrase[len(phrase)-1] += ":*"
+		}
+		and[i] = strings.Join(phrase, " <-> ")
+		if len(phrase) > 1 {
+			and[i] = "(" + and[i] + ")"
+		}
+	}
+	return strings.Join(and, " & ")
+}
+
+// Search returns the records of a user matching the query with the best
+// matches first, see SearchQuery. Records match by their name and notes,
+// matches of the name rank higher.
+func (ts *TimeRecordStore) Search(ctx context.Context, q SearchQuery) (_ []SearchResult, err error) {
+	ctx, end := ts.instrument(ctx, "Search")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	// the snippet is escaped before matches are highlighted, the tags of
+	// ts_headline are the only HTML in it
+	query := `
+  SELECT` + recordColumns + `,
+	ts_rank(time_record_document(tr.name, tr.notes), q.query) AS rank,
+	ts_headline('simple',
+	  replace(replace(replace(concat_ws(' – ', NULLIF(tr.name, ''), NULLIF(tr.notes, '')), '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+	  q.query,
+	  'StartSel=<mark>, StopSel=</mark>, MinWords=10, MaxWords=30')
+  FROM time_records
+  AS tr
+  CROSS JOIN to_tsquery('simple', $2) AS q(query)
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.user_id = $1
+  AND time_record_document(tr.name, tr.notes) @@ q.query
+  AND tr.deleted_at IS NULL
+  AND tr.stop_time >= $3
+  AND ($4::timestamptz IS NULL OR tr.start_time < $4)
+  AND ($5::bigint = 0 OR tr.project_id = $5)
+  AND tr.tags @> $6
+  ORDER BY rank DESC, tr.start_time DESC
+  LIMIT NULLIF($7, 0);
+  `
+	to := sql.NullTime{Time: q.To, Valid: !q.To.IsZero()}
+	rows, err := db.QueryContext(ctx, query, q.UserID, tsquery(q.Terms), q.From, to, q.ProjectID, tagsValue(q.Tags), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0)
+	for rows.Next() {
+		var res SearchResult
+		rec, err := scanRecord(scanWith(rows.Scan, &res.Rank, &res.Snippet))
+		if err != nil {
+			return nil, err
+		}
+		res.Record = *rec
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// Weights of matches in the name and notes of a record, as weighted by
+// time_record_document.
+const (
+	nameWeight  = 1.0
+	notesWeight = 0.4
+)
+
+// snippetWords is the number of words of a snippet.
+const snippetWords = 30
+
+// SearchRecords searches records in memory like TimeRecordStore.Search, for
+// datastores without full-text search. The user is not checked, recs must be
+// the records of the user. Ranks count the matches of the terms, weighted
+// like the database weighs them.
+func SearchRecords(recs []TimeRecord, q SearchQuery) []SearchResult {
+	results := make([]SearchResult, 0)
+	for _, rec := range recs {
+		if !q.filter(rec) {
+			continue
+		}
+		name, notes := matchTerms(rec.Name, q.Terms), matchTerms(rec.Notes, q.Terms)
+		rank, matched := 0.0, true
+		for i := range q.Terms {
+			if len(name[i]) == 0 && len(notes[i]) == 0 {
+				matched = false
+				break
+			}
+			rank += nameWeight*float64(len(name[i])) + notesWeight*float64(len(notes[i]))
+		}
+		if !matched {
+			continue
+		}
+		results = append(results, SearchResult{Record: rec, Rank: rank, Snippet: snippet(rec.Name, rec.Notes, q.Terms)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		ri, rj := results[i].Record, results[j].Record
+		return InLocation(ri.Start, ri.StartLoc).After(InLocation(rj.Start, rj.StartLoc))
+	})
+	if q.Limit > 0 && len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	return results
+}
+
+// filter reports whether the record is in the period, project and tags of
+// the query. The period is made of points in time, the times of the record
+// are wall clocks in the user's location.
+func (q SearchQuery) filter(rec TimeRecord) bool {
+	switch {
+	case rec.Deleted != nil:
+		return false
+	case InLocation(rec.Stop, rec.StopLoc).Before(q.From):
+		return false
+	case !q.To.IsZero() && !InLocation(rec.Start, rec.StartLoc).Before(q.To):
+		return false
+	case q.ProjectID != 0 && rec.ProjectID != q.ProjectID:
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !containsTag(rec.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTerms returns the indexes of the words of s matched by each term. A
+// phrase matches all of its words.
+func matchTerms(s string, terms []SearchTerm) [][]int {
+	words := splitWords(s)
+	lower := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = strings.ToLower(s[w[0]:w[1]])
+	}
+	matches := make([][]int, len(terms))
+	for i, t := range terms {
+		for start := 0; start+len(t.Words) <= len(lower); start++ {
+			if matchPhrase(lower[start:start+len(t.Words)], t) {
+				for j := range t.Words {
+					matches[i] = append(matches[i], start+j)
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func matchPhrase(words []string, t SearchTerm) bool {
+	for i, w := range t.Words {
+		if t.Prefix && i == len(t.Words)-1 {
+			if !strings.HasPrefix(words[i], w) {
+				return false
+			}
+		} else if words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// snippet returns the matched part of the name and notes as HTML with the
+// matched words highlighted, like the snippets of the database.
+func snippet(name, notes string, terms []SearchTerm) string {
+	var parts []string
+	for _, s := range []string{name, notes} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	s := strings.Join(parts, " – ")
+	words := splitWords(s)
+	highlight := make(map[int]bool)
+	for _, m := range matchTerms(s, terms) {
+		for _, i := range m {
+			highlight[i] = true
+		}
+	}
+
+	// a window of snippetWords words starting shortly before the first match
+	first, last := 0, len(words)
+	if len(words) > snippetWords {
+		for i := range words {
+			if highlight[i] {
+				first = i
+				break
+			}
+		}
+		if first -= 5; first < 0 {
+			first = 0
+		}
+		if last = first + snippetWords; last > len(words) {
+			last, first = len(words), len(words)-snippetWords
+		}
+	}
+	var b strings.Builder
+	start, end := 0, len(s)
+	if first > 0 {
+		start = words[first][0]
+		b.WriteString("… ")
+	}
+	if last < len(words) {
+		end = words[last-1][1]
+	}
+	pos := start
+	for i := first; i < last; i++ {
+		if !highlight[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(s[pos:words[i][0]]))
+		b.WriteString("<mark>" + html.EscapeString(s[words[i][0]:words[i][1]]) + "</mark>")
+		pos = words[i][1]
+	}
+	b.WriteString(html.EscapeString(s[pos:end]))
+	if end < len(s) {
+		b.WriteString(" …")
+	}
+	return b.String()
+}
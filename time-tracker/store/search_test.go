@@ -0,0 +1,106 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		d   string             // description of test case
+		q   string             // search
+		out []store.SearchTerm // expected terms
+		err bool               // expect an error
+	}{
+		{d: "words", q: "Client  call", out: []store.SearchTerm{{Words: []string{"client"}}, {Words: []string{"call"}}}},
+		{d: "phrase", q: `"client call" march`, out: []store.SearchTerm{{Words: []string{"client", "call"}}, {Words: []string{"march"}}}},
+		{d: "prefix", q: `budg* "client ca*"`, out: []store.SearchTerm{{Words: []string{"budg"}, Prefix: true}, {Words: []string{"client", "ca"}, Prefix: true}}},
+		{d: "split words", q: "client-call", out: []store.SearchTerm{{Words: []string{"client", "call"}}}},
+		{d: "unclosed quote", q: `"Büro meeting`, out: []store.SearchTerm{{Words: []string{"büro", "meeting"}}}},
+		{d: "no words", q: `* "" -`, err: true},
+	}
+	for _, tt := range tests {
+		out, err := store.ParseSearch(tt.q)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: want error %v got %v", tt.d, tt.err, err)
+		}
+		if !reflect.DeepEqual(tt.out, out) {
+			t.Errorf("%s: want %+v got %+v", tt.d, tt.out, out)
+		}
+	}
+}
+
+func TestSearchRecords(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, time.March, d, 9, 0, 0, 0, time.UTC) }
+	deleted := day(20)
+	recs := []store.TimeRecord{
+		{RecordID: 1, Name: "Client call", Notes: "budget for <Q2>", Start: day(2), Stop: day(2), Tags: []string{"call"}},
+		{RecordID: 2, Name: "Planning", Notes: "prepare the client call with Anna", Start: day(3), Stop: day(3), ProjectID: 7},
+		{RecordID: 3, Name: "Call with the client", Start: day(4), Stop: day(4)},
+		{RecordID: 4, Name: "client call", Start: day(5), Stop: day(5), Deleted: &deleted},
+	}
+	search := func(q string) store.SearchQuery {
+		terms, err := store.ParseSearch(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store.SearchQuery{Terms: terms}
+	}
+	ids := func(results []store.SearchResult) []uint64 {
+		ids := make([]uint64, 0)
+		for _, res := range results {
+			ids = append(ids, res.Record.RecordID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		d   string            // description of test case
+		q   store.SearchQuery // query
+		ids []uint64          // expected records
+	}{
+		{d: "names rank higher, then the latest", q: search("client"), ids: []uint64{3, 1, 2}},
+		{d: "phrase", q: search(`"client call"`), ids: []uint64{1, 2}},
+		{d: "prefix", q: search("budg*"), ids: []uint64{1}},
+		{d: "all terms", q: search("client anna"), ids: []uint64{2}},
+		{d: "project", q: store.SearchQuery{Terms: search("client").Terms, ProjectID: 7}, ids: []uint64{2}},
+		{d: "tags", q: store.SearchQuery{Terms: search("client").Terms, Tags: []string{"call"}}, ids: []uint64{1}},
+		{d: "period", q: store.SearchQuery{Terms: search("client").Terms, From: day(3), To: day(4)}, ids: []uint64{2}},
+		{d: "limit", q: store.SearchQuery{Terms: search("client").Terms, Limit: 1}, ids: []uint64{3}},
+	}
+	for _, tt := range tests {
+		if got := ids(store.SearchRecords(recs, tt.q)); !reflect.DeepEqual(tt.ids, got) {
+			t.Errorf("%s: want records %v got %v", tt.d, tt.ids, got)
+		}
+	}
+
+	// the store reads wall clocks in the user's location with a +00 offset,
+	// the period is made of points in time
+	wall := func(d, hour, min int) time.Time {
+		return time.Date(2020, time.March, d, hour, min, 0, 0, time.FixedZone("", 0))
+	}
+	berlin := []store.TimeRecord{
+		// stops at 08:30 UTC, before the period
+		{RecordID: 5, Name: "client call", Start: wall(3, 9, 0), StartLoc: "Europe/Berlin", Stop: wall(3, 9, 30), StopLoc: "Europe/Berlin"},
+		// starts at 08:30 UTC, inside the period
+		{RecordID: 6, Name: "client call", Start: wall(4, 9, 30), StartLoc: "Europe/Berlin", Stop: wall(4, 10, 0), StopLoc: "Europe/Berlin"},
+	}
+	q := store.SearchQuery{Terms: search("client").Terms, From: day(3), To: day(4)}
+	if want, got := []uint64{6}, ids(store.SearchRecords(berlin, q)); !reflect.DeepEqual(want, got) {
+		t.Errorf("period in other location: want records %v got %v", want, got)
+	}
+
+	// snippets are escaped and highlight the matched words
+	results := store.SearchRecords(recs[:1], search("budget"))
+	if want, got := "Client call – <mark>budget</mark> for &lt;Q2&gt;", results[0].Snippet; want != got {
+		t.Errorf("want snippet %s got %s", want, got)
+	}
+	long := store.TimeRecord{Name: "Notes", Notes: "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty twenty-one twenty-two twenty-three twenty-four twenty-five twenty-six twenty-seven twenty-eight twenty-nine thirty thirty-one thirty-two thirty-three"}
+	results = store.SearchRecords([]store.TimeRecord{long}, search("ten"))
+	if want, got := "… five six seven eight nine <mark>ten</mark> eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty twenty-one twenty-two twenty-three twenty-four twenty-five twenty-six twenty-seven …", results[0].Snippet; want != got {
+		t.Errorf("want snippet %s got %s", want, got)
+	}
+}
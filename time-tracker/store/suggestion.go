@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/lib/pq"
+)
+
+// Similarities of names compared by trigrams, see the pg_trgm extension.
+const (
+	// suggestionSimilarity is the minimum word similarity of a suggested
+	// name and the typed prefix, low enough to suggest names despite typos.
+	suggestionSimilarity = 0.4
+	// DefaultMergeSimilarity is the minimum similarity of names merged into
+	// a canonical name if the names are not given.
+	DefaultMergeSimilarity = 0.6
+)
+
+// NameSuggestion is a name a user gave records, to complete the name of a new
+// record.
+type NameSuggestion struct {
+	Name      string    `json:"name"`
+	Records   uint64    `json:"records"`              // records with the name
+	LastUsed  time.Time `json:"last_used"`            // start of the latest record with the name
+	ProjectID uint64    `json:"project_id,omitempty"` // project most records with the name are assigned to
+	Tags      []string  `json:"tags,omitempty"`       // tags most records with the name are tagged with
+}
+
+// NameSuggestions returns up to limit names of the records of a user
+// completing the prefix. Names starting with the prefix come first, followed
+// by names containing a word similar to it, which catches typos. Names used
+// more often and more recently rank higher. An empty prefix returns the
+// user's names by use. Records in the trash are not suggested.
+func (ts *TimeRecordStore) NameSuggestions(ctx context.Context, userID uint64, prefix string, limit int) (_ []NameSuggestion, err error) {
+	ctx, end := ts.instrument(ctx, "NameSuggestions")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	// every record counts, a record of a week ago half as much as one of
+	// today, records starting in the future count fully
+	query := `
+  SELECT
+    tr.name,
+    count(*),
+    max(tr.start_time),
+    COALESCE(mode() WITHIN GROUP (ORDER BY tr.project_id), 0),
+    mode() WITHIN GROUP (ORDER BY tr.tags)
+  FROM time_records
+  AS tr
+  WHERE tr.user_id = $1
+  AND tr.deleted_at IS NULL
+  AND tr.name <> ''
+  AND ($2 = '' OR tr.name ILIKE $3 OR word_similarity($2, tr.name) >= $4)
+  GROUP BY tr.name
+  ORDER BY
+    tr.name ILIKE $3 DESC,
+    sum(1 / (1 + extract(epoch FROM GREATEST(now() - tr.start_time, interval '0')) / 604800)) DESC,
+    tr.name
+  LIMIT $5;
+  `
+	rows, err := db.QueryContext(ctx, query, userID, prefix, likePrefix(prefix), suggestionSimilarity, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]NameSuggestion, 0)
+	for rows.Next() {
+		var s NameSuggestion
+		if err := rows.Scan(&s.Name, &s.Records, &s.LastUsed, &s.ProjectID, pq.Array(&s.Tags)); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, rows.Err()
+}
+
+// likePrefix returns the LIKE pattern of the names starting with prefix.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// NameMerge renames the records of a user with names similar to a canonical
+// name to it. The names to merge are the given names or, if there are none,
+// the names with at least the given trigram similarity to the canonical name.
+// A dry run reports what a merge would rename without renaming.
+type NameMerge struct {
+	UserID     uint64   `json:"user_id"`
+	Name       string   `json:"name"`                 // canonical name
+	Names      []string `json:"names,omitempty"`      // names to merge
+	Similarity float64  `json:"similarity,omitempty"` // DefaultMergeSimilarity if 0
+	DryRun     bool     `json:"dry_run,omitempty"`
+}
+
+// Validate returns an error if the merge has no user or canonical name or
+// the similarity is not between 0 and 1.
+func (m NameMerge) Validate() error {
+	switch {
+	case m.UserID == 0:
+		return errors.New("missing user id")
+	case strings.TrimSpace(m.Name) == "":
+		return errors.New("missing name")
+	case utf8.RuneCountInString(m.Name) > MaxNameLength:
+		return fmt.Errorf("name longer than %d characters", MaxNameLength)
+	case m.Similarity < 0 || m.Similarity > 1:
+		return fmt.Errorf("similarity not between 0 and 1: %v", m.Similarity)
+	}
+	return nil
+}
+
+// MergedName is a name merged into the canonical name. Records locked by an
+// invoice or an approved timesheet keep their name.
+type MergedName struct {
+	Name    string `json:"name"`
+	Renamed uint64 `json:"renamed"` // records renamed
+	Locked  uint64 `json:"locked"`  // records not renamed
+}
+
+// NameMergeResult is the outcome of a name merge.
+type NameMergeResult struct {
+	Name   string       `json:"name"`
+	Merged []MergedName `json:"merged"`
+	DryRun bool         `json:"dry_run,omitempty"`
+}
+
+// MergeNames renames the records of a user with names similar to a canonical
+// name, see NameMerge. Every rename is recorded in the record's history like
+// an update. Records in the trash are not renamed.
+func (ts *TimeRecordStore) MergeNames(ctx context.Context, m NameMerge) (_ *NameMergeResult, err error) {
+	ctx, end := ts.instrument(ctx, "MergeNames")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	similarity := m.Similarity
+	if similarity == 0 {
+		similarity = DefaultMergeSimilarity
+	}
+	query := `
+  SELECT tr.id, tr.name
+  FROM time_records
+  AS tr
+  WHERE tr.user_id = $1
+  AND tr.deleted_at IS NULL
+  AND tr.name <> $2
+  AND CASE WHEN cardinality($3::text[]) > 0
+    THEN tr.name = ANY($3)
+    ELSE similarity(tr.name, $2) >= $4
+  END
+  ORDER BY tr.name, tr.start_time;
+  `
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	rows, err := tx.QueryContext(ctx, query, m.UserID, m.Name, pq.Array(m.Names), similarity)
+	if err != nil {
+		return nil, err
+	}
+	type named struct {
+		id   uint64
+		name string
+	}
+	var recs []named
+	for rows.Next() {
+		var r named
+		if err := rows.Scan(&r.id, &r.name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		recs = append(recs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &NameMergeResult{Name: m.Name, Merged: make([]MergedName, 0), DryRun: m.DryRun}
+	var evs []events.Event
+	for _, r := range recs {
+		if n := len(result.Merged); n == 0 || result.Merged[n-1].Name != r.name {
+			result.Merged = append(result.Merged, MergedName{Name: r.name})
+		}
+		merged := &result.Merged[len(result.Merged)-1]
+		_, ev, err := renameRecord(ctx, tx, r.id, m.Name)
+		switch err {
+		case nil:
+			merged.Renamed++
+			evs = append(evs, ev)
+		case ErrRecordLocked:
+			merged.Locked++
+		default:
+			return nil, err
+		}
+	}
+	// a dry run renames like a merge and rolls the renames back
+	if m.DryRun {
+		return result, nil
+	}
+	return result, ts.commit(ctx, tx, evs...)
+}
+
+// renameRecord renames a record in the transaction and records the change in
+// the history and the webhook outbox like updateRecord. Only the name and
+// version are written, the other values are left as they are. Returns the
+// renamed record and the event to publish on commit.
+func renameRecord(ctx context.Context, tx *sql.Tx, id uint64, name string) (*TimeRecord, events.Event, error) {
+	query := `
+  WITH tr AS (
+    UPDATE time_records
+    SET
+      name = $2,
+	  version = version + 1
+    WHERE id = $1
+    RETURNING *
+  )
+  SELECT` + recordColumns + `,
+	to_jsonb(tr)
+  FROM tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  `
+	before, err := lockRecord(ctx, tx, id, false)
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	var after []byte
+	rec, err := scanRecord(scanWith(tx.QueryRowContext(ctx, query, id, name).Scan, &after))
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	if err := insertHistory(ctx, tx, actionUpdate, rec.RecordID, rec.UserID, before, after); err != nil {
+		return nil, events.Event{}, err
+	}
+	ev, err := insertEvent(ctx, tx, webhook.RecordUpdated, rec.UserID, rec)
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	return rec, ev, nil
+}
@@ -0,0 +1,30 @@
+package store_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fgrimme/time-tracker/time-tracker/store"
+)
+
+func TestNameMergeValidate(t *testing.T) {
+	tests := []struct {
+		d   string          // description of test case
+		m   store.NameMerge // merge to validate
+		err bool            // expect an error
+	}{
+		{d: "similar names", m: store.NameMerge{UserID: 1, Name: "Client call"}},
+		{d: "given names", m: store.NameMerge{UserID: 1, Name: "Client call", Names: []string{"client cal"}, DryRun: true}},
+		{d: "similarity", m: store.NameMerge{UserID: 1, Name: "Client call", Similarity: 1}},
+		{d: "missing user", m: store.NameMerge{Name: "Client call"}, err: true},
+		{d: "blank name", m: store.NameMerge{UserID: 1, Name: "  "}, err: true},
+		{d: "long name", m: store.NameMerge{UserID: 1, Name: strings.Repeat("ü", store.MaxNameLength+1)}, err: true},
+		{d: "negative similarity", m: store.NameMerge{UserID: 1, Name: "Client call", Similarity: -0.1}, err: true},
+		{d: "similarity above 1", m: store.NameMerge{UserID: 1, Name: "Client call", Similarity: 1.5}, err: true},
+	}
+	for _, tt := range tests {
+		if err := tt.m.Validate(); (err != nil) != tt.err {
+			t.Errorf("%s: want error %v got %v", tt.d, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/lib/pq"
+)
+
+// Operations of mutations.
+const (
+	MutationUpsert = "upsert"
+	MutationDelete = "delete"
+)
+
+// Outcomes of mutations.
+const (
+	MutationApplied  = "applied"
+	MutationConflict = "conflict"
+)
+
+// Reasons of conflicts. Conflicts are resolved in favor of the server, the
+// record is left as it is and returned with the conflict.
+const (
+	ConflictStale   = "stale"   // the record changed since the base version
+	ConflictDeleted = "deleted" // the record is in the trash or purged
+	ConflictExists  = "exists"  // a record with the uuid exists already
+	ConflictLocked  = "locked"  // the record is locked by an invoice or an approved timesheet
+)
+
+// Mutation is a change of a record made by a client, possibly while it was
+// offline. Records are identified by their uuid, which the client generates
+// for new records. A mutation is applied at most once per user, replaying it
+// returns the outcome of its first application.
+type Mutation struct {
+	MutationID  string      `json:"mutation_id"`  // uuid generated by the client
+	Op          string      `json:"op"`           // MutationUpsert or MutationDelete
+	UUID        string      `json:"uuid"`         // uuid of the record
+	BaseVersion uint64      `json:"base_version"` // version the change is based on, 0 for new records
+	Record      *TimeRecord `json:"record,omitempty"`
+}
+
+// Validate returns an error if the mutation is malformed.
+func (m Mutation) Validate() error {
+	if !ValidUUID(m.MutationID) {
+		return fmt.Errorf("invalid mutation id: %q", m.MutationID)
+	}
+	if !ValidUUID(m.UUID) {
+		return fmt.Errorf("invalid uuid: %q", m.UUID)
+	}
+	switch m.Op {
+	case MutationUpsert:
+		if m.Record == nil {
+			return errors.New("upsert without record")
+		}
+		if m.Record.UUID != "" && m.Record.UUID != m.UUID {
+			return errors.New("uuid of record does not match")
+		}
+	case MutationDelete:
+	default:
+		return fmt.Errorf("unknown operation: %q", m.Op)
+	}
+	return nil
+}
+
+// MutationResult is the outcome of a mutation. Record is the record as
+// stored after the mutation, the version kept by the server for conflicts
+// and nil if the record was purged.
+type MutationResult struct {
+	MutationID string      `json:"mutation_id"`
+	Status     string      `json:"status"`
+	Reason     string      `json:"reason,omitempty"`
+	Record     *TimeRecord `json:"record,omitempty"`
+}
+
+// Sync applies the mutations of a user in order. Conflicting mutations are
+// not applied, which is reported in their result:
+//
+//   - an upsert of an unknown uuid creates the record if the base version is
+//     0, the record has been purged otherwise
+//   - an upsert or delete based on an outdated version is stale, concurrent
+//     changes on the server win
+//   - an upsert of a record in the trash conflicts, a delete of a record in
+//     the trash or purged is applied without a change
+//
+// Each mutation is applied in its own transaction, so mutations applied
+// before an error are kept and skipped as replays when the batch is retried.
+func (ts *TimeRecordStore) Sync(ctx context.Context, userID uint64, mutations []Mutation) (_ []MutationResult, err error) {
+	ctx, end := ts.instrument(ctx, "Sync")
+	defer end(&err)
+	results := make([]MutationResult, 0, len(mutations))
+	for _, m := range mutations {
+		res, err := ts.applyMutation(ctx, userID, m)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *res)
+	}
+	return results, nil
+}
+
+func (ts *TimeRecordStore) applyMutation(ctx context.Context, userID uint64, m Mutation) (*MutationResult, error) {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	// claim the mutation, a concurrent application of the same mutation
+	// blocks until this transaction ends
+	res, err := tx.ExecContext(ctx, `
+  INSERT INTO sync_mutations(user_id, mutation_id, status)
+  VALUES($1,$2,$3)
+  ON CONFLICT DO NOTHING
+  `, userID, m.MutationID, MutationApplied)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return replayMutation(ctx, tx, userID, m.MutationID)
+	}
+
+	// conflicts roll back the partial changes of the mutation
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT mutation"); err != nil {
+		return nil, err
+	}
+	result, ev, err := mutate(ctx, tx, userID, m)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status == MutationConflict {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT mutation"); err != nil {
+			return nil, err
+		}
+		ev = nil
+	}
+	var recordID sql.NullInt64
+	if result.Record != nil {
+		recordID = sql.NullInt64{Int64: int64(result.Record.RecordID), Valid: true}
+	}
+	if _, err := tx.ExecContext(ctx, `
+  UPDATE sync_mutations
+  SET status = $3, reason = $4, record_id = $5
+  WHERE user_id = $1 AND mutation_id = $2
+  `, userID, m.MutationID, result.Status, result.Reason, recordID); err != nil {
+		return nil, err
+	}
+	// conflicts return the record as stored on the server
+	if result.Status == MutationConflict && result.Record != nil {
+		if result.Record, err = recordByID(ctx, tx, result.Record.RecordID); err != nil {
+			return nil, err
+		}
+	}
+	return result, ts.commit(ctx, tx, ev...)
+}
+
+// mutate applies a mutation in the transaction. Returns the result and the
+// events to publish on commit. Changes made for conflicting mutations must be
+// rolled back, the record of the result is the record before the mutation.
+func mutate(ctx context.Context, tx *sql.Tx, userID uint64, m Mutation) (*MutationResult, []events.Event, error) {
+	result := &MutationResult{MutationID: m.MutationID, Status: MutationApplied}
+	conflict := func(reason string, rec *TimeRecord) (*MutationResult, []events.Event, error) {
+		result.Status, result.Reason, result.Record = MutationConflict, reason, rec
+		return result, nil, nil
+	}
+
+	current, err := recordByUUID(ctx, tx, m.UUID)
+	if err != nil && err != ErrNotFound {
+		return nil, nil, err
+	}
+	if current != nil && current.UserID != userID {
+		// uuids are not shared between users, the record is not revealed
+		return conflict(ConflictExists, nil)
+	}
+
+	switch {
+	case m.Op == MutationDelete && (current == nil || current.Deleted != nil):
+		// deletes are idempotent
+		result.Record = current
+		return result, nil, nil
+	case m.Op == MutationUpsert && current == nil && m.BaseVersion != 0:
+		return conflict(ConflictDeleted, nil)
+	case current != nil && current.Deleted != nil:
+		return conflict(ConflictDeleted, current)
+	case m.Op == MutationUpsert && current != nil && m.BaseVersion == 0:
+		return conflict(ConflictExists, current)
+	case current != nil && current.Version != m.BaseVersion:
+		return conflict(ConflictStale, current)
+	}
+
+	var ev events.Event
+	switch {
+	case m.Op == MutationDelete:
+		ev, err = deleteRecord(ctx, tx, current.RecordID)
+	case current == nil:
+		r := *m.Record
+		r.UserID, r.UUID = userID, m.UUID
+		result.Record, ev, err = insertRecord(ctx, tx, r)
+	default:
+		r := *m.Record
+		r.RecordID, r.UserID = current.RecordID, userID
+		result.Record, ev, err = updateRecord(ctx, tx, r)
+	}
+	switch err {
+	case nil:
+	case ErrRecordLocked:
+		return conflict(ConflictLocked, current)
+	case ErrRecordExists:
+		// created concurrently
+		return conflict(ConflictExists, nil)
+	default:
+		return nil, nil, err
+	}
+	if m.Op == MutationDelete {
+		if result.Record, err = recordByID(ctx, tx, current.RecordID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return result, []events.Event{ev}, nil
+}
+
+// replayMutation returns the stored outcome of a mutation with the current
+// state of its record.
+func replayMutation(ctx context.Context, tx *sql.Tx, userID uint64, mutationID string) (*MutationResult, error) {
+	result := &MutationResult{MutationID: mutationID}
+	var recordID sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `
+  SELECT status, reason, record_id
+  FROM sync_mutations
+  WHERE user_id = $1 AND mutation_id = $2
+  `, userID, mutationID).Scan(&result.Status, &result.Reason, &recordID); err != nil {
+		return nil, err
+	}
+	if !recordID.Valid {
+		return result, nil
+	}
+	rec, err := recordByID(ctx, tx, uint64(recordID.Int64))
+	if err == ErrNotFound {
+		return result, nil // purged
+	}
+	result.Record = rec
+	return result, err
+}
+
+// recordByUUID returns the record with the uuid, including records in the
+// trash, and locks it for the rest of the transaction. Returns ErrNotFound if
+// there is none.
+func recordByUUID(ctx context.Context, tx *sql.Tx, uuid string) (*TimeRecord, error) {
+	rec, err := scanRecord(tx.QueryRowContext(ctx, `
+  SELECT`+recordColumns+`
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.uuid = $1
+  FOR UPDATE OF tr
+  `, uuid).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rec, err
+}
+
+// recordByID returns the record with the id, including records in the
+// trash. Returns ErrNotFound if there is none.
+func recordByID(ctx context.Context, tx *sql.Tx, id uint64) (*TimeRecord, error) {
+	rec, err := scanRecord(tx.QueryRowContext(ctx, `
+  SELECT`+recordColumns+`
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.id = $1
+  `, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return rec, err
+}
+
+// Kinds of entries in the record feed.
+const (
+	FeedCreate    = "create"
+	FeedUpdate    = "update"
+	FeedTombstone = "tombstone"
+)
+
+// FeedEntry is the latest change of a record in the record feed. Records
+// created after the cursor the feed was requested for are creates, deleted
+// and purged records are tombstones without record.
+type FeedEntry struct {
+	Cursor   uint64      `json:"cursor"` // pass as since to get the following entries
+	Kind     string      `json:"type"`
+	RecordID uint64      `json:"record_id"`
+	UUID     string      `json:"uuid"`
+	Version  uint64      `json:"version"`
+	Record   *TimeRecord `json:"record,omitempty"`
+}
+
+// Feed returns the records of a user which changed after the cursor since,
+// at most limit entries ordered by their latest change. Unlike Changes, a
+// record changed several times appears once with its current values. The
+// cursor of an entry is the id of the record's latest change.
+func (ts *TimeRecordStore) Feed(ctx context.Context, userID, since uint64, limit int) (_ []FeedEntry, err error) {
+	ctx, end := ts.instrument(ctx, "Feed")
+	defer end(&err)
+	query := `
+  WITH c AS (
+    SELECT record_id, MAX(id) AS change_id, bool_or(action = $4) AS created
+    FROM record_history
+    WHERE user_id = $1
+    AND id > $2
+    GROUP BY record_id
+    ORDER BY change_id
+    LIMIT $3
+  )
+  SELECT
+	c.change_id,
+	c.record_id,
+	c.created,
+	h.action IN ($5, $6),
+	COALESCE(COALESCE(h.after, h.before)->>'uuid', ''),
+	COALESCE((COALESCE(h.after, h.before)->>'version')::bigint, 0)
+  FROM c
+  JOIN record_history AS h ON h.id = c.change_id
+  ORDER BY c.change_id;
+  `
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, userID, since, limit, actionCreate, actionDelete, actionPurge)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]FeedEntry, 0)
+	var ids []int64
+	for rows.Next() {
+		var e FeedEntry
+		var created, deleted bool
+		if err := rows.Scan(&e.Cursor, &e.RecordID, &created, &deleted, &e.UUID, &e.Version); err != nil {
+			return nil, err
+		}
+		switch {
+		case deleted:
+			e.Kind = FeedTombstone
+		case created:
+			e.Kind = FeedCreate
+		default:
+			e.Kind = FeedUpdate
+		}
+		if !deleted {
+			ids = append(ids, int64(e.RecordID))
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	recs, err := ts.queryRecords(ctx, `
+  SELECT`+recordColumns+`
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.id = ANY($1)
+  AND tr.deleted_at IS NULL;
+  `, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]*TimeRecord, len(recs))
+	for i := range recs {
+		byID[recs[i].RecordID] = &recs[i]
+	}
+	for i := range entries {
+		e := &entries[i]
+		if e.Kind == FeedTombstone {
+			continue
+		}
+		// deleted since the changes were read
+		if e.Record = byID[e.RecordID]; e.Record == nil {
+			e.Kind = FeedTombstone
+			continue
+		}
+		e.Version = e.Record.Version
+	}
+	return entries, nil
+}
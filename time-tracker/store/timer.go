@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+)
+
+// timerColumns are the columns of timers selected from the table timers.
+// Use scanTimer to scan the columns.
+const timerColumns = `
+	user_id,
+	name,
+	start_time AT TIME ZONE start_time_loc,
+	start_time_loc,
+	COALESCE(project_id, 0),
+	billable`
+
+// StartTimer starts a timer for the user. Returns ErrTimerRunning if the user
+// has a running timer already.
+func (ts *TimeRecordStore) StartTimer(ctx context.Context, t Timer) (_ *Timer, err error) {
+	ctx, end := ts.instrument(ctx, "StartTimer")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	started, err := scanTimer(tx.QueryRowContext(ctx, `
+  INSERT INTO timers(
+    user_id,
+	name,
+	start_time,
+	start_time_loc,
+	project_id,
+	billable)
+  VALUES($1,$2,$3,$4,NULLIF($5,0),$6)
+  ON CONFLICT (user_id) DO NOTHING
+  RETURNING`+timerColumns,
+		t.UserID,
+		t.Name,
+		t.Start,
+		t.StartLoc,
+		t.ProjectID,
+		t.Billable).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrTimerRunning
+	}
+	if err != nil {
+		return nil, err
+	}
+	ev, err := insertEvent(ctx, tx, webhook.TimerStarted, started.UserID, started)
+	if err != nil {
+		return nil, err
+	}
+	return started, ts.commit(ctx, tx, ev)
+}
+
+// Timer returns the running timer of a user. Returns ErrNotFound if the user
+// has no running timer.
+func (ts *TimeRecordStore) Timer(ctx context.Context, userID uint64) (_ *Timer, err error) {
+	ctx, end := ts.instrument(ctx, "Timer")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	t, err := scanTimer(db.QueryRowContext(ctx, `
+  SELECT`+timerColumns+`
+  FROM timers
+  WHERE user_id = $1
+  `, userID).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
+
+// StopTimer stops the running timer of a user and creates a time record from
+// it. Returns ErrNotFound if the user has no running timer, ErrInvalidStop if
+// stop is before the start of the timer and ErrRecordLocked if the timer
+// started inside an approved timesheet.
+func (ts *TimeRecordStore) StopTimer(ctx context.Context, userID uint64, stop time.Time, stopLoc string) (_ *TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "StopTimer")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	var start time.Time // absolute start time
+	t, err := scanTimer(scanWith(tx.QueryRowContext(ctx, `
+  DELETE FROM timers
+  WHERE user_id = $1
+  RETURNING`+timerColumns+`,
+	start_time
+  `, userID).Scan, &start))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stop.Before(start) {
+		return nil, ErrInvalidStop
+	}
+	loc, err := time.LoadLocation(t.StartLoc)
+	if err != nil {
+		return nil, err
+	}
+	rec, created, err := insertRecord(ctx, tx, TimeRecord{
+		UserID:    t.UserID,
+		Name:      t.Name,
+		Start:     start.In(loc),
+		StartLoc:  t.StartLoc,
+		Stop:      stop,
+		StopLoc:   stopLoc,
+		Duration:  int64(stop.Sub(start) / time.Second),
+		ProjectID: t.ProjectID,
+		Billable:  t.Billable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stopped, err := insertEvent(ctx, tx, webhook.TimerStopped, rec.UserID, rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, ts.commit(ctx, tx, stopped, created)
+}
+
+// scanTimer scans a timer from the columns selected by timerColumns.
+func scanTimer(scan func(dest ...interface{}) error) (*Timer, error) {
+	var t Timer
+	if err := scan(
+		&t.UserID,
+		&t.Name,
+		&t.Start,
+		&t.StartLoc,
+		&t.ProjectID,
+		&t.Billable); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -2,13 +2,45 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
 	"github.com/fgrimme/time-tracker/time-tracker/database"
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
 )
 
+// recordColumns are the columns of time records selected from the table
+// time_records aliased as tr joined with projects aliased as p.
+// Use scanRecord to scan the columns.
+const recordColumns = `
+	tr.id,
+	tr.user_id,
+	tr.name,
+	tr.start_time AT TIME ZONE tr.start_time_loc,
+	tr.start_time_loc,
+	tr.stop_time AT TIME ZONE tr.stop_time_loc,
+	tr.stop_time_loc,
+	tr.duration,
+	COALESCE(tr.project_id, 0),
+	COALESCE(p.client_id, 0),
+	tr.billable,
+	tr.rate_amount::text,
+	tr.rate_currency,
+	COALESCE(tr.invoice_id, 0),
+	tr.deleted_at,
+	tr.uuid,
+	tr.version,
+	tr.notes,
+	tr.tags`
+
 type TimeRecordStore struct {
-	db *database.DB
+	db        *database.DB
+	publisher events.Publisher
+	metrics   *storeMetrics
 }
 
 func New(db *database.DB) *TimeRecordStore {
@@ -17,116 +49,585 @@ func New(db *database.DB) *TimeRecordStore {
 	}
 }
 
+// PublishTo publishes the changes of time records and timers to p after they
+// are committed.
+func (ts *TimeRecordStore) PublishTo(p events.Publisher) {
+	ts.publisher = p
+}
+
+// commit commits the transaction and publishes its events. Events which fail
+// to be published are logged since the change is committed already.
+func (ts *TimeRecordStore) commit(ctx context.Context, tx *sql.Tx, evs ...events.Event) error {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if ts.publisher == nil {
+		return nil
+	}
+	for _, e := range evs {
+		if err := ts.publisher.Publish(ctx, e); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Str("event", e.Type).Msg("failed to publish event")
+		}
+	}
+	return nil
+}
+
 // Create inserts a new time record to the datastore. The record id is not inserted
-// and must be created by the datastore. Returns the newly created record with
-// the generated id.
-func (ts *TimeRecordStore) Create(ctx context.Context, r TimeRecord) (*TimeRecord, error) {
+// and must be created by the datastore, as well as the uuid if it is empty.
+// Returns the newly created record with the generated id. The creation is
+// recorded in the record's history. Returns ErrRecordLocked if the record
+// starts inside an approved timesheet and ErrRecordExists if a record with
+// the uuid exists.
+func (ts *TimeRecordStore) Create(ctx context.Context, r TimeRecord) (_ *TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Create")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	rec, ev, err := insertRecord(ctx, tx, r)
+	if err != nil {
+		return nil, err
+	}
+	return rec, ts.commit(ctx, tx, ev)
+}
+
+// insertRecord inserts a new time record in the transaction and records the
+// creation in the history and the webhook outbox. Returns the created record
+// and the event to publish on commit.
+func insertRecord(ctx context.Context, tx *sql.Tx, r TimeRecord) (*TimeRecord, events.Event, error) {
 	query := `
-  INSERT INTO time_records(
-    user_id,
-	name,
-	start_time,
-	start_time_loc,
-	stop_time,
-	stop_time_loc,
-	duration)
-  VALUES($1,$2,$3,$4,$5,$6,$7)
-  RETURNING
-    id,
-    user_id,
-	name,
-	start_time AT TIME ZONE start_time_loc,
-	start_time_loc,
-	stop_time AT TIME ZONE stop_time_loc,
-	stop_time_loc,
-	duration
+  WITH tr AS (
+    INSERT INTO time_records(
+      user_id,
+	  name,
+	  start_time,
+	  start_time_loc,
+	  stop_time,
+	  stop_time_loc,
+	  duration,
+	  project_id,
+	  billable,
+	  rate_amount,
+	  rate_currency,
+	  uuid,
+	  notes,
+	  tags)
+    VALUES($1,$2,$3,$4,$5,$6,$7,NULLIF($8,0),$9,$10,$11,COALESCE(NULLIF($12,'')::uuid, gen_random_uuid()),$13,$14)
+    RETURNING *
+  )
+  SELECT` + recordColumns + `,
+	to_jsonb(tr)
+  FROM tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
   `
+	if err := checkTimesheet(ctx, tx, r.UserID, r.Start); err != nil {
+		return nil, events.Event{}, err
+	}
+	rateAmount, rateCurrency := rateValues(r.Rate)
+	var after []byte
+	row := tx.QueryRowContext(ctx, query,
+		r.UserID,
+		r.Name,
+		r.Start,
+		r.StartLoc,
+		r.Stop,
+		r.StopLoc,
+		r.Duration,
+		r.ProjectID,
+		r.Billable,
+		rateAmount,
+		rateCurrency,
+		r.UUID,
+		r.Notes,
+		tagsValue(r.Tags))
+	rec, err := scanRecord(scanWith(row.Scan, &after))
+	if isUniqueViolation(err, "time_records_uuid_key") {
+		return nil, events.Event{}, ErrRecordExists
+	}
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	if err := insertHistory(ctx, tx, actionCreate, rec.RecordID, rec.UserID, nil, after); err != nil {
+		return nil, events.Event{}, err
+	}
+	ev, err := insertEvent(ctx, tx, webhook.RecordCreated, rec.UserID, rec)
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	return rec, ev, nil
+}
+
+// Update replaces the values of the record with the id r.RecordID. The user
+// and uuid of a record can not be changed. The change is recorded in the
+// record's history. Returns ErrNotFound if the record does not exist and
+// ErrRecordLocked if it is locked by an invoice or an approved timesheet.
+func (ts *TimeRecordStore) Update(ctx context.Context, r TimeRecord) (_ *TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Update")
+	defer end(&err)
 	db := ts.db.GetDB()
 	ctx, cancel := ts.db.RequestContext(ctx)
 	defer cancel()
 
-	var tr TimeRecord
-	err := db.QueryRowContext(ctx, query,
-		r.UserID,
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	rec, ev, err := updateRecord(ctx, tx, r)
+	if err != nil {
+		return nil, err
+	}
+	return rec, ts.commit(ctx, tx, ev)
+}
+
+// updateRecord replaces the values of a record in the transaction and records
+// the change in the history and the webhook outbox. Returns the updated record
+// and the event to publish on commit.
+func updateRecord(ctx context.Context, tx *sql.Tx, r TimeRecord) (*TimeRecord, events.Event, error) {
+	query := `
+  WITH tr AS (
+    UPDATE time_records
+    SET
+      name = $2,
+	  start_time = $3,
+	  start_time_loc = $4,
+	  stop_time = $5,
+	  stop_time_loc = $6,
+	  duration = $7,
+	  project_id = NULLIF($8,0),
+	  billable = $9,
+	  rate_amount = $10,
+	  rate_currency = $11,
+	  notes = $12,
+	  tags = $13,
+	  version = version + 1
+    WHERE id = $1
+    RETURNING *
+  )
+  SELECT` + recordColumns + `,
+	to_jsonb(tr)
+  FROM tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  `
+	before, err := lockRecord(ctx, tx, r.RecordID, false)
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	rateAmount, rateCurrency := rateValues(r.Rate)
+	var after []byte
+	row := tx.QueryRowContext(ctx, query,
+		r.RecordID,
 		r.Name,
 		r.Start,
 		r.StartLoc,
 		r.Stop,
 		r.StopLoc,
-		r.Duration).
-		Scan(
-			&tr.RecordID,
-			&tr.UserID,
-			&tr.Name,
-			&tr.Start,
-			&tr.StartLoc,
-			&tr.Stop,
-			&tr.StopLoc,
-			&tr.Duration)
+		r.Duration,
+		r.ProjectID,
+		r.Billable,
+		rateAmount,
+		rateCurrency,
+		r.Notes,
+		tagsValue(r.Tags))
+	rec, err := scanRecord(scanWith(row.Scan, &after))
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	// the record must not be moved into an approved timesheet either
+	if err := checkTimesheet(ctx, tx, rec.UserID, r.Start); err != nil {
+		return nil, events.Event{}, err
+	}
+	if err := insertHistory(ctx, tx, actionUpdate, rec.RecordID, rec.UserID, before, after); err != nil {
+		return nil, events.Event{}, err
+	}
+	ev, err := insertEvent(ctx, tx, webhook.RecordUpdated, rec.UserID, rec)
+	if err != nil {
+		return nil, events.Event{}, err
+	}
+	return rec, ev, nil
+}
+
+// Delete moves the record with the given id to the trash. Deleted records
+// are excluded from all queries except Trash until they are restored or
+// purged. The deletion is recorded in the record's history. Returns
+// ErrNotFound if the record does not exist or is deleted already and
+// ErrRecordLocked if it is locked by an invoice or an approved timesheet.
+func (ts *TimeRecordStore) Delete(ctx context.Context, id uint64) (err error) {
+	ctx, end := ts.instrument(ctx, "Delete")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	ev, err := deleteRecord(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	return ts.commit(ctx, tx, ev)
+}
+
+// deleteRecord moves a record to the trash in the transaction and records the
+// deletion in the history and the webhook outbox. Returns the event to
+// publish on commit.
+func deleteRecord(ctx context.Context, tx *sql.Tx, id uint64) (events.Event, error) {
+	query := `
+  WITH tr AS (
+    UPDATE time_records
+    SET
+      deleted_at = now(),
+	  version = version + 1
+    WHERE id = $1
+    RETURNING *
+  )
+  SELECT` + recordColumns + `,
+	to_jsonb(tr)
+  FROM tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  `
+	before, err := lockRecord(ctx, tx, id, false)
+	if err != nil {
+		return events.Event{}, err
+	}
+	var after []byte
+	rec, err := scanRecord(scanWith(tx.QueryRowContext(ctx, query, id).Scan, &after))
+	if err != nil {
+		return events.Event{}, err
+	}
+	if err := insertHistory(ctx, tx, actionDelete, id, rec.UserID, before, after); err != nil {
+		return events.Event{}, err
+	}
+	return insertEvent(ctx, tx, webhook.RecordDeleted, rec.UserID, rec)
+}
+
+// Restore moves the record with the given id out of the trash. The
+// restoration is recorded in the record's history. Returns ErrNotFound if the
+// record is not in the trash.
+func (ts *TimeRecordStore) Restore(ctx context.Context, id uint64) (_ *TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Restore")
+	defer end(&err)
+	query := `
+  WITH tr AS (
+    UPDATE time_records
+    SET
+      deleted_at = NULL,
+	  version = version + 1
+    WHERE id = $1
+    RETURNING *
+  )
+  SELECT` + recordColumns + `,
+	to_jsonb(tr)
+  FROM tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  `
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return &tr, nil
+	defer tx.Rollback() // no-op after commit
+
+	before, err := lockRecord(ctx, tx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	var after []byte
+	rec, err := scanRecord(scanWith(tx.QueryRowContext(ctx, query, id).Scan, &after))
+	if err != nil {
+		return nil, err
+	}
+	if err := insertHistory(ctx, tx, actionRestore, rec.RecordID, rec.UserID, before, after); err != nil {
+		return nil, err
+	}
+	ev, err := insertEvent(ctx, tx, webhook.RecordRestored, rec.UserID, rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, ts.commit(ctx, tx, ev)
 }
 
-func (ts *TimeRecordStore) Get(ctx context.Context, userID uint64, t time.Time) ([]TimeRecord, error) {
+// Trash returns the deleted records of a user, latest deletion first.
+func (ts *TimeRecordStore) Trash(ctx context.Context, userID uint64) (_ []TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Trash")
+	defer end(&err)
 	query := `
-  SELECT
-  	id,
-	name,
-	start_time AT TIME ZONE tr.start_time_loc,
-	start_time_loc,
-	stop_time AT TIME ZONE tr.stop_time_loc,
-	stop_time_loc,
-	duration
+  SELECT` + recordColumns + `
   FROM time_records
   AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.user_id = $1
+  AND tr.deleted_at IS NOT NULL
+  ORDER BY tr.deleted_at DESC;
+  `
+	return ts.queryRecords(ctx, query, userID)
+}
+
+// Purge permanently deletes records which have been deleted before the given
+// time. Records which have been billed by a voided invoice are kept since the
+// invoice refers to them. Purges are recorded in the records' history.
+// Returns the number of purged records.
+func (ts *TimeRecordStore) Purge(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, end := ts.instrument(ctx, "Purge")
+	defer end(&err)
+	query := `
+  WITH tr AS (
+    DELETE FROM time_records
+    WHERE deleted_at < $1
+    AND NOT EXISTS (
+      SELECT 1 FROM invoice_lines AS l WHERE l.record_id = time_records.id)
+    RETURNING *
+  )
+  INSERT INTO record_history(
+    record_id,
+	user_id,
+	actor_id,
+	action,
+	before)
+  SELECT id, user_id, user_id, $2, to_jsonb(tr)
+  FROM tr
+  `
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	// the deletion and its history are written in a single statement
+	res, err := db.ExecContext(ctx, query, before, actionPurge)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// lockRecord locks the record with the given id for the rest of the
+// transaction and returns its current values as JSON. Only matches records
+// in the trash if deleted is true and only records not in the trash
+// otherwise. Returns ErrNotFound if there is no matching record and
+// ErrRecordLocked if it is locked by an invoice or an approved timesheet.
+func lockRecord(ctx context.Context, tx *sql.Tx, id uint64, deleted bool) ([]byte, error) {
+	var before []byte
+	var invoiced bool
+	var userID uint64
+	var start time.Time
+	err := tx.QueryRowContext(ctx, `
+  SELECT to_jsonb(tr), tr.invoice_id IS NOT NULL, tr.user_id, tr.start_time
+  FROM time_records AS tr
+  WHERE tr.id = $1
+  AND (tr.deleted_at IS NOT NULL) = $2
+  FOR UPDATE
+  `, id, deleted).Scan(&before, &invoiced, &userID, &start)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invoiced {
+		return nil, ErrRecordLocked
+	}
+	if err := checkTimesheet(ctx, tx, userID, start); err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// rateValues returns the nullable column values of a per-record rate override.
+func rateValues(rate *billing.Money) (amount, currency sql.NullString) {
+	if rate == nil {
+		return
+	}
+	return sql.NullString{String: rate.Decimal(), Valid: true},
+		sql.NullString{String: rate.Currency, Valid: true}
+}
+
+// tagsValue returns the column value of tags, records without tags have an
+// empty array.
+func tagsValue(tags []string) interface{} {
+	if tags == nil {
+		tags = []string{}
+	}
+	return pq.Array(tags)
+}
+
+// Get returns the records of a user which stopped at or after time t, latest
+// first. Deleted records are excluded.
+func (ts *TimeRecordStore) Get(ctx context.Context, userID uint64, t time.Time) (_ []TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Get")
+	defer end(&err)
+	query := `
+  SELECT` + recordColumns + `
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
   WHERE tr.user_id = $1
   AND
   tr.stop_time >= $2
+  AND tr.deleted_at IS NULL
   ORDER BY start_time DESC;
   `
+	return ts.queryRecords(ctx, query, userID, t)
+}
+
+// Record returns the record with the id, including records in the trash.
+// Returns ErrNotFound if there is none.
+func (ts *TimeRecordStore) Record(ctx context.Context, id uint64) (_ *TimeRecord, err error) {
+	ctx, end := ts.instrument(ctx, "Record")
+	defer end(&err)
+	query := `
+  SELECT` + recordColumns + `
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.id = $1;
+  `
+	recs, err := ts.queryRecords(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &recs[0], nil
+}
 
+// RecordsVersion identifies the records returned by Get. Versions are kept
+// per record, so the count and the maximum version alone do not change on
+// every change: the sum of the versions changes on updates, deletions and
+// restores, the count and the maximum id on creations.
+type RecordsVersion struct {
+	Count      uint64
+	MaxID      uint64
+	VersionSum uint64
+}
+
+// GetVersion returns the version of the records Get returns for the same
+// arguments without reading them.
+func (ts *TimeRecordStore) GetVersion(ctx context.Context, userID uint64, t time.Time) (_ RecordsVersion, err error) {
+	ctx, end := ts.instrument(ctx, "GetVersion")
+	defer end(&err)
 	db := ts.db.GetDB()
 	ctx, cancel := ts.db.RequestContext(ctx)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, userID, t)
+	var v RecordsVersion
+	err = db.QueryRowContext(ctx, `
+  SELECT count(*), COALESCE(max(tr.id), 0), COALESCE(sum(tr.version), 0)
+  FROM time_records
+  AS tr
+  WHERE tr.user_id = $1
+  AND
+  tr.stop_time >= $2
+  AND tr.deleted_at IS NULL;
+  `, userID, t).Scan(&v.Count, &v.MaxID, &v.VersionSum)
+	return v, err
+}
+
+// queryRecords runs a query selecting recordColumns and returns the scanned
+// records.
+func (ts *TimeRecordStore) queryRecords(ctx context.Context, query string, args ...interface{}) ([]TimeRecord, error) {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	recs := make([]TimeRecord, 0)
-
-	var id uint64
-	var name string
-	var start, stop time.Time
-	var startLoc, stopLoc string
-	var duration int64
 	for rows.Next() {
-		if err := rows.Scan(
-			&id,
-			&name,
-			&start,
-			&startLoc,
-			&stop,
-			&stopLoc,
-			&duration); err != nil {
+		rec, err := scanRecord(rows.Scan)
+		if err != nil {
 			return nil, err
 		}
-		rec := TimeRecord{
-			RecordID: id,
-			UserID:   userID,
-			Name:     name,
-			Start:    start,
-			StartLoc: startLoc,
-			Stop:     stop,
-			StopLoc:  stopLoc,
-			Duration: duration,
-		}
-		recs = append(recs, rec)
+		recs = append(recs, *rec)
 	}
-
 	return recs, rows.Err()
 }
+
+// scanRecord scans a time record from the columns selected by recordColumns.
+func scanRecord(scan func(dest ...interface{}) error) (*TimeRecord, error) {
+	var tr TimeRecord
+	var rateAmount, rateCurrency sql.NullString
+	var deleted sql.NullTime
+	if err := scan(
+		&tr.RecordID,
+		&tr.UserID,
+		&tr.Name,
+		&tr.Start,
+		&tr.StartLoc,
+		&tr.Stop,
+		&tr.StopLoc,
+		&tr.Duration,
+		&tr.ProjectID,
+		&tr.ClientID,
+		&tr.Billable,
+		&rateAmount,
+		&rateCurrency,
+		&tr.InvoiceID,
+		&deleted,
+		&tr.UUID,
+		&tr.Version,
+		&tr.Notes,
+		pq.Array(&tr.Tags)); err != nil {
+		return nil, err
+	}
+	if deleted.Valid {
+		tr.Deleted = &deleted.Time
+	}
+	rate, err := scanRate(rateAmount, rateCurrency)
+	if err != nil {
+		return nil, err
+	}
+	tr.Rate = rate
+	return &tr, nil
+}
+
+// isUniqueViolation reports whether err is the violation of the unique
+// constraint with the given name.
+func isUniqueViolation(err error, constraint string) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == "23505" && e.Constraint == constraint
+}
+
+// isUndefinedTable reports whether err is caused by a missing table.
+func isUndefinedTable(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && e.Code == "42P01"
+}
+
+// scanWith returns a scan function which scans the columns of scan into dest
+// followed by extra.
+func scanWith(scan func(dest ...interface{}) error, extra ...interface{}) func(dest ...interface{}) error {
+	return func(dest ...interface{}) error {
+		return scan(append(dest, extra...)...)
+	}
+}
+
+// scanRate returns the per-record rate override from nullable columns.
+func scanRate(amount, currency sql.NullString) (*billing.Money, error) {
+	if !amount.Valid || !currency.Valid {
+		return nil, nil
+	}
+	m, err := billing.ParseMoney(amount.String, currency.String)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// timesheetColumns are the columns of timesheets selected from the table
+// timesheets aliased as t. Use scanTimesheet to scan the columns.
+const timesheetColumns = `
+	t.id,
+	t.user_id,
+	t.iso_year,
+	t.iso_week,
+	t.week_loc,
+	t.period_from,
+	t.period_to,
+	t.state`
+
+// CreateTimesheet creates a draft timesheet for the user's week. If the
+// timesheet exists already, the existing one is returned.
+func (ts *TimeRecordStore) CreateTimesheet(ctx context.Context, t Timesheet) (_ *Timesheet, err error) {
+	ctx, end := ts.instrument(ctx, "CreateTimesheet")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	if _, err := db.ExecContext(ctx, `
+  INSERT INTO timesheets(
+    user_id,
+	iso_year,
+	iso_week,
+	week_loc,
+	period_from,
+	period_to,
+	state)
+  VALUES($1,$2,$3,$4,$5,$6,$7)
+  ON CONFLICT (user_id, iso_year, iso_week) DO NOTHING
+  `,
+		t.UserID,
+		t.Year,
+		t.Week,
+		t.Loc,
+		t.Start,
+		t.Stop,
+		TimesheetDraft); err != nil {
+		return nil, err
+	}
+	var id uint64
+	if err := db.QueryRowContext(ctx, `
+  SELECT id FROM timesheets WHERE user_id = $1 AND iso_year = $2 AND iso_week = $3
+  `, t.UserID, t.Year, t.Week).Scan(&id); err != nil {
+		return nil, err
+	}
+	return ts.Timesheet(ctx, id)
+}
+
+// Timesheet returns the timesheet with the given id including its events and
+// the records of the week. Returns ErrNotFound if it does not exist.
+func (ts *TimeRecordStore) Timesheet(ctx context.Context, id uint64) (_ *Timesheet, err error) {
+	ctx, end := ts.instrument(ctx, "Timesheet")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	t, err := scanTimesheet(db.QueryRowContext(ctx, `
+  SELECT`+timesheetColumns+`
+  FROM timesheets AS t
+  WHERE t.id = $1
+  `, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT state, actor_id, comment, created_at
+  FROM timesheet_events
+  WHERE timesheet_id = $1
+  ORDER BY id;
+  `, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	t.Events = make([]TimesheetEvent, 0)
+	for rows.Next() {
+		var e TimesheetEvent
+		if err := rows.Scan(&e.State, &e.ActorID, &e.Comment, &e.Created); err != nil {
+			return nil, err
+		}
+		t.Events = append(t.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	t.Records, err = ts.queryRecords(ctx, `
+  SELECT`+recordColumns+`
+  FROM time_records
+  AS tr
+  LEFT JOIN projects AS p ON p.id = tr.project_id
+  WHERE tr.user_id = $1
+  AND tr.start_time >= $2
+  AND tr.start_time < $3
+  AND tr.deleted_at IS NULL
+  ORDER BY tr.start_time;
+  `, t.UserID, t.Start, t.Stop)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Timesheets returns the timesheets of a user, latest week first.
+func (ts *TimeRecordStore) Timesheets(ctx context.Context, userID uint64) (_ []Timesheet, err error) {
+	ctx, end := ts.instrument(ctx, "Timesheets")
+	defer end(&err)
+	query := `
+  SELECT` + timesheetColumns + `
+  FROM timesheets AS t
+  WHERE t.user_id = $1
+  ORDER BY t.period_from DESC;
+  `
+	return ts.queryTimesheets(ctx, query, userID)
+}
+
+// PendingTimesheets returns the submitted timesheets of the team of a lead,
+// which are the users the lead is assigned to, oldest week first.
+func (ts *TimeRecordStore) PendingTimesheets(ctx context.Context, leadID uint64) (_ []Timesheet, err error) {
+	ctx, end := ts.instrument(ctx, "PendingTimesheets")
+	defer end(&err)
+	query := `
+  SELECT` + timesheetColumns + `
+  FROM timesheets AS t
+  JOIN users AS u ON u.id = t.user_id
+  WHERE u.lead_id = $1
+  AND t.state = $2
+  ORDER BY t.period_from, t.id;
+  `
+	return ts.queryTimesheets(ctx, query, leadID, TimesheetSubmitted)
+}
+
+// SetTimesheetState changes the state of a timesheet and records the change
+// with the actor's comment. Only the user may submit a timesheet and only the
+// user's lead may approve or reject it. Returns ErrNotFound if the timesheet
+// does not exist, ErrTransition if the state change is not allowed and
+// ErrForbidden if there is no actor or the actor may not make the change.
+func (ts *TimeRecordStore) SetTimesheetState(ctx context.Context, id uint64, to TimesheetState, actorID uint64, comment string) (_ *Timesheet, err error) {
+	ctx, end := ts.instrument(ctx, "SetTimesheetState")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	var from TimesheetState
+	var userID uint64
+	var leadID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+  SELECT t.state, t.user_id, u.lead_id
+  FROM timesheets AS t
+  JOIN users AS u ON u.id = t.user_id
+  WHERE t.id = $1
+  FOR UPDATE OF t
+  `, id).Scan(&from, &userID, &leadID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !from.CanTransition(to) {
+		return nil, ErrTransition
+	}
+	// every change needs an actor, a submission without one would be
+	// recorded as the user's own
+	if actorID == 0 {
+		return nil, ErrForbidden
+	}
+	if to == TimesheetSubmitted {
+		if actorID != userID {
+			return nil, ErrForbidden
+		}
+	} else if !leadID.Valid || uint64(leadID.Int64) != actorID {
+		return nil, ErrForbidden
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+  UPDATE timesheets SET state = $2 WHERE id = $1
+  `, id, to); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+  INSERT INTO timesheet_events(
+    timesheet_id,
+	state,
+	actor_id,
+	comment)
+  VALUES($1,$2,$3,$4)
+  `, id, to, actorID, comment); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ts.Timesheet(ctx, id)
+}
+
+// queryTimesheets runs a query selecting timesheetColumns and returns the
+// scanned timesheets without events and records.
+func (ts *TimeRecordStore) queryTimesheets(ctx context.Context, query string, args ...interface{}) ([]Timesheet, error) {
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sheets := make([]Timesheet, 0)
+	for rows.Next() {
+		t, err := scanTimesheet(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, *t)
+	}
+	return sheets, rows.Err()
+}
+
+// scanTimesheet scans a timesheet from the columns selected by
+// timesheetColumns.
+func scanTimesheet(scan func(dest ...interface{}) error) (*Timesheet, error) {
+	var t Timesheet
+	if err := scan(
+		&t.TimesheetID,
+		&t.UserID,
+		&t.Year,
+		&t.Week,
+		&t.Loc,
+		&t.Start,
+		&t.Stop,
+		&t.State); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// checkTimesheet returns ErrRecordLocked if a record of the user starting at
+// time start is inside an approved timesheet. The timesheets of the start are
+// share locked for the rest of the transaction, whatever their state, so a
+// concurrent approval waits for the change of the record, or the check waits
+// for the approval and sees it.
+func checkTimesheet(ctx context.Context, tx *sql.Tx, userID uint64, start time.Time) error {
+	rows, err := tx.QueryContext(ctx, `
+  SELECT state FROM timesheets
+  WHERE user_id = $1
+  AND period_from <= $2
+  AND period_to > $2
+  FOR SHARE
+  `, userID, start)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	approved := false
+	for rows.Next() {
+		var state TimesheetState
+		if err := rows.Scan(&state); err != nil {
+			return err
+		}
+		approved = approved || state == TimesheetApproved
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if approved {
+		return ErrRecordLocked
+	}
+	return nil
+}
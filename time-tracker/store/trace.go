@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("github.com/fgrimme/time-tracker/time-tracker/store")
+
+// instrument starts the span of a call of the method, the statements of the
+// call are traced as its children. The returned function ends the span and
+// records the latency and error of the call in the metrics. It is deferred
+// at the beginning of the method with the address of its error result.
+func (ts *TimeRecordStore) instrument(ctx context.Context, method string) (context.Context, func(err *error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "TimeRecordStore."+method)
+	return ctx, func(err *error) {
+		if *err != nil && !expected(*err) {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+		ts.observe(method, start, *err)
+	}
+}
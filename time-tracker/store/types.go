@@ -3,7 +3,12 @@ package store
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/fgrimme/time-tracker/time-tracker/billing"
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
 )
 
 // User input must always be a timestamp of seconds since UNIX epoch and a
@@ -24,14 +29,43 @@ import (
 // In other words, it contains the start and stop time in an UTC-offset aware
 // format after conversion from the user's input.
 type TimeRecord struct {
-	RecordID uint64
-	UserID   uint64
-	Name     string
-	Start    time.Time // time in the user's location
-	StartLoc string
-	Stop     time.Time // time in the user's location
-	StopLoc  string
-	Duration int64
+	RecordID  uint64
+	UserID    uint64
+	Name      string
+	Start     time.Time // time in the user's location
+	StartLoc  string
+	Stop      time.Time // time in the user's location
+	StopLoc   string
+	Duration  int64
+	ProjectID uint64         // 0 if not assigned to a project
+	ClientID  uint64         // client of the project, read only
+	Billable  bool           // time is invoiced to the client
+	Rate      *billing.Money // hourly rate overriding all other rates
+	InvoiceID uint64         // invoice the record is locked by, read only
+	Deleted   *time.Time     // time the record was moved to the trash, read only
+	UUID      string         // identifies the record across devices, generated by the datastore if empty
+	Version   uint64         // incremented by every change, read only
+	Notes     string         // details of the work, searched with the name
+	Tags      []string       // lower case and without duplicates, see NormalizeTags
+}
+
+// InLocation returns the wall clock of t in the location with the given name.
+// The store reads the start and stop of records as the wall clock in the
+// user's location with a +00 offset, see recordColumns, so they are anchored
+// in the location before they are used as points in time. t is returned
+// unchanged if it is in the location already or the location is empty or
+// unknown.
+func InLocation(t time.Time, name string) time.Time {
+	if t.IsZero() || name == "" || t.Location().String() == name {
+		return t
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return t
+	}
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	return time.Date(year, month, day, hour, min, sec, t.Nanosecond(), loc)
 }
 
 // TimeStamp is a timezone naive representation of a time record.
@@ -47,6 +81,45 @@ type TimeStamp struct {
 	Stop     int64  `json:"stop_time"` // seconds since UNIX epoch
 	StopLoc  string `json:"stop_loc"`
 	Duration int64  `json:"duration"`
+
+	ProjectID uint64         `json:"project_id,omitempty"`
+	Billable  bool           `json:"billable,omitempty"`
+	Rate      *billing.Money `json:"rate,omitempty"`
+	UUID      string         `json:"uuid,omitempty"`
+	Notes     string         `json:"notes,omitempty"`
+	Tags      []string       `json:"tags,omitempty"`
+}
+
+// Limits of the name, notes and tags of a record.
+const (
+	MaxNameLength  = 256   // characters
+	MaxNotesLength = 10000 // characters
+	MaxTags        = 20
+	MaxTagLength   = 50 // characters
+)
+
+// NormalizeTags returns the tags in lower case without surrounding spaces,
+// empty tags and duplicates, so records are tagged alike regardless of how a
+// tag is typed. Returns an error if there are too many tags or a tag is too
+// long.
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag longer than %d characters: %q", MaxTagLength, tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("more than %d tags", MaxTags)
+	}
+	return normalized, nil
 }
 
 // UnmarshalJSON unmarshals an offset naive timestamp with start and stop time
@@ -57,61 +130,434 @@ func (tr *TimeRecord) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &ts); err != nil {
 		return err
 	}
+	r, err := NewTimeRecord(ts)
+	if err != nil {
+		return err
+	}
+	*tr = r
+	return nil
+}
 
+// NewTimeRecord converts an offset naive timestamp to an offset aware time
+// record with the start and stop time in the user's location. The record id
+// is not taken over, it is assigned by the datastore or taken from the
+// request path. Returns an error if a location, the uuid, the rate, the notes
+// or the tags are invalid.
+func NewTimeRecord(ts TimeStamp) (TimeRecord, error) {
+	if ts.UUID != "" && !ValidUUID(ts.UUID) {
+		return TimeRecord{}, fmt.Errorf("invalid uuid: %q", ts.UUID)
+	}
+	if utf8.RuneCountInString(ts.Notes) > MaxNotesLength {
+		return TimeRecord{}, fmt.Errorf("notes longer than %d characters", MaxNotesLength)
+	}
+	tags, err := NormalizeTags(ts.Tags)
+	if err != nil {
+		return TimeRecord{}, err
+	}
+	if ts.Rate != nil {
+		if err := ts.Rate.Validate(); err != nil {
+			return TimeRecord{}, fmt.Errorf("invalid rate: %v", err)
+		}
+	}
 	// get the start time in the users location
 	loc, err := time.LoadLocation(ts.StartLoc)
 	if err != nil {
-		return err
+		return TimeRecord{}, err
 	}
 	startInLoc := time.Unix(ts.Start, 0).In(loc)
 
 	// get the stop time in the users location
 	loc, err = time.LoadLocation(ts.StopLoc)
 	if err != nil {
-		return err
+		return TimeRecord{}, err
 	}
 	stopInLoc := time.Unix(ts.Stop, 0).In(loc)
 
-	tr.UserID = ts.UserID
-	tr.Name = ts.Name
-	tr.Start = startInLoc
-	tr.StartLoc = ts.StartLoc
-	tr.Stop = stopInLoc
-	tr.StopLoc = ts.StopLoc
-	tr.Duration = ts.Duration
+	return TimeRecord{
+		UserID:    ts.UserID,
+		Name:      ts.Name,
+		Start:     startInLoc,
+		StartLoc:  ts.StartLoc,
+		Stop:      stopInLoc,
+		StopLoc:   ts.StopLoc,
+		Duration:  ts.Duration,
+		ProjectID: ts.ProjectID,
+		Billable:  ts.Billable,
+		Rate:      ts.Rate,
+		UUID:      ts.UUID,
+		Notes:     ts.Notes,
+		Tags:      tags,
+	}, nil
+}
 
-	return nil
+// Format is the representation of the times and durations of time records
+// in JSON.
+type Format string
+
+// Formats of time records. FormatHuman is the default, clients have to ask
+// for the others.
+const (
+	// FormatHuman formats times in the user's location without offset like
+	// 02 Jan 2006 15:04:05 and durations like 01:30:00.
+	FormatHuman Format = "human"
+	// FormatRFC3339 formats times in the user's location with offset like
+	// 2006-01-02T15:04:05+01:00, adds them as seconds since UNIX epoch and
+	// formats durations as ISO 8601 durations like PT1H30M.
+	FormatRFC3339 Format = "rfc3339"
+)
+
+// ParseFormat parses the name of a format, an empty name is FormatHuman.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatHuman, nil
+	case FormatHuman, FormatRFC3339:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format: %q", s)
 }
 
-// MarshalJSON formats the dates and duration.
+// MarshalJSON formats the dates and duration in FormatHuman in English.
 func (tr *TimeRecord) MarshalJSON() ([]byte, error) {
+	return tr.marshalJSON(FormatHuman, locale.Formatter{})
+}
+
+// FormattedRecord is a time record marshaled to JSON in a format. Records
+// in FormatHuman are formatted in the locale of the user.
+type FormattedRecord struct {
+	*TimeRecord
+	Format Format
+	Locale locale.Formatter
+}
+
+// MarshalJSON formats the dates and duration in the format of the record.
+func (fr FormattedRecord) MarshalJSON() ([]byte, error) {
+	return fr.TimeRecord.marshalJSON(fr.Format, fr.Locale)
+}
+
+// FormatRecords returns the records marshaled to JSON in format f and the
+// locale of lf.
+func FormatRecords(recs []TimeRecord, f Format, lf locale.Formatter) []FormattedRecord {
+	formatted := make([]FormattedRecord, len(recs))
+	for i := range recs {
+		formatted[i] = FormattedRecord{&recs[i], f, lf}
+	}
+	return formatted
+}
+
+func (tr *TimeRecord) marshalJSON(f Format, lf locale.Formatter) ([]byte, error) {
 	t := struct {
-		RecordID uint64 `json:"record_id"`
-		UserID   uint64 `json:"user_id"`
-		Name     string `json:"name"`
-		Start    string `json:"start_time"`
-		StartLoc string `json:"start_loc"`
-		Stop     string `json:"stop_time"`
-		StopLoc  string `json:"stop_loc"`
-		Duration string `json:"duration"`
+		RecordID   uint64 `json:"record_id"`
+		UserID     uint64 `json:"user_id"`
+		Name       string `json:"name"`
+		Start      string `json:"start_time"`
+		StartEpoch *int64 `json:"start_epoch,omitempty"`
+		StartLoc   string `json:"start_loc"`
+		Stop       string `json:"stop_time"`
+		StopEpoch  *int64 `json:"stop_epoch,omitempty"`
+		StopLoc    string `json:"stop_loc"`
+		Duration   string `json:"duration"`
+
+		ProjectID uint64         `json:"project_id,omitempty"`
+		ClientID  uint64         `json:"client_id,omitempty"`
+		Billable  bool           `json:"billable,omitempty"`
+		Rate      *billing.Money `json:"rate,omitempty"`
+		InvoiceID uint64         `json:"invoice_id,omitempty"`
+		Deleted   *time.Time     `json:"deleted_at,omitempty"`
+		UUID      string         `json:"uuid,omitempty"`
+		Version   uint64         `json:"version,omitempty"`
+		Notes     string         `json:"notes,omitempty"`
+		Tags      []string       `json:"tags,omitempty"`
 	}{
 		RecordID: tr.RecordID,
 		UserID:   tr.UserID,
 		Name:     tr.Name,
-		Start:    tr.Start.Format("02 Jan 2006 15:04:05"),
 		StartLoc: tr.StartLoc,
-		Stop:     tr.Stop.Format("02 Jan 2006 15:04:05"),
 		StopLoc:  tr.StopLoc,
-		Duration: formatDuration(time.Second * time.Duration(tr.Duration)),
+
+		ProjectID: tr.ProjectID,
+		ClientID:  tr.ClientID,
+		Billable:  tr.Billable,
+		Rate:      tr.Rate,
+		InvoiceID: tr.InvoiceID,
+		Deleted:   tr.Deleted,
+		UUID:      tr.UUID,
+		Version:   tr.Version,
+		Notes:     tr.Notes,
+		Tags:      tr.Tags,
+	}
+	d := time.Second * time.Duration(tr.Duration)
+	switch f {
+	case FormatRFC3339:
+		// the offset and epoch need the points in time, not the wall clocks
+		start, stop := InLocation(tr.Start, tr.StartLoc), InLocation(tr.Stop, tr.StopLoc)
+		startEpoch, stopEpoch := start.Unix(), stop.Unix()
+		t.Start = start.Format(time.RFC3339)
+		t.StartEpoch = &startEpoch
+		t.Stop = stop.Format(time.RFC3339)
+		t.StopEpoch = &stopEpoch
+		t.Duration = FormatISODuration(d)
+	default:
+		t.Start = lf.DateTime(tr.Start)
+		t.Stop = lf.DateTime(tr.Stop)
+		t.Duration = lf.Duration(d)
 	}
 	return json.Marshal(t)
 }
 
-func formatDuration(d time.Duration) string {
+// ValidUUID reports whether s is a UUID in its canonical textual form, e.g.
+// 9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a41.
+func ValidUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, c := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FormatDuration formats d as hours, minutes and seconds, e.g. "01:30:00".
+func FormatDuration(d time.Duration) string {
+	return locale.Formatter{}.Duration(d)
+}
+
+// FormatISODuration formats d as ISO 8601 duration in hours, minutes and
+// seconds, e.g. "PT1H30M". Days are not used, they are not always 24 hours
+// long.
+func FormatISODuration(d time.Duration) string {
+	if d < time.Second && d > -time.Second {
+		return "PT0S"
+	}
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
 	h := d / time.Hour
 	d -= h * time.Hour
 	m := d / time.Minute
 	d -= m * time.Minute
 	s := d / time.Second
-	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+	var b strings.Builder
+	b.WriteString(sign + "PT")
+	if h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+	}
+	if m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	if s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
+
+// Invoice bills the billable time records of a client in a period. The
+// records of an invoice are locked against changes until it is voided.
+type Invoice struct {
+	InvoiceID  uint64
+	Number     uint64 // sequential number assigned by the datastore
+	ClientID   uint64
+	ClientName string
+	From       time.Time // start of the period, inclusive
+	To         time.Time // end of the period, exclusive
+	Created    time.Time
+	Voided     *time.Time
+	Rounding   billing.Rounding
+	Lines      []InvoiceLine
+	Total      billing.Money
+}
+
+// InvoiceLine is the entry of a time record in an invoice. The rate and
+// amount are fixed when the invoice is created.
+type InvoiceLine struct {
+	RecordID uint64
+	UserID   uint64
+	Name     string
+	Start    time.Time // time in the user's location
+	StartLoc string
+	Duration int64 // exact duration of the record
+	Billed   int64 // duration rounded according to the invoice's policy
+	Rate     billing.Money
+	Amount   billing.Money // rounded to the minor unit of the currency
+	Version  uint64        // version of the record the line is built from, not stored
+}
+
+// Change is an entry in the append-only history of time records. Before and
+// after hold the JSON representation of the stored record, before is null
+// for creations and after for purges.
+type Change struct {
+	ChangeID  uint64          `json:"change_id"`
+	RecordID  uint64          `json:"record_id"`
+	UserID    uint64          `json:"user_id"`
+	ActorID   uint64          `json:"actor_id"`
+	Action    string          `json:"action"`
+	Changed   time.Time       `json:"changed_at"`
+	RequestID string          `json:"request_id"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+}
+
+// TimesheetState is the state of a timesheet in the approval workflow.
+type TimesheetState string
+
+// Timesheet states. A draft is submitted by the user and approved or
+// rejected by the user's lead. Rejected timesheets can be submitted again.
+// Records inside approved timesheets are locked against changes.
+const (
+	TimesheetDraft     TimesheetState = "draft"
+	TimesheetSubmitted TimesheetState = "submitted"
+	TimesheetApproved  TimesheetState = "approved"
+	TimesheetRejected  TimesheetState = "rejected"
+)
+
+// transitions holds the valid state transitions of timesheets.
+var transitions = map[TimesheetState][]TimesheetState{
+	TimesheetDraft:     {TimesheetSubmitted},
+	TimesheetSubmitted: {TimesheetApproved, TimesheetRejected},
+	TimesheetRejected:  {TimesheetSubmitted},
+}
+
+// CanTransition reports whether a timesheet in state s may change to state to.
+func (s TimesheetState) CanTransition(to TimesheetState) bool {
+	for _, t := range transitions[s] {
+		if t == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Timesheet is the time of a user in an ISO week, which is submitted to the
+// user's lead for approval.
+type Timesheet struct {
+	TimesheetID uint64           `json:"timesheet_id"`
+	UserID      uint64           `json:"user_id"`
+	Year        int              `json:"year"` // ISO year
+	Week        int              `json:"week"` // ISO week
+	Loc         string           `json:"tz"`
+	Start       time.Time        `json:"-"` // first day of the week in Loc
+	Stop        time.Time        `json:"-"` // first day of the next week in Loc
+	State       TimesheetState   `json:"state"`
+	Events      []TimesheetEvent `json:"events,omitempty"`
+	Records     []TimeRecord     `json:"records,omitempty"`
+}
+
+// TimesheetEvent is a state change of a timesheet with the comment of the
+// user or lead who made the change.
+type TimesheetEvent struct {
+	State   TimesheetState `json:"state"`
+	ActorID uint64         `json:"actor_id"`
+	Comment string         `json:"comment"`
+	Created time.Time      `json:"created_at"`
+}
+
+// MarshalJSON formats the start and stop of the week in the timesheet's
+// location.
+func (t *Timesheet) MarshalJSON() ([]byte, error) {
+	type timesheet Timesheet // prevents recursion
+	loc, err := time.LoadLocation(t.Loc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		*timesheet
+		Start string `json:"start_time"`
+		Stop  string `json:"stop_time"`
+	}{
+		timesheet: (*timesheet)(t),
+		Start:     t.Start.In(loc).Format("02 Jan 2006 15:04:05"),
+		Stop:      t.Stop.In(loc).Format("02 Jan 2006 15:04:05"),
+	})
+}
+
+// Timer is the running time record of a user. A user has at most one running
+// timer, stopping it creates a time record.
+type Timer struct {
+	UserID    uint64
+	Name      string
+	Start     time.Time // time in the user's location
+	StartLoc  string
+	ProjectID uint64 // 0 if not assigned to a project
+	Billable  bool
+}
+
+// TimerStamp is the timezone naive representation of a timer, see TimeStamp.
+type TimerStamp struct {
+	UserID    uint64 `json:"user_id"`
+	Name      string `json:"name"`
+	Start     int64  `json:"start_time"` // seconds since UNIX epoch
+	StartLoc  string `json:"start_loc"`
+	ProjectID uint64 `json:"project_id,omitempty"`
+	Billable  bool   `json:"billable,omitempty"`
+}
+
+// UnmarshalJSON unmarshals a timer with the start time as UNIX timestamp to a
+// timer with the start time in the user's location.
+func (t *Timer) UnmarshalJSON(data []byte) error {
+	var ts TimerStamp
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return err
+	}
+	timer, err := NewTimer(ts)
+	if err != nil {
+		return err
+	}
+	*t = timer
+	return nil
+}
+
+// NewTimer converts a timezone naive timer to a timer with the start time in
+// the user's location. A missing start time is left zero. Returns an error if
+// the location is unknown.
+func NewTimer(ts TimerStamp) (Timer, error) {
+	loc, err := time.LoadLocation(ts.StartLoc)
+	if err != nil {
+		return Timer{}, err
+	}
+	t := Timer{
+		UserID:    ts.UserID,
+		Name:      ts.Name,
+		StartLoc:  ts.StartLoc,
+		ProjectID: ts.ProjectID,
+		Billable:  ts.Billable,
+	}
+	if ts.Start != 0 {
+		t.Start = time.Unix(ts.Start, 0).In(loc)
+	}
+	return t, nil
+}
+
+// MarshalJSON formats the start time.
+func (t *Timer) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UserID    uint64 `json:"user_id"`
+		Name      string `json:"name"`
+		Start     string `json:"start_time"`
+		StartLoc  string `json:"start_loc"`
+		ProjectID uint64 `json:"project_id,omitempty"`
+		Billable  bool   `json:"billable,omitempty"`
+	}{
+		UserID:    t.UserID,
+		Name:      t.Name,
+		Start:     t.Start.Format("02 Jan 2006 15:04:05"),
+		StartLoc:  t.StartLoc,
+		ProjectID: t.ProjectID,
+		Billable:  t.Billable,
+	})
+}
+
+// Profile holds the preferences of a user.
+type Profile struct {
+	UserID uint64 `json:"user_id"`
+	locale.Preferences
 }
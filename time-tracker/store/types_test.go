@@ -1,10 +1,15 @@
 package store_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/fgrimme/time-tracker/time-tracker/locale"
 	"github.com/fgrimme/time-tracker/time-tracker/store"
 )
 
@@ -56,6 +61,32 @@ func TestUnmarshall(t *testing.T) {
 				Duration: 3600,
 			},
 		},
+		unmarshalTest{
+			d:  "notes and normalized tags",
+			in: []byte(`{"user_id":3,"name":"foo","start_time":1577833200,"start_loc":"Asia/Tokyo","stop_time":1577836800,"stop_loc":"Asia/Tokyo", "duration":3600,"notes":"call with the client","tags":[" Call","call","","client"]}`),
+			out: store.TimeRecord{
+				RecordID: 0,
+				UserID:   3,
+				Name:     "foo",
+				Start:    time.Date(2020, time.January, 1, 8, 0, 0, 0, locs["Asia/Tokyo"]),
+				StartLoc: "Asia/Tokyo",
+				Stop:     time.Date(2020, time.January, 1, 9, 0, 0, 0, locs["Asia/Tokyo"]),
+				StopLoc:  "Asia/Tokyo",
+				Duration: 3600,
+				Notes:    "call with the client",
+				Tags:     []string{"call", "client"},
+			},
+		},
+		unmarshalTest{
+			d:  "too long tag",
+			in: []byte(`{"user_id":3,"start_loc":"UTC","stop_loc":"UTC","tags":["` + strings.Repeat("a", 51) + `"]}`),
+			e:  fmt.Errorf("tag longer than 50 characters: %q", strings.Repeat("a", 51)),
+		},
+		unmarshalTest{
+			d:  "negative rate",
+			in: []byte(`{"user_id":3,"start_loc":"UTC","stop_loc":"UTC","rate":{"amount":"-80","currency":"EUR"}}`),
+			e:  errors.New("invalid rate: invalid amount"),
+		},
 	}
 	for _, tc := range unmarhsalTests {
 		var got store.TimeRecord
@@ -137,3 +168,113 @@ func TestMarshall(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshalFormat(t *testing.T) {
+	initLoc(t, "Europe/Berlin")
+	tr := store.TimeRecord{
+		RecordID: 1,
+		UserID:   3,
+		Name:     "foo",
+		Start:    time.Date(2020, time.January, 25, 9, 0, 0, 0, locs["Europe/Berlin"]),
+		StartLoc: "Europe/Berlin",
+		Stop:     time.Date(2020, time.January, 25, 10, 30, 0, 0, locs["Europe/Berlin"]),
+		StopLoc:  "Europe/Berlin",
+		Duration: 5400,
+	}
+	tests := []struct {
+		f   store.Format
+		l   locale.Formatter
+		out string
+	}{
+		{f: store.FormatHuman, out: `{"record_id":1,"user_id":3,"name":"foo","start_time":"25 Jan 2020 09:00:00","start_loc":"Europe/Berlin","stop_time":"25 Jan 2020 10:30:00","stop_loc":"Europe/Berlin","duration":"01:30:00"}`},
+		{f: store.FormatHuman, l: locale.Formatter{Locale: locale.German, Clock: locale.Clock12, DecimalHours: true}, out: `{"record_id":1,"user_id":3,"name":"foo","start_time":"25.01.2020 9:00:00 AM","start_loc":"Europe/Berlin","stop_time":"25.01.2020 10:30:00 AM","stop_loc":"Europe/Berlin","duration":"1,50"}`},
+		{f: store.FormatRFC3339, l: locale.Formatter{Locale: locale.German}, out: `{"record_id":1,"user_id":3,"name":"foo","start_time":"2020-01-25T09:00:00+01:00","start_epoch":1579939200,"start_loc":"Europe/Berlin","stop_time":"2020-01-25T10:30:00+01:00","stop_epoch":1579944600,"stop_loc":"Europe/Berlin","duration":"PT1H30M"}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(store.FormatRecords([]store.TimeRecord{tr}, tt.f, tt.l))
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", tt.f, err)
+		}
+		if want, got := "["+tt.out+"]", string(b); want != got {
+			t.Errorf("%s:\nwant\n%s\ngot\n%s", tt.f, want, got)
+		}
+	}
+
+	// the store reads the wall clock in the user's location with a +00 offset
+	read := tr
+	read.Start = time.Date(2020, time.January, 25, 9, 0, 0, 0, time.FixedZone("", 0))
+	read.Stop = time.Date(2020, time.January, 25, 10, 30, 0, 0, time.FixedZone("", 0))
+	for _, tt := range tests {
+		b, err := json.Marshal(store.FormatRecords([]store.TimeRecord{read}, tt.f, tt.l))
+		if err != nil {
+			t.Fatalf("%s: unexpected err: %v", tt.f, err)
+		}
+		if want, got := "["+tt.out+"]", string(b); want != got {
+			t.Errorf("%s of read record:\nwant\n%s\ngot\n%s", tt.f, want, got)
+		}
+	}
+
+	for s, want := range map[string]store.Format{"": store.FormatHuman, "RFC3339": store.FormatRFC3339} {
+		if got, err := store.ParseFormat(s); err != nil || got != want {
+			t.Errorf("%q: want format %s got %s, %v", s, want, got, err)
+		}
+	}
+	if _, err := store.ParseFormat("unix"); err == nil {
+		t.Error("unknown format: want error")
+	}
+}
+
+func TestFormatISODuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                                  "PT0S",
+		90 * time.Minute:                   "PT1H30M",
+		26*time.Hour + 5*time.Second:       "PT26H5S",
+		-(45*time.Minute + 30*time.Second): "-PT45M30S",
+	}
+	for d, want := range tests {
+		if got := store.FormatISODuration(d); want != got {
+			t.Errorf("%s: want %s got %s", d, want, got)
+		}
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to store.TimesheetState
+		want     bool
+	}{
+		{store.TimesheetDraft, store.TimesheetSubmitted, true},
+		{store.TimesheetDraft, store.TimesheetApproved, false},
+		{store.TimesheetSubmitted, store.TimesheetApproved, true},
+		{store.TimesheetSubmitted, store.TimesheetRejected, true},
+		{store.TimesheetRejected, store.TimesheetSubmitted, true},
+		{store.TimesheetRejected, store.TimesheetApproved, false},
+		{store.TimesheetApproved, store.TimesheetSubmitted, false},
+		{store.TimesheetApproved, store.TimesheetRejected, false},
+	}
+	for _, tc := range tests {
+		if got := tc.from.CanTransition(tc.to); got != tc.want {
+			t.Errorf("%s to %s: want %t got %t", tc.from, tc.to, tc.want, got)
+		}
+	}
+}
+
+func TestValidUUID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a41", true},
+		{"9B2F5F3E-4A8C-4D2E-9F1A-0C6D2B7E8A41", true},
+		{"9b2f5f3e4a8c4d2e9f1a0c6d2b7e8a41", false},
+		{"9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a4", false},
+		{"9b2f5f3e-4a8c-4d2e-9f1a_0c6d2b7e8a41", false},
+		{"9b2f5f3e-4a8c-4d2e-9f1a-0c6d2b7e8a4g", false},
+		{"", false},
+	}
+	for _, tc := range tests {
+		if got := store.ValidUUID(tc.in); got != tc.want {
+			t.Errorf("%q: want %t got %t", tc.in, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/events"
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/lib/pq"
+)
+
+// insertEvent adds an event to the webhook outbox of every subscription to
+// the event. It must be called in the transaction making the change, so an
+// event is delivered if and only if the change is committed. The data is
+// delivered in its JSON representation. Returns the event to publish to live
+// subscribers once the transaction is committed, see commit.
+func insertEvent(ctx context.Context, tx *sql.Tx, event string, userID uint64, data interface{}) (events.Event, error) {
+	e := events.Event{Type: event, UserID: userID}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return e, err
+	}
+	e.Data = b
+	_, err = tx.ExecContext(ctx, `
+  INSERT INTO webhook_outbox(
+    subscription_id,
+	event,
+	data)
+  SELECT id, $1::text, $3::jsonb
+  FROM webhook_subscriptions
+  WHERE $1::text = ANY(events)
+  AND (user_id IS NULL OR user_id = $2)
+  `, event, userID, jsonValue(b))
+	return e, err
+}
+
+// CreateWebhook stores a new webhook subscription. The returned subscription
+// does not contain the secret.
+func (ts *TimeRecordStore) CreateWebhook(ctx context.Context, s webhook.Subscription) (_ *webhook.Subscription, err error) {
+	ctx, end := ts.instrument(ctx, "CreateWebhook")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	var userID sql.NullInt64
+	if s.UserID != 0 {
+		userID = sql.NullInt64{Int64: int64(s.UserID), Valid: true}
+	}
+	err = db.QueryRowContext(ctx, `
+  INSERT INTO webhook_subscriptions(
+    user_id,
+	url,
+	secret,
+	events)
+  VALUES($1,$2,$3,$4)
+  RETURNING id, created_at
+  `, userID, s.URL, s.Secret, pq.Array(s.Events)).Scan(&s.SubscriptionID, &s.Created)
+	if err != nil {
+		return nil, err
+	}
+	s.Secret = ""
+	return &s, nil
+}
+
+// Webhooks returns all webhook subscriptions without their secrets.
+func (ts *TimeRecordStore) Webhooks(ctx context.Context) (_ []webhook.Subscription, err error) {
+	ctx, end := ts.instrument(ctx, "Webhooks")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT id, COALESCE(user_id, 0), url, events, created_at
+  FROM webhook_subscriptions
+  ORDER BY id;
+  `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := make([]webhook.Subscription, 0)
+	for rows.Next() {
+		var s webhook.Subscription
+		if err := rows.Scan(&s.SubscriptionID, &s.UserID, &s.URL, pq.Array(&s.Events), &s.Created); err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// DeleteWebhook deletes a webhook subscription including its pending
+// messages and delivery log. Returns ErrNotFound if it does not exist.
+func (ts *TimeRecordStore) DeleteWebhook(ctx context.Context, id uint64) (err error) {
+	ctx, end := ts.instrument(ctx, "DeleteWebhook")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the latest delivery attempts of a subscription, latest
+// first. Returns ErrNotFound if the subscription does not exist.
+func (ts *TimeRecordStore) Deliveries(ctx context.Context, subscriptionID uint64, limit int) (_ []webhook.Delivery, err error) {
+	ctx, end := ts.instrument(ctx, "Deliveries")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `
+  SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = $1)
+  `, subscriptionID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := db.QueryContext(ctx, `
+  SELECT
+    d.id,
+	d.outbox_id,
+	d.subscription_id,
+	o.event,
+	d.attempt,
+	d.status_code,
+	d.error,
+	d.duration_ms,
+	d.delivered_at
+  FROM webhook_deliveries AS d
+  JOIN webhook_outbox AS o ON o.id = d.outbox_id
+  WHERE d.subscription_id = $1
+  ORDER BY d.id DESC
+  LIMIT $2;
+  `, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		var d webhook.Delivery
+		var ms int64
+		if err := rows.Scan(
+			&d.DeliveryID,
+			&d.MessageID,
+			&d.SubscriptionID,
+			&d.Event,
+			&d.Attempt,
+			&d.StatusCode,
+			&d.Error,
+			&ms,
+			&d.Delivered); err != nil {
+			return nil, err
+		}
+		d.Duration = time.Duration(ms) * time.Millisecond
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimMessages returns up to limit pending messages of the webhook outbox
+// which are due for delivery, oldest first. The messages are hidden from
+// other dispatchers for the duration of the lease, so they are delivered
+// again if the dispatcher dies before logging the delivery.
+func (ts *TimeRecordStore) ClaimMessages(ctx context.Context, limit int, lease time.Duration) (_ []webhook.Message, err error) {
+	ctx, end := ts.instrument(ctx, "ClaimMessages")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+  UPDATE webhook_outbox AS o
+  SET next_attempt_at = now() + $2::bigint * interval '1 millisecond'
+  FROM webhook_subscriptions AS s
+  WHERE s.id = o.subscription_id
+  AND o.id IN (
+    SELECT id FROM webhook_outbox
+    WHERE state = 'pending'
+    AND next_attempt_at <= now()
+    ORDER BY id
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED)
+  RETURNING
+    o.id,
+	o.subscription_id,
+	s.url,
+	s.secret,
+	o.event,
+	o.data,
+	o.created_at,
+	o.attempts
+  `, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgs := make([]webhook.Message, 0)
+	for rows.Next() {
+		var m webhook.Message
+		var data []byte
+		if err := rows.Scan(
+			&m.MessageID,
+			&m.SubscriptionID,
+			&m.URL,
+			&m.Secret,
+			&m.Event,
+			&data,
+			&m.Created,
+			&m.Attempts); err != nil {
+			return nil, err
+		}
+		m.Data = data
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// LogDelivery appends a delivery attempt to the delivery log and updates the
+// state of the message. Failed messages are retried at next or given up if
+// next is zero.
+func (ts *TimeRecordStore) LogDelivery(ctx context.Context, d webhook.Delivery, next time.Time) (err error) {
+	ctx, end := ts.instrument(ctx, "LogDelivery")
+	defer end(&err)
+	db := ts.db.GetDB()
+	ctx, cancel := ts.db.RequestContext(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after commit
+
+	if _, err := tx.ExecContext(ctx, `
+  INSERT INTO webhook_deliveries(
+    outbox_id,
+	subscription_id,
+	attempt,
+	status_code,
+	error,
+	duration_ms)
+  VALUES($1,$2,$3,$4,$5,$6)
+  `,
+		d.MessageID,
+		d.SubscriptionID,
+		d.Attempt,
+		d.StatusCode,
+		d.Error,
+		d.Duration.Milliseconds()); err != nil {
+		return err
+	}
+
+	state := "pending"
+	switch {
+	case d.OK():
+		state = "delivered"
+	case next.IsZero():
+		state = "failed"
+	}
+	if _, err := tx.ExecContext(ctx, `
+  UPDATE webhook_outbox
+  SET state = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at)
+  WHERE id = $1
+  `, d.MessageID, state, d.Attempt, nullTime(next)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// nullTime returns a nullable column value which is NULL for the zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,100 @@
+// Package tracing sets up the export of OpenTelemetry traces.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/exporters/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Exporters of traces.
+const (
+	None   = "none"   // traces are propagated but not recorded
+	Stdout = "stdout" // spans are written to stdout as JSON
+	File   = "file"   // spans are appended to a file as JSON
+	Zipkin = "zipkin" // spans are sent to a Zipkin collector
+)
+
+// Exporters are the names of the exporters to choose from.
+var Exporters = []string{None, Stdout, File, Zipkin}
+
+// Config configures the export of traces.
+type Config struct {
+	Service     string
+	Version     string
+	Exporter    string  // one of Exporters
+	File        string  // path of the file exporter
+	ZipkinURL   string  // span endpoint of the Zipkin collector
+	SampleRatio float64 // ratio of traces started by the service which are sampled
+}
+
+// Provider exports the spans of the service.
+type Provider struct {
+	tp   *sdktrace.TracerProvider
+	file io.Closer
+}
+
+// Setup installs the W3C trace context propagator and a tracer provider
+// exporting spans as configured. Traces started by callers, with a sampled
+// traceparent header, are sampled regardless of the ratio.
+func Setup(cfg Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	p := &Provider{}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case None, "":
+		return p, nil
+	case Stdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case File:
+		var f *os.File
+		if f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return nil, err
+		}
+		p.file = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case Zipkin:
+		exporter, err = zipkin.New(cfg.ZipkinURL)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String(cfg.Service),
+			semconv.ServiceVersionKey.String(cfg.Version))),
+	)
+	otel.SetTracerProvider(p.tp)
+	return p, nil
+}
+
+// Shutdown exports the spans ended so far and stops the export.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	err := p.tp.Shutdown(ctx)
+	if p.file != nil {
+		if cerr := p.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// MaxAttempts is the number of attempts after which a message is given up.
+	MaxAttempts = 12
+	// batchSize is the maximum number of messages delivered concurrently.
+	batchSize = 50
+	// minLease is the minimum time claimed messages are hidden from other
+	// dispatchers, see leaseFor.
+	minLease = 5 * time.Minute
+	// leaseMargin is the time left to log a delivery after the HTTP client
+	// gave up on the receiver.
+	leaseMargin = time.Minute
+
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+)
+
+// Store provides the messages of the outbox.
+type Store interface {
+	// ClaimMessages returns up to limit messages which are due for delivery
+	// and hides them from other dispatchers for the duration of the lease.
+	ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]Message, error)
+	// LogDelivery records a delivery attempt. If it failed, the message is
+	// retried at next or given up if next is zero.
+	LogDelivery(ctx context.Context, d Delivery, next time.Time) error
+}
+
+// Backoff returns the delay before the next attempt after the given number of
+// failed attempts. The delay doubles with every attempt starting at 30
+// seconds, up to 6 hours.
+func Backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// leaseFor returns the time messages delivered by a client with the timeout
+// are claimed. It is longer than the timeout, so messages are not claimed
+// again and delivered twice while their delivery is running.
+func leaseFor(timeout time.Duration) time.Duration {
+	if l := timeout + leaseMargin; l > minLease {
+		return l
+	}
+	return minLease
+}
+
+// Dispatcher delivers the messages of the outbox to the subscribers.
+type Dispatcher struct {
+	store    Store
+	client   *http.Client
+	lease    time.Duration
+	interval time.Duration
+	logger   zerolog.Logger
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New returns a Dispatcher which polls the outbox every interval and delivers
+// messages with client. The client's timeout limits the time waited for a
+// receiver, it must be set so messages are not claimed forever.
+func New(s Store, client *http.Client, interval time.Duration, logger zerolog.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:    s,
+		client:   client,
+		lease:    leaseFor(client.Timeout),
+		interval: interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run delivers messages until Shutdown is called.
+func (d *Dispatcher) Run() {
+	defer close(d.done)
+	d.logger.Info().Msgf("delivering webhooks every %s", d.interval)
+
+	// running deliveries are canceled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-d.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		for d.dispatch(ctx) == batchSize {
+			// keep going while the outbox is backed up
+		}
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// dispatch delivers a batch of due messages and returns the number of
+// messages in the batch.
+func (d *Dispatcher) dispatch(ctx context.Context) int {
+	msgs, err := d.store.ClaimMessages(ctx, batchSize, d.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Error().Err(err).Msg("failed to claim webhook messages")
+		}
+		return 0
+	}
+	var wg sync.WaitGroup
+	for _, m := range msgs {
+		wg.Add(1)
+		go func(m Message) {
+			defer wg.Done()
+			d.deliver(ctx, m)
+		}(m)
+	}
+	wg.Wait()
+	return len(msgs)
+}
+
+// deliver sends a message to the subscriber and logs the attempt.
+func (d *Dispatcher) deliver(ctx context.Context, m Message) {
+	dl := Delivery{
+		MessageID:      m.MessageID,
+		SubscriptionID: m.SubscriptionID,
+		Event:          m.Event,
+		Attempt:        m.Attempts + 1,
+	}
+	start := time.Now()
+	code, err := d.send(ctx, m)
+	dl.StatusCode = code
+	dl.Duration = time.Since(start)
+	if err != nil {
+		if ctx.Err() != nil {
+			return // shutting down, the message is retried after the lease
+		}
+		dl.Error = err.Error()
+	}
+
+	var next time.Time
+	if !dl.OK() && dl.Attempt < MaxAttempts {
+		next = time.Now().Add(Backoff(dl.Attempt))
+	}
+	logger := d.logger.With().
+		Uint64("message_id", m.MessageID).
+		Uint64("subscription_id", m.SubscriptionID).
+		Int("attempt", dl.Attempt).
+		Int("status", dl.StatusCode).
+		Logger()
+	switch {
+	case dl.OK():
+		logger.Debug().Msg("delivered webhook")
+	case next.IsZero():
+		logger.Error().Str("error", dl.Error).Msg("giving up webhook delivery")
+	default:
+		logger.Warn().Str("error", dl.Error).Time("retry", next).Msg("failed webhook delivery")
+	}
+	if err := d.store.LogDelivery(ctx, dl, next); err != nil && ctx.Err() == nil {
+		logger.Error().Err(err).Msg("failed to log webhook delivery")
+	}
+}
+
+// send posts the signed payload of a message to the subscriber and returns
+// the status code of the response.
+func (d *Dispatcher) send(ctx context.Context, m Message) (int, error) {
+	body, err := m.Payload()
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("POST", m.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "time-tracker-webhook")
+	req.Header.Set(IDHeader, strconv.FormatUint(m.MessageID, 10))
+	req.Header.Set(EventHeader, m.Event)
+	req.Header.Set(SignatureHeader, Sign(m.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	return resp.StatusCode, nil
+}
+
+// Shutdown stops the dispatcher and waits for running deliveries to finish or
+// to be canceled, at the latest until ctx is done.
+func (d *Dispatcher) Shutdown(ctx context.Context) {
+	d.logger.Info().Msg("shutting down webhook dispatcher")
+	close(d.stop)
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		d.logger.Error().Err(ctx.Err()).Msg("webhook dispatcher shutdown error")
+	}
+}
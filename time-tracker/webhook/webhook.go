@@ -0,0 +1,175 @@
+// Package webhook notifies subscribers of changes to time records and timers.
+//
+// Events are written to an outbox in the transaction making the change and
+// are delivered by a Dispatcher in the background, so slow or unavailable
+// receivers never delay API requests. Failed deliveries are retried with
+// exponential backoff.
+//
+// Every delivery is a POST request with a JSON payload signed with the
+// subscription's secret. Receivers verify the Webhook-Signature header with Verify.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Event types subscribers can subscribe to.
+const (
+	RecordCreated  = "record.created"
+	RecordUpdated  = "record.updated"
+	RecordDeleted  = "record.deleted"
+	RecordRestored = "record.restored"
+	TimerStarted   = "timer.started"
+	TimerStopped   = "timer.stopped"
+)
+
+// Events are all event types.
+var Events = []string{
+	RecordCreated,
+	RecordUpdated,
+	RecordDeleted,
+	RecordRestored,
+	TimerStarted,
+	TimerStopped,
+}
+
+// Headers of delivery requests.
+const (
+	SignatureHeader = "Webhook-Signature"
+	EventHeader     = "Webhook-Event"
+	IDHeader        = "Webhook-Id"
+)
+
+// minSecretLength is the minimum length of subscription secrets.
+const minSecretLength = 16
+
+// Subscription subscribes a URL to events. The secret is used to sign the
+// payloads and is never returned by the API.
+type Subscription struct {
+	SubscriptionID uint64    `json:"subscription_id"`
+	UserID         uint64    `json:"user_id,omitempty"` // 0 subscribes to events of all users
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"`
+	Events         []string  `json:"events"`
+	Created        time.Time `json:"created_at"`
+}
+
+// Validate checks that the subscription has an absolute HTTP URL, a secret
+// of sufficient length and known event types.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	if len(s.Secret) < minSecretLength {
+		return errors.New("secret is too short")
+	}
+	if len(s.Events) == 0 {
+		return errors.New("missing event types")
+	}
+	for _, e := range s.Events {
+		if !knownEvent(e) {
+			return errors.New("unknown event type " + e)
+		}
+	}
+	return nil
+}
+
+func knownEvent(e string) bool {
+	for _, known := range Events {
+		if e == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Message is an event in the outbox waiting to be delivered to a subscriber.
+type Message struct {
+	MessageID      uint64
+	SubscriptionID uint64
+	URL            string
+	Secret         string
+	Event          string
+	Data           json.RawMessage
+	Created        time.Time
+	Attempts       int // number of failed delivery attempts so far
+}
+
+// Payload returns the JSON body delivered for the message. The body is the
+// same for every attempt, receivers can use the id to detect duplicates.
+func (m Message) Payload() ([]byte, error) {
+	return json.Marshal(struct {
+		ID      uint64          `json:"id"`
+		Event   string          `json:"event"`
+		Created int64           `json:"created_at"`
+		Data    json.RawMessage `json:"data"`
+	}{m.MessageID, m.Event, m.Created.Unix(), m.Data})
+}
+
+// Delivery is an attempt to deliver a message, as shown in the delivery log.
+type Delivery struct {
+	DeliveryID     uint64        `json:"delivery_id"`
+	MessageID      uint64        `json:"message_id"`
+	SubscriptionID uint64        `json:"subscription_id"`
+	Event          string        `json:"event"`
+	Attempt        int           `json:"attempt"`
+	StatusCode     int           `json:"status_code,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	Duration       time.Duration `json:"-"` // marshaled in milliseconds
+	Delivered      time.Time     `json:"delivered_at"`
+}
+
+// OK reports whether the receiver accepted the message.
+func (d Delivery) OK() bool {
+	return d.Error == "" && d.StatusCode >= 200 && d.StatusCode < 300
+}
+
+// MarshalJSON formats the duration in milliseconds.
+func (d Delivery) MarshalJSON() ([]byte, error) {
+	type delivery Delivery // prevents recursion
+	return json.Marshal(struct {
+		delivery
+		Duration int64 `json:"duration_ms"`
+	}{delivery(d), d.Duration.Milliseconds()})
+}
+
+// UnmarshalJSON parses the duration in milliseconds.
+func (d *Delivery) UnmarshalJSON(data []byte) error {
+	type delivery Delivery // prevents recursion
+	v := struct {
+		*delivery
+		Duration int64 `json:"duration_ms"`
+	}{delivery: (*delivery)(d)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	d.Duration = time.Duration(v.Duration) * time.Millisecond
+	return nil
+}
+
+// Sign returns the signature of a payload, which is the hex encoded
+// HMAC-SHA256 of the payload prefixed with the algorithm.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of the payload.
+func Verify(secret string, payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, payload)))
+}
@@ -0,0 +1,206 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fgrimme/time-tracker/time-tracker/webhook"
+	"github.com/rs/zerolog"
+)
+
+func TestSign(t *testing.T) {
+	payload := []byte(`{"id":1}`)
+	sig := webhook.Sign("0123456789abcdef", payload)
+	if !webhook.Verify("0123456789abcdef", payload, sig) {
+		t.Errorf("want valid signature %s", sig)
+	}
+	if webhook.Verify("fedcba9876543210", payload, sig) {
+		t.Error("want invalid signature for other secret")
+	}
+	if webhook.Verify("0123456789abcdef", []byte(`{"id":2}`), sig) {
+		t.Error("want invalid signature for other payload")
+	}
+	if webhook.Verify("0123456789abcdef", payload, sig[len("sha256="):]) {
+		t.Error("want invalid signature without algorithm")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tc := range tests {
+		if got := webhook.Backoff(tc.attempts); got != tc.want {
+			t.Errorf("attempt %d: want %s got %s", tc.attempts, tc.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		d string               // description of test case
+		s webhook.Subscription // subscription to validate
+		e bool                 // expect error
+	}{
+		{
+			d: "expect valid subscription",
+			s: webhook.Subscription{URL: "https://example.com/hook", Secret: "0123456789abcdef", Events: []string{webhook.RecordCreated}},
+		},
+		{
+			d: "expect error for relative url",
+			s: webhook.Subscription{URL: "/hook", Secret: "0123456789abcdef", Events: []string{webhook.RecordCreated}},
+			e: true,
+		},
+		{
+			d: "expect error for other scheme",
+			s: webhook.Subscription{URL: "ftp://example.com/hook", Secret: "0123456789abcdef", Events: []string{webhook.RecordCreated}},
+			e: true,
+		},
+		{
+			d: "expect error for short secret",
+			s: webhook.Subscription{URL: "https://example.com/hook", Secret: "secret", Events: []string{webhook.RecordCreated}},
+			e: true,
+		},
+		{
+			d: "expect error for unknown event",
+			s: webhook.Subscription{URL: "https://example.com/hook", Secret: "0123456789abcdef", Events: []string{"record.eaten"}},
+			e: true,
+		},
+		{
+			d: "expect error without events",
+			s: webhook.Subscription{URL: "https://example.com/hook", Secret: "0123456789abcdef"},
+			e: true,
+		},
+	}
+	for _, tc := range tests {
+		if err := tc.s.Validate(); (err != nil) != tc.e {
+			t.Errorf("%s: unexpected error %v", tc.d, err)
+		}
+	}
+}
+
+// mockStore hands out its messages once and records the logged deliveries.
+type mockStore struct {
+	mu         sync.Mutex
+	msgs       []webhook.Message
+	deliveries []webhook.Delivery
+	next       []time.Time
+	lease      time.Duration
+	logged     chan struct{}
+}
+
+func (ms *mockStore) ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]webhook.Message, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.lease = lease
+	msgs := ms.msgs
+	ms.msgs = nil
+	return msgs, nil
+}
+
+func (ms *mockStore) LogDelivery(ctx context.Context, d webhook.Delivery, next time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deliveries = append(ms.deliveries, d)
+	ms.next = append(ms.next, next)
+	ms.logged <- struct{}{}
+	return nil
+}
+
+func TestDispatcher(t *testing.T) {
+	const secret = "0123456789abcdef"
+	tests := []struct {
+		d        string // description of test case
+		status   int    // response status of the receiver
+		attempts int    // failed attempts of the message
+		ok       bool   // expect successful delivery
+		retry    bool   // expect retry
+	}{
+		{d: "expect delivery", status: http.StatusNoContent, ok: true},
+		{d: "expect retry on error", status: http.StatusInternalServerError, retry: true},
+		{d: "expect to give up after the last attempt", status: http.StatusInternalServerError, attempts: webhook.MaxAttempts - 1},
+	}
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.d, func(t *testing.T) {
+			var got struct {
+				ID    uint64          `json:"id"`
+				Event string          `json:"event"`
+				Data  json.RawMessage `json:"data"`
+			}
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				if !webhook.Verify(secret, body, r.Header.Get(webhook.SignatureHeader)) {
+					t.Errorf("want valid signature")
+				}
+				if want, got := webhook.RecordCreated, r.Header.Get(webhook.EventHeader); want != got {
+					t.Errorf("want event header %s got %s", want, got)
+				}
+				if err := json.Unmarshal(body, &got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			s := &mockStore{
+				msgs: []webhook.Message{{
+					MessageID:      7,
+					SubscriptionID: 3,
+					URL:            receiver.URL,
+					Secret:         secret,
+					Event:          webhook.RecordCreated,
+					Data:           json.RawMessage(`{"record_id":1}`),
+					Created:        time.Now(),
+					Attempts:       tt.attempts,
+				}},
+				logged: make(chan struct{}, 1),
+			}
+			client := receiver.Client()
+			client.Timeout = 10 * time.Minute
+			d := webhook.New(s, client, time.Hour, zerolog.Nop())
+			go d.Run()
+			select {
+			case <-s.logged:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for delivery")
+			}
+			d.Shutdown(context.Background())
+
+			// deliveries may take up to the client timeout
+			if s.lease <= client.Timeout {
+				t.Errorf("want lease longer than the client timeout %s got %s", client.Timeout, s.lease)
+			}
+			if got.ID != 7 || string(got.Data) != `{"record_id":1}` {
+				t.Errorf("unexpected payload %+v", got)
+			}
+			dl := s.deliveries[0]
+			if dl.OK() != tt.ok {
+				t.Errorf("want ok %t got delivery %+v", tt.ok, dl)
+			}
+			if want, got := tt.attempts+1, dl.Attempt; want != got {
+				t.Errorf("want attempt %d got %d", want, got)
+			}
+			if retry := !tt.ok && !s.next[0].IsZero(); retry != tt.retry {
+				t.Errorf("want retry %t got next attempt %s", tt.retry, s.next[0])
+			}
+		})
+	}
+}